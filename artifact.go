@@ -0,0 +1,204 @@
+package thema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/format"
+	"github.com/cockroachdb/errors"
+
+	"github.com/grafana/thema/internal/envvars"
+)
+
+// BindArtifact records that a lineage passed all of the checks performed by
+// [BindLineage]. Passing an artifact to BindLineage via [TrustBindArtifact]
+// allows the most expensive of those checks to be skipped when binding the
+// same lineage again, such as on each startup of a program.
+//
+// An artifact is typically produced once, by [NewBindArtifact] at code
+// generation time, and serialized as JSON or Go code.
+type BindArtifact struct {
+	// Name is the name of the lineage.
+	Name string `json:"name"`
+
+	// Fingerprint is the fingerprint of the CUE source from which the lineage
+	// was built, as returned from [SourceFingerprint].
+	Fingerprint string `json:"fingerprint"`
+
+	// Versions are the versions of all schemas in the lineage, in order.
+	Versions []SyntacticVersion `json:"versions"`
+}
+
+// NewBindArtifact creates a [BindArtifact] for the provided lineage, which
+// must have been built from the provided CUE instance. As lineages can only be
+// created by [BindLineage], the lineage is known to have passed all checks.
+func NewBindArtifact(lin Lineage, inst *build.Instance) (*BindArtifact, error) {
+	isValidLineage(lin)
+
+	fp, err := SourceFingerprint(inst)
+	if err != nil {
+		return nil, err
+	}
+	return &BindArtifact{
+		Name:        lin.Name(),
+		Fingerprint: fp,
+		Versions:    append([]SyntacticVersion(nil), lin.allVersions()...),
+	}, nil
+}
+
+// TrustBindArtifact indicates that [BindLineage] may trust that the lineage
+// being bound, built from the provided CUE instance, is valid if it matches
+// the provided [BindArtifact]. BindLineage then skips the checks of backwards
+// compatibility between schemas, and of the completeness of the lineage's CUE
+// lenses. All other checks are still performed.
+//
+// The lineage matches the artifact if its name and schema versions, and the
+// fingerprint of the instance, are all the same as those recorded in the
+// artifact. If they are not, BindLineage silently falls back to performing
+// all checks. Checks on [ImperativeLenses] are always performed, as Go lenses
+// do not contribute to the fingerprint.
+//
+// As with [SkipBuggyChecks], setting the THEMA_FORCEVERIFY environment
+// variable causes the artifact to be ignored.
+func TrustBindArtifact(art *BindArtifact, inst *build.Instance) BindOption {
+	return func(c *bindConfig) {
+		if !envvars.ForceVerify {
+			c.artifact, c.artifactInst = art, inst
+		}
+	}
+}
+
+// SourceFingerprint returns a fingerprint of the CUE source of the provided
+// instance, as a hex-encoded SHA-256 hash.
+//
+// The fingerprint covers the source files of the instance and of all the
+// packages it imports, and the CUE definitions of Thema itself, as changes
+// to any of these may change the outcome of the checks performed by
+// [BindLineage]. As CUE source is hashed without being evaluated, computing
+// the fingerprint is much cheaper than the checks it allows to be skipped.
+func SourceFingerprint(inst *build.Instance) (string, error) {
+	if inst == nil {
+		return "", errors.New("nil build instance")
+	}
+	rtfp, err := runtimeFingerprint()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(rtfp)
+	seen := make(map[*build.Instance]bool)
+	var hashInst func(inst *build.Instance) error
+	hashInst = func(inst *build.Instance) error {
+		// Thema's own packages are covered by the runtime fingerprint
+		if seen[inst] || inst.ImportPath == themaModule || strings.HasPrefix(inst.ImportPath, themaModule+"/") {
+			return nil
+		}
+		seen[inst] = true
+		if inst.Err != nil {
+			return fmt.Errorf("unable to fingerprint instance with errors: %w", inst.Err)
+		}
+
+		fmt.Fprintf(h, "%s\x00%d\x00", inst.ImportPath, len(inst.BuildFiles))
+		for _, f := range inst.BuildFiles {
+			b, err := sourceBytes(f)
+			if err != nil {
+				return fmt.Errorf("unable to read %s for fingerprinting: %w", f.Filename, err)
+			}
+			fmt.Fprintf(h, "%d\x00", len(b))
+			h.Write(b)
+		}
+		// Imports are sorted by import path
+		for _, imp := range inst.Imports {
+			if err := hashInst(imp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := hashInst(inst); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+const themaModule = "github.com/grafana/thema"
+
+// sourceBytes returns the source of the provided file, as read by the CUE
+// loader.
+func sourceBytes(f *build.File) ([]byte, error) {
+	switch src := f.Source.(type) {
+	case nil:
+		return os.ReadFile(f.Filename)
+	case []byte:
+		return src, nil
+	case string:
+		return []byte(src), nil
+	case *ast.File:
+		return format.Node(src)
+	default:
+		return nil, fmt.Errorf("unsupported source type %T", src)
+	}
+}
+
+var (
+	rtfpOnce sync.Once
+	rtfp     []byte
+	rtfpErr  error
+)
+
+// runtimeFingerprint returns a hash of all the files in [CueJointFS].
+func runtimeFingerprint() ([]byte, error) {
+	rtfpOnce.Do(func() {
+		h := sha256.New()
+		// WalkDir visits files in lexical order
+		rtfpErr = fs.WalkDir(CueJointFS, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			f, err := CueJointFS.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close() // nolint: errcheck
+
+			io.WriteString(h, path) // nolint: errcheck
+			_, err = io.Copy(h, f)
+			return err
+		})
+		rtfp = h.Sum(nil)
+	})
+	return rtfp, rtfpErr
+}
+
+// matches reports whether the artifact was produced from a lineage with the
+// provided name, built from the provided instance.
+func (art *BindArtifact) matches(name string, inst *build.Instance) bool {
+	if art.Name != name || inst == nil {
+		return false
+	}
+	fp, err := SourceFingerprint(inst)
+	return err == nil && fp == art.Fingerprint
+}
+
+// matchesVersions reports whether the artifact records exactly the provided
+// schema versions.
+func (art *BindArtifact) matchesVersions(allv []SyntacticVersion) bool {
+	if len(art.Versions) != len(allv) {
+		return false
+	}
+	for i, v := range art.Versions {
+		if allv[i] != v {
+			return false
+		}
+	}
+	return true
+}
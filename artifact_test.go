@@ -0,0 +1,144 @@
+package thema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/load"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+const artifactIncompatLinstr = `name: "ship"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		kind: "ship"
+		version?: string
+		name: string
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		kind: "ship"
+		version?: string
+		name: int
+	}
+}]
+`
+
+const artifactUnorderedLinstr = `name: "ship"
+schemas: [{
+	version: [0, 1]
+	schema: name: string
+}, {
+	version: [0, 0]
+	schema: name: string
+}]
+`
+
+func loadInstanceString(t testing.TB, src string) *build.Instance {
+	t.Helper()
+	inst := load.Instances([]string{"-"}, &load.Config{
+		Stdin: strings.NewReader(src),
+	})[0]
+	require.NoError(t, inst.Err)
+	return inst
+}
+
+func TestBindArtifact(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	inst := loadInstanceString(t, registryShipLinstr)
+	lin, err := BindLineage(ctx.BuildInstance(inst), rt)
+	require.NoError(t, err)
+
+	art, err := NewBindArtifact(lin, inst)
+	require.NoError(t, err)
+	assert.Equal(t, "ship", art.Name)
+	assert.Equal(t, []SyntacticVersion{SV(0, 0), SV(0, 1)}, art.Versions)
+
+	t.Run("roundtrip", func(t *testing.T) {
+		b, err := json.Marshal(art)
+		require.NoError(t, err)
+		var got BindArtifact
+		require.NoError(t, json.Unmarshal(b, &got))
+		assert.Equal(t, *art, got)
+	})
+
+	t.Run("fingerprint", func(t *testing.T) {
+		fp, err := SourceFingerprint(loadInstanceString(t, registryShipLinstr))
+		require.NoError(t, err)
+		assert.Equal(t, art.Fingerprint, fp, "fingerprint of the same source should be stable")
+
+		fp, err = SourceFingerprint(loadInstanceString(t, registryShipLinstr+"\n// a comment\n"))
+		require.NoError(t, err)
+		assert.NotEqual(t, art.Fingerprint, fp, "fingerprint should change with the source")
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		tinst := loadInstanceString(t, registryShipLinstr)
+		tlin, err := BindLineage(ctx.BuildInstance(tinst), rt, TrustBindArtifact(art, tinst))
+		require.NoError(t, err)
+		assert.Equal(t, lin.allVersions(), tlin.allVersions())
+
+		_, err = SchemaP(tlin, SV(0, 1)).Validate(ctx.CompileString(`{kind: "ship", name: "Argo", crew: 50}`))
+		assert.NoError(t, err)
+		_, err = SchemaP(tlin, SV(0, 0)).Validate(ctx.CompileString(`{kind: "ship", name: "Argo", crew: 50}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("skipscompat", func(t *testing.T) {
+		// An artifact for a lineage bound without compatibility checks is
+		// trusted, showing that the checks are skipped
+		iinst := loadInstanceString(t, artifactIncompatLinstr)
+		ilin, err := BindLineage(ctx.BuildInstance(iinst), rt, SkipBuggyChecks())
+		require.NoError(t, err)
+		iart, err := NewBindArtifact(ilin, iinst)
+		require.NoError(t, err)
+		assert.NotEqual(t, art.Fingerprint, iart.Fingerprint)
+
+		_, err = BindLineage(ctx.BuildInstance(iinst), rt, TrustBindArtifact(iart, iinst))
+		assert.NoError(t, err)
+	})
+
+	t.Run("checksothers", func(t *testing.T) {
+		// Checks other than compatibility are performed even when the
+		// artifact matches
+		uinst := loadInstanceString(t, artifactUnorderedLinstr)
+		fp, err := SourceFingerprint(uinst)
+		require.NoError(t, err)
+		forged := &BindArtifact{
+			Name:        "ship",
+			Fingerprint: fp,
+			Versions:    []SyntacticVersion{SV(0, 1), SV(0, 0)},
+		}
+		_, err = BindLineage(ctx.BuildInstance(uinst), rt, TrustBindArtifact(forged, uinst))
+		assert.True(t, errors.Is(err, terrors.ErrInvalidSchemasOrder), "unexpected error: %s", err)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		// Same name, but the second schema is not backwards compatible
+		iinst := loadInstanceString(t, artifactIncompatLinstr)
+		_, err := BindLineage(ctx.BuildInstance(iinst), rt, TrustBindArtifact(art, iinst))
+		assert.True(t, errors.Is(err, terrors.ErrInvalidLineage), "unexpected error: %s", err)
+
+		// Without the instance, the artifact cannot be checked
+		_, err = BindLineage(ctx.BuildInstance(iinst), rt, TrustBindArtifact(art, nil))
+		assert.True(t, errors.Is(err, terrors.ErrInvalidLineage), "unexpected error: %s", err)
+
+		// A forged artifact with the right fingerprint but wrong versions also
+		// falls back to a full bind
+		forged := *art
+		forged.Versions = []SyntacticVersion{SV(0, 0)}
+		flin, err := BindLineage(ctx.BuildInstance(inst), rt, TrustBindArtifact(&forged, inst))
+		require.NoError(t, err)
+		assert.Equal(t, lin.allVersions(), flin.allVersions())
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"reflect"
 
 	"cuelang.org/go/cue"
+
+	terrors "github.com/grafana/thema/errors"
 )
 
 // AssignableTo indicates whether all valid instances of the provided Thema
@@ -32,6 +34,38 @@ func AssignableTo(sch Schema, T any) error {
 	return assignable(sch.Underlying().LookupPath(pathSchDef), T)
 }
 
+// TypeRegistry maps the versions of the schemas in a lineage to the Go types
+// that represent them, such as the registries generated by
+// [gocode.GenerateLineageTypesOpenAPI].
+//
+// [gocode.GenerateLineageTypesOpenAPI]: https://pkg.go.dev/github.com/grafana/thema/encoding/gocode#GenerateLineageTypesOpenAPI
+type TypeRegistry map[SyntacticVersion]reflect.Type
+
+// New returns a pointer to a new zero value of the Go type registered for the
+// provided version, suitable for passing to [AssignableTo] or [BindType].
+func (r TypeRegistry) New(v SyntacticVersion) (any, error) {
+	t, has := r[v]
+	if !has {
+		return nil, fmt.Errorf("no Go type registered for version %s: %w", v, terrors.ErrVersionNotExist)
+	}
+	return reflect.New(t).Interface(), nil
+}
+
+// Check verifies that a Go type is registered for every schema in the provided
+// lineage, and that each schema is [AssignableTo] its registered type.
+func (r TypeRegistry) Check(lin Lineage) error {
+	for sch := lin.First(); sch != nil; sch = sch.Successor() {
+		t, err := r.New(sch.Version())
+		if err != nil {
+			return err
+		}
+		if err = AssignableTo(sch, t); err != nil {
+			return fmt.Errorf("schema %s is not assignable to %s: %w", sch.Version(), r[sch.Version()], err)
+		}
+	}
+	return nil
+}
+
 // ErrPointerDepth indicates that a Go type having pointer indirection depth greater than 1, such as
 //
 //	**struct{ V: string })
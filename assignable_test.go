@@ -3,12 +3,15 @@ package thema
 import (
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/cuecontext"
+
+	terrors "github.com/grafana/thema/errors"
 )
 
 func TestAssignable(t *testing.T) {
@@ -438,3 +441,71 @@ func TestNoDeepPointer(t *testing.T) {
 		t.Fatal("unexpected error received when passing pointer with more than one level of indirection")
 	}
 }
+
+func TestTypeRegistry(t *testing.T) {
+	type shipV0 struct {
+		Kind    string  `json:"kind"`
+		Version *string `json:"version,omitempty"`
+		Name    string  `json:"name"`
+	}
+	type shipV1 struct {
+		Kind    string  `json:"kind"`
+		Version *string `json:"version,omitempty"`
+		Name    string  `json:"name"`
+		Crew    *int64  `json:"crew,omitempty"`
+	}
+
+	linstr := `name: "ship"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		kind: "ship"
+		version?: string
+		name: string
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		kind: "ship"
+		version?: string
+		name: string
+		crew?: int64
+	}
+}]
+`
+
+	ctx := cuecontext.New()
+	lin, err := BindLineage(ctx.CompileString(linstr), NewRuntime(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := TypeRegistry{
+		SV(0, 0): reflect.TypeOf(shipV0{}),
+		SV(0, 1): reflect.TypeOf(shipV1{}),
+	}
+	if err = reg.Check(lin); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := reg.New(SV(0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(*shipV1); !ok {
+		t.Fatalf("expected *shipV1, got %T", v)
+	}
+	if _, err = BindType(lin.Latest(), v.(*shipV1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = reg.New(SV(1, 0)); !errors.Is(err, terrors.ErrVersionNotExist) {
+		t.Fatalf("expected ErrVersionNotExist for unregistered version, got %v", err)
+	}
+	if err = (TypeRegistry{SV(0, 0): reflect.TypeOf(shipV0{})}).Check(lin); !errors.Is(err, terrors.ErrVersionNotExist) {
+		t.Fatalf("expected ErrVersionNotExist for lineage with unregistered schema, got %v", err)
+	}
+	if err = (TypeRegistry{SV(0, 0): reflect.TypeOf(shipV1{}), SV(0, 1): reflect.TypeOf(shipV0{})}).Check(lin); err == nil {
+		t.Fatal("expected error when schema is not assignable to registered type")
+	}
+}
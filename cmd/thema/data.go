@@ -13,14 +13,20 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
 	"github.com/grafana/thema/vmux"
 )
 
 type dataCommand struct {
 	format  string
+	output  string
 	quiet   bool
 	inbytes []byte
 
+	// stream and parallel govern translation of streams of records
+	stream   bool
+	parallel int
+
 	datval cue.Value
 
 	lla *lineageLoadArgs
@@ -43,6 +49,7 @@ func (dc *dataCommand) setup(cmd *cobra.Command) {
 	validateCmd.Flags().StringVarP(&dc.lla.verstr, "version", "v", "", "schema syntactic version to validate data against. defaults to latest")
 	validateCmd.Flags().StringVarP(&dc.format, "format", "e", "", "input data format. Autodetected by default, but can be constrained to \"json\" or \"yaml\".")
 	validateCmd.Flags().BoolVarP(&dc.quiet, "quiet", "q", false, "emit no output, exit status only")
+	validateCmd.Flags().StringVarP(&dc.output, "output", "o", "text", "output format. \"text\" or \"json\".")
 	validateCmd.PersistentPreRunE = mergeCobraefuncs(dc.lla.validateLineageInput, dc.lla.validateVersionInputOptional, dc.validateDataInput)
 	validateCmd.RunE = dc.runValidate
 
@@ -57,6 +64,8 @@ func (dc *dataCommand) setup(cmd *cobra.Command) {
 	translateCmd.Flags().StringVarP(&dc.lla.verstr, "to", "v", "", "schema version to translate input data to")
 	translateCmd.MarkFlagRequired("to")
 	translateCmd.Flags().StringVarP(&dc.format, "format", "e", "", "input data format. Autodetected by default, but can be constrained to \"json\" or \"yaml\".")
+	translateCmd.Flags().BoolVar(&dc.stream, "stream", false, "translate a stream of newline-delimited JSON or multi-document YAML records")
+	translateCmd.Flags().IntVarP(&dc.parallel, "parallel", "j", 1, "number of records to translate concurrently. Only valid with --stream")
 	translateCmd.PersistentPreRunE = mergeCobraefuncs(dc.lla.validateLineageInput, dc.lla.validateVersionInput, dc.validateDataInput)
 	translateCmd.RunE = dc.runTranslate
 
@@ -71,6 +80,13 @@ func (dc *dataCommand) setup(cmd *cobra.Command) {
 	dehydrateCmd.Flags().StringVarP(&dc.format, "format", "e", "", "input data format. Autodetected by default, but can be constrained to \"json\" or \"yaml\".")
 	dehydrateCmd.PersistentPreRunE = mergeCobraefuncs(dc.lla.validateLineageInput, dc.lla.validateVersionInputOptional, dc.validateDataInput)
 	dehydrateCmd.RunE = dc.runDehydrate
+
+	dataCmd.AddCommand(dataDiffCmd)
+	dataDiffCmd.Flags().StringVarP(&dc.lla.verstr, "version", "v", "", "schema syntactic version to compare inputs at. Defaults to the version of the newer input")
+	dataDiffCmd.Flags().StringVarP(&dc.format, "format", "e", "", "input data format. Autodetected by default, but can be constrained to \"json\" or \"yaml\".")
+	dataDiffCmd.Flags().StringVarP(&dc.output, "output", "o", "text", "output format. \"text\" or \"json\".")
+	dataDiffCmd.PersistentPreRunE = mergeCobraefuncs(dc.lla.validateLineageInput, dc.lla.validateVersionInputOptional)
+	dataDiffCmd.RunE = dc.runDiff
 }
 
 var dataCmd = &cobra.Command{
@@ -98,6 +114,12 @@ var validateCmd = &cobra.Command{
 ` + dataReuseText + `
 Success outputs nothing and exits 0. Failure outputs the validation problem
 (unless quieted) and exits 1.
+
+With -o json, the output is a JSON array of validation errors, which is empty
+on success. Each error has a "code" classifying the failure, a JSON Pointer
+"dataPath" to the offending value, the "schemaPath" of the field, the
+"expected" and "actual" values, and the source positions of each. Failures
+with the "Unclassified" code are instead described by a "message".
 `,
 	Args: cobra.MaximumNArgs(1),
 }
@@ -107,11 +129,33 @@ func (dc *dataCommand) runValidate(cmd *cobra.Command, args []string) error {
 		panic("datval does not exist")
 	}
 
+	if dc.output != "text" && dc.output != "json" {
+		return fmt.Errorf("unknown output format %q, must be \"text\" or \"json\"", dc.output)
+	}
+
 	_, err := dc.lla.dl.sch.Validate(dc.datval)
-	if err != nil {
+	if dc.output == "text" {
 		return err
 	}
-	return nil
+
+	verrs := terrors.ValidationErrors(err)
+	if err != nil && verrs == nil {
+		// Not a validation failure
+		return err
+	}
+	if verrs == nil {
+		verrs = []*terrors.ValidationError{}
+	}
+	enc := json.NewEncoder(cmd.OutOrStdout())
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if jerr := enc.Encode(verrs); jerr != nil {
+		return jerr
+	}
+
+	// The JSON output already describes the failure
+	cmd.SilenceErrors = true
+	return err
 }
 
 var validateAnyCmd = &cobra.Command{
@@ -156,7 +200,7 @@ func (dc *dataCommand) runValidateAny(cmd *cobra.Command, args []string) error {
 }
 
 var translateCmd = &cobra.Command{
-	Use:   "translate -l <lineage-fs-path> [-p <cue-path>] [--to <synver>] [-e <format>] [<data-fs-path>]",
+	Use:   "translate -l <lineage-fs-path> [-p <cue-path>] [--to <synver>] [-e <format>] [--stream [-j <n>]] [<data-fs-path>]",
 	Short: "Translate some valid input data from one schema to another",
 	Long: `Translate some valid input data from one schema to another.
 ` + dataReuseText + `
@@ -166,11 +210,27 @@ error.
 
 Note that Thema's invariants (once finalized) guarantee that failures can only
 arise during data input decoding or validation, never during translation.
+
+With --stream, the input is instead read as a stream of records, either
+newline-delimited JSON or multi-document YAML, and is not read into memory all
+at once. Each record is validated against any schema in the lineage and
+translated independently. Each line of JSON input must hold exactly one record;
+a line that cannot be decoded fails only that record. One line of JSON is output per record, in input
+order, containing the record's zero-based index along with either the
+translation result and lacunas, or an error. A summary is written to stderr once
+the stream is exhausted, and the exit status is 1 if any record failed.
+
+Records in a stream may be translated concurrently with --parallel. Each
+additional worker binds its own copy of the lineage, which has a fixed startup
+cost, but avoids contention between workers.
 `,
 	Args: cobra.MaximumNArgs(1),
 }
 
 func (dc *dataCommand) runTranslate(cmd *cobra.Command, args []string) error {
+	if dc.stream {
+		return dc.runTranslateStream(cmd, args)
+	}
 	if !dc.datval.Exists() {
 		panic("datval does not exist")
 	}
@@ -185,7 +245,7 @@ func (dc *dataCommand) runTranslate(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	if err = dc.validateTranslationResult(tinst, lac); err != nil {
+	if err = validateTranslationResult(tinst, lac); err != nil {
 		return err
 	}
 
@@ -302,6 +362,88 @@ func (dc *dataCommand) runDehydrate(cmd *cobra.Command, args []string) error {
 	return err
 }
 
+var dataDiffCmd = &cobra.Command{
+	Use:   "diff -l <lineage-fs-path> [-p <cue-path>] [-v <synver>] [-e <format>] [-o <format>] <old-data-fs-path> <new-data-fs-path>",
+	Short: "Show differences between two valid data inputs",
+	Long: `Show differences between two valid data inputs.
+
+A filesystem path to a Thema lineage must be provided. It may be relative or
+absolute. Lineages are necessarily validated prior to validation of the input
+data.
+
+Paths to two data files must be provided, the older followed by the newer. Each
+is validated against any schema in the lineage, then translated to the schema
+at --version, or to the schema of the newer input if omitted. Values that are
+only explicitly set to their schema default are ignored.
+
+Each added, removed and replaced value is printed along with its path and the
+schema for the value. With --output json, the changes are instead printed as a
+JSON Patch (RFC 6902) that transforms the older input into the newer one, with
+the schema for each value in an additional "schema" member.
+
+Any lacunas emitted while translating the inputs are printed to stderr.
+`,
+	Args: cobra.ExactArgs(2),
+}
+
+func (dc *dataCommand) runDiff(cmd *cobra.Command, args []string) error {
+	if dc.output != "text" && dc.output != "json" {
+		return fmt.Errorf("unknown output format %q, must be \"text\" or \"json\"", dc.output)
+	}
+
+	insts := make([]*thema.Instance, len(args))
+	for i, path := range args {
+		byt, err := pathOrStdin(args[i : i+1])
+		if err != nil {
+			return err
+		}
+		v, _, err := decodeData(rt.Context(), byt, dc.format, extFormat(path), path)
+		if err != nil {
+			return err
+		}
+		if insts[i] = dc.lla.dl.lin.ValidateAny(v); insts[i] == nil {
+			return fmt.Errorf("%s: input data is not valid for any schema in lineage", path)
+		}
+	}
+
+	var opts []thema.DiffOption
+	if dc.lla.dl.sch != nil {
+		opts = append(opts, thema.DiffAtVersion(dc.lla.dl.sch.Version()))
+	}
+	d, lac, err := thema.DiffInstances(insts[0], insts[1], opts...)
+	if err != nil {
+		return err
+	}
+	if lac != nil {
+		for _, l := range lac.AsList() {
+			fmt.Fprintf(cmd.ErrOrStderr(), "lacuna (%s): %s\n", l.Type, l.Message)
+		}
+	}
+
+	if dc.output == "json" {
+		byt, err := d.JSONPatch()
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, byt, "", "  "); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), buf.String())
+		return nil
+	}
+
+	if len(d.Changes) == 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "no differences at schema %s\n", d.Version)
+		return nil
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%d changes at schema %s:\n", len(d.Changes), d.Version)
+	for _, c := range d.Changes {
+		fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", c)
+	}
+	return nil
+}
+
 func pathOrStdin(args []string) ([]byte, error) {
 	var byt []byte
 	switch len(args) {
@@ -347,6 +489,14 @@ func pathOrStdin(args []string) ([]byte, error) {
 }
 
 func (dc *dataCommand) validateDataInput(cmd *cobra.Command, args []string) error {
+	if dc.stream {
+		// Streams are read incrementally when the command runs
+		return nil
+	}
+	if cmd.Flags().Changed("parallel") {
+		return errors.New("--parallel may only be used with --stream")
+	}
+
 	var ext string
 
 	byt, err := pathOrStdin(args)
@@ -358,62 +508,71 @@ func (dc *dataCommand) validateDataInput(cmd *cobra.Command, args []string) erro
 	}
 
 	if len(args) == 1 {
-		switch filepath.Ext(args[0]) {
-		case ".json", ".ldjson":
-			ext = "json"
-		case ".yaml", ".yml":
-			ext = "yaml"
-		}
+		ext = extFormat(args[0])
 	}
 
-	jd := vmux.NewJSONCodec("stdin")
-	yd := vmux.NewYAMLCodec("stdin")
+	dc.datval, dc.format, err = decodeData(rt.Context(), dc.inbytes, dc.format, ext, "stdin")
+	return err
+}
+
+// extFormat returns the data format implied by the extension of the provided
+// path, if any.
+func extFormat(path string) string {
+	switch filepath.Ext(path) {
+	case ".json", ".ldjson":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return ""
+	}
+}
 
-	switch dc.format {
+// decodeData decodes the provided bytes into a cue.Value in ctx. If format is empty,
+// JSON and then YAML are attempted, and the format that succeeded is returned.
+// If ext is non-empty, it is the format implied by the input's file extension,
+// and must agree with an explicitly requested format.
+func decodeData(ctx *cue.Context, byt []byte, format, ext, name string) (cue.Value, string, error) {
+	jd := vmux.NewJSONCodec(name)
+	yd := vmux.NewYAMLCodec(name)
+
+	switch format {
 	case "":
 		// Figure it out; try JSON first
-		dc.datval, err = jd.Decode(rt.Underlying().Context(), dc.inbytes)
+		v, err := jd.Decode(ctx, byt)
 		if err == nil {
-			dc.format = "json"
-			break
+			return v, "json", nil
 		}
 		// Nope, try yaml
-		dc.datval, err = yd.Decode(rt.Underlying().Context(), dc.inbytes)
+		v, err = yd.Decode(ctx, byt)
 		if err == nil {
-			dc.format = "yaml"
-			break
+			return v, "yaml", nil
 		}
 		// Double nope
-		return errors.New("unrecognized format of input data")
+		return cue.Value{}, "", errors.New("unrecognized format of input data")
 
 	case "json":
-		if ext != "json" {
-			return fmt.Errorf("JSON input format specified, but file extension is %s", ext)
+		if ext != "" && ext != "json" {
+			return cue.Value{}, "", fmt.Errorf("JSON input format specified, but file extension is %s", ext)
 		}
 
-		dc.datval, err = jd.Decode(rt.Underlying().Context(), dc.inbytes)
-		if err != nil {
-			return err
-		}
+		v, err := jd.Decode(ctx, byt)
+		return v, format, err
 	case "yaml":
-		if ext != "yaml" {
-			return fmt.Errorf("YAML input format specified, but file extension is %s", ext)
+		if ext != "" && ext != "yaml" {
+			return cue.Value{}, "", fmt.Errorf("YAML input format specified, but file extension is %s", ext)
 		}
 
-		dc.datval, err = yd.Decode(rt.Underlying().Context(), dc.inbytes)
-		if err != nil {
-			return err
-		}
+		v, err := yd.Decode(ctx, byt)
+		return v, format, err
 	default:
-		return fmt.Errorf("unknown input format %q requested", dc.format)
+		return cue.Value{}, "", fmt.Errorf("unknown input format %q requested", format)
 	}
-
-	return nil
 }
 
 // Everything here should become unnecessary once Thema's key invariants are in
 // place
-func (dc *dataCommand) validateTranslationResult(tinst *thema.Instance, lac thema.TranslationLacunas) error {
+func validateTranslationResult(tinst *thema.Instance, lac thema.TranslationLacunas) error {
 	if tinst == nil {
 		panic("unreachable, thema.Translate() should never return a nil instance")
 	}
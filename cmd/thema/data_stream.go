@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/spf13/cobra"
+
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/vmux"
+)
+
+// streamRecord is a single input document read from a stream.
+type streamRecord struct {
+	// index is the zero-based position of the record in the input stream.
+	index int
+	byt   []byte
+}
+
+// streamResult is the NDJSON output for a single record in a stream.
+type streamResult struct {
+	Record int `json:"record"`
+	*translationResult
+	Error string `json:"error,omitempty"`
+
+	// from is the version the record validated against, for the summary.
+	from string
+	byt  []byte
+}
+
+// streamSummary is written to stderr after all records in a stream have been
+// processed.
+type streamSummary struct {
+	total, failed int
+	from          map[string]int
+}
+
+func (s streamSummary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "translated %d of %d records", s.total-s.failed, s.total)
+	if s.failed > 0 {
+		fmt.Fprintf(&sb, " (%d failed)", s.failed)
+	}
+
+	vers := make([]string, 0, len(s.from))
+	for v := range s.from {
+		vers = append(vers, v)
+	}
+	sort.Slice(vers, func(i, j int) bool {
+		vi, _ := thema.ParseSyntacticVersion(vers[i])
+		vj, _ := thema.ParseSyntacticVersion(vers[j])
+		return vi.Less(vj)
+	})
+	for i, v := range vers {
+		if i == 0 {
+			sb.WriteString("; input versions:")
+		}
+		fmt.Fprintf(&sb, " %s (%d)", v, s.from[v])
+	}
+	return sb.String()
+}
+
+// streamWorker translates records using its own lineage, bound in a distinct
+// CUE context, so that workers do not contend with one another.
+type streamWorker struct {
+	lin    thema.Lineage
+	to     thema.SyntacticVersion
+	format string
+	name   string
+}
+
+func (dc *dataCommand) runTranslateStream(cmd *cobra.Command, args []string) error {
+	r, ext, err := streamInput(args)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	format := dc.format
+	if format != "" && ext != "" && format != ext {
+		return fmt.Errorf("%s input format specified, but file extension is %s", format, ext)
+	} else if format == "" {
+		format = ext
+	}
+
+	br := bufio.NewReader(r)
+	if format == "" {
+		format = sniffStreamFormat(br)
+	}
+
+	var next func() ([]byte, error)
+	switch format {
+	case "json":
+		next = jsonStream(br)
+	case "yaml":
+		next = yamlStream(br)
+	default:
+		return fmt.Errorf("unknown input format %q requested", format)
+	}
+
+	n := dc.parallel
+	if n < 1 {
+		n = 1
+	}
+	workers := make([]*streamWorker, n)
+	workers[0] = &streamWorker{lin: dc.lla.dl.lin, to: dc.lla.dl.sch.Version(), format: format, name: "stdin"}
+	if len(args) == 1 {
+		workers[0].name = args[0]
+	}
+
+	// Building CUE instances mutates their underlying syntax trees, so the
+	// additional workers' lineages must be loaded one at a time.
+	for i := 1; i < n; i++ {
+		lin, err := loadone(thema.NewRuntime(cuecontext.New()), dc.lla.dl.binst, dc.lla.inputLinFilePath, dc.lla.lincuepath)
+		if err != nil {
+			return fmt.Errorf("error loading lineage for parallel worker: %w", err)
+		}
+		workers[i] = &streamWorker{lin: lin, to: workers[0].to, format: format, name: workers[0].name}
+	}
+
+	var wg sync.WaitGroup
+	in := make(chan streamRecord, n)
+	out := make(chan *streamResult, n)
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *streamWorker) {
+			defer wg.Done()
+			for rec := range in {
+				out <- w.translate(rec)
+			}
+		}(w)
+	}
+
+	// Bound the number of records in flight, as a single slow record would
+	// otherwise cause unbounded buffering of the results that follow it.
+	window := make(chan struct{}, 4*n)
+	var readErr error
+	go func() {
+		defer close(in)
+		for i := 0; ; i++ {
+			window <- struct{}{}
+			byt, err := next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				readErr = fmt.Errorf("error reading record %d: %w", i, err)
+				return
+			}
+			in <- streamRecord{index: i, byt: byt}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	// Results arrive out of order when running in parallel. Buffer them so
+	// that output order matches input order.
+	sum := streamSummary{from: make(map[string]int)}
+	pending := make(map[int]*streamResult)
+	bw := bufio.NewWriter(cmd.OutOrStdout())
+	var writeErr error
+	for res := range out {
+		pending[res.Record] = res
+		for {
+			res, has := pending[sum.total]
+			if !has {
+				break
+			}
+			delete(pending, sum.total)
+			<-window
+			sum.total++
+			if res.Error != "" {
+				sum.failed++
+			} else {
+				sum.from[res.from]++
+			}
+			if writeErr == nil {
+				_, writeErr = bw.Write(append(res.byt, '\n'))
+			}
+		}
+	}
+	if writeErr == nil {
+		writeErr = bw.Flush()
+	}
+
+	fmt.Fprintln(cmd.ErrOrStderr(), sum)
+	switch {
+	case readErr != nil:
+		return readErr
+	case writeErr != nil:
+		return writeErr
+	case sum.failed > 0:
+		return fmt.Errorf("%d of %d records failed translation", sum.failed, sum.total)
+	}
+	return nil
+}
+
+func (w *streamWorker) translate(rec streamRecord) *streamResult {
+	res := &streamResult{Record: rec.index}
+	if err := w.do(rec, res); err != nil {
+		res.translationResult = nil
+		res.Error = err.Error()
+	}
+
+	var err error
+	res.byt, err = json.Marshal(res)
+	if err != nil {
+		res.translationResult = nil
+		res.Error = fmt.Sprintf("error marshaling translation result to JSON: %s", err)
+		res.byt, _ = json.Marshal(res) // nolint: errcheck
+	}
+	return res
+}
+
+func (w *streamWorker) do(rec streamRecord, res *streamResult) error {
+	name := fmt.Sprintf("%s:%d", w.name, rec.index)
+	var dec vmux.Decoder = vmux.NewJSONCodec(name)
+	if w.format == "yaml" {
+		dec = vmux.NewYAMLCodec(name)
+	}
+	v, err := dec.Decode(w.lin.Runtime().Underlying().Context(), rec.byt)
+	if err != nil {
+		return err
+	}
+
+	inst := w.lin.ValidateAny(v)
+	if inst == nil {
+		return errors.New("input data is not valid for any schema in lineage")
+	}
+
+	tinst, lac, err := inst.Translate(w.to)
+	if err != nil {
+		return err
+	}
+	if err = validateTranslationResult(tinst, lac); err != nil {
+		return err
+	}
+
+	res.from = inst.Schema().Version().String()
+	res.translationResult = &translationResult{
+		From:    res.from,
+		To:      tinst.Schema().Version().String(),
+		Result:  tinst.Underlying(),
+		Lacunas: lac,
+	}
+	return nil
+}
+
+// streamInput opens the provided path, or stdin if no path is given, without
+// reading it into memory. The format implied by the file extension, if any,
+// is also returned.
+func streamInput(args []string) (io.ReadCloser, string, error) {
+	switch len(args) {
+	case 0:
+		return io.NopCloser(os.Stdin), "", nil
+	case 1:
+		f, err := os.Open(args[0])
+		if err != nil {
+			return nil, "", fmt.Errorf("could not open provided path: %w", err)
+		}
+		switch filepath.Ext(args[0]) {
+		case ".json", ".ldjson", ".ndjson", ".jsonl":
+			return f, "json", nil
+		case ".yaml", ".yml":
+			return f, "yaml", nil
+		}
+		return f, "", nil
+	default:
+		return nil, "", errors.New("too many args: either provide path to input or pass input on stdin")
+	}
+}
+
+// sniffStreamFormat guesses the format of a stream from its first
+// non-whitespace byte.
+func sniffStreamFormat(br *bufio.Reader) string {
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return "json"
+		}
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return "json"
+		default:
+			return "yaml"
+		}
+	}
+}
+
+// jsonStream returns an iterator over the lines of a newline-delimited JSON
+// stream. Blank lines are skipped. Lines are not decoded here, so that a
+// malformed record is reported in its own result rather than ending the stream.
+func jsonStream(br *bufio.Reader) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		for {
+			line, err := br.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+				return trimmed, nil
+			}
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+		}
+	}
+}
+
+// yamlStream returns an iterator over the documents in a multi-document YAML
+// stream. Documents are split on "---" and "..." markers; empty documents are
+// skipped.
+func yamlStream(br *bufio.Reader) func() ([]byte, error) {
+	var done bool
+	// doc holds the document being read, which may begin on the same line as
+	// the marker that ended the previous document.
+	var doc bytes.Buffer
+	flush := func() []byte {
+		if len(bytes.TrimSpace(doc.Bytes())) == 0 {
+			doc.Reset()
+			return nil
+		}
+		byt := append([]byte(nil), doc.Bytes()...)
+		doc.Reset()
+		return byt
+	}
+
+	return func() ([]byte, error) {
+		for !done {
+			line, err := br.ReadBytes('\n')
+			if err == io.EOF {
+				done = true
+			} else if err != nil {
+				return nil, err
+			}
+
+			if rest, is := cutYAMLDocMarker(line); is {
+				byt := flush()
+				doc.Write(rest)
+				if byt != nil {
+					return byt, nil
+				}
+				continue
+			}
+			doc.Write(line)
+		}
+
+		if byt := flush(); byt != nil {
+			return byt, nil
+		}
+		return nil, io.EOF
+	}
+}
+
+// cutYAMLDocMarker reports whether the line begins with a document marker,
+// returning any content that follows the marker on the same line.
+func cutYAMLDocMarker(line []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(line, []byte("---")) && !bytes.HasPrefix(line, []byte("...")) {
+		return nil, false
+	}
+	rest := line[3:]
+	if len(bytes.TrimSpace(rest)) == 0 {
+		return nil, true
+	}
+	if rest[0] != ' ' && rest[0] != '\t' {
+		return nil, false
+	}
+	return rest[1:], true
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamLinstr = `
+import "github.com/grafana/thema"
+
+thema.#Lineage
+name: "stream"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+	}
+},
+{
+	version: [0, 1]
+	schema: {
+		title: string
+		count?: int
+	}
+}]
+
+lenses: [{
+	from: [0, 1]
+	to: [0, 0]
+	input: _
+	result: {
+		title: input.title
+	}
+}]
+`
+
+// runStream runs translate --stream against the stream lineage, with the
+// provided input written to a file named name. It returns the decoded output
+// records, the summary written to stderr, and the command error.
+func runStream(t *testing.T, name, input string, parallel int) ([]map[string]interface{}, string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	linpath := filepath.Join(dir, "lineage.cue")
+	require.NoError(t, os.WriteFile(linpath, []byte(streamLinstr), 0o644))
+	inpath := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(inpath, []byte(input), 0o644))
+
+	dc := &dataCommand{
+		stream:   true,
+		parallel: parallel,
+		lla: &lineageLoadArgs{
+			inputLinFilePath: linpath,
+			verstr:           "0.1",
+		},
+	}
+	require.NoError(t, dc.lla.validateVersionInput(nil, nil))
+
+	var stdout, stderr bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	err := dc.runTranslateStream(cmd, []string{inpath})
+
+	var recs []map[string]interface{}
+	sc := bufio.NewScanner(&stdout)
+	for sc.Scan() {
+		rec := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &rec), "output line is not JSON: %s", sc.Text())
+		recs = append(recs, rec)
+	}
+	return recs, stderr.String(), err
+}
+
+func TestTranslateStreamOrder(t *testing.T) {
+	var in strings.Builder
+	for i := 0; i < 24; i++ {
+		fmt.Fprintf(&in, `{"title": "rec%d"}`+"\n", i)
+	}
+
+	for _, n := range []int{1, 4} {
+		t.Run(fmt.Sprintf("parallel%d", n), func(t *testing.T) {
+			recs, sum, err := runStream(t, "in.ndjson", in.String(), n)
+			require.NoError(t, err)
+			require.Len(t, recs, 24)
+			for i, rec := range recs {
+				assert.EqualValues(t, i, rec["record"])
+				assert.Equal(t, "0.1", rec["to"])
+				assert.Equal(t, map[string]interface{}{"title": fmt.Sprintf("rec%d", i)}, rec["result"])
+			}
+			assert.Contains(t, sum, "translated 24 of 24 records")
+		})
+	}
+}
+
+func TestTranslateStreamBadRecords(t *testing.T) {
+	in := `{"title": "first"}
+
+{"title": "unterminated"
+{"title": 42}
+{"title": "last"}
+`
+	recs, sum, err := runStream(t, "in.ndjson", in, 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 4 records failed")
+	assert.Contains(t, sum, "translated 2 of 4 records (2 failed)")
+
+	require.Len(t, recs, 4)
+	for i, rec := range recs {
+		assert.EqualValues(t, i, rec["record"])
+	}
+	assert.NotContains(t, recs[0], "error")
+	assert.NotEmpty(t, recs[1]["error"], "malformed JSON must fail only its own record")
+	assert.Contains(t, recs[2]["error"], "not valid for any schema")
+	assert.Equal(t, map[string]interface{}{"title": "last"}, recs[3]["result"])
+}
+
+func TestTranslateStreamYAML(t *testing.T) {
+	in := `title: first
+---
+title: second
+...
+--- title: third
+---
+---
+`
+	recs, _, err := runStream(t, "in.yaml", in, 2)
+	require.NoError(t, err)
+	require.Len(t, recs, 3)
+	for i, title := range []string{"first", "second", "third"} {
+		assert.EqualValues(t, i, recs[i]["record"])
+		assert.Equal(t, map[string]interface{}{"title": title}, recs[i]["result"])
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	next := jsonStream(bufio.NewReader(strings.NewReader("{\"a\": 1}\n\n  \n{bad\r\n{\"b\": 2}")))
+	var got []string
+	for {
+		byt, err := next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, string(byt))
+	}
+	assert.Equal(t, []string{`{"a": 1}`, `{bad`, `{"b": 2}`}, got)
+}
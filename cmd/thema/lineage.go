@@ -33,6 +33,11 @@ func setupLineageCommand(cmd *cobra.Command) {
 
 	fc := new(fixCommand)
 	fc.setup(linCmd)
+
+	dc := new(diffCommand)
+	dc.setup(linCmd)
+
+	setupLensCommand(linCmd)
 }
 
 func toSubpath(subpath string, f *ast.File) (*ast.File, error) {
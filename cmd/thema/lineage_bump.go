@@ -2,7 +2,11 @@ package main
 
 import (
 	"fmt"
+	"os"
 
+	"cuelang.org/go/cue"
+	cuenc "github.com/grafana/thema/encoding/cue"
+	tastutil "github.com/grafana/thema/internal/astutil"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +17,14 @@ var lineageBumpCmd = &cobra.Command{
 	Long: `Add a new schema to an existing lineage.
 
 Generate the necessary stubs to "bump" the latest schema version in an existing lineage by adding a new schema to it.
+
+By default, the new schema is the next minor version of the latest schema. With --major, the new schema
+is instead the first schema in a new major version, and stubs are added for the forward and reverse lenses
+between the latest schema and the new one. These lens stubs must be implemented before the lineage is valid.
+
+Unless --no-fill is passed, the new schema is pre-filled with the contents of the latest schema.
+
+The lineage's source file is modified in place.
 `,
 }
 
@@ -29,51 +41,35 @@ func (bc *bumpCommand) setup(cmd *cobra.Command) {
 	addLinPathVars(lineageBumpCmd, bc.lla)
 
 	lineageBumpCmd.Flags().BoolVar(&bc.maj, "major", false, "Bump the major version (breaking change) instead of the minor version")
-	lineageBumpCmd.Flags().BoolVar(&bc.maj, "no-fill", false, "Do not pre-fill the new schema with the prior schema")
+	lineageBumpCmd.Flags().BoolVar(&bc.skipfill, "no-fill", false, "Do not pre-fill the new schema with the prior schema")
 	lineageBumpCmd.PreRunE = bc.lla.validateLineageInput
-	// lineageBumpCmd.Run = bc.run
-	// TODO un-hide this once cue encoding package is refactored
-	lineageBumpCmd.Hidden = true
-	lineageBumpCmd.Run = func(cmd *cobra.Command, args []string) {
-		fmt.Fprint(cmd.OutOrStdout(), "bump subcommand is disabled, pending refactor")
+	lineageBumpCmd.Run = bc.run
+}
+
+func (bc *bumpCommand) run(cmd *cobra.Command, args []string) {
+	if err := bc.do(cmd, args); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+		os.Exit(1)
 	}
 }
 
-// func (bc *bumpCommand) run(cmd *cobra.Command, args []string) {
-// 	if err := bc.do(cmd, args); err != nil {
-// 		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
-// 		os.Exit(1)
-// 	}
-// }
+func (bc *bumpCommand) do(cmd *cobra.Command, args []string) error {
+	f, v, err := cuenc.Bump(ctx.BuildInstance(bc.lla.dl.binst), cue.ParsePath(bc.lla.lincuepath), cuenc.BumpConfig{
+		Major:  bc.maj,
+		NoFill: bc.skipfill,
+	})
+	if err != nil {
+		return err
+	}
+
+	b, err := tastutil.FmtNode(f)
+	if err != nil {
+		return err
+	}
 
-// func (bc *bumpCommand) do(cmd *cobra.Command, args []string) error {
-// 	lv := thema.LatestVersion(bc.lla.dl.lin)
-// 	lsch := thema.SchemaP(bc.lla.dl.lin, lv)
-// 	// TODO UGH EVAL
-// 	schlit := tastutil.Format(lsch.Underlying().Eval())
-//
-// 	var err error
-// 	var nlin ast.Node
-// 	if bc.maj {
-// 		nlin = bc.lla.dl.lin.Underlying().Source()
-// 		err = cue.InsertSchemaNodeAs(nlin, tastutil.ToExpr(schlit), thema.SV(lv[0]+1, 0))
-// 		if err != nil {
-// 			return err
-// 		}
-// 	} else {
-// 		nlin, err = cue.Append(bc.lla.dl.lin, lsch.Underlying())
-// 		if err != nil {
-// 			return err
-// 		}
-// 	}
-//
-// 	b, err := tastutil.FmtNode(tastutil.ToExpr(nlin))
-// 	if err != nil {
-// 		return err
-// 	}
-//
-// 	// TODO write back to subpath
-//
-// 	fmt.Fprint(cmd.OutOrStdout(), string(b))
-// 	return nil
-// }
+	if err = os.WriteFile(f.Filename, b, 0666); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "added schema version %s to lineage %q in %s\n", v, bc.lla.dl.lin.Name(), f.Filename)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/grafana/thema"
+	"github.com/spf13/cobra"
+)
+
+var lineageDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Args:  cobra.MaximumNArgs(0),
+	Short: "Show semantic differences between two schemas in a lineage",
+	Long: `Show semantic differences between two schemas in a lineage.
+
+Compares the schema at the --from version with the schema at the --to version, and prints
+each added, removed and renamed field, along with changes to types, defaults, constraints
+and optionality. Each change is identified by the CUE path to the field it affects.
+
+If --from is omitted, the predecessor of the --to schema is used. If --to is omitted,
+the latest schema in the lineage is used.
+
+Output is text by default, or JSON with --format json.
+`,
+}
+
+type diffCommand struct {
+	fromstr string
+	format  string
+
+	lla *lineageLoadArgs
+}
+
+func (dc *diffCommand) setup(cmd *cobra.Command) {
+	cmd.AddCommand(lineageDiffCmd)
+	dc.lla = new(lineageLoadArgs)
+	addLinPathVars(lineageDiffCmd, dc.lla)
+
+	lineageDiffCmd.Flags().StringVar(&dc.fromstr, "from", "", "schema version to diff from. Defaults to the predecessor of --to")
+	lineageDiffCmd.Flags().StringVar(&dc.lla.verstr, "to", "", "schema version to diff to. Defaults to latest")
+	lineageDiffCmd.Flags().StringVarP(&dc.format, "format", "f", "text", "output format. \"text\" or \"json\".")
+	lineageDiffCmd.PreRunE = dc.lla.validateLineageInput
+	lineageDiffCmd.Run = dc.run
+}
+
+func (dc *diffCommand) run(cmd *cobra.Command, args []string) {
+	if err := dc.do(cmd, args); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func (dc *diffCommand) do(cmd *cobra.Command, args []string) error {
+	lin := dc.lla.dl.lin
+
+	to := lin.Latest()
+	if dc.lla.verstr != "" {
+		synv, err := thema.ParseSyntacticVersion(dc.lla.verstr)
+		if err != nil {
+			return err
+		}
+		if to, err = lin.Schema(synv); err != nil {
+			return err
+		}
+	}
+
+	from := to.Predecessor()
+	if dc.fromstr != "" {
+		synv, err := thema.ParseSyntacticVersion(dc.fromstr)
+		if err != nil {
+			return err
+		}
+		if from, err = lin.Schema(synv); err != nil {
+			return err
+		}
+	}
+	if from == nil {
+		return fmt.Errorf("schema %s has no predecessor to diff from, specify one with --from", to.Version())
+	}
+
+	d := thema.Diff(from, to)
+	switch dc.format {
+	case "json":
+		b, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(b))
+	case "text":
+		if len(d.Changes) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "no changes between schemas %s and %s\n", d.From, d.To)
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d changes from schema %s to %s:\n", len(d.Changes), d.From, d.To)
+		for _, c := range d.Changes {
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", c)
+		}
+	default:
+		return fmt.Errorf("unknown output format %q, must be \"text\" or \"json\"", dc.format)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"cuelang.org/go/cue"
+	"github.com/grafana/thema"
+	cuenc "github.com/grafana/thema/encoding/cue"
+	tastutil "github.com/grafana/thema/internal/astutil"
+	"github.com/spf13/cobra"
+)
+
+var lineageLensCmd = &cobra.Command{
+	Use:   "lens <command>",
+	Short: "Perform operations on the lenses in a lineage",
+}
+
+var lineageLensScaffoldCmd = &cobra.Command{
+	Use:   "scaffold",
+	Args:  cobra.MaximumNArgs(0),
+	Short: "Generate forward and reverse lenses between two schemas",
+	Long: `Generate forward and reverse lenses between two schemas.
+
+The schemas at the --from and --to versions are compared, and a lens is generated for
+each direction. Unchanged fields are copied from input to result, renamed and changed
+fields are copied and flagged with TODO comments, and lacunas are added for fields
+that are dropped or must be filled with a placeholder. Fields for which no value can
+be derived, such as those that changed type, are left as _|_ with a TODO comment.
+
+If --to is omitted, the latest schema in the lineage is used. If --from is omitted,
+the schema preceding the --to schema is used.
+
+Stub lenses between the two versions, such as those added by "lineage bump --major",
+are replaced. Lenses that are already implemented are never overwritten. The lineage
+does not need to be valid, and its source file is modified in place.
+`,
+}
+
+type lensScaffoldCommand struct {
+	fromstr string
+	tostr   string
+
+	lla *lineageLoadArgs
+}
+
+func setupLensCommand(cmd *cobra.Command) {
+	cmd.AddCommand(lineageLensCmd)
+
+	sc := new(lensScaffoldCommand)
+	sc.setup(lineageLensCmd)
+}
+
+func (sc *lensScaffoldCommand) setup(cmd *cobra.Command) {
+	cmd.AddCommand(lineageLensScaffoldCmd)
+	sc.lla = new(lineageLoadArgs)
+	addLinPathVars(lineageLensScaffoldCmd, sc.lla)
+	// Lineages with stub lenses are not valid, but are the usual input
+	sc.lla.skipBindLineage = true
+
+	lineageLensScaffoldCmd.Flags().StringVar(&sc.fromstr, "from", "", "schema version to generate lenses from. Defaults to the predecessor of --to")
+	lineageLensScaffoldCmd.Flags().StringVar(&sc.tostr, "to", "", "schema version to generate lenses to. Defaults to latest")
+	lineageLensScaffoldCmd.PreRunE = sc.lla.validateLineageInput
+	lineageLensScaffoldCmd.Run = sc.run
+}
+
+func (sc *lensScaffoldCommand) run(cmd *cobra.Command, args []string) {
+	if err := sc.do(cmd, args); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%s\n", err)
+		os.Exit(1)
+	}
+}
+
+func (sc *lensScaffoldCommand) do(cmd *cobra.Command, args []string) error {
+	inst := ctx.BuildInstance(sc.lla.dl.binst)
+	path := cue.ParsePath(sc.lla.lincuepath)
+
+	var allv []thema.SyntacticVersion
+	iter, err := inst.LookupPath(path).LookupPath(cue.MakePath(cue.Str("schemas"))).List()
+	if err != nil {
+		return fmt.Errorf("could not read schemas list of lineage: %w", err)
+	}
+	for iter.Next() {
+		var v thema.SyntacticVersion
+		if err = iter.Value().LookupPath(cue.MakePath(cue.Str("version"))).Decode(&v); err != nil {
+			return fmt.Errorf("could not decode schema version: %w", err)
+		}
+		allv = append(allv, v)
+	}
+	if len(allv) < 2 {
+		return fmt.Errorf("lineage must contain at least two schemas to generate lenses")
+	}
+
+	to, from := allv[len(allv)-1], allv[len(allv)-2]
+	if sc.tostr != "" {
+		if to, err = thema.ParseSyntacticVersion(sc.tostr); err != nil {
+			return err
+		}
+		from = to
+		for i, v := range allv {
+			if v == to && i > 0 {
+				from = allv[i-1]
+			}
+		}
+	}
+	if sc.fromstr != "" {
+		if from, err = thema.ParseSyntacticVersion(sc.fromstr); err != nil {
+			return err
+		}
+	}
+	if from == to {
+		return fmt.Errorf("schema %s has no predecessor to generate lenses from, specify one with --from", to)
+	}
+
+	f, err := cuenc.ScaffoldLenses(inst, path, from, to)
+	if err != nil {
+		return err
+	}
+
+	b, err := tastutil.FmtNode(f)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(f.Filename, b, 0666); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "generated lenses between schemas %s and %s in %s\n", from, to, f.Filename)
+	return nil
+}
@@ -17,9 +17,11 @@ import (
 	"golang.org/x/mod/modfile"
 
 	"github.com/grafana/thema"
+	"github.com/grafana/thema/encoding/crd"
 	"github.com/grafana/thema/encoding/gocode"
 	"github.com/grafana/thema/encoding/jsonschema"
 	"github.com/grafana/thema/encoding/openapi"
+	"github.com/grafana/thema/encoding/typescript"
 )
 
 type genCommand struct {
@@ -32,6 +34,12 @@ type genCommand struct {
 	noembed bool
 	// don't generate the themaFSFunc impl
 	nofsfunc bool
+	// embed a bind artifact in the generated bindings
+	artifact bool
+	// generate for all schemas in the lineage
+	all bool
+	// generate Validate methods on Go types
+	validate bool
 
 	// write to stdout instead of generator-specific file
 	stdout bool
@@ -50,6 +58,14 @@ type genCommand struct {
 	// path for embedding
 	epath string
 
+	// CRD api group, kind, scope, and served and storage versions
+	crdgroup   string
+	crdkind    string
+	crdscope   string
+	crdserved  []string
+	crdstorage string
+	crdwebhook string
+
 	lla *lineageLoadArgs
 }
 
@@ -73,8 +89,10 @@ func (gc *genCommand) setup(cmd *cobra.Command) {
 
 	ggt := genGoTypesLineageCmd
 	genLineageCmd.AddCommand(ggt)
-	ggt.Use = "gotypes -l <path> [-p <cue-path>] [-v <synver>] [--pkgname <name>] [--stdout]"
+	ggt.Use = "gotypes -l <path> [-p <cue-path>] [-v <synver> | --all] [--pkgname <name>] [--validate] [--stdout]"
 	ggt.Flags().StringVarP(&gc.lla.verstr, "version", "v", "", "schema syntactic version to generate. Defaults to latest")
+	ggt.Flags().BoolVar(&gc.all, "all", false, "Generate types for all schemas in the lineage, with a registry of types by version")
+	ggt.Flags().BoolVar(&gc.validate, "validate", false, "Generate Validate() methods that check schema constraints without CUE")
 	ggt.Flags().StringVar(&gc.pkgname, "pkgname", "", "Name for generated Go package. Defaults to lowercase lineage name")
 	ggt.Flags().BoolVar(&gc.noembed, "stdout", false, "Write to stdout instead of '<lineage.name>_types_gen.go'")
 	ggt.Flags().BoolVarP(&gc.quiet, "quiet", "q", false, "Do not print generated filename")
@@ -91,12 +109,27 @@ func (gc *genCommand) setup(cmd *cobra.Command) {
 	ggb.Flags().BoolVar(&gc.noembed, "no-embed", false, "Do not generate an embed.FS")
 	ggb.Flags().BoolVar(&gc.nofsfunc, "no-fs-func", false, "Do not generate the func that returns fs.FS for loading")
 	ggb.Flags().BoolVar(&gc.stdout, "stdout", false, "Write to stdout instead of an adjacent file")
+	ggb.Flags().BoolVar(&gc.artifact, "artifact", false, "Embed a bind artifact, allowing the factory to skip lineage compatibility and lens checks while the lineage source is unchanged")
 	ggb.Flags().BoolVarP(&gc.quiet, "quiet", "q", false, "Do not print generated filename")
 	ggb.Run = gc.run
 
-	// TODO
-	// genLineageCmd.AddCommand(genTSTypesLineageCmd)
-	// genTSTypesLineageCmd.Flags().StringVarP((*string)(&verstr), "version", "v", "", "schema syntactic version to generate. Defaults to latest")
+	gcrd := genCRDLineageCmd
+	genLineageCmd.AddCommand(gcrd)
+	gcrd.Flags().StringVar(&gc.crdgroup, "group", "", "API group of the custom resource, e.g. \"stable.example.com\". Required.")
+	gcrd.MarkFlagRequired("group")
+	gcrd.Flags().StringVar(&gc.crdkind, "kind", "", "Kind of the custom resource. Defaults to the lineage name")
+	gcrd.Flags().StringVar(&gc.crdscope, "scope", "Namespaced", "Scope of the custom resource. \"Namespaced\" or \"Cluster\".")
+	gcrd.Flags().StringSliceVar(&gc.crdserved, "served", nil, "Schema versions to serve. Defaults to all versions")
+	gcrd.Flags().StringVar(&gc.crdstorage, "storage", "", "Schema version to use for storage. Defaults to latest served")
+	gcrd.Flags().StringVar(&gc.crdwebhook, "webhook-url", "", "URL of a conversion webhook. If omitted, the conversion strategy is \"None\"")
+	gcrd.Flags().StringVarP(&gc.format, "format", "f", "yaml", "output format. \"json\" or \"yaml\".")
+	gcrd.Run = gc.run
+
+	gts := genTSTypesLineageCmd
+	genLineageCmd.AddCommand(gts)
+	gts.Flags().StringVarP(&gc.lla.verstr, "version", "v", "", "schema syntactic version to generate. Defaults to latest")
+	gts.Flags().BoolVar(&gc.all, "all", false, "Generate types for all schemas in the lineage, with a union type and version detection")
+	gts.Run = gc.run
 }
 
 func (gc *genCommand) run(cmd *cobra.Command, args []string) {
@@ -125,6 +158,8 @@ func (gc *genCommand) run(cmd *cobra.Command, args []string) {
 		err = gc.runGoTypes(cmd, args)
 	case "gobindings":
 		err = gc.runGoBindings(cmd, args)
+	case "crd":
+		err = gc.runCRD(cmd, args)
 	case "tstypes":
 		err = gc.runTSTypes(cmd, args)
 	default:
@@ -229,11 +264,87 @@ func (gc *genCommand) runJSONSchema(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+var genCRDLineageCmd = &cobra.Command{
+	Use:   "crd",
+	Short: "Generate a Kubernetes CustomResourceDefinition from a lineage",
+	Long: `Generate a Kubernetes CustomResourceDefinition from a lineage.
+
+Generate a CustomResourceDefinition containing one entry in its versions list
+for each served schema in the lineage, each with an OpenAPI schema generated
+from the corresponding lineage schema, and print it to stdout.
+
+By default, all schema versions are served, and the latest served schema
+version is used for storage. The storage version must be served.
+
+Pass --webhook-url to set the CRD's conversion strategy to "Webhook". The
+ConversionHandler in github.com/grafana/thema/encoding/crd implements such a
+webhook, translating custom resources between versions using the lineage.
+`,
+}
+
+func (gc *genCommand) runCRD(cmd *cobra.Command, args []string) error {
+	cfg := crd.Config{
+		Group: gc.crdgroup,
+		Kind:  gc.crdkind,
+		Scope: gc.crdscope,
+	}
+	for _, vs := range gc.crdserved {
+		synv, err := thema.ParseSyntacticVersion(vs)
+		if err != nil {
+			return err
+		}
+		cfg.Served = append(cfg.Served, synv)
+	}
+	if gc.crdstorage != "" {
+		synv, err := thema.ParseSyntacticVersion(gc.crdstorage)
+		if err != nil {
+			return err
+		}
+		cfg.Storage = &synv
+	}
+	if gc.crdwebhook != "" {
+		cfg.Webhook = &crd.WebhookConfig{URL: gc.crdwebhook}
+	}
+
+	f, err := crd.GenerateCRD(gc.lin, cfg)
+	if err != nil {
+		return err
+	}
+
+	var str string
+	switch gc.format {
+	case "json":
+		var b []byte
+		b, err = rt.Context().BuildFile(f).MarshalJSON()
+		if b != nil {
+			nb := new(bytes.Buffer)
+			err = json.Indent(nb, b, "", "  ")
+			str = nb.String()
+		}
+	case "yaml", "yml":
+		str, err = yaml.Marshal(rt.Context().BuildFile(f))
+	default:
+		return fmt.Errorf(`unrecognized output format %q - must choose "yaml" or "json"`, gc.format)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), str)
+	return nil
+}
+
 var genGoTypesLineageCmd = &cobra.Command{
 	Short: "Generate Go types from a lineage",
 	Long: `Generate Go types from a lineage.
 
-Generate Go types that correspond to a single schema in a lineage.
+Generate Go types that correspond to a single schema in a lineage. If --all is
+passed, types are generated for every schema in the lineage, with names suffixed
+by schema version, along with a thema.TypeRegistry mapping each version to its
+type.
+
+If --validate is passed, a Validate() method is generated on each type generated
+for a schema. It checks constraints that Go types cannot express, such as bounds
+and patterns, without relying on CUE.
 
 By default, the generated types are written to the same directory that contains the lineage,
 in a file named $NAME_types_gen.go, where $NAME is the lowercase string value of
@@ -252,9 +363,24 @@ func (gc *genCommand) runGoTypes(cmd *cobra.Command, args []string) error {
 	} else {
 		fmt.Fprintf(buf, fmt.Sprintf(goheaderp, gc.epath))
 	}
-	b, err := gocode.GenerateTypesOpenAPI(gc.sch, &gocode.TypeConfigOpenAPI{
-		PackageName: gc.pkgname,
-	})
+	var b []byte
+	var err error
+	if gc.all {
+		if gc.lla.verstr != "" {
+			return fmt.Errorf("--version and --all are mutually exclusive")
+		}
+		b, err = gocode.GenerateLineageTypesOpenAPI(gc.lin, &gocode.LineageTypeConfigOpenAPI{
+			TypeConfigOpenAPI: gocode.TypeConfigOpenAPI{
+				PackageName:     gc.pkgname,
+				ValidateMethods: gc.validate,
+			},
+		})
+	} else {
+		b, err = gocode.GenerateTypesOpenAPI(gc.sch, &gocode.TypeConfigOpenAPI{
+			PackageName:     gc.pkgname,
+			ValidateMethods: gc.validate,
+		})
+	}
 	if err != nil {
 		return err
 	}
@@ -314,6 +440,9 @@ func (gc *genCommand) runGoBindings(cmd *cobra.Command, args []string) error {
 	if gc.lla.lincuepath != "" {
 		cfg.CuePath = cue.ParsePath(gc.lla.lincuepath)
 	}
+	if gc.artifact {
+		cfg.ArtifactInstance = gc.lla.dl.binst
+	}
 
 	// emitting on stdout just skips all the complicated conditional gen bits
 	if !gc.stdout {
@@ -459,12 +588,31 @@ var genTSTypesLineageCmd = &cobra.Command{
 	Short: "Generate TypeScript types from a lineage",
 	Long: `Generate TypeScript types from a lineage.
 
-Generate a JSON Schema document representing a single schema in a lineage.
+Generate TypeScript types and defaults representing a single schema in a lineage,
+and print them to stdout.
+
+Pass --all to instead generate types for every schema in the lineage, named by
+version (e.g. FooV0_0), along with a union of all of them, a type guard for each,
+and a function that detects the version of a value.
 `,
 }
 
 func (gc *genCommand) runTSTypes(cmd *cobra.Command, args []string) error {
-	panic("TODO")
+	var f fmt.Stringer
+	var err error
+	if gc.all {
+		if gc.lla.verstr != "" {
+			return fmt.Errorf("--version and --all are mutually exclusive")
+		}
+		f, err = typescript.GenerateLineageTypes(gc.lin, nil)
+	} else {
+		f, err = typescript.GenerateTypes(gc.sch, nil)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(cmd.OutOrStdout(), f.String())
+	return nil
 }
 
 var goheader = `// THIS FILE IS GENERATED. EDITING IS FUTILE.
@@ -14,6 +14,7 @@ var rt = thema.NewRuntime(ctx)
 func main() {
 	setupDataCommand(rootCmd)
 	setupLineageCommand(rootCmd)
+	setupSrvCommand(rootCmd)
 
 	// Stop cobra from being so "helpful"
 	for _, cmd := range allCmds {
@@ -24,8 +25,6 @@ func main() {
 		}
 	}
 
-	// srv commands
-	// TODO
 	err := rootCmd.Execute()
 	if err != nil {
 		os.Exit(1)
@@ -60,6 +59,7 @@ var allCmds = []*cobra.Command{
 	genGoTypesLineageCmd,
 	genOapiLineageCmd,
 	genJschLineageCmd,
+	genCRDLineageCmd,
 }
 
 var rootCmd = &cobra.Command{
@@ -72,7 +72,7 @@ This program offers several kinds of behavior for working with Thema:
 * Validating and inspecting of written lineages.
 * Given a valid lineage, provides basic Thema operations (validate, translate,
   [de]hydrate) on some input data.
-* Run an HTTP server that exposes basic Thema operations to the network. (TODO)
+* Run an HTTP server that exposes basic Thema operations to the network.
 * Provides scaffolding for writing lineages, lenses, and schema. (TODO)
 `,
 }
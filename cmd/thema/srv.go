@@ -1,10 +1,55 @@
 package main
 
-import "github.com/spf13/cobra"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"cuelang.org/go/cue"
+	"github.com/spf13/cobra"
+
+	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
+)
+
+type srvCommand struct {
+	addr    string
+	tlscert string
+	tlskey  string
+
+	// fs paths to each of the lineages to serve
+	linpaths []string
+	// cue path to the lineage within each loaded instance (default root)
+	lincuepath string
+	// maximum size in bytes of request bodies
+	maxBody int64
+
+	h *srvHandler
+}
 
 func setupSrvCommand(cmd *cobra.Command) {
 	cmd.AddCommand(srvCmd)
+	sc := new(srvCommand)
+	sc.setup(srvCmd)
+}
 
+func (sc *srvCommand) setup(cmd *cobra.Command) {
+	cmd.AddCommand(httpCmd)
+	httpCmd.Flags().StringArrayVarP(&sc.linpaths, "lineage", "l", nil, "path to .cue file or package containing a lineage to serve. May be passed multiple times")
+	httpCmd.MarkFlagRequired("lineage")
+	httpCmd.Flags().StringVarP(&sc.lincuepath, "path", "p", "", "CUE expression for path to the lineage object within each file, if not root")
+	httpCmd.Flags().StringVar(&sc.addr, "addr", ":8080", "address on which to listen for requests")
+	httpCmd.Flags().StringVar(&sc.tlscert, "tls-cert", "", "path to a TLS certificate file. Serves HTTPS if provided along with --tls-key")
+	httpCmd.Flags().StringVar(&sc.tlskey, "tls-key", "", "path to a TLS private key file. Serves HTTPS if provided along with --tls-cert")
+	httpCmd.Flags().Int64Var(&sc.maxBody, "max-body-size", 10<<20, "maximum size in bytes of request bodies. Larger requests are rejected with status 413")
+	httpCmd.PreRunE = sc.loadLineages
+	httpCmd.RunE = sc.runHTTP
 }
 
 var srvCmd = &cobra.Command{
@@ -12,14 +57,462 @@ var srvCmd = &cobra.Command{
 	Short: "Run a server that offers Thema operations over the network",
 	Long: `Run a server that offers Thema operations over the network.
 
-	TODO not yet implemented
+Servers expose the same operations as the "thema data" subcommands - validate,
+validate-any, translate, hydrate and dehydrate - for one or more lineages.
 `,
 }
 
 var httpCmd = &cobra.Command{
-	Use:    "http",
-	Hidden: true,
-	Short:  "Start an HTTP(S) server",
+	Use:   "http -l <lineage-fs-path> [-l <lineage-fs-path>...] [-p <cue-path>] [--addr <addr>] [--tls-cert <path> --tls-key <path>] [--max-body-size <bytes>]",
+	Args:  cobra.MaximumNArgs(0),
+	Short: "Start an HTTP(S) server",
 	Long: `Start an HTTP(S) server.
+
+Each lineage passed via -l is loaded and bound at startup, and is then served
+under its #Lineage.name. Names must be unique across all lineages. Requests are
+served concurrently, each using one of a pool of replicas of the lineage,
+bound on demand up to the number of CPUs.
+
+The following endpoints are available:
+
+  GET  /lineages
+       List all served lineages and their schema versions.
+  POST /lineages/<name>/validate[?version=<synver>]
+       Validate the request body against a schema. Defaults to latest.
+  POST /lineages/<name>/validate-any[?version=<synver>]
+       Search the lineage for a schema that validates the request body.
+  POST /lineages/<name>/translate?to=<synver>
+       Translate the request body to the requested schema version.
+  POST /lineages/<name>/hydrate[?version=<synver>]
+       Fill the request body with schema-specified defaults.
+  POST /lineages/<name>/dehydrate[?version=<synver>]
+       Remove schema-specified defaults from the request body.
+
+Request bodies may be JSON or YAML. The format is determined by the
+Content-Type header if it is set to a JSON or YAML media type, and is
+autodetected otherwise. Request bodies larger than --max-body-size are rejected
+with status 413. All responses are JSON.
+
+Validation failures are reported with status 422, and contain the list of
+individual validation errors in the "details" field.
 `,
 }
+
+func (sc *srvCommand) loadLineages(cmd *cobra.Command, args []string) error {
+	if (sc.tlscert == "") != (sc.tlskey == "") {
+		return errors.New("--tls-cert and --tls-key must be provided together")
+	}
+
+	sc.h = &srvHandler{
+		lins:    make(map[string]*thema.LineagePool, len(sc.linpaths)),
+		maxBody: sc.maxBody,
+	}
+	for _, path := range sc.linpaths {
+		path := path
+		lla := &lineageLoadArgs{
+			inputLinFilePath: path,
+			lincuepath:       sc.lincuepath,
+		}
+		if err := lla.validateLineageInput(cmd, args); err != nil {
+			return fmt.Errorf("error loading lineage from %s: %w", path, err)
+		}
+
+		name := lla.dl.lin.Name()
+		if _, has := sc.h.pool(name); has {
+			return fmt.Errorf("lineage from %s has name %q, which is already in use by another lineage", path, name)
+		}
+
+		binst := lla.dl.binst
+		pool, err := thema.NewLineagePool(func(rt *thema.Runtime, opts ...thema.BindOption) (thema.Lineage, error) {
+			return loadone(rt, binst, path, sc.lincuepath)
+		}, 0)
+		if err != nil {
+			return fmt.Errorf("error loading lineage from %s: %w", path, err)
+		}
+		sc.h.add(name, pool)
+	}
+	return nil
+}
+
+func (sc *srvCommand) runHTTP(cmd *cobra.Command, args []string) error {
+	srv := &http.Server{
+		Addr:    sc.addr,
+		Handler: sc.h,
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "serving lineages %s on %s\n", strings.Join(sc.h.names(), ", "), sc.addr)
+
+	if sc.tlscert != "" {
+		return srv.ListenAndServeTLS(sc.tlscert, sc.tlskey)
+	}
+	return srv.ListenAndServe()
+}
+
+// srvHandler is an http.Handler that performs Thema operations against a set
+// of lineages, keyed by name.
+type srvHandler struct {
+	// Each request decodes its body, performs its operation and encodes its
+	// response using a replica of the lineage that it has exclusive use of, so
+	// mut only guards lookups and additions to lins.
+	mut  sync.RWMutex
+	lins map[string]*thema.LineagePool
+
+	// maxBody is the maximum size in bytes of request bodies. Bodies are not
+	// limited if it is not positive.
+	maxBody int64
+}
+
+// pool returns the pool of replicas for the named lineage.
+func (h *srvHandler) pool(name string) (*thema.LineagePool, bool) {
+	h.mut.RLock()
+	defer h.mut.RUnlock()
+	pool, has := h.lins[name]
+	return pool, has
+}
+
+// add serves the lineage in pool under name, replacing any existing lineage
+// with that name.
+func (h *srvHandler) add(name string, pool *thema.LineagePool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if h.lins == nil {
+		h.lins = make(map[string]*thema.LineagePool)
+	}
+	h.lins[name] = pool
+}
+
+// names returns the sorted names of all served lineages.
+func (h *srvHandler) names() []string {
+	h.mut.RLock()
+	defer h.mut.RUnlock()
+	names := make([]string, 0, len(h.lins))
+	for name := range h.lins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// srvError is the JSON body of all non-2xx responses.
+type srvError struct {
+	Error   string           `json:"error"`
+	Details []srvErrorDetail `json:"details,omitempty"`
+}
+
+type srvErrorDetail struct {
+	Message string `json:"message"`
+
+	// Validation is the structured form of a validation failure
+	Validation *terrors.ValidationError `json:"validation,omitempty"`
+}
+
+// srvValidateResult is the JSON body of a successful validate or validate-any response.
+type srvValidateResult struct {
+	Lineage string `json:"lineage"`
+	Version string `json:"version"`
+}
+
+// srvHydrateResult is the JSON body of a successful hydrate or dehydrate response.
+type srvHydrateResult struct {
+	Lineage string    `json:"lineage"`
+	Version string    `json:"version"`
+	Result  cue.Value `json:"result"`
+}
+
+type srvLineageInfo struct {
+	Name     string   `json:"name"`
+	Versions []string `json:"versions"`
+}
+
+func (h *srvHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 0 || parts[0] != "lineages" || len(parts) > 3 {
+		writeSrvError(w, http.StatusNotFound, fmt.Errorf("no such endpoint %s", r.URL.Path))
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			writeSrvError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		h.serveList(w, r)
+		return
+	}
+
+	pool, has := h.pool(parts[1])
+	if !has {
+		writeSrvError(w, http.StatusNotFound, fmt.Errorf("no lineage named %q", parts[1]))
+		return
+	}
+	if len(parts) != 3 {
+		writeSrvError(w, http.StatusNotFound, fmt.Errorf("no such endpoint %s", r.URL.Path))
+		return
+	}
+
+	var op func(http.ResponseWriter, *http.Request, thema.Lineage, cue.Value)
+	switch parts[2] {
+	case "validate":
+		op = h.serveValidate
+	case "validate-any":
+		op = h.serveValidateAny
+	case "translate":
+		op = h.serveTranslate
+	case "hydrate", "dehydrate":
+		op = h.serveHydrate
+	default:
+		writeSrvError(w, http.StatusNotFound, fmt.Errorf("no such operation %q", parts[2]))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeSrvError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	body := r.Body
+	if h.maxBody > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	}
+	byt, err := io.ReadAll(body)
+	if err != nil {
+		var mberr *http.MaxBytesError
+		if errors.As(err, &mberr) {
+			writeSrvError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds the maximum size of %d bytes", mberr.Limit))
+			return
+		}
+		writeSrvError(w, http.StatusBadRequest, fmt.Errorf("error reading request body: %w", err))
+		return
+	}
+
+	err = pool.Do(func(lin thema.Lineage) error {
+		datval, _, err := decodeData(lin.Runtime().Context(), byt, contentFormat(r), "", "request")
+		if err != nil {
+			writeSrvError(w, http.StatusBadRequest, err)
+			return nil
+		}
+		op(w, r, lin, datval)
+		return nil
+	})
+	if err != nil {
+		writeSrvError(w, http.StatusInternalServerError, fmt.Errorf("error loading lineage: %w", err))
+	}
+}
+
+func (h *srvHandler) serveList(w http.ResponseWriter, r *http.Request) {
+	names := h.names()
+	infos := make([]srvLineageInfo, 0, len(names))
+	for _, name := range names {
+		pool, _ := h.pool(name)
+		info := srvLineageInfo{
+			Name: name,
+		}
+		err := pool.Do(func(lin thema.Lineage) error {
+			for _, sch := range lin.All() {
+				info.Versions = append(info.Versions, sch.Version().String())
+			}
+			return nil
+		})
+		if err != nil {
+			writeSrvError(w, http.StatusInternalServerError, fmt.Errorf("error loading lineage %q: %w", name, err))
+			return
+		}
+		infos = append(infos, info)
+	}
+	writeSrvJSON(w, http.StatusOK, infos)
+}
+
+func (h *srvHandler) serveValidate(w http.ResponseWriter, r *http.Request, lin thema.Lineage, datval cue.Value) {
+	sch, ok := schemaFromQuery(w, r, lin, "version")
+	if !ok {
+		return
+	}
+
+	inst, err := sch.Validate(datval)
+	if err != nil {
+		writeSrvError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeSrvJSON(w, http.StatusOK, srvValidateResult{
+		Lineage: lin.Name(),
+		Version: inst.Schema().Version().String(),
+	})
+}
+
+func (h *srvHandler) serveValidateAny(w http.ResponseWriter, r *http.Request, lin thema.Lineage, datval cue.Value) {
+	var sch thema.Schema
+	if r.URL.Query().Get("version") != "" {
+		var ok bool
+		if sch, ok = schemaFromQuery(w, r, lin, "version"); !ok {
+			return
+		}
+	}
+
+	// Mirror "thema data validate-any": check the requested version first, and
+	// report its error if no schema in the lineage matches.
+	var reterr error
+	if sch != nil {
+		var inst *thema.Instance
+		if inst, reterr = sch.Validate(datval); reterr == nil {
+			writeSrvJSON(w, http.StatusOK, srvValidateResult{
+				Lineage: lin.Name(),
+				Version: inst.Schema().Version().String(),
+			})
+			return
+		}
+	}
+
+	if inst := lin.ValidateAny(datval); inst != nil {
+		writeSrvJSON(w, http.StatusOK, srvValidateResult{
+			Lineage: lin.Name(),
+			Version: inst.Schema().Version().String(),
+		})
+		return
+	}
+
+	if reterr == nil {
+		reterr = errNoValidSchema
+	}
+	writeSrvError(w, http.StatusUnprocessableEntity, reterr)
+}
+
+func (h *srvHandler) serveTranslate(w http.ResponseWriter, r *http.Request, lin thema.Lineage, datval cue.Value) {
+	if r.URL.Query().Get("to") == "" {
+		writeSrvError(w, http.StatusBadRequest, errors.New("must specify a target schema version with the 'to' query parameter"))
+		return
+	}
+	sch, ok := schemaFromQuery(w, r, lin, "to")
+	if !ok {
+		return
+	}
+
+	inst := lin.ValidateAny(datval)
+	if inst == nil {
+		writeSrvError(w, http.StatusUnprocessableEntity, errNoValidSchema)
+		return
+	}
+
+	tinst, lac, err := inst.Translate(sch.Version())
+	if err == nil {
+		err = validateTranslationResult(tinst, lac)
+	}
+	if err != nil {
+		writeSrvError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeSrvJSON(w, http.StatusOK, translationResult{
+		From:    inst.Schema().Version().String(),
+		To:      tinst.Schema().Version().String(),
+		Result:  tinst.Underlying(),
+		Lacunas: lac,
+	})
+}
+
+func (h *srvHandler) serveHydrate(w http.ResponseWriter, r *http.Request, lin thema.Lineage, datval cue.Value) {
+	var inst *thema.Instance
+	if r.URL.Query().Get("version") != "" {
+		sch, ok := schemaFromQuery(w, r, lin, "version")
+		if !ok {
+			return
+		}
+
+		var err error
+		if inst, err = sch.Validate(datval); err != nil {
+			writeSrvError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+	} else if inst = lin.ValidateAny(datval); inst == nil {
+		writeSrvError(w, http.StatusUnprocessableEntity, errNoValidSchema)
+		return
+	}
+
+	if strings.HasSuffix(r.URL.Path, "/dehydrate") {
+		inst = inst.Dehydrate()
+	} else {
+		inst = inst.Hydrate()
+	}
+	writeSrvJSON(w, http.StatusOK, srvHydrateResult{
+		Lineage: lin.Name(),
+		Version: inst.Schema().Version().String(),
+		Result:  inst.Underlying(),
+	})
+}
+
+var errNoValidSchema = errors.New("input data is not valid for any schema in lineage")
+
+// schemaFromQuery returns the schema with the version given in the named query
+// parameter, or the latest schema if the parameter is absent. If the version is
+// malformed or does not exist, an error response is written and ok is false.
+func schemaFromQuery(w http.ResponseWriter, r *http.Request, lin thema.Lineage, param string) (sch thema.Schema, ok bool) {
+	verstr := r.URL.Query().Get(param)
+	if verstr == "" {
+		return lin.Latest(), true
+	}
+
+	synv, err := thema.ParseSyntacticVersion(verstr)
+	if err != nil {
+		writeSrvError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	sch, err = lin.Schema(synv)
+	if err != nil {
+		writeSrvError(w, http.StatusNotFound, err)
+		return nil, false
+	}
+	return sch, true
+}
+
+// contentFormat maps the request's Content-Type to an input format understood
+// by decodeData. Unrecognized or absent types result in autodetection.
+func contentFormat(r *http.Request) string {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	switch mt {
+	case "application/json":
+		return "json"
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "yaml"
+	default:
+		return ""
+	}
+}
+
+func writeSrvError(w http.ResponseWriter, status int, err error) {
+	body := srvError{
+		Error: err.Error(),
+	}
+
+	// Validation failures are composed of multiple individual errors; report
+	// each one separately so that clients need not parse the combined message.
+	// Each individual error has a structured form, in the same order.
+	if errors.Is(err, terrors.ErrInvalidData) {
+		var multi interface{ Errors() []error }
+		if errors.As(err, &multi) {
+			verrs := terrors.ValidationErrors(err)
+			for i, e := range multi.Errors() {
+				detail := srvErrorDetail{Message: e.Error()}
+				if i < len(verrs) {
+					detail.Validation = verrs[i]
+				}
+				body.Details = append(body.Details, detail)
+			}
+		}
+	}
+	writeSrvJSON(w, status, body)
+}
+
+func writeSrvJSON(w http.ResponseWriter, status int, body interface{}) {
+	byt, err := json.MarshalIndent(body, "", "  ")
+	if err != nil {
+		status = http.StatusInternalServerError
+		byt, _ = json.Marshal(srvError{Error: fmt.Sprintf("error marshaling response to JSON: %s", err)}) //nolint:errchkjson
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(byt, '\n')) //nolint:errcheck,gosec
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/thema"
+)
+
+const srvLinstr = `name: "srv"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		size:  int | *3
+	}
+}, {
+	version: [1, 0]
+	schema: {
+		title: string
+		size:  int | *3
+		owner: string
+	}
+}]
+lenses: [{
+	to: [0, 0]
+	from: [1, 0]
+	input: _
+	result: {
+		title: input.title
+		size:  input.size
+	}
+	lacunas: [{
+		sourceFields: [{path: "owner", value: input.owner}]
+		message: "owner was dropped"
+		type: {name: "DroppedField", id: 2}
+	}]
+}, {
+	to: [1, 0]
+	from: [0, 0]
+	input: _
+	result: {
+		title: input.title
+		size:  input.size
+		owner: "PLACEHOLDER"
+	}
+	lacunas: [{
+		targetFields: [{path: "owner", value: result.owner}]
+		message: "owner is a placeholder"
+		type: {name: "Placeholder", id: 1}
+	}]
+}]
+`
+
+func bindSrvLineage(rt *thema.Runtime, opts ...thema.BindOption) (thema.Lineage, error) {
+	return thema.BindLineage(rt.Context().CompileString(srvLinstr), rt, opts...)
+}
+
+func newSrvTestHandler(t *testing.T) *srvHandler {
+	t.Helper()
+	pool, err := thema.NewLineagePool(bindSrvLineage, 0)
+	require.NoError(t, err)
+	h := new(srvHandler)
+	h.add("srv", pool)
+	return h
+}
+
+// srvDo performs a request against the handler, returning the status code and
+// the decoded JSON body of the response.
+func srvDo(t *testing.T, h http.Handler, method, target, ctype, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var ret interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &ret), "response is not JSON: %s", rec.Body)
+	if m, ok := ret.(map[string]interface{}); ok {
+		return rec.Code, m
+	}
+	return rec.Code, map[string]interface{}{"list": ret}
+}
+
+func TestSrvList(t *testing.T) {
+	h := newSrvTestHandler(t)
+	code, body := srvDo(t, h, http.MethodGet, "/lineages", "", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name":     "srv",
+		"versions": []interface{}{"0.0", "1.0"},
+	}}, body["list"])
+
+	code, _ = srvDo(t, h, http.MethodPost, "/lineages", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestSrvValidate(t *testing.T) {
+	h := newSrvTestHandler(t)
+
+	code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=0.0", "application/json", `{"title": "foo"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, map[string]interface{}{"lineage": "srv", "version": "0.0"}, body)
+
+	code, body = srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=0.0", "application/yaml", "title: foo\n")
+	require.Equal(t, http.StatusOK, code, body)
+
+	t.Run("invalid", func(t *testing.T) {
+		code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/validate", "", `{"title": 42}`)
+		require.Equal(t, http.StatusUnprocessableEntity, code)
+		assert.NotEmpty(t, body["error"])
+
+		details, _ := body["details"].([]interface{})
+		require.Len(t, details, 2)
+		got := make(map[string]interface{})
+		for _, d := range details {
+			detail := d.(map[string]interface{})
+			assert.NotEmpty(t, detail["message"])
+			val, ok := detail["validation"].(map[string]interface{})
+			require.True(t, ok, "detail has no structured validation error: %v", detail)
+			got[val["dataPath"].(string)] = val["code"]
+		}
+		assert.Equal(t, map[string]interface{}{
+			"/title": "KindConflict",
+			"/owner": "MissingField",
+		}, got)
+	})
+
+	t.Run("badversion", func(t *testing.T) {
+		code, _ := srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=x", "", `{"title": "foo"}`)
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=3.0", "", `{"title": "foo"}`)
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("baddata", func(t *testing.T) {
+		code, _ := srvDo(t, h, http.MethodPost, "/lineages/srv/validate", "application/json", `{"title":`)
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestSrvValidateAny(t *testing.T) {
+	h := newSrvTestHandler(t)
+
+	code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/validate-any", "", `{"title": "foo"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "0.0", body["version"])
+
+	// The error for the requested version is reported if no schema matches
+	code, body = srvDo(t, h, http.MethodPost, "/lineages/srv/validate-any?version=1.0", "", `{"title": 42}`)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Len(t, body["details"], 2)
+
+	code, body = srvDo(t, h, http.MethodPost, "/lineages/srv/validate-any", "", `{"title": 42}`)
+	require.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, errNoValidSchema.Error(), body["error"])
+}
+
+func TestSrvTranslate(t *testing.T) {
+	h := newSrvTestHandler(t)
+
+	code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/translate?to=1.0", "", `{"title": "foo", "size": 2}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "0.0", body["from"])
+	assert.Equal(t, "1.0", body["to"])
+	assert.Equal(t, map[string]interface{}{"title": "foo", "size": float64(2), "owner": "PLACEHOLDER"}, body["result"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"v": []interface{}{float64(1), float64(0)},
+		"lacunas": []interface{}{map[string]interface{}{
+			"targetFields": []interface{}{map[string]interface{}{"path": "owner", "value": "PLACEHOLDER"}},
+			"type":         float64(thema.LacunaPlaceholder),
+			"message":      "owner is a placeholder",
+		}},
+	}}, body["lacunas"])
+
+	code, _ = srvDo(t, h, http.MethodPost, "/lineages/srv/translate", "", `{"title": "foo"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = srvDo(t, h, http.MethodPost, "/lineages/srv/translate?to=1.0", "", `{"title": 42}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
+
+func TestSrvHydrate(t *testing.T) {
+	h := newSrvTestHandler(t)
+
+	code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/hydrate", "", `{"title": "foo"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "0.0", body["version"])
+	assert.Equal(t, map[string]interface{}{"title": "foo", "size": float64(3)}, body["result"])
+
+	code, body = srvDo(t, h, http.MethodPost, "/lineages/srv/dehydrate?version=1.0", "", `{"title": "foo", "size": 3, "owner": "me"}`)
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "1.0", body["version"])
+	assert.Equal(t, map[string]interface{}{"title": "foo", "owner": "me"}, body["result"])
+
+	code, _ = srvDo(t, h, http.MethodPost, "/lineages/srv/hydrate?version=1.0", "", `{"title": "foo"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+}
+
+func TestSrvNotFound(t *testing.T) {
+	h := newSrvTestHandler(t)
+	for _, target := range []string{"/", "/other", "/lineages/none/validate", "/lineages/srv", "/lineages/srv/frobnicate", "/lineages/srv/validate/extra"} {
+		code, body := srvDo(t, h, http.MethodPost, target, "", `{}`)
+		assert.Equal(t, http.StatusNotFound, code, target)
+		assert.NotEmpty(t, body["error"], target)
+	}
+
+	code, _ := srvDo(t, h, http.MethodGet, "/lineages/srv/validate", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestSrvMaxBodySize(t *testing.T) {
+	h := newSrvTestHandler(t)
+	h.maxBody = 32
+
+	code, _ := srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=0.0", "", `{"title": "foo"}`)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=0.0", "", `{"title": "`+strings.Repeat("a", 32)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.Contains(t, body["error"], "32 bytes")
+}
+
+// TestSrvConcurrent checks that requests are served concurrently without
+// interfering with one another. It is most useful when run with the race
+// detector.
+func TestSrvConcurrent(t *testing.T) {
+	h := newSrvTestHandler(t)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				title := fmt.Sprintf("foo%d", g*10+i)
+				code, body := srvDo(t, h, http.MethodPost, "/lineages/srv/translate?to=1.0", "", fmt.Sprintf(`{"title": %q, "size": %d}`, title, i))
+				if assert.Equal(t, http.StatusOK, code, body) {
+					assert.Equal(t, map[string]interface{}{"title": title, "size": float64(i), "owner": "PLACEHOLDER"}, body["result"])
+				}
+
+				code, body = srvDo(t, h, http.MethodPost, "/lineages/srv/validate?version=1.0", "", fmt.Sprintf(`{"title": %q}`, title))
+				assert.Equal(t, http.StatusUnprocessableEntity, code, body)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestWriteSrvError(t *testing.T) {
+	lin, err := bindSrvLineage(rt)
+	require.NoError(t, err)
+	_, err = lin.Latest().Validate(rt.Context().CompileString(`{title: 42, size: "big"}`))
+	require.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	writeSrvError(rec, http.StatusUnprocessableEntity, err)
+	var body struct {
+		Details []struct {
+			Message    string                 `json:"message"`
+			Validation map[string]interface{} `json:"validation"`
+		} `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Details, 3)
+	for _, d := range body.Details {
+		assert.NotEmpty(t, d.Validation["code"], "detail %q has no structured form", d.Message)
+	}
+}
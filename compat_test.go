@@ -0,0 +1,138 @@
+package thema
+
+import (
+	"fmt"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+func TestCompatError(t *testing.T) {
+	var tests = []struct {
+		name     string
+		prev     string
+		next     string
+		version  SyntacticVersion
+		sentinel error
+		paths    []string
+	}{
+		{
+			name:     "changed-kind",
+			prev:     `{a: string, b: int}`,
+			next:     `{a: int, b: int}`,
+			version:  SV(0, 1),
+			sentinel: terrors.ErrNotBackwardsCompatible,
+			paths:    []string{"a"},
+		},
+		{
+			name:     "nested",
+			prev:     `{a: {b: string, c?: int}, d: bool}`,
+			next:     `{a: {b: string, e: int}, d: bool}`,
+			version:  SV(0, 1),
+			sentinel: terrors.ErrNotBackwardsCompatible,
+			paths:    []string{"a.e", "a.c"},
+		},
+		{
+			name:     "optional-to-required",
+			prev:     `{a?: string, "b-c"?: string}`,
+			next:     `{a: string, "b-c": string}`,
+			version:  SV(0, 1),
+			sentinel: terrors.ErrNotBackwardsCompatible,
+			paths:    []string{"a", `"b-c"`},
+		},
+		{
+			name:     "list-element",
+			prev:     `{a: [...string], b: [...{c: string}]}`,
+			next:     `{a: [...int], b: [...{c: string, d: int}]}`,
+			version:  SV(0, 1),
+			sentinel: terrors.ErrNotBackwardsCompatible,
+			paths:    []string{"a.[_]", "b.[_].d"},
+		},
+		{
+			name:     "pattern",
+			prev:     `{a: {[string]: int}, b?: {[string]: _}}`,
+			next:     `{a: {[string]: string}, b?: {c?: int}}`,
+			version:  SV(0, 1),
+			sentinel: terrors.ErrNotBackwardsCompatible,
+			paths:    []string{"a.[_]", "b.c", "b.[_]"},
+		},
+		{
+			name:     "major-not-breaking",
+			prev:     `{a: string}`,
+			next:     `{a: string, b?: int}`,
+			version:  SV(1, 0),
+			sentinel: terrors.ErrNotBackwardsIncompatible,
+		},
+	}
+
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			linstr := fmt.Sprintf(`name: %q
+schemas: [{version: [0, 0], schema: %s}, {version: [%d, %d], schema: %s}]
+`, tc.name, tc.prev, tc.version[0], tc.version[1], tc.next)
+			if tc.version[1] == 0 {
+				linstr += `lenses: [{to: [0, 0], from: [1, 0], input: _, result: {a: input.a}}, {to: [1, 0], from: [0, 0], input: _, result: {a: input.a}}]`
+			}
+
+			_, err := BindLineage(ctx.CompileString(linstr), rt)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, terrors.ErrInvalidLineage))
+			assert.True(t, errors.Is(err, tc.sentinel))
+
+			var cerr *CompatError
+			require.True(t, errors.As(err, &cerr))
+			assert.Equal(t, SV(0, 0), cerr.Prev)
+			assert.Equal(t, tc.version, cerr.Next)
+			assert.Equal(t, tc.version[1] == 0, cerr.Major())
+
+			var paths []string
+			for _, p := range cerr.Paths {
+				paths = append(paths, p.String())
+			}
+			assert.Equal(t, tc.paths, paths)
+		})
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	var tests = []struct {
+		name string
+		prev string
+		next string
+	}{
+		{
+			name: "pattern-in-definition",
+			prev: `{#Opts: {options?: {[string]: _}}, a: #Opts}`,
+			next: `{#Opts: {options?: {[string]: _}}, a: #Opts, b?: #Opts}`,
+		},
+		{
+			name: "widened",
+			prev: `{a: int, b: {[string]: int}, c: [...int32], d: *"foo" | "bar"}`,
+			next: `{a: int | string, b: {[string]: int | string}, c: [...int64], d: *"foo" | "bar" | "baz"}`,
+		},
+		{
+			name: "pattern-allows-removed",
+			prev: `{a: {b: int}}`,
+			next: `{a: {[string]: int}}`,
+		},
+	}
+
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			linstr := fmt.Sprintf(`name: %q
+schemas: [{version: [0, 0], schema: %s}, {version: [0, 1], schema: %s}]
+`, tc.name, tc.prev, tc.next)
+			_, err := BindLineage(ctx.CompileString(linstr), rt)
+			require.NoError(t, err)
+		})
+	}
+}
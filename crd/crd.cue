@@ -8,17 +8,45 @@ import (
 
 // CRD transforms a lineage into a Kubernetes custom resource definition, or a series thereof.
 #CRD: {
-	_sv: [<len(lin.seqs), <len(lin.seqs[_sv[0]].schemas)]
-	served: [..._sv]
-	storage: _sv
-	lin:     thema.#Lineage
+	lin: thema.#Lineage
+
+	#CRDFromVersions & {
+		name: lin.name
+		versions: [ for sch in lin.schemas {sch.version}]
+	}
+}
+
+// CRDFromVersions is the same transform as #CRD, but operates only on the name
+// and schema versions of a lineage, rather than the lineage itself.
+//
+// This is the form used by the Go encoder, which fills in the openAPIV3Schema
+// for each version after generating it from the lineage's schemas.
+#CRDFromVersions: {
+	// The name of the lineage.
+	name: string
+
+	// The versions of all schemas in the lineage, in ascending order.
+	versions: [...thema.#SyntacticVersion] & list.MinItems(1)
+
+	// The versions of schemas in the lineage that are served by the CRD, in
+	// ascending order. The CRD has one entry in its versions list for each.
+	// All schema versions are served by default.
+	SV=served: [...thema.#SyntacticVersion] & list.MinItems(1) | *versions
+
+	// The version of schema in the lineage that is used when persisting
+	// custom resources to storage. It must be one of the served versions. The
+	// latest served schema is used by default.
+	ST=storage: thema.#SyntacticVersion | *SV[len(SV)-1]
+
+	_served: [ for sv in served {"\(sv[0]).\(sv[1])"}]
+	_storageServed: true & list.Contains(_served, "\(ST[0]).\(ST[1])")
 
 	// Additional metadata necessary to convert a thema lineage into a
 	// Kubernetes Custom Resource Definition (CRD).
 	crdspec = spec: {
 		// scope indicates whether the defined custom resource is cluster-
 		// or namespace-scoped.
-		scope: "Namespaced" | "Cluster"
+		scope: *"Namespaced" | "Cluster"
 
 		// group is the API group of the defined custom resource. The
 		// custom resources are served under `/apis/<group>/...`.
@@ -35,8 +63,7 @@ import (
 			// kind is the serialized kind of the resource. It is normally
 			// CamelCase and singular. Custom resource instances will use
 			// this value as the `kind` attribute in API calls.
-			// TODO default this to thema name
-			kind: string | *lin.name
+			kind: string | *name
 
 			// listKind is the serialized kind of the list for this resource.
 			listKind: string | *"\(kind)List"
@@ -44,7 +71,7 @@ import (
 			// plural is the plural name of the resource to serve. The custom
 			// resources are served under
 			// `/apis/<group>/<version>/.../<plural>`.
-			plural: string | =~#"[a-z]"# | *"\(lin.name)s"
+			plural: *"\(singular)s" | =~#"^[a-z][-a-z0-9]*$"#
 
 			// shortNames allow shorter string to match your resource on the CLI
 			shortNames?: [...string]
@@ -60,10 +87,36 @@ import (
 
 		// conversion defines conversion settings for the CRD.
 		conversion?: {
-			// TODO for now, only allow this and not webhook, because what we
-			// really want to do is swap thema translation logic in for
-			// Scheme
-			strategy: "None"
+			// strategy is "None" if custom resources are only ever served
+			// with the schema version in which they were stored, or
+			// "Webhook" if they are converted between schema versions by a
+			// conversion webhook. A thema lineage is the single source of
+			// truth for these conversions when the webhook is implemented by
+			// the Go ConversionHandler in github.com/grafana/thema/encoding/crd.
+			strategy: *"None" | "Webhook"
+
+			if strategy == "Webhook" {
+				webhook: {
+					// conversionReviewVersions is the list of ConversionReview
+					// versions the webhook accepts.
+					conversionReviewVersions: [...string] | *["v1"]
+
+					// clientConfig is how the API server reaches the webhook.
+					// Exactly one of url or service must be specified.
+					clientConfig: {
+						url?: string
+						service?: {
+							namespace: string
+							name:      string
+							path?:     string
+							port?:     int
+						}
+						// caBundle is a base64-encoded PEM bundle used to
+						// validate the webhook's server certificate.
+						caBundle?: string
+					}
+				}
+			}
 		}
 	}
 
@@ -76,15 +129,13 @@ import (
 		}
 		spec: crdspec
 		spec: versions: [
-			for seqv, seq in lin.seqs {
-				for schv, sch in seq.schemas {
-					served:  list.Contains(served, [seqv, schv])
-					storage: [seqv, schv] == storage
-					name:    "v\(seqv).\(schv)" // Not sure if the dot is allowed
-					schema: {
-						openAPIV3Schema: {...} // This is what needs to be filled in by the encoder
-						cueSchema:             sch
-					}
+			for v in SV {
+				served:  true
+				storage: v[0] == ST[0] && v[1] == ST[1]
+				// Dots are not permitted in CRD version names, which must be DNS-1035 labels
+				name: "v\(v[0])-\(v[1])"
+				schema: {
+					openAPIV3Schema: {...} // This is what needs to be filled in by the encoder
 				}
 			},
 		]
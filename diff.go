@@ -0,0 +1,348 @@
+package thema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/ast/astutil"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/token"
+)
+
+// ChangeKind identifies the kind of a single [SchemaChange] in a [SchemaDiff].
+type ChangeKind string
+
+const (
+	// FieldAdded indicates a field that exists in the newer schema, but not the
+	// older one.
+	FieldAdded ChangeKind = "added"
+
+	// FieldRemoved indicates a field that exists in the older schema, but not
+	// the newer one.
+	FieldRemoved ChangeKind = "removed"
+
+	// FieldRenamed indicates a field that was removed from the older schema and
+	// replaced in the newer schema by a field with a different name, but an
+	// identical definition.
+	FieldRenamed ChangeKind = "renamed"
+
+	// TypeChanged indicates a field whose kind changed, such that neither the old
+	// nor the new kind is a subset of the other. Example: string to int
+	TypeChanged ChangeKind = "type"
+
+	// DefaultChanged indicates a field whose default value was added, removed, or
+	// changed.
+	DefaultChanged ChangeKind = "default"
+
+	// ConstraintNarrowed indicates a field that accepts a strict subset of the
+	// values it previously accepted. Example: >3 to >5
+	ConstraintNarrowed ChangeKind = "narrowed"
+
+	// ConstraintWidened indicates a field that accepts a strict superset of the
+	// values it previously accepted. Example: "a" | "b" to "a" | "b" | "c"
+	ConstraintWidened ChangeKind = "widened"
+
+	// ConstraintChanged indicates a field whose set of accepted values changed,
+	// but is neither a subset nor a superset of the values it previously
+	// accepted. Example: =~"^a" to =~"^b"
+	ConstraintChanged ChangeKind = "constraint"
+
+	// MadeRequired indicates a field that was optional, and is now required.
+	MadeRequired ChangeKind = "required"
+
+	// MadeOptional indicates a field that was required, and is now optional.
+	MadeOptional ChangeKind = "optional"
+)
+
+// SchemaChange describes a single difference between two schemas.
+type SchemaChange struct {
+	// Kind is the kind of change.
+	Kind ChangeKind
+
+	// Path is the path to the changed field. For [FieldRemoved], this is a path
+	// within the older schema; otherwise, it is within the newer schema.
+	Path cue.Path
+
+	// OldPath is the path to the field in the older schema. It is only set for
+	// [FieldRenamed].
+	OldPath cue.Path
+
+	// Old is the CUE representation of the field's prior value. Empty for
+	// [FieldAdded].
+	Old string
+
+	// New is the CUE representation of the field's new value. Empty for
+	// [FieldRemoved].
+	New string
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case FieldAdded:
+		return fmt.Sprintf("%s: %s (%s)", c.Kind, c.Path, c.New)
+	case FieldRemoved:
+		return fmt.Sprintf("%s: %s (%s)", c.Kind, c.Path, c.Old)
+	case FieldRenamed:
+		return fmt.Sprintf("%s: %s -> %s", c.Kind, c.OldPath, c.Path)
+	case MadeRequired, MadeOptional:
+		return fmt.Sprintf("%s: %s", c.Kind, c.Path)
+	default:
+		return fmt.Sprintf("%s: %s (%s -> %s)", c.Kind, c.Path, c.Old, c.New)
+	}
+}
+
+// MarshalJSON implements [json.Marshaler], representing paths as strings.
+func (c SchemaChange) MarshalJSON() ([]byte, error) {
+	jc := struct {
+		Kind    ChangeKind `json:"kind"`
+		Path    string     `json:"path"`
+		OldPath string     `json:"oldPath,omitempty"`
+		Old     string     `json:"old,omitempty"`
+		New     string     `json:"new,omitempty"`
+	}{
+		Kind: c.Kind,
+		Path: c.Path.String(),
+		Old:  c.Old,
+		New:  c.New,
+	}
+	if c.Kind == FieldRenamed {
+		jc.OldPath = c.OldPath.String()
+	}
+	return json.Marshal(jc)
+}
+
+// SchemaDiff is the set of semantic changes between two schemas, as produced
+// by [Diff].
+type SchemaDiff struct {
+	// From is the version of the older schema.
+	From SyntacticVersion `json:"from"`
+
+	// To is the version of the newer schema.
+	To SyntacticVersion `json:"to"`
+
+	// Changes are the individual changes between the schemas, in the order
+	// fields appear in the schemas.
+	Changes []SchemaChange `json:"changes"`
+}
+
+// Diff computes the semantic differences between schemas a and b, treating a
+// as the older schema and b as the newer one. The schemas need not be from
+// the same lineage, nor adjacent within a lineage.
+//
+// Struct-kinded fields are descended into, such that changes are reported on
+// the most deeply nested field possible. All other fields, including lists,
+// are compared as a whole. Renames are detected only among fields within the
+// same parent struct, and only when exactly one removed field and one added
+// field have identical definitions.
+func Diff(a, b Schema) *SchemaDiff {
+	return &SchemaDiff{
+		From:    a.Version(),
+		To:      b.Version(),
+		Changes: diffValues(schemaValue(a), schemaValue(b), nil),
+	}
+}
+
+func schemaValue(sch Schema) cue.Value {
+	if def, ok := sch.(*schemaDef); ok {
+		return def.def
+	}
+	return sch.Underlying().LookupPath(pathSchDef)
+}
+
+func diffValues(a, b cue.Value, sels []cue.Selector) []SchemaChange {
+	if a.IncompleteKind() == cue.StructKind && b.IncompleteKind() == cue.StructKind {
+		return diffStructs(a, b, sels)
+	}
+
+	path := cue.MakePath(sels...)
+	olds, news := fmt.Sprint(a), fmt.Sprint(b)
+	if olds == news {
+		return nil
+	}
+
+	ak, bk := a.IncompleteKind(), b.IncompleteKind()
+	if ak&bk != ak && ak&bk != bk {
+		return []SchemaChange{{Kind: TypeChanged, Path: path, Old: olds, New: news}}
+	}
+
+	var changes []SchemaChange
+	ad, adok := a.Default()
+	bd, bdok := b.Default()
+	if adok != bdok || (adok && fmt.Sprint(ad) != fmt.Sprint(bd)) {
+		c := SchemaChange{Kind: DefaultChanged, Path: path}
+		if adok {
+			c.Old = fmt.Sprint(ad)
+		}
+		if bdok {
+			c.New = fmt.Sprint(bd)
+		}
+		changes = append(changes, c)
+	}
+
+	an, bn := a, b
+	if adok || bdok {
+		an, bn = stripDefaults(a), stripDefaults(b)
+	}
+	anarrower := bn.Subsume(an, cue.Raw(), cue.Schema()) == nil
+	bnarrower := an.Subsume(bn, cue.Raw(), cue.Schema()) == nil
+	switch {
+	case anarrower && bnarrower:
+		// Only the default changed
+	case bnarrower:
+		changes = append(changes, SchemaChange{Kind: ConstraintNarrowed, Path: path, Old: olds, New: news})
+	case anarrower:
+		changes = append(changes, SchemaChange{Kind: ConstraintWidened, Path: path, Old: olds, New: news})
+	default:
+		changes = append(changes, SchemaChange{Kind: ConstraintChanged, Path: path, Old: olds, New: news})
+	}
+	return changes
+}
+
+type diffField struct {
+	sel      cue.Selector
+	val      cue.Value
+	optional bool
+}
+
+func structFields(v cue.Value) []diffField {
+	var fields []diffField
+	iter, _ := v.Fields(cue.Optional(true))
+	for iter != nil && iter.Next() {
+		fields = append(fields, diffField{
+			sel:      iter.Selector(),
+			val:      iter.Value(),
+			optional: iter.IsOptional(),
+		})
+	}
+	return fields
+}
+
+func diffStructs(a, b cue.Value, sels []cue.Selector) []SchemaChange {
+	afields, bfields := structFields(a), structFields(b)
+	aidx := make(map[string]diffField, len(afields))
+	for _, f := range afields {
+		aidx[f.sel.String()] = f
+	}
+	bidx := make(map[string]diffField, len(bfields))
+	for _, f := range bfields {
+		bidx[f.sel.String()] = f
+	}
+
+	var added, removed []diffField
+	for _, f := range bfields {
+		if _, has := aidx[f.sel.String()]; !has {
+			added = append(added, f)
+		}
+	}
+	for _, f := range afields {
+		if _, has := bidx[f.sel.String()]; !has {
+			removed = append(removed, f)
+		}
+	}
+	renames := matchRenames(removed, added)
+
+	var changes []SchemaChange
+	for _, bf := range bfields {
+		path := appendSel(sels, bf.sel)
+		af, has := aidx[bf.sel.String()]
+		if !has {
+			if from, is := renames[bf.sel.String()]; is {
+				changes = append(changes, SchemaChange{
+					Kind:    FieldRenamed,
+					Path:    cue.MakePath(path...),
+					OldPath: cue.MakePath(appendSel(sels, from.sel)...),
+					Old:     fmt.Sprint(from.val),
+					New:     fmt.Sprint(bf.val),
+				})
+			} else {
+				changes = append(changes, SchemaChange{Kind: FieldAdded, Path: cue.MakePath(path...), New: fmt.Sprint(bf.val)})
+			}
+			continue
+		}
+
+		if af.optional && !bf.optional {
+			changes = append(changes, SchemaChange{Kind: MadeRequired, Path: cue.MakePath(path...)})
+		} else if !af.optional && bf.optional {
+			changes = append(changes, SchemaChange{Kind: MadeOptional, Path: cue.MakePath(path...)})
+		}
+		changes = append(changes, diffValues(af.val, bf.val, path)...)
+	}
+
+	renamed := make(map[string]bool, len(renames))
+	for _, from := range renames {
+		renamed[from.sel.String()] = true
+	}
+	for _, af := range removed {
+		if !renamed[af.sel.String()] {
+			changes = append(changes, SchemaChange{Kind: FieldRemoved, Path: cue.MakePath(appendSel(sels, af.sel)...), Old: fmt.Sprint(af.val)})
+		}
+	}
+	return changes
+}
+
+// matchRenames pairs removed fields with added fields that have identical
+// definitions and optionality. Only unambiguous, one-to-one matches are
+// considered renames. The returned map is keyed by the added field's selector.
+func matchRenames(removed, added []diffField) map[string]diffField {
+	key := func(f diffField) string {
+		return fmt.Sprintf("%t %v", f.optional, f.val)
+	}
+
+	rcount := make(map[string]int)
+	for _, f := range removed {
+		rcount[key(f)]++
+	}
+	acount := make(map[string]int)
+	for _, f := range added {
+		acount[key(f)]++
+	}
+
+	renames := make(map[string]diffField)
+	for _, af := range added {
+		k := key(af)
+		if acount[k] != 1 || rcount[k] != 1 {
+			continue
+		}
+		for _, rf := range removed {
+			if key(rf) == k {
+				renames[af.sel.String()] = rf
+			}
+		}
+	}
+	return renames
+}
+
+func appendSel(sels []cue.Selector, sel cue.Selector) []cue.Selector {
+	return append(append(make([]cue.Selector, 0, len(sels)+1), sels...), sel)
+}
+
+// stripDefaults returns the provided value with default markers removed from
+// all its disjunctions, such that subsumption checks compare only the sets of
+// values accepted. If the value cannot be rebuilt without its defaults, it is
+// returned unmodified.
+func stripDefaults(v cue.Value) cue.Value {
+	expr, ok := v.Syntax(cue.Raw()).(ast.Expr)
+	if !ok {
+		return v
+	}
+	expr = astutil.Apply(expr, func(c astutil.Cursor) bool {
+		if x, is := c.Node().(*ast.UnaryExpr); is && x.Op == token.MUL {
+			c.Replace(x.X)
+		}
+		return true
+	}, nil).(ast.Expr)
+
+	// Rebuild from source, as the positions and references retained in the raw
+	// syntax are not safe to pass back to the evaluator directly.
+	b, err := format.Node(expr)
+	if err != nil {
+		return v
+	}
+	nv := v.Context().CompileBytes(b)
+	if nv.Err() != nil {
+		return v
+	}
+	return nv
+}
@@ -0,0 +1,111 @@
+package thema
+
+import (
+	"fmt"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	var tests = []struct {
+		name     string
+		from, to string
+		expected []string
+	}{
+		{
+			name:     "identical",
+			from:     `{a: string, b?: int}`,
+			to:       `{a: string, b?: int}`,
+			expected: nil,
+		},
+		{
+			name:     "added-removed",
+			from:     `{a: string, b: int}`,
+			to:       `{a: string, c?: bool}`,
+			expected: []string{"added: c (bool)", "removed: b (int)"},
+		},
+		{
+			name:     "renamed",
+			from:     `{a: string, oldname: =~"^x"}`,
+			to:       `{a: string, newname: =~"^x"}`,
+			expected: []string{"renamed: oldname -> newname"},
+		},
+		{
+			name:     "type",
+			from:     `{a: string}`,
+			to:       `{a: int}`,
+			expected: []string{"type: a (string -> int)"},
+		},
+		{
+			name:     "default",
+			from:     `{a: *"x" | string}`,
+			to:       `{a: *"y" | string}`,
+			expected: []string{`default: a ("x" -> "y")`},
+		},
+		{
+			name:     "default-added",
+			from:     `{a: "x" | "y"}`,
+			to:       `{a: *"x" | "y"}`,
+			expected: []string{`default: a ( -> "x")`},
+		},
+		{
+			name:     "default-and-widened",
+			from:     `{a: *1 | int32}`,
+			to:       `{a: *2 | int64}`,
+			expected: []string{"default: a (1 -> 2)", "widened: a (*1 | int32 -> *2 | int64)"},
+		},
+		{
+			name: "narrowed-widened",
+			from: `{a: >3, b: "x" | "y", c: int32}`,
+			to:   `{a: >5, b: "x" | "y" | "z", c: int64}`,
+			expected: []string{
+				"narrowed: a (>3 -> >5)",
+				`widened: b ("x" | "y" -> "x" | "y" | "z")`,
+				"widened: c (int32 -> int64)",
+			},
+		},
+		{
+			name:     "constraint",
+			from:     `{a: =~"^a"}`,
+			to:       `{a: =~"^b"}`,
+			expected: []string{`constraint: a (=~"^a" -> =~"^b")`},
+		},
+		{
+			name:     "optionality",
+			from:     `{a?: string, b: string}`,
+			to:       `{a: string, b?: string}`,
+			expected: []string{"required: a", "optional: b"},
+		},
+		{
+			name:     "nested",
+			from:     `{a: {b: string, c: {d: int}}}`,
+			to:       `{a: {b: string, c: {d: int, e?: string}}}`,
+			expected: []string{"added: a.c.e (string)"},
+		},
+	}
+
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			linstr := fmt.Sprintf(`name: "diff"
+schemas: [{version: [0, 0], schema: %s}, {version: [0, 1], schema: %s}]
+`, tc.from, tc.to)
+			lin, err := BindLineage(ctx.CompileString(linstr), rt, SkipBuggyChecks())
+			require.NoError(t, err)
+
+			d := Diff(SchemaP(lin, SV(0, 0)), SchemaP(lin, SV(0, 1)))
+			assert.Equal(t, SV(0, 0), d.From)
+			assert.Equal(t, SV(0, 1), d.To)
+
+			var changes []string
+			for _, c := range d.Changes {
+				changes = append(changes, c.String())
+			}
+			assert.Equal(t, tc.expected, changes)
+		})
+	}
+}
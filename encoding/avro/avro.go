@@ -0,0 +1,379 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"cuelang.org/go/cue"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/util"
+)
+
+var pathSchDef = cue.MakePath(cue.Hid("_#schema", "github.com/grafana/thema"))
+
+// Config controls Avro schema derivation from a Thema schema.
+type Config struct {
+	// Group indicates that the [thema.Schema] is from a grouped lineage - the root
+	// schema itself does not represent an object that is ever expected to exist
+	// independently, but each of its top-level fields do, including definitions and
+	// optional fields. A record schema is generated for each struct-kinded
+	// top-level field that is not a map, and the result is an Avro union of
+	// those records.
+	//
+	// NOTE - https://github.com/grafana/thema/issues/62 is the issue for formalizing
+	// the group concept. Fixing that issue will obviate this field. Once fixed,
+	// this field will be deprecated and ignored.
+	Group bool
+
+	// RootName specifies the name to use for the record representing the root of
+	// the schema. If empty, this defaults to the lineage name, sanitized to be a
+	// valid Avro name.
+	//
+	// No-op if [Group] is true.
+	RootName string
+
+	// Subpath specifies a path within the provided [thema.Schema] that should be
+	// translated, rather than the root schema. If the value at the path is not a
+	// struct, the generated schema is not a record, and does not carry version
+	// metadata.
+	//
+	// No-op if [Group] is true.
+	Subpath cue.Path
+
+	// Namespace specifies the namespace of the generated records. If empty, this
+	// defaults to the sanitized lineage name, suffixed with the schema's major
+	// version. Example: "my_lineage.v1"
+	Namespace string
+}
+
+// GenerateSchema creates an Avro schema, as JSON, that represents the provided
+// Thema Schema as a record.
+//
+// Structs are represented as records, lists as arrays, and structs containing
+// only a pattern constraint (e.g. [string]: int) as maps. Optional fields and
+// disjunctions of different kinds are represented as unions, with optional
+// fields defaulting to null. Schema defaults on scalar fields are carried over
+// as Avro field defaults.
+//
+// An error is returned if the schema contains a value that cannot be
+// represented in Avro, such as a disjunction of multiple structs, or a field
+// label that is not a valid Avro name.
+func GenerateSchema(sch thema.Schema, cfg *Config) ([]byte, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	g := newGenerator(sch, cfg.Namespace)
+	schdef := sch.Underlying().LookupPath(pathSchDef)
+
+	var out interface{}
+	if cfg.Group {
+		iter, err := schdef.Fields(cue.Definitions(true), cue.Optional(true))
+		if err != nil {
+			panic(fmt.Errorf("unreachable - should always be able to get iter for struct kinds: %w", err))
+		}
+
+		// Schemas with no record fields yield an empty union, rather than null
+		group := make([]*schema, 0)
+		for iter.Next() {
+			if iter.Value().IncompleteKind() != cue.StructKind || isMap(iter.Value()) {
+				continue
+			}
+			name := strings.Trim(iter.Selector().String(), "?#")
+			rec, err := g.record(avroName(util.SanitizeLabelString(name)), iter.Value(), []cue.Selector{iter.Selector()})
+			if err != nil {
+				return nil, fmt.Errorf("failed generation for grouped field %s: %w", iter.Selector(), err)
+			}
+			rec.namespace = g.namespace
+			rec.doc = g.doc
+			group = append(group, rec)
+		}
+		out = group
+	} else {
+		name := util.SanitizeLabelString(sch.Lineage().Name())
+		val := schdef
+		if sels := cfg.Subpath.Selectors(); len(sels) > 0 {
+			for i, sel := range sels {
+				if !val.Allows(sel) {
+					return nil, fmt.Errorf("subpath %q not present in schema", cue.MakePath(sels[:i+1]...))
+				}
+				val = val.LookupPath(cue.MakePath(sel))
+			}
+			name = util.SanitizeLabelString(sels[len(sels)-1].String())
+		}
+		if cfg.RootName != "" {
+			name = cfg.RootName
+		}
+
+		// A subpath may refer to a value that is not a record, in which case
+		// there is no named type to carry version metadata.
+		var root *schema
+		var err error
+		if val.IncompleteKind() == cue.StructKind && !isMap(val) {
+			root, err = g.record(avroName(name), val, cfg.Subpath.Selectors())
+		} else {
+			root, err = g.valueSchema(avroName(name), name, val, cfg.Subpath.Selectors())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if root.kind == kindRecord {
+			root.namespace = g.namespace
+			root.doc = g.doc
+		}
+		out = root
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// rootSchema generates the schema model for the root of the provided schema,
+// as used by codecs.
+func rootSchema(sch thema.Schema) (*schema, error) {
+	g := newGenerator(sch, "")
+	rec, err := g.record(avroName(util.SanitizeLabelString(sch.Lineage().Name())), sch.Underlying().LookupPath(pathSchDef), nil)
+	if err != nil {
+		return nil, err
+	}
+	rec.namespace = g.namespace
+	rec.doc = g.doc
+	return rec, nil
+}
+
+type generator struct {
+	namespace string
+	doc       string
+	// names holds all record names that have been used.
+	names map[string]bool
+}
+
+func newGenerator(sch thema.Schema, namespace string) *generator {
+	if namespace == "" {
+		namespace = fmt.Sprintf("%s.v%d", avroName(util.SanitizeLabelString(sch.Lineage().Name())), sch.Version()[0])
+	}
+	return &generator{
+		namespace: namespace,
+		doc:       fmt.Sprintf("Schema %s of the %q lineage.", sch.Version(), sch.Lineage().Name()),
+		names:     make(map[string]bool),
+	}
+}
+
+// recordName returns an unused record name for a struct at the provided label,
+// within the named parent record.
+func (g *generator) recordName(parent, label string) string {
+	name := avroName(titleCase(label))
+	if g.names[name] {
+		name = parent + name
+	}
+	for i, base := 2, name; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return name
+}
+
+func (g *generator) record(name string, v cue.Value, sels []cue.Selector) (*schema, error) {
+	if op, args := v.Expr(); op == cue.OrOp {
+		if _, has := v.Default(); !has && len(args) > 1 {
+			return nil, fmt.Errorf("%s: disjunctions of structs cannot be represented in Avro", cue.MakePath(sels...))
+		}
+	}
+
+	g.names[name] = true
+	rec := &schema{kind: kindRecord, name: name}
+
+	iter, err := v.Fields(cue.Optional(true))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cue.MakePath(sels...), err)
+	}
+	for iter.Next() {
+		label := iter.Label()
+		fsels := appendSel(sels, iter.Selector())
+		if !isAvroName(label) {
+			return nil, fmt.Errorf("%s: label is not a valid Avro field name", cue.MakePath(fsels...))
+		}
+
+		f := &field{name: label}
+		fv := iter.Value()
+		if f.typ, err = g.valueSchema(name, label, fv, fsels); err != nil {
+			return nil, err
+		}
+
+		if iter.IsOptional() {
+			if f.typ.kind != kindUnion {
+				f.typ = &schema{kind: kindUnion, branches: []*schema{f.typ}}
+			}
+			if !f.typ.hasBranch(kindNull) {
+				f.typ.branches = append([]*schema{{kind: kindNull}}, f.typ.branches...)
+			}
+			f.def = json.RawMessage("null")
+		} else if f.typ.kind != kindUnion && f.typ.kind != kindBytes && f.typ.isPrimitive() {
+			if d, has := fv.Default(); has && d.IsConcrete() {
+				if f.def, err = json.Marshal(d); err != nil {
+					return nil, fmt.Errorf("%s: %w", cue.MakePath(fsels...), err)
+				}
+			}
+		}
+		rec.fields = append(rec.fields, f)
+	}
+	return rec, nil
+}
+
+// valueSchema generates the schema for the value v of the field with the
+// provided label, within the named parent record.
+func (g *generator) valueSchema(parent, label string, v cue.Value, sels []cue.Selector) (*schema, error) {
+	path := cue.MakePath(sels...)
+	k := v.IncompleteKind()
+	if k == cue.TopKind {
+		return nil, fmt.Errorf("%s: unconstrained values cannot be represented in Avro", path)
+	}
+
+	var kinds []cue.Kind
+	for _, ik := range []cue.Kind{cue.NullKind, cue.BoolKind, cue.IntKind, cue.FloatKind, cue.StringKind, cue.BytesKind, cue.ListKind, cue.StructKind} {
+		if k&ik != 0 {
+			kinds = append(kinds, ik)
+		}
+	}
+	// Both ints and floats are accepted, so a double suffices for both
+	if k&cue.NumberKind == cue.NumberKind {
+		kinds = removeKind(kinds, cue.IntKind)
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("%s: values of kind %s cannot be represented in Avro", path, k)
+	}
+	if len(kinds) == 1 {
+		return g.kindSchema(parent, label, kinds[0], v, sels)
+	}
+
+	// Multiple kinds become a union. Each composite-kinded branch must be
+	// derived from the single arm of the disjunction having that kind.
+	u := &schema{kind: kindUnion}
+	for _, ik := range kinds {
+		arm := v
+		if ik == cue.ListKind || ik == cue.StructKind {
+			var err error
+			if arm, err = disjunctArm(v, ik); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		bs, err := g.kindSchema(parent, label, ik, arm, sels)
+		if err != nil {
+			return nil, err
+		}
+		u.branches = append(u.branches, bs)
+	}
+	return u, nil
+}
+
+func (g *generator) kindSchema(parent, label string, k cue.Kind, v cue.Value, sels []cue.Selector) (*schema, error) {
+	switch k {
+	case cue.NullKind:
+		return &schema{kind: kindNull}, nil
+	case cue.BoolKind:
+		return &schema{kind: kindBoolean}, nil
+	case cue.IntKind:
+		if v.Context().CompileString("int32").Subsume(v, cue.Raw(), cue.Schema()) == nil {
+			return &schema{kind: kindInt}, nil
+		}
+		return &schema{kind: kindLong}, nil
+	case cue.FloatKind:
+		return &schema{kind: kindDouble}, nil
+	case cue.StringKind:
+		return &schema{kind: kindString}, nil
+	case cue.BytesKind:
+		return &schema{kind: kindBytes}, nil
+	case cue.ListKind:
+		ev := v.LookupPath(cue.MakePath(cue.AnyIndex))
+		if !ev.Exists() {
+			return nil, fmt.Errorf("%s: lists must have an element type to be represented in Avro", cue.MakePath(sels...))
+		}
+		items, err := g.valueSchema(parent, label, ev, appendSel(sels, cue.AnyIndex))
+		if err != nil {
+			return nil, err
+		}
+		return &schema{kind: kindArray, items: items}, nil
+	default:
+		if isMap(v) {
+			vals, err := g.valueSchema(parent, label, v.LookupPath(cue.MakePath(cue.AnyString)), appendSel(sels, cue.AnyString))
+			if err != nil {
+				return nil, err
+			}
+			return &schema{kind: kindMap, items: vals}, nil
+		}
+		return g.record(g.recordName(parent, label), v, sels)
+	}
+}
+
+// disjunctArm returns the single arm of the disjunction v that has kind k.
+func disjunctArm(v cue.Value, k cue.Kind) (cue.Value, error) {
+	op, args := v.Expr()
+	if op != cue.OrOp {
+		return cue.Value{}, fmt.Errorf("cannot determine the %s branch of a union of kind %s", k, v.IncompleteKind())
+	}
+
+	var arm cue.Value
+	var n int
+	for _, a := range args {
+		if a.IncompleteKind()&k != 0 {
+			arm = a
+			n++
+		}
+	}
+	if n != 1 {
+		return cue.Value{}, fmt.Errorf("unions may contain only one %s branch to be represented in Avro", k)
+	}
+	return arm, nil
+}
+
+func removeKind(kinds []cue.Kind, k cue.Kind) []cue.Kind {
+	var ret []cue.Kind
+	for _, ik := range kinds {
+		if ik != k {
+			ret = append(ret, ik)
+		}
+	}
+	return ret
+}
+
+func appendSel(sels []cue.Selector, sel cue.Selector) []cue.Selector {
+	return append(append(make([]cue.Selector, 0, len(sels)+1), sels...), sel)
+}
+
+// isMap reports whether v is a struct with no regular fields and a pattern
+// constraint on its labels, which is represented in Avro as a map.
+func isMap(v cue.Value) bool {
+	if !v.LookupPath(cue.MakePath(cue.AnyString)).Exists() {
+		return false
+	}
+	iter, err := v.Fields(cue.Optional(true))
+	return err == nil && !iter.Next()
+}
+
+// isAvroName reports whether s is a valid Avro name: [A-Za-z_][A-Za-z0-9_]*
+func isAvroName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// avroName converts an already-sanitized name into a valid Avro name.
+func avroName(s string) string {
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		return "_" + s
+	}
+	return s
+}
+
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + util.SanitizeLabelString(s[1:])
+}
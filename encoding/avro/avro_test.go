@@ -0,0 +1,243 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/txtartest/bindlin"
+	"github.com/grafana/thema/internal/txtartest/vanilla"
+)
+
+func TestGenerate(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/avro/TestGenerate",
+		ThemaFS: thema.CueJointFS,
+	}
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	for _, cfg := range []struct {
+		name string
+		cfg  *Config
+	}{
+		{
+			name: "nilcfg",
+			cfg:  nil,
+		},
+		{
+			name: "group",
+			cfg: &Config{
+				Group: true,
+			},
+		},
+		{
+			name: "subpath",
+			cfg: &Config{
+				Subpath: cue.ParsePath("someField"),
+			},
+		},
+		{
+			name: "subpathroot",
+			cfg: &Config{
+				Subpath:  cue.ParsePath("someField"),
+				RootName: "overriddenName",
+			},
+		},
+	} {
+		tcfg := cfg
+		t.Run(tcfg.name, func(t *testing.T) {
+			testcpy := test
+			testcpy.Name += "/" + tcfg.name
+
+			testcpy.Run(t, func(tc *vanilla.Test) {
+				if strings.HasPrefix(tcfg.name, "subpath") && !tc.HasTag("subpath") {
+					return
+				}
+				if testing.Short() && tc.HasTag("slow") {
+					t.Skip("case is tagged #slow, skipping for -short")
+				}
+
+				lin, err := bindlin.BindTxtarLineage(tc, rt)
+				if err != nil {
+					tc.Fatal(err)
+				}
+				for sch := lin.First(); sch != nil; sch = sch.Successor() {
+					b, err := GenerateSchema(sch, tcfg.cfg)
+					if err != nil {
+						fmt.Fprintf(tc, "// %s error: %s\n", sch.Version(), err)
+						continue
+					}
+					_, err = tc.Write(append(b, '\n'))
+					require.NoError(t, err)
+				}
+			})
+		})
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/avro/TestCodecRoundTrip",
+		ThemaFS: thema.CueJointFS,
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		for sch := lin.First(); sch != nil; sch = sch.Successor() {
+			bin, err := NewCodec(sch)
+			if err != nil {
+				continue
+			}
+			ocf, err := NewOCFCodec(sch)
+			require.NoError(t, err)
+
+			for name, inst := range sch.Examples() {
+				for mode, codec := range map[string]*Codec{"binary": bin, "ocf": ocf} {
+					tc.Run(fmt.Sprintf("%s/%s/%s", sch.Version(), name, mode), func(t *testing.T) {
+						b, err := codec.Encode(inst.Underlying())
+						require.NoError(t, err)
+						v, err := codec.Decode(ctx, b)
+						require.NoError(t, err)
+						_, err = sch.Validate(v)
+						require.NoError(t, err)
+
+						want, err := json.Marshal(inst.Underlying())
+						require.NoError(t, err)
+						got, err := json.Marshal(v)
+						require.NoError(t, err)
+						assert.JSONEq(t, string(want), string(got))
+					})
+				}
+			}
+		}
+	})
+}
+
+func TestCodecTypes(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	linstr := `name: "types"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		str:  string
+		i32:  int32
+		i64:  int
+		num:  number
+		f:    float
+		b:    bool
+		byt:  bytes
+		opt?: string
+		list: [...int32]
+		m: [string]: bool
+		nested: {
+			a: string | int
+			n: null | string
+		}
+	}
+}]
+`
+	lin, err := thema.BindLineage(ctx.CompileString(linstr), rt)
+	require.NoError(t, err)
+	sch := lin.First()
+
+	input := `{
+		str: "hello"
+		i32: -5
+		i64: 9007199254740993
+		num: 1.5
+		f: 2.0
+		b: true
+		byt: 'abc'
+		list: [1, 2, 3]
+		m: {x: true, y: false}
+		nested: {a: 4, n: null}
+	}`
+
+	for _, mk := range []func(thema.Schema) (*Codec, error){NewCodec, NewOCFCodec} {
+		codec, err := mk(sch)
+		require.NoError(t, err)
+
+		b, err := codec.Encode(ctx.CompileString(input))
+		require.NoError(t, err)
+		v, err := codec.Decode(ctx, b)
+		require.NoError(t, err)
+		_, err = sch.Validate(v)
+		require.NoError(t, err)
+		assert.True(t, v.Equals(ctx.CompileString(input)), "round-tripped value differs: %v", v)
+		assert.False(t, v.LookupPath(cue.ParsePath("opt")).Exists())
+	}
+
+	codec, err := NewCodec(sch)
+	require.NoError(t, err)
+	_, err = codec.Encode(ctx.CompileString(strings.Replace(input, "-5", "9007199254740993", 1)))
+	assert.Error(t, err, "int32 overflow should be rejected")
+}
+
+func TestOCFWriterSchema(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	linstr := `name: "evolve"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		first: string
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		first:   string
+		second?: int32
+	}
+}]
+`
+	lin, err := thema.BindLineage(ctx.CompileString(linstr), rt)
+	require.NoError(t, err)
+
+	c0, err := NewOCFCodec(thema.SchemaP(lin, thema.SV(0, 0)))
+	require.NoError(t, err)
+	c1, err := NewOCFCodec(thema.SchemaP(lin, thema.SV(0, 1)))
+	require.NoError(t, err)
+
+	// Files carry their writer schema, so each codec can read the other's output
+	b, err := c1.Encode(ctx.CompileString(`{first: "foo", second: 2}`))
+	require.NoError(t, err)
+	v, err := c0.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.True(t, v.Equals(ctx.CompileString(`{first: "foo", second: 2}`)))
+
+	b, err = c0.Encode(ctx.CompileString(`{first: "bar"}`))
+	require.NoError(t, err)
+	v, err = c1.Decode(ctx, b)
+	require.NoError(t, err)
+	assert.True(t, v.Equals(ctx.CompileString(`{first: "bar"}`)))
+
+	var sch map[string]interface{}
+	out, err := GenerateSchema(thema.SchemaP(lin, thema.SV(0, 1)), nil)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(out, &sch))
+	assert.Equal(t, "evolve.v0", sch["namespace"])
+	assert.Contains(t, sch["doc"], "0.1")
+}
@@ -0,0 +1,439 @@
+package avro
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+	"github.com/grafana/thema"
+)
+
+var errTruncated = errors.New("unexpected end of Avro input")
+
+// Codec decodes Avro data into CUE, and encodes CUE values into Avro data,
+// according to the record schema generated for a particular [thema.Schema] by
+// [GenerateSchema].
+//
+// Codec satisfies the vmux.Codec interface.
+type Codec struct {
+	sch *schema
+	ocf bool
+}
+
+// NewCodec creates a [Codec] that reads and writes a single datum in the Avro
+// binary encoding, using the record schema of the provided Thema schema as
+// both the reader and writer schema.
+//
+// The binary encoding carries no schema information, so the Codec can only
+// decode data that was written using the same Avro schema.
+func NewCodec(sch thema.Schema) (*Codec, error) {
+	s, err := rootSchema(sch)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{sch: s}, nil
+}
+
+// NewOCFCodec creates a [Codec] that reads and writes Avro object container
+// files containing a single datum.
+//
+// Encoded files embed the record schema of the provided Thema schema. When
+// decoding, the schema embedded in the file is used to read the datum, so
+// files written with any schema, such as those for other versions in the same
+// lineage, can be decoded. Files using the "null" and "deflate" codecs are
+// supported.
+func NewOCFCodec(sch thema.Schema) (*Codec, error) {
+	s, err := rootSchema(sch)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{sch: s, ocf: true}, nil
+}
+
+// Decode converts Avro data into a [cue.Value].
+func (c *Codec) Decode(ctx *cue.Context, b []byte) (cue.Value, error) {
+	s := c.sch
+	if c.ocf {
+		var err error
+		if s, b, err = readOCF(b); err != nil {
+			return cue.Value{}, err
+		}
+	}
+
+	r := &reader{b: b}
+	expr, err := r.value(s)
+	if err != nil {
+		return cue.Value{}, err
+	}
+	if len(r.b) > 0 {
+		return cue.Value{}, fmt.Errorf("%d bytes of unexpected trailing Avro input", len(r.b))
+	}
+	return ctx.BuildExpr(expr), nil
+}
+
+// Encode converts the provided [cue.Value], which must be concrete, into Avro
+// data.
+func (c *Codec) Encode(v cue.Value) ([]byte, error) {
+	b, err := appendValue(nil, c.sch, v)
+	if err != nil {
+		return nil, err
+	}
+	if c.ocf {
+		return writeOCF(c.sch, b)
+	}
+	return b, nil
+}
+
+type reader struct {
+	b []byte
+}
+
+func (r *reader) long() (int64, error) {
+	x, n := binary.Varint(r.b)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.b = r.b[n:]
+	return x, nil
+}
+
+func (r *reader) fixed(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errTruncated
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	n, err := r.long()
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt32 {
+		return nil, errTruncated
+	}
+	return r.fixed(int(n))
+}
+
+// blocks reads the blocks of an array or map, calling fn once per item.
+func (r *reader) blocks(fn func() error) error {
+	for {
+		n, err := r.long()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if n < 0 {
+			// A negative count is followed by the block's size in bytes
+			n = -n
+			if _, err = r.long(); err != nil {
+				return err
+			}
+		}
+		for ; n > 0; n-- {
+			if err = fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (r *reader) value(s *schema) (ast.Expr, error) {
+	switch s.kind {
+	case kindNull:
+		return ast.NewNull(), nil
+	case kindBoolean:
+		b, err := r.fixed(1)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewBool(b[0] != 0), nil
+	case kindInt, kindLong:
+		x, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewLit(token.INT, strconv.FormatInt(x, 10)), nil
+	case kindFloat:
+		b, err := r.fixed(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatLit(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case kindDouble:
+		b, err := r.fixed(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatLit(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case kindBytes:
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewLit(token.STRING, literal.Bytes.Quote(string(b))), nil
+	case kindFixed:
+		b, err := r.fixed(s.size)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewLit(token.STRING, literal.Bytes.Quote(string(b))), nil
+	case kindString:
+		b, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewString(string(b)), nil
+	case kindEnum:
+		i, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.symbols)) {
+			return nil, fmt.Errorf("enum %s has no symbol at index %d", s.name, i)
+		}
+		return ast.NewString(s.symbols[i]), nil
+	case kindUnion:
+		i, err := r.long()
+		if err != nil {
+			return nil, err
+		}
+		if i < 0 || i >= int64(len(s.branches)) {
+			return nil, fmt.Errorf("union has no branch at index %d", i)
+		}
+		return r.value(s.branches[i])
+	case kindArray:
+		lst := &ast.ListLit{}
+		err := r.blocks(func() error {
+			x, err := r.value(s.items)
+			lst.Elts = append(lst.Elts, x)
+			return err
+		})
+		return lst, err
+	case kindMap:
+		st := &ast.StructLit{}
+		err := r.blocks(func() error {
+			k, err := r.bytes()
+			if err != nil {
+				return err
+			}
+			x, err := r.value(s.items)
+			st.Elts = append(st.Elts, &ast.Field{Label: ast.NewString(string(k)), Value: x})
+			return err
+		})
+		return st, err
+	case kindRecord:
+		st := &ast.StructLit{}
+		for _, f := range s.fields {
+			x, err := r.value(f.typ)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", s.name, f.name, err)
+			}
+			// Optional fields, which default to null, are omitted when null
+			if lit, is := x.(*ast.BasicLit); is && lit.Kind == token.NULL && f.optional() {
+				continue
+			}
+			st.Elts = append(st.Elts, &ast.Field{Label: ast.NewString(f.name), Value: x})
+		}
+		return st, nil
+	default:
+		return nil, fmt.Errorf("unsupported Avro type %q", s.kind)
+	}
+}
+
+func floatLit(f float64) (ast.Expr, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%v cannot be represented in CUE", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return ast.NewLit(token.FLOAT, s), nil
+}
+
+func appendValue(b []byte, s *schema, v cue.Value) ([]byte, error) {
+	var err error
+	switch s.kind {
+	case kindNull:
+		if v.Exists() && v.Kind() != cue.NullKind {
+			err = fmt.Errorf("expected null, got %s", v.Kind())
+		}
+	case kindBoolean:
+		var x bool
+		if x, err = v.Bool(); err == nil {
+			if x {
+				b = append(b, 1)
+			} else {
+				b = append(b, 0)
+			}
+		}
+	case kindInt, kindLong:
+		var x int64
+		if x, err = v.Int64(); err == nil {
+			if s.kind == kindInt && (x < math.MinInt32 || x > math.MaxInt32) {
+				err = fmt.Errorf("%d overflows Avro int", x)
+			}
+			b = binary.AppendVarint(b, x)
+		}
+	case kindFloat:
+		var x float64
+		if x, err = v.Float64(); err == nil {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(x)))
+		}
+	case kindDouble:
+		var x float64
+		if x, err = v.Float64(); err == nil {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+		}
+	case kindBytes, kindFixed:
+		var x []byte
+		if x, err = v.Bytes(); err == nil {
+			if s.kind == kindFixed {
+				if len(x) != s.size {
+					err = fmt.Errorf("expected %d bytes for fixed %s, got %d", s.size, s.name, len(x))
+				}
+				b = append(b, x...)
+			} else {
+				b = appendBytes(b, x)
+			}
+		}
+	case kindString:
+		var x string
+		if x, err = v.String(); err == nil {
+			b = appendBytes(b, []byte(x))
+		}
+	case kindEnum:
+		var x string
+		if x, err = v.String(); err == nil {
+			i := indexOf(s.symbols, x)
+			if i < 0 {
+				err = fmt.Errorf("%q is not a symbol of enum %s", x, s.name)
+			}
+			b = binary.AppendVarint(b, int64(i))
+		}
+	case kindUnion:
+		i := unionBranch(s, v)
+		if i < 0 {
+			err = fmt.Errorf("no union branch accepts a value of kind %s", v.Kind())
+			break
+		}
+		b = binary.AppendVarint(b, int64(i))
+		return appendValue(b, s.branches[i], v)
+	case kindArray:
+		var iter cue.Iterator
+		if iter, err = v.List(); err == nil {
+			var items []byte
+			var n int64
+			for iter.Next() {
+				if items, err = appendValue(items, s.items, iter.Value()); err != nil {
+					return nil, err
+				}
+				n++
+			}
+			b = appendBlock(b, n, items)
+		}
+	case kindMap:
+		var iter *cue.Iterator
+		if iter, err = v.Fields(); err == nil {
+			var items []byte
+			var n int64
+			for iter.Next() {
+				items = appendBytes(items, []byte(iter.Label()))
+				if items, err = appendValue(items, s.items, iter.Value()); err != nil {
+					return nil, err
+				}
+				n++
+			}
+			b = appendBlock(b, n, items)
+		}
+	case kindRecord:
+		for _, f := range s.fields {
+			fv := v.LookupPath(cue.MakePath(cue.Str(f.name)))
+			if !fv.Exists() && !(f.typ.kind == kindUnion && f.typ.hasBranch(kindNull)) {
+				return nil, fmt.Errorf("%s: missing required field %s", v.Path(), f.name)
+			}
+			if b, err = appendValue(b, f.typ, fv); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported Avro type %q", s.kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", v.Path(), err)
+	}
+	return b, nil
+}
+
+// unionBranch selects the index of the branch of union s to use for v, or -1
+// if no branch is suitable.
+func unionBranch(s *schema, v cue.Value) int {
+	var want []kind
+	switch {
+	case !v.Exists():
+		want = []kind{kindNull}
+	default:
+		switch v.Kind() {
+		case cue.NullKind:
+			want = []kind{kindNull}
+		case cue.BoolKind:
+			want = []kind{kindBoolean}
+		case cue.IntKind:
+			want = []kind{kindLong, kindInt, kindDouble, kindFloat}
+		case cue.FloatKind:
+			want = []kind{kindDouble, kindFloat}
+		case cue.StringKind:
+			want = []kind{kindString, kindEnum}
+		case cue.BytesKind:
+			want = []kind{kindBytes, kindFixed}
+		case cue.ListKind:
+			want = []kind{kindArray}
+		case cue.StructKind:
+			want = []kind{kindRecord, kindMap}
+		}
+	}
+	for _, k := range want {
+		for i, br := range s.branches {
+			if br.kind == k {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func indexOf(strs []string, s string) int {
+	for i, str := range strs {
+		if str == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func appendBytes(b, x []byte) []byte {
+	b = binary.AppendVarint(b, int64(len(x)))
+	return append(b, x...)
+}
+
+// appendBlock appends the encoding of an array or map with n items, which are
+// already encoded in items.
+func appendBlock(b []byte, n int64, items []byte) []byte {
+	if n > 0 {
+		b = binary.AppendVarint(b, n)
+		b = append(b, items...)
+	}
+	return binary.AppendVarint(b, 0)
+}
@@ -0,0 +1,8 @@
+// Package avro provides tools for representing Thema schemas as Avro
+// schemas: generating Avro record schemas from a [thema.Schema], and encoding
+// and decoding Avro binary data and object container files (OCF).
+//
+// Each generated schema records the version of the Thema schema from which
+// it was generated. The major version appears in the namespace and the full
+// version appears in the doc of the root record.
+package avro
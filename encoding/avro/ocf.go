@@ -0,0 +1,105 @@
+package avro
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+var ocfMagic = []byte{'O', 'b', 'j', 1}
+
+const syncSize = 16
+
+// readOCF reads an Avro object container file containing exactly one datum,
+// returning the writer schema and the encoded datum.
+func readOCF(b []byte) (*schema, []byte, error) {
+	if !bytes.HasPrefix(b, ocfMagic) {
+		return nil, nil, fmt.Errorf("input is not an Avro object container file")
+	}
+	r := &reader{b: b[len(ocfMagic):]}
+
+	meta := make(map[string][]byte)
+	err := r.blocks(func() error {
+		k, err := r.bytes()
+		if err != nil {
+			return err
+		}
+		v, err := r.bytes()
+		meta[string(k)] = v
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid Avro object container file header: %w", err)
+	}
+	sync, err := r.fixed(syncSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sch, err := parseSchema(meta["avro.schema"])
+	if err != nil {
+		return nil, nil, err
+	}
+	codec := string(meta["avro.codec"])
+	if codec != "" && codec != "null" && codec != "deflate" {
+		return nil, nil, fmt.Errorf("unsupported Avro object container file codec %q", codec)
+	}
+
+	var data []byte
+	var count int64
+	for len(r.b) > 0 {
+		n, err := r.long()
+		if err != nil {
+			return nil, nil, err
+		}
+		block, err := r.bytes()
+		if err != nil {
+			return nil, nil, err
+		}
+		if bsync, err := r.fixed(syncSize); err != nil || !bytes.Equal(bsync, sync) {
+			return nil, nil, fmt.Errorf("invalid sync marker after Avro object container file block")
+		}
+		if codec == "deflate" {
+			if block, err = io.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+				return nil, nil, fmt.Errorf("error inflating Avro object container file block: %w", err)
+			}
+		}
+		count += n
+		data = append(data, block...)
+	}
+	if count != 1 {
+		return nil, nil, fmt.Errorf("Avro object container file contains %d data, but only one can be decoded", count)
+	}
+	return sch, data, nil
+}
+
+// writeOCF writes an Avro object container file, using the null codec,
+// containing the single encoded datum.
+func writeOCF(sch *schema, datum []byte) ([]byte, error) {
+	sjson, err := json.Marshal(sch)
+	if err != nil {
+		return nil, err
+	}
+
+	b := append([]byte(nil), ocfMagic...)
+	var meta []byte
+	meta = appendBytes(meta, []byte("avro.codec"))
+	meta = appendBytes(meta, []byte("null"))
+	meta = appendBytes(meta, []byte("avro.schema"))
+	meta = appendBytes(meta, sjson)
+	b = appendBlock(b, 2, meta)
+
+	// The sync marker need only be unlikely to appear in the data. Deriving it
+	// from the schema keeps output deterministic.
+	sum := sha256.Sum256(sjson)
+	sync := sum[:syncSize]
+	b = append(b, sync...)
+
+	b = binary.AppendVarint(b, 1)
+	b = appendBytes(b, datum)
+	return append(b, sync...), nil
+}
@@ -0,0 +1,264 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type kind string
+
+const (
+	kindNull    kind = "null"
+	kindBoolean kind = "boolean"
+	kindInt     kind = "int"
+	kindLong    kind = "long"
+	kindFloat   kind = "float"
+	kindDouble  kind = "double"
+	kindBytes   kind = "bytes"
+	kindString  kind = "string"
+	kindRecord  kind = "record"
+	kindEnum    kind = "enum"
+	kindArray   kind = "array"
+	kindMap     kind = "map"
+	kindFixed   kind = "fixed"
+	kindUnion   kind = "union"
+)
+
+// schema is an Avro schema. Only the subset of Avro needed to represent Thema
+// schemas is generated, but all types are supported when reading the schema of
+// an object container file.
+type schema struct {
+	kind kind
+
+	// name, namespace and doc are set for named types: records, enums and fixed.
+	name      string
+	namespace string
+	doc       string
+
+	// fields is set for records.
+	fields []*field
+	// items is the element type of arrays, and the value type of maps.
+	items *schema
+	// branches is set for unions.
+	branches []*schema
+	// symbols is set for enums.
+	symbols []string
+	// size is set for fixed.
+	size int
+}
+
+type field struct {
+	name string
+	typ  *schema
+	// def is the JSON encoding of the field's default value, if any.
+	def json.RawMessage
+}
+
+// optional reports whether the field represents an optional field in a Thema
+// schema, which is a union with null that defaults to null.
+func (f *field) optional() bool {
+	return string(f.def) == "null" && f.typ.kind == kindUnion && f.typ.hasBranch(kindNull)
+}
+
+func (s *schema) isPrimitive() bool {
+	switch s.kind {
+	case kindRecord, kindEnum, kindArray, kindMap, kindFixed, kindUnion:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *schema) hasBranch(k kind) bool {
+	for _, b := range s.branches {
+		if b.kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON implements [json.Marshaler], producing the canonical JSON form
+// of the schema with fields in a stable order.
+func (s *schema) MarshalJSON() ([]byte, error) {
+	switch s.kind {
+	case kindRecord:
+		type jfield struct {
+			Name    string          `json:"name"`
+			Type    *schema         `json:"type"`
+			Default json.RawMessage `json:"default,omitempty"`
+		}
+		fields := make([]jfield, len(s.fields))
+		for i, f := range s.fields {
+			fields[i] = jfield{Name: f.name, Type: f.typ, Default: f.def}
+		}
+		return json.Marshal(struct {
+			Type      kind     `json:"type"`
+			Name      string   `json:"name"`
+			Namespace string   `json:"namespace,omitempty"`
+			Doc       string   `json:"doc,omitempty"`
+			Fields    []jfield `json:"fields"`
+		}{kindRecord, s.name, s.namespace, s.doc, fields})
+	case kindArray:
+		return json.Marshal(struct {
+			Type  kind    `json:"type"`
+			Items *schema `json:"items"`
+		}{kindArray, s.items})
+	case kindMap:
+		return json.Marshal(struct {
+			Type   kind    `json:"type"`
+			Values *schema `json:"values"`
+		}{kindMap, s.items})
+	case kindEnum:
+		return json.Marshal(struct {
+			Type      kind     `json:"type"`
+			Name      string   `json:"name"`
+			Namespace string   `json:"namespace,omitempty"`
+			Symbols   []string `json:"symbols"`
+		}{kindEnum, s.name, s.namespace, s.symbols})
+	case kindFixed:
+		return json.Marshal(struct {
+			Type      kind   `json:"type"`
+			Name      string `json:"name"`
+			Namespace string `json:"namespace,omitempty"`
+			Size      int    `json:"size"`
+		}{kindFixed, s.name, s.namespace, s.size})
+	case kindUnion:
+		return json.Marshal(s.branches)
+	default:
+		return json.Marshal(s.kind)
+	}
+}
+
+// parseSchema parses an Avro schema from its JSON representation.
+func parseSchema(b []byte) (*schema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("invalid Avro schema: %w", err)
+	}
+	p := &schemaParser{named: make(map[string]*schema)}
+	return p.parse(raw, "")
+}
+
+type schemaParser struct {
+	// named holds all named types, keyed by full name.
+	named map[string]*schema
+}
+
+func (p *schemaParser) parse(raw interface{}, ns string) (*schema, error) {
+	switch x := raw.(type) {
+	case string:
+		return p.ref(x, ns)
+	case []interface{}:
+		u := &schema{kind: kindUnion}
+		for _, b := range x {
+			bs, err := p.parse(b, ns)
+			if err != nil {
+				return nil, err
+			}
+			u.branches = append(u.branches, bs)
+		}
+		return u, nil
+	case map[string]interface{}:
+		return p.parseObject(x, ns)
+	default:
+		return nil, fmt.Errorf("invalid Avro schema: unexpected JSON value %v", raw)
+	}
+}
+
+func (p *schemaParser) ref(name, ns string) (*schema, error) {
+	switch k := kind(name); k {
+	case kindNull, kindBoolean, kindInt, kindLong, kindFloat, kindDouble, kindBytes, kindString:
+		return &schema{kind: k}, nil
+	}
+	if s, has := p.named[fullName(name, ns)]; has {
+		return s, nil
+	}
+	if s, has := p.named[name]; has {
+		return s, nil
+	}
+	return nil, fmt.Errorf("invalid Avro schema: unknown type %q", name)
+}
+
+func (p *schemaParser) parseObject(obj map[string]interface{}, ns string) (*schema, error) {
+	typ, _ := obj["type"].(string)
+	if typ == "" {
+		// e.g. {"type": {"type": "array", ...}}
+		return p.parse(obj["type"], ns)
+	}
+
+	s := &schema{kind: kind(typ)}
+	switch s.kind {
+	case kindRecord, kindEnum, kindFixed:
+		s.name, _ = obj["name"].(string)
+		if s.name == "" {
+			return nil, fmt.Errorf("invalid Avro schema: %s has no name", typ)
+		}
+		if nsv, has := obj["namespace"].(string); has {
+			ns = nsv
+		}
+		s.namespace = ns
+		if i := strings.LastIndexByte(s.name, '.'); i >= 0 {
+			ns = s.name[:i]
+		}
+		s.doc, _ = obj["doc"].(string)
+		p.named[fullName(s.name, s.namespace)] = s
+	}
+
+	switch s.kind {
+	case kindRecord:
+		fields, _ := obj["fields"].([]interface{})
+		for _, rf := range fields {
+			fobj, ok := rf.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid Avro schema: field of record %s is not an object", s.name)
+			}
+			f := &field{}
+			f.name, _ = fobj["name"].(string)
+			var err error
+			if f.typ, err = p.parse(fobj["type"], ns); err != nil {
+				return nil, err
+			}
+			if def, has := fobj["default"]; has {
+				if f.def, err = json.Marshal(def); err != nil {
+					return nil, err
+				}
+			}
+			s.fields = append(s.fields, f)
+		}
+	case kindEnum:
+		syms, _ := obj["symbols"].([]interface{})
+		for _, sym := range syms {
+			str, _ := sym.(string)
+			s.symbols = append(s.symbols, str)
+		}
+	case kindFixed:
+		size, _ := obj["size"].(float64)
+		s.size = int(size)
+	case kindArray, kindMap:
+		key := "items"
+		if s.kind == kindMap {
+			key = "values"
+		}
+		var err error
+		if s.items, err = p.parse(obj[key], ns); err != nil {
+			return nil, err
+		}
+	default:
+		if !s.isPrimitive() {
+			return nil, fmt.Errorf("invalid Avro schema: unknown type %q", typ)
+		}
+		// Primitive types may be written in object form, possibly with
+		// logical type annotations, which do not affect encoding.
+		return p.ref(typ, ns)
+	}
+	return s, nil
+}
+
+func fullName(name, ns string) string {
+	if ns == "" || strings.ContainsRune(name, '.') {
+		return name
+	}
+	return ns + "." + name
+}
@@ -0,0 +1,294 @@
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cuelang.org/go/cue"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/vmux"
+)
+
+// LacunaAnnotation is the annotation key under which a [ConversionHandler]
+// records the lacunas emitted while translating an object. The annotation value
+// is the JSON encoding of the list of lacunas.
+const LacunaAnnotation = "thema.grafana.com/lacunas"
+
+// VersionName returns the name of the version in a CustomResourceDefinition
+// generated by [GenerateCRD] that corresponds to the provided schema version.
+//
+// Dots are not permitted in CRD version names, so the major and minor versions
+// are separated by a hyphen: 1.2 becomes "v1-2".
+func VersionName(v thema.SyntacticVersion) string {
+	return fmt.Sprintf("v%d-%d", v[0], v[1])
+}
+
+// ParseVersionName parses a CRD version name, as produced by [VersionName],
+// into a [thema.SyntacticVersion]. An apiVersion containing a group prefix,
+// such as "stable.example.com/v1-2", is also accepted.
+func ParseVersionName(name string) (thema.SyntacticVersion, error) {
+	if idx := strings.LastIndex(name, "/"); idx != -1 {
+		name = name[idx+1:]
+	}
+
+	parts := strings.Split(strings.TrimPrefix(name, "v"), "-")
+	if !strings.HasPrefix(name, "v") || len(parts) != 2 {
+		return thema.SyntacticVersion{}, fmt.Errorf("%q is not a valid thema CRD version name, must be of the form \"v<major>-<minor>\"", name)
+	}
+
+	var synv thema.SyntacticVersion
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return thema.SyntacticVersion{}, fmt.Errorf("%q is not a valid thema CRD version name: %w", name, err)
+		}
+		synv[i] = uint(n)
+	}
+	return synv, nil
+}
+
+// ConversionHandler is an [http.Handler] that implements the Kubernetes
+// apiextensions.k8s.io/v1 ConversionReview protocol for custom resources
+// whose CustomResourceDefinition was generated from a lineage by [GenerateCRD].
+//
+// Each object in a ConversionReview request is validated against the lineage
+// with [thema.Lineage.ValidateAny], then translated to the requested version
+// with [thema.Instance.Translate]. Any lacunas emitted by the translation are
+// recorded on the converted object in the [LacunaAnnotation] annotation.
+//
+// An object's status is translated along with the rest of the object if any
+// schema in the lineage declares a top-level status field. Otherwise, it is
+// not part of the lineage's schemas, and is copied unchanged to the converted
+// object.
+type ConversionHandler struct {
+	lin   thema.Lineage
+	codec vmux.Codec
+
+	// schemaStatus is true if status is declared by the lineage's schemas.
+	schemaStatus bool
+
+	// CUE is not safe for concurrent use, so all conversions are serialized.
+	mut sync.Mutex
+}
+
+// NewConversionHandler returns a [ConversionHandler] that converts custom
+// resources between the schema versions of the provided lineage.
+func NewConversionHandler(lin thema.Lineage) *ConversionHandler {
+	h := &ConversionHandler{
+		lin:   lin,
+		codec: vmux.NewJSONCodec("conversion.json"),
+	}
+	for _, sch := range lin.All() {
+		if sch.Underlying().LookupPath(cue.ParsePath("schema.status")).Exists() {
+			h.schemaStatus = true
+			break
+		}
+	}
+	return h
+}
+
+type conversionReview struct {
+	APIVersion string              `json:"apiVersion"`
+	Kind       string              `json:"kind"`
+	Request    *conversionRequest  `json:"request,omitempty"`
+	Response   *conversionResponse `json:"response,omitempty"`
+}
+
+type conversionRequest struct {
+	UID               string            `json:"uid"`
+	DesiredAPIVersion string            `json:"desiredAPIVersion"`
+	Objects           []json.RawMessage `json:"objects"`
+}
+
+type conversionResponse struct {
+	UID              string            `json:"uid"`
+	ConvertedObjects []json.RawMessage `json:"convertedObjects"`
+	Result           conversionStatus  `json:"result"`
+}
+
+type conversionStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func (h *ConversionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, fmt.Sprintf("method %s not allowed, must be POST", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+
+	var review conversionReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		http.Error(w, fmt.Sprintf("could not decode ConversionReview: %s", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "ConversionReview contains no request", http.StatusBadRequest)
+		return
+	}
+
+	resp := &conversionResponse{
+		UID:              review.Request.UID,
+		ConvertedObjects: []json.RawMessage{},
+		Result:           conversionStatus{Status: "Success"},
+	}
+	converted, err := h.convert(review.Request)
+	if err != nil {
+		resp.Result = conversionStatus{
+			Status:  "Failure",
+			Message: err.Error(),
+		}
+	} else {
+		resp.ConvertedObjects = converted
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversionReview{ //nolint:errcheck
+		APIVersion: review.APIVersion,
+		Kind:       review.Kind,
+		Response:   resp,
+	})
+}
+
+func (h *ConversionHandler) convert(req *conversionRequest) ([]json.RawMessage, error) {
+	to, err := ParseVersionName(req.DesiredAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = h.lin.Schema(to); err != nil {
+		return nil, fmt.Errorf("desired version %s does not exist in lineage %q", to, h.lin.Name())
+	}
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	converted := make([]json.RawMessage, 0, len(req.Objects))
+	for i, obj := range req.Objects {
+		out, err := h.convertObject(obj, req.DesiredAPIVersion, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert object %d: %w", i, err)
+		}
+		converted = append(converted, out)
+	}
+	return converted, nil
+}
+
+// convertObject translates a single object. The Kubernetes type and object
+// metadata are not part of the lineage's schemas, so they are set aside during
+// validation and translation, then restored on the result. The same is done
+// with status, unless the lineage's schemas declare it.
+func (h *ConversionHandler) convertObject(obj json.RawMessage, apiVersion string, to thema.SyntacticVersion) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(obj, &fields); err != nil {
+		return nil, err
+	}
+
+	rawmeta := fields["metadata"]
+	kind := fields["kind"]
+	delete(fields, "apiVersion")
+	delete(fields, "kind")
+	delete(fields, "metadata")
+
+	var status json.RawMessage
+	if !h.schemaStatus {
+		status = fields["status"]
+		delete(fields, "status")
+	}
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	v, err := h.codec.Decode(h.lin.Runtime().Context(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	inst := h.lin.ValidateAny(v)
+	if inst == nil {
+		return nil, fmt.Errorf("object is not valid against any schema in lineage %q", h.lin.Name())
+	}
+	tinst, lac, err := inst.Translate(to)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := h.codec.Encode(tinst.Underlying())
+	if err != nil {
+		return nil, err
+	}
+	fields = nil
+	if err = json.Unmarshal(out, &fields); err != nil {
+		return nil, err
+	}
+
+	if rawmeta != nil || (lac != nil && len(lac.AsList()) > 0) {
+		if rawmeta, err = annotateLacunas(rawmeta, lac); err != nil {
+			return nil, err
+		}
+	}
+
+	if fields["apiVersion"], err = json.Marshal(apiVersion); err != nil {
+		return nil, err
+	}
+	if kind != nil {
+		fields["kind"] = kind
+	}
+	if rawmeta != nil {
+		fields["metadata"] = rawmeta
+	}
+	if status != nil {
+		fields["status"] = status
+	}
+	return json.Marshal(fields)
+}
+
+// annotateLacunas sets the lacuna annotation in the provided raw object
+// metadata to the provided lacunas, or removes it if there are none. All other
+// metadata passes through unmodified.
+func annotateLacunas(rawmeta json.RawMessage, lac thema.TranslationLacunas) (json.RawMessage, error) {
+	var meta map[string]json.RawMessage
+	if rawmeta != nil {
+		if err := json.Unmarshal(rawmeta, &meta); err != nil {
+			return nil, fmt.Errorf("invalid object metadata: %w", err)
+		}
+	}
+	if meta == nil {
+		meta = make(map[string]json.RawMessage)
+	}
+
+	var annotations map[string]string
+	if raw, has := meta["annotations"]; has {
+		if err := json.Unmarshal(raw, &annotations); err != nil {
+			return nil, fmt.Errorf("invalid object annotations: %w", err)
+		}
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	if lac != nil && len(lac.AsList()) > 0 {
+		lb, err := json.Marshal(lac.AsList())
+		if err != nil {
+			return nil, err
+		}
+		annotations[LacunaAnnotation] = string(lb)
+	} else {
+		delete(annotations, LacunaAnnotation)
+	}
+
+	if len(annotations) == 0 {
+		delete(meta, "annotations")
+	} else {
+		raw, err := json.Marshal(annotations)
+		if err != nil {
+			return nil, err
+		}
+		meta["annotations"] = raw
+	}
+	return json.Marshal(meta)
+}
@@ -0,0 +1,164 @@
+package crd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/exemplars"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversionHandler(t *testing.T) {
+	rt := thema.NewRuntime(cuecontext.New())
+	lin, err := exemplars.NarrowingLineage(rt)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(NewConversionHandler(lin))
+	defer srv.Close()
+
+	review := func(t *testing.T, desired string, objs ...string) conversionResponse {
+		t.Helper()
+		req := conversionReview{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "ConversionReview",
+			Request: &conversionRequest{
+				UID:               "705ab4f5-6393-11e8-b7cc-42010a800002",
+				DesiredAPIVersion: desired,
+			},
+		}
+		for _, obj := range objs {
+			req.Request.Objects = append(req.Request.Objects, json.RawMessage(obj))
+		}
+		b, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(b))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var out conversionReview
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+		require.Equal(t, "ConversionReview", out.Kind)
+		require.NotNil(t, out.Response)
+		require.Equal(t, req.Request.UID, out.Response.UID)
+		return *out.Response
+	}
+
+	t.Run("forward", func(t *testing.T) {
+		resp := review(t, "example.com/v1-0",
+			`{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"a","namespace":"default"},"boolish":"true"}`,
+			`{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"b"},"boolish":false}`,
+		)
+		require.Equal(t, "Success", resp.Result.Status, resp.Result.Message)
+		require.Len(t, resp.ConvertedObjects, 2)
+		require.JSONEq(t, `{"apiVersion":"example.com/v1-0","kind":"Narrowing","metadata":{"name":"a","namespace":"default"},"properbool":true}`, string(resp.ConvertedObjects[0]))
+		require.JSONEq(t, `{"apiVersion":"example.com/v1-0","kind":"Narrowing","metadata":{"name":"b"},"properbool":false}`, string(resp.ConvertedObjects[1]))
+	})
+
+	t.Run("lacunas", func(t *testing.T) {
+		resp := review(t, "example.com/v1-0",
+			`{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"a"},"boolish":"maybe"}`,
+		)
+		require.Equal(t, "Success", resp.Result.Status, resp.Result.Message)
+		require.Len(t, resp.ConvertedObjects, 1)
+
+		var obj struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			Properbool bool `json:"properbool"`
+		}
+		require.NoError(t, json.Unmarshal(resp.ConvertedObjects[0], &obj))
+		require.False(t, obj.Properbool)
+
+		var lacunas []thema.Lacuna
+		require.NoError(t, json.Unmarshal([]byte(obj.Metadata.Annotations[LacunaAnnotation]), &lacunas))
+		require.Len(t, lacunas, 1)
+		require.Equal(t, thema.LacunaType(3), lacunas[0].Type) // LossyFieldMapping
+	})
+
+	t.Run("reverse-clears-lacunas", func(t *testing.T) {
+		resp := review(t, "example.com/v0-0",
+			`{"apiVersion":"example.com/v1-0","kind":"Narrowing","metadata":{"name":"a","annotations":{"thema.grafana.com/lacunas":"[]","other":"x"}},"properbool":true}`,
+		)
+		require.Equal(t, "Success", resp.Result.Status, resp.Result.Message)
+		require.JSONEq(t, `{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"a","annotations":{"other":"x"}},"boolish":true}`, string(resp.ConvertedObjects[0]))
+	})
+
+	t.Run("status", func(t *testing.T) {
+		resp := review(t, "example.com/v1-0",
+			`{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"a"},"boolish":"true","status":{"phase":"Ready","conditions":[{"type":"Synced","status":"True"}]}}`,
+		)
+		require.Equal(t, "Success", resp.Result.Status, resp.Result.Message)
+		require.JSONEq(t, `{"apiVersion":"example.com/v1-0","kind":"Narrowing","metadata":{"name":"a"},"properbool":true,"status":{"phase":"Ready","conditions":[{"type":"Synced","status":"True"}]}}`, string(resp.ConvertedObjects[0]))
+	})
+
+	t.Run("invalid-object", func(t *testing.T) {
+		resp := review(t, "example.com/v1-0",
+			`{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"a"},"notafield":42}`,
+		)
+		require.Equal(t, "Failure", resp.Result.Status)
+		require.Empty(t, resp.ConvertedObjects)
+	})
+
+	t.Run("unknown-version", func(t *testing.T) {
+		resp := review(t, "example.com/v3-0",
+			`{"apiVersion":"example.com/v0-0","kind":"Narrowing","metadata":{"name":"a"},"boolish":true}`,
+		)
+		require.Equal(t, "Failure", resp.Result.Status)
+	})
+
+	t.Run("bad-method", func(t *testing.T) {
+		resp, err := http.Get(srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+}
+
+func TestConversionHandlerSchemaStatus(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+	lin, err := thema.BindLineage(ctx.CompileString(`name: "withstatus"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		status: phase: string
+	}
+}]`), rt)
+	require.NoError(t, err)
+
+	h := NewConversionHandler(lin)
+	require.True(t, h.schemaStatus)
+
+	out, err := h.convertObject(json.RawMessage(`{"apiVersion":"example.com/v0-0","kind":"WithStatus","title":"foo","status":{"phase":"Ready"}}`), "example.com/v0-0", thema.SV(0, 0))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"apiVersion":"example.com/v0-0","kind":"WithStatus","title":"foo","status":{"phase":"Ready"}}`, string(out))
+
+	_, err = h.convertObject(json.RawMessage(`{"apiVersion":"example.com/v0-0","kind":"WithStatus","title":"foo","status":{"phase":42}}`), "example.com/v0-0", thema.SV(0, 0))
+	require.Error(t, err, "status must be validated against the schema that declares it")
+}
+
+func TestParseVersionName(t *testing.T) {
+	for _, v := range []thema.SyntacticVersion{thema.SV(0, 0), thema.SV(1, 2), thema.SV(10, 11)} {
+		got, err := ParseVersionName(VersionName(v))
+		require.NoError(t, err)
+		require.Equal(t, v, got)
+
+		got, err = ParseVersionName("example.com/" + VersionName(v))
+		require.NoError(t, err)
+		require.Equal(t, v, got)
+	}
+
+	for _, bad := range []string{"", "v1", "1-0", "v1.0", "v1-0-1", "va-b"} {
+		_, err := ParseVersionName(bad)
+		require.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,290 @@
+// Package crd provides helpers for generating Kubernetes CustomResourceDefinitions
+// from Thema lineages.
+package crd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/ast/astutil"
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/load"
+	cueopenapi "cuelang.org/go/encoding/openapi"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/encoding/openapi"
+	"github.com/grafana/thema/internal/util"
+)
+
+// Config controls CustomResourceDefinition generation from a Thema lineage.
+//
+// Fields left empty take on the defaults specified in the #CRD definition in
+// the github.com/grafana/thema/crd CUE package.
+type Config struct {
+	// Group is the API group of the defined custom resource. It is required.
+	Group string
+
+	// Scope indicates whether the defined custom resource is "Namespaced" or
+	// "Cluster"-scoped. Defaults to "Namespaced".
+	Scope string
+
+	// Kind is the serialized kind of the resource. It is normally CamelCase and
+	// singular. Defaults to the name of the lineage.
+	Kind string
+
+	// ListKind is the serialized kind of the list for this resource. Defaults to
+	// Kind with a "List" suffix.
+	ListKind string
+
+	// Plural is the plural name of the resource to serve. Defaults to Singular
+	// with an "s" suffix.
+	Plural string
+
+	// Singular is the singular name of the resource. Defaults to the lowercase
+	// form of Kind.
+	Singular string
+
+	// ShortNames are shorter strings that match the resource on the CLI.
+	ShortNames []string
+
+	// Categories is a list of grouped resources the custom resource belongs to.
+	Categories []string
+
+	// Served is the list of schema versions in the lineage that are served by
+	// the CRD, each of which has an entry in the CRD's versions list. Versions
+	// that are not served are omitted from the CRD. If empty, all schema
+	// versions are served.
+	Served []thema.SyntacticVersion
+
+	// Storage is the version of schema in the lineage that is used when
+	// persisting custom resources. It must be one of the served versions. If
+	// nil, the latest served schema is used.
+	Storage *thema.SyntacticVersion
+
+	// Webhook configures how the Kubernetes API server reaches a conversion
+	// webhook, typically one served by a [ConversionHandler]. If nil, the
+	// CRD's conversion strategy is "None".
+	Webhook *WebhookConfig
+}
+
+// WebhookConfig specifies how the Kubernetes API server reaches the conversion
+// webhook for a CRD. Exactly one of URL or Service must be set.
+type WebhookConfig struct {
+	// URL is the location of the webhook, in standard URL form.
+	URL string
+
+	// Service references the in-cluster service fronting the webhook.
+	Service *ServiceReference
+
+	// CABundle is a PEM-encoded CA bundle used to validate the webhook's
+	// server certificate.
+	CABundle []byte
+}
+
+// ServiceReference refers to a Kubernetes service.
+type ServiceReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Path      string `json:"path,omitempty"`
+	Port      int32  `json:"port,omitempty"`
+}
+
+type crdSpec struct {
+	Scope      string         `json:"scope,omitempty"`
+	Group      string         `json:"group"`
+	Names      crdNames       `json:"names"`
+	Conversion *crdConversion `json:"conversion,omitempty"`
+}
+
+type crdConversion struct {
+	Strategy string     `json:"strategy"`
+	Webhook  crdWebhook `json:"webhook"`
+}
+
+type crdWebhook struct {
+	ClientConfig crdClientConfig `json:"clientConfig"`
+}
+
+type crdClientConfig struct {
+	URL      string            `json:"url,omitempty"`
+	Service  *ServiceReference `json:"service,omitempty"`
+	CABundle string            `json:"caBundle,omitempty"`
+}
+
+type crdNames struct {
+	Categories []string `json:"categories,omitempty"`
+	Kind       string   `json:"kind,omitempty"`
+	ListKind   string   `json:"listKind,omitempty"`
+	Plural     string   `json:"plural,omitempty"`
+	ShortNames []string `json:"shortNames,omitempty"`
+	Singular   string   `json:"singular,omitempty"`
+}
+
+// GenerateCRD creates a Kubernetes CustomResourceDefinition that represents the
+// provided lineage. The CRD contains one entry in its versions list for each
+// served schema in the lineage, in ascending order, with the schema's OpenAPI
+// representation - generated by [openapi.GenerateSchema] - as its
+// openAPIV3Schema.
+//
+// Returns the result as a CUE AST, which is suitable for direct manipulation and
+// marshaling to either JSON or YAML.
+func GenerateCRD(lin thema.Lineage, cfg Config) (*ast.File, error) {
+	if cfg.Group == "" {
+		return nil, fmt.Errorf("a group must be specified for the CRD")
+	}
+	for _, v := range cfg.Served {
+		if _, err := lin.Schema(v); err != nil {
+			return nil, fmt.Errorf("served version %s does not exist in lineage %q", v, lin.Name())
+		}
+	}
+	if cfg.Storage != nil {
+		if _, err := lin.Schema(*cfg.Storage); err != nil {
+			return nil, fmt.Errorf("storage version %s does not exist in lineage %q", *cfg.Storage, lin.Name())
+		}
+	}
+
+	var versions, served []thema.SyntacticVersion
+	for sch := lin.First(); sch != nil; sch = sch.Successor() {
+		versions = append(versions, sch.Version())
+		if len(cfg.Served) == 0 || containsVersion(cfg.Served, sch.Version()) {
+			served = append(served, sch.Version())
+		}
+	}
+	if cfg.Storage != nil && !containsVersion(served, *cfg.Storage) {
+		return nil, fmt.Errorf("storage version %s is not served", *cfg.Storage)
+	}
+	var conv *crdConversion
+	if cfg.Webhook != nil {
+		if (cfg.Webhook.URL == "") == (cfg.Webhook.Service == nil) {
+			return nil, fmt.Errorf("exactly one of URL or Service must be set in webhook config")
+		}
+		conv = &crdConversion{
+			Strategy: "Webhook",
+			Webhook: crdWebhook{
+				ClientConfig: crdClientConfig{
+					URL:     cfg.Webhook.URL,
+					Service: cfg.Webhook.Service,
+				},
+			},
+		}
+		if len(cfg.Webhook.CABundle) > 0 {
+			conv.Webhook.ClientConfig.CABundle = base64.StdEncoding.EncodeToString(cfg.Webhook.CABundle)
+		}
+	}
+
+	ctx := lin.Runtime().Context()
+	def := ctx.BuildInstance(loadCRD()).LookupPath(cue.MakePath(cue.Def("CRDFromVersions")))
+	if def.Err() != nil {
+		return nil, def.Err()
+	}
+
+	v := def.FillPath(cue.MakePath(cue.Str("name")), lin.Name()).
+		FillPath(cue.MakePath(cue.Str("versions")), versions).
+		FillPath(cue.MakePath(cue.Str("served")), served).
+		FillPath(cue.MakePath(cue.Str("spec")), crdSpec{
+			Scope: cfg.Scope,
+			Group: cfg.Group,
+			Names: crdNames{
+				Categories: cfg.Categories,
+				Kind:       cfg.Kind,
+				ListKind:   cfg.ListKind,
+				Plural:     cfg.Plural,
+				ShortNames: cfg.ShortNames,
+				Singular:   cfg.Singular,
+			},
+			Conversion: conv,
+		})
+	if cfg.Storage != nil {
+		v = v.FillPath(cue.MakePath(cue.Str("storage")), *cfg.Storage)
+	}
+
+	crd := v.LookupPath(cue.MakePath(cue.Str("crd")))
+	kind, err := crd.LookupPath(cue.ParsePath("spec.names.kind")).String()
+	if err != nil {
+		return nil, fmt.Errorf("could not determine CRD kind: %w", err)
+	}
+
+	for i, sv := range served {
+		oapi, err := genVersionSchema(thema.SchemaP(lin, sv), kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate OpenAPI for schema %s: %w", sv, err)
+		}
+		crd = crd.FillPath(cue.MakePath(cue.Str("spec"), cue.Str("versions"), cue.Index(i), cue.Str("schema"), cue.Str("openAPIV3Schema")), oapi)
+	}
+
+	if err := crd.Validate(cue.Concrete(true)); err != nil {
+		return nil, err
+	}
+
+	switch x := crd.Syntax(cue.Final(), cue.Concrete(true)).(type) {
+	case *ast.File:
+		return x, nil
+	case ast.Expr:
+		return astutil.ToFile(x)
+	default:
+		return nil, fmt.Errorf("unexpected node type %T from CRD formatting", x)
+	}
+}
+
+func containsVersion(vs []thema.SyntacticVersion, v thema.SyntacticVersion) bool {
+	for _, sv := range vs {
+		if sv == v {
+			return true
+		}
+	}
+	return false
+}
+
+// genVersionSchema generates the OpenAPI schema for a single schema version
+// in the form required by openAPIV3Schema - a single schema object, with all
+// references expanded.
+func genVersionSchema(sch thema.Schema, kind string) (cue.Value, error) {
+	name := util.SanitizeLabelString(kind)
+	f, err := openapi.GenerateSchema(sch, &openapi.Config{
+		Config: &cueopenapi.Config{
+			ExpandReferences: true,
+		},
+		RootName: name,
+	})
+	if err != nil {
+		return cue.Value{}, err
+	}
+
+	v := sch.Underlying().Context().BuildFile(f)
+	if v.Err() != nil {
+		return cue.Value{}, v.Err()
+	}
+	root := v.LookupPath(cue.MakePath(cue.Str("components"), cue.Str("schemas"), cue.Str(name)))
+	if !root.Exists() {
+		return cue.Value{}, fmt.Errorf("no schema component named %q in generated OpenAPI", name)
+	}
+	return root, nil
+}
+
+var crdOnce sync.Once
+var crdBI *build.Instance
+
+func loadCRD() *build.Instance {
+	crdOnce.Do(func() {
+		path := filepath.Join(util.Prefix, "github.com", "grafana", "thema")
+
+		overlay := make(map[string]load.Source)
+		if err := util.ToOverlay(path, thema.CueJointFS, overlay); err != nil {
+			// Only reachable due to a bug in the layout of the embedded thema
+			// CUE files, which CI would catch.
+			panic(err)
+		}
+
+		crdBI = load.Instances(nil, &load.Config{
+			Overlay: overlay,
+			Package: "crd",
+			Module:  "github.com/grafana/thema",
+			Dir:     filepath.Join(path, "crd"),
+		})[0]
+	})
+
+	return crdBI
+}
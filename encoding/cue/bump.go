@@ -0,0 +1,167 @@
+package cue
+
+import (
+	"fmt"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/parser"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/astutil"
+)
+
+// BumpConfig controls the behavior of [Bump].
+type BumpConfig struct {
+	// Major indicates that the new schema should be the first in a new major
+	// version, rather than the next minor version of the latest schema.
+	Major bool
+
+	// NoFill indicates that the new schema should be empty, rather than
+	// pre-filled with the contents of the latest schema.
+	NoFill bool
+}
+
+// Bump adds a new schema to the lineage declared at the provided path
+// within inst, the root of a CUE package instance. If the entire package
+// instance is the thema lineage, the provided path may be empty.
+//
+// By default, the new schema is the next minor version after the latest
+// schema in the lineage. If cfg.Major is true, it is instead the first schema
+// in a new major version, and stub forward and reverse lenses between the
+// latest schema and the new schema are also added to the lineage.
+//
+// The lineage's source AST is modified in place, preserving comments. The
+// returned [*ast.File] is the file in which the lineage is declared, and the
+// returned version is that of the newly added schema. The result is not
+// checked for Thema validity; in particular, stub lenses must be implemented
+// before the lineage will be valid.
+//
+// Lineage definitions implicitly unified across multiple files in the same
+// package cannot be bumped by this function.
+func Bump(inst cue.Value, path cue.Path, cfg BumpConfig) (*ast.File, thema.SyntacticVersion, error) {
+	var v thema.SyntacticVersion
+	f, lin, err := findLineageNode(inst, path)
+	if err != nil {
+		return nil, v, err
+	}
+
+	schl, err := astutil.SchemaList(lin)
+	if err != nil {
+		return nil, v, err
+	}
+	if len(schl.Elts) == 0 {
+		return nil, v, fmt.Errorf("lineage has no schemas to bump from")
+	}
+	latest := schl.Elts[len(schl.Elts)-1]
+	lv, err := schemaNodeVersion(latest)
+	if err != nil {
+		return nil, v, err
+	}
+
+	var sch ast.Expr = ast.NewStruct()
+	if !cfg.NoFill {
+		sch, err = copySchemaNode(latest)
+		if err != nil {
+			return nil, v, fmt.Errorf("failed to copy schema %s: %w", lv, err)
+		}
+	}
+
+	if cfg.Major {
+		v = thema.SV(lv[0]+1, 0)
+	} else {
+		v = thema.SV(lv[0], lv[1]+1)
+	}
+
+	if err = insertSchemaNodeAs(lin, sch, v); err != nil {
+		return nil, v, err
+	}
+	if cfg.Major {
+		if err = insertLensStubs(lin, lv, v); err != nil {
+			return nil, v, err
+		}
+	}
+
+	return f, v, nil
+}
+
+// copySchemaNode returns a deep copy of the schema field's value from an
+// element of a lineage's schemas list.
+func copySchemaNode(n ast.Node) (ast.Expr, error) {
+	f, err := astutil.GetFieldByLabel(n, "schema")
+	if err != nil {
+		return nil, err
+	}
+
+	// Round-tripping through the formatter is the simplest way to get a deep
+	// copy that retains comments, without the copy sharing position
+	// information with the original.
+	b, err := astutil.FmtNode(f.Value)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ParseExpr("schema", b, parser.ParseComments)
+}
+
+// findLineageNode finds the struct-ish AST node in which the lineage at the
+// provided path is declared, along with the file containing that node.
+func findLineageNode(inst cue.Value, path cue.Path) (*ast.File, ast.Node, error) {
+	if inst.BuildInstance() == nil {
+		return nil, nil, fmt.Errorf("provided cue.Value must be the root of a CUE package instance")
+	}
+	v := inst.LookupPath(path)
+	if !v.Exists() {
+		return nil, nil, fmt.Errorf("no value exists at CUE path %q", path)
+	}
+
+	lin := findSchemasNode(v)
+	if lin == nil {
+		return nil, nil, fmt.Errorf("could not find a literal schemas list for the lineage at CUE path %q", path)
+	}
+
+	for _, f := range inst.BuildInstance().Files {
+		var found bool
+		ast.Walk(f, func(node ast.Node) bool {
+			if !found {
+				found = node == lin
+			}
+			return !found
+		}, nil)
+
+		if found {
+			return f, lin, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("could not find file containing lineage at CUE path %q", path)
+}
+
+// findSchemasNode walks down the unification list of the provided value,
+// looking for the source node that directly contains a schemas list literal.
+func findSchemasNode(v cue.Value) ast.Node {
+	var n ast.Node
+	switch x := v.Source().(type) {
+	case *ast.File:
+		n = x
+	case *ast.StructLit:
+		n = x
+	case *ast.Field:
+		n = x.Value
+	}
+	if n != nil {
+		if _, err := astutil.SchemaList(n); err == nil {
+			return n
+		}
+	}
+
+	if op, dvals := v.Expr(); op == cue.AndOp {
+		for _, val := range dvals {
+			if !val.LookupPath(cue.MakePath(cue.Str("schemas"))).Exists() {
+				continue
+			}
+			if dn := findSchemasNode(val); dn != nil {
+				return dn
+			}
+		}
+	}
+
+	return nil
+}
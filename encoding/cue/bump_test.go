@@ -0,0 +1,38 @@
+package cue
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/grafana/thema"
+	tastutil "github.com/grafana/thema/internal/astutil"
+	"github.com/grafana/thema/internal/txtartest/vanilla"
+)
+
+func TestBump(t *testing.T) {
+	for name, cfg := range map[string]BumpConfig{
+		"minor":        {},
+		"major":        {Major: true},
+		"minor-nofill": {NoFill: true},
+		"major-nofill": {Major: true, NoFill: true},
+	} {
+		tcfg := cfg
+		(&vanilla.TxTarTest{
+			Root:    "./testdata/bump",
+			Name:    "bump/" + name,
+			ThemaFS: thema.CueJointFS,
+		}).Run(t, func(tc *vanilla.Test) {
+			// Bump modifies the AST in place, so each run needs its own instance
+			inst := cuecontext.New().BuildInstance(tc.Instance())
+			val, _ := tc.Value("sub")
+			f, v, err := Bump(inst, cue.ParsePath(val), tcfg)
+			if err != nil {
+				tc.Fatal(err)
+			}
+
+			tc.Writer("version").Write([]byte(v.String() + "\n"))
+			tc.Write(tastutil.FmtNodeP(f))
+		})
+	}
+}
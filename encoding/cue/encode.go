@@ -3,14 +3,15 @@ package cue
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"text/template"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/parser"
+	"cuelang.org/go/cue/token"
 	"github.com/grafana/thema"
 	"github.com/grafana/thema/internal/astutil"
-	"github.com/grafana/thema/internal/compat"
 )
 
 // NewLineage constructs a CUE ast.File with a new lineage declaration in it,
@@ -49,48 +50,118 @@ func NewLineage(sch cue.Value, name, pkgname string) (*ast.File, error) {
 	return f, nil
 }
 
-// InsertSchemaNodeAs inserts the provided schema ast.Expr into the provided
+// insertSchemaNodeAs inserts the provided schema ast.Expr into the provided
 // lineage ast.Node at the position corresponding to the provided version. The
 // provided schema will either replace an existing schema, or be appended to the
-// end of an existing sequence.
+// end of the schemas list as the next minor or major version.
 //
 // The provided lineage node is modified in place. Corresponding lenses are not
 // generated. The result is not checked for Thema validity. Behavior is
 // undefined if the provided lineage node is not well-formed.
 func insertSchemaNodeAs(lin ast.Node, sch ast.Expr, v thema.SyntacticVersion) error {
-	seql := astutil.FindSeqs(lin)
-	if seql == nil {
-		return fmt.Errorf("could not find seqs list in input - invalid lineage ast?")
+	schl, err := astutil.SchemaList(lin)
+	if err != nil {
+		return fmt.Errorf("could not find schemas list in input - invalid lineage ast?: %w", err)
 	}
 
-	// Handle inserting new sequence path separately
-	if v[0] == uint(len(seql.Elts)) {
-		ast.AddComment(sch, versionComment(v))
-		seql.Elts = append(seql.Elts, newSequenceNode(sch))
-		return nil
+	node := ast.NewStruct(
+		"version", synvToAST(v),
+		"schema", sch,
+	)
+	for i, el := range schl.Elts {
+		elv, err := schemaNodeVersion(el)
+		if err != nil {
+			return err
+		}
+		if elv == v {
+			// replace
+			schl.Elts[i] = node
+			return nil
+		}
 	}
 
-	seql, err := astutil.SchemaListFor(lin, v[0])
-	if err != nil {
-		return err
+	if len(schl.Elts) == 0 {
+		if v != thema.SV(0, 0) {
+			return fmt.Errorf("cannot insert version %s into empty lineage, first version must be 0.0", v)
+		}
+	} else {
+		lv, _ := schemaNodeVersion(schl.Elts[len(schl.Elts)-1])
+		if v != thema.SV(lv[0], lv[1]+1) && v != thema.SV(lv[0]+1, 0) {
+			return fmt.Errorf("cannot insert version %s, previous version does not exist in lineage", v)
+		}
 	}
 
-	if v[1] > uint(len(seql.Elts)) {
-		return fmt.Errorf("cannot insert version %s, previous version does not exist in lineage", v)
-	}
+	// append
+	schl.Elts = append(schl.Elts, node)
+	return nil
+}
 
-	ast.AddComment(sch, versionComment(v))
-	if v[1] == uint(len(seql.Elts)) {
-		// append
-		seql.Elts = append(seql.Elts, sch)
-	} else {
-		// replace
-		seql.Elts[v[1]] = sch
+// insertLensStubs appends stub lenses for the forward and reverse translations
+// between the two provided versions to the lenses list in the provided lineage
+// ast.Node, creating the list if it does not already exist.
+//
+// The provided lineage node is modified in place.
+func insertLensStubs(lin ast.Node, from, to thema.SyntacticVersion) error {
+	lensl, err := astutil.LensList(lin)
+	if err != nil {
+		lensl = ast.NewList()
+		field := &ast.Field{
+			Label: ast.NewIdent("lenses"),
+			Value: lensl,
+		}
+		switch x := lin.(type) {
+		case *ast.File:
+			x.Decls = append(x.Decls, field)
+		case *ast.StructLit:
+			x.Elts = append(x.Elts, field)
+		default:
+			return fmt.Errorf("lineage node must be an *ast.File or *ast.StructLit, got %T", lin)
+		}
 	}
 
+	for _, ll := range []legacyLens{{to: from, from: to}, {to: to, from: from}} {
+		lensast, err := ll.toAST()
+		if err != nil {
+			return err
+		}
+		lensl.Elts = append(lensl.Elts, lensast)
+	}
 	return nil
 }
 
+// schemaNodeVersion extracts the version from an element of a lineage's
+// schemas list.
+func schemaNodeVersion(n ast.Node) (thema.SyntacticVersion, error) {
+	return versionField(n, "version")
+}
+
+// versionField extracts the version from the field with the provided label in
+// a struct-ish node, such as the version of a schema or the to and from
+// versions of a lens.
+func versionField(n ast.Node, label string) (thema.SyntacticVersion, error) {
+	var v thema.SyntacticVersion
+	f, err := astutil.GetFieldByLabel(n, label)
+	if err != nil {
+		return v, fmt.Errorf("no %s field: %w", label, err)
+	}
+	l, is := f.Value.(*ast.ListLit)
+	if !is || len(l.Elts) != 2 {
+		return v, fmt.Errorf("%s field must be a list literal with two elements", label)
+	}
+	for i, el := range l.Elts {
+		lit, is := el.(*ast.BasicLit)
+		if !is || lit.Kind != token.INT {
+			return v, fmt.Errorf("%s field must contain only integer literals", label)
+		}
+		n, err := strconv.ParseUint(lit.Value, 10, 32)
+		if err != nil {
+			return v, fmt.Errorf("invalid %s element %q: %w", label, lit.Value, err)
+		}
+		v[i] = uint(n)
+	}
+	return v, nil
+}
+
 type linTplVars struct {
 	PkgName string
 	Name    string
@@ -113,67 +184,3 @@ schemas: [
     }
 ]
 `))
-
-// Append adds the provided cue.Value as a new schema to the provided Lineage.
-//
-// If the provided schema is backwards compatible with the latest schema in the
-// lineage, the new schema will be appended to the latest sequence (minor
-// version bump). Otherwise, a new sequence will be created with the provided
-// schema as its only element (major version bump).
-func appendlin(lin thema.Lineage, sch cue.Value) (ast.Node, error) {
-	linf := astutil.Format(lin.Underlying()).(*ast.File)
-	schnode := astutil.ToExpr(astutil.Format(sch))
-
-	lv := lin.Latest().Version()
-	lsch := thema.SchemaP(lin, lv)
-	if err := compat.ThemaCompatible(lsch.Underlying(), sch); err == nil {
-		// Is compatible, bump minor version
-		tgtv := thema.SyntacticVersion{lv[0], lv[1] + 1}
-		ast.AddComment(schnode, versionComment(tgtv))
-
-		schl, err := astutil.LatestSchemaList(linf)
-		if err != nil {
-			return nil, fmt.Errorf("could not get lineage's latest seq's schema list: %w", err)
-		}
-
-		schl.Elts = append(schl.Elts, schnode)
-		// TODO add boilerplate lenses, etc.
-	} else {
-		// Not compatible, bump major version
-		tgtv := thema.SyntacticVersion{lv[0] + 1, 0}
-		ast.AddComment(schnode, versionComment(tgtv))
-
-		seql := astutil.FindSeqs(linf)
-		if seql == nil {
-			return nil, fmt.Errorf("could not find seqs list in lineage input")
-		}
-
-		seql.Elts = append(seql.Elts, newSequenceNode(schnode))
-		// TODO add boilerplate lenses, etc.
-	}
-
-	return linf, nil
-}
-
-func newSequenceNode(sch ast.Expr) *ast.StructLit {
-	if sch == nil {
-		sch = ast.NewStruct() // use empty struct
-	}
-
-	return ast.NewStruct(&ast.Field{
-		Label: ast.NewString("schemas"),
-		Value: ast.NewList(sch),
-	})
-}
-
-func versionComment(v thema.SyntacticVersion) *ast.CommentGroup {
-	return &ast.CommentGroup{
-		Doc:  true,
-		Line: true,
-		List: []*ast.Comment{
-			{
-				Text: fmt.Sprint("// v", v.String()),
-			},
-		},
-	}
-}
@@ -0,0 +1,418 @@
+package cue
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	cueastutil "cuelang.org/go/cue/ast/astutil"
+	"cuelang.org/go/cue/parser"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/astutil"
+)
+
+// ScaffoldLens generates a lens that translates from one schema to another,
+// returned as CUE AST suitable for use as an element of a lineage's lenses list.
+//
+// The lens is derived from [thema.Diff] of the two schemas. Fields that are
+// unchanged are copied from input to result. Renamed and changed fields are
+// copied where possible and flagged with TODO comments. Removed fields produce
+// a DroppedField lacuna, and new required fields produce a Placeholder lacuna.
+// Where no value can be derived - for example, a field that changed type - the
+// field's value is left as _|_ with a TODO, which must be implemented before
+// the lineage will be valid.
+//
+// The generated lens refers to lacuna definitions in the thema CUE package,
+// which must be imported as "thema" in the file where the lens is used.
+func ScaffoldLens(from, to thema.Schema) (ast.Expr, error) {
+	return scaffoldLens(thema.Diff(from, to), from.Version(), to.Version(), schemaValue(to))
+}
+
+// ScaffoldLenses generates the forward and reverse lenses between the schemas
+// with the provided versions in the lineage declared at the provided path
+// within inst, the root of a CUE package instance. If the entire package
+// instance is the thema lineage, the provided path may be empty.
+//
+// Each lens is generated as by [ScaffoldLens]. Existing stub lenses between the
+// two versions, such as those added by [Bump], are replaced. It is an error if
+// either lens already exists and is not a stub - that is, if its result does
+// not contain a _|_ literal.
+//
+// Unlike ScaffoldLens, the lineage need not be valid, as is typically the case
+// immediately after adding a new major version. The lineage's source AST is
+// modified in place, preserving comments, and the returned [*ast.File] is the
+// file in which the lineage is declared.
+func ScaffoldLenses(inst cue.Value, path cue.Path, from, to thema.SyntacticVersion) (*ast.File, error) {
+	f, lin, err := findLineageNode(inst, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fromsch, tosch, err := bindSchemaPair(inst.LookupPath(path), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	forward, err := scaffoldLens(thema.Diff(fromsch, tosch), from, to, schemaValue(tosch))
+	if err != nil {
+		return nil, err
+	}
+	reverse, err := scaffoldLens(thema.Diff(tosch, fromsch), to, from, schemaValue(fromsch))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lens := range []ast.Expr{reverse, forward} {
+		if err = insertLens(lin, lens); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// bindSchemaPair binds a minimal lineage containing only the schemas with the
+// provided versions from the lineage value linv, returning them as the first
+// and second schemas of the minimal lineage.
+//
+// This allows schemas to be obtained from lineages that are not themselves
+// valid, most commonly because they contain stub lenses.
+func bindSchemaPair(linv cue.Value, from, to thema.SyntacticVersion) (thema.Schema, thema.Schema, error) {
+	find := func(v thema.SyntacticVersion) (cue.Value, error) {
+		iter, err := linv.LookupPath(cue.MakePath(cue.Str("schemas"))).List()
+		if err != nil {
+			return cue.Value{}, fmt.Errorf("could not read schemas list of lineage: %w", err)
+		}
+		for iter.Next() {
+			var sv thema.SyntacticVersion
+			if err := iter.Value().LookupPath(cue.MakePath(cue.Str("version"))).Decode(&sv); err != nil {
+				return cue.Value{}, fmt.Errorf("could not decode schema version: %w", err)
+			}
+			if sv == v {
+				return iter.Value().LookupPath(cue.MakePath(cue.Str("schema"))), nil
+			}
+		}
+		return cue.Value{}, fmt.Errorf("lineage contains no schema with version %s", v)
+	}
+
+	fromv, err := find(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	tov, err := find(to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The pair is declared as consecutive minor versions so that no lenses are
+	// required, and compatibility checks are skipped, as the schemas may be from
+	// different major versions.
+	ctx := linv.Context()
+	pair := ctx.CompileString(`name: "scaffold", schemas: [{version: [0, 0]}, {version: [0, 1]}]`).
+		FillPath(cue.MakePath(cue.Str("schemas"), cue.Index(0), cue.Str("schema")), fromv).
+		FillPath(cue.MakePath(cue.Str("schemas"), cue.Index(1), cue.Str("schema")), tov)
+	plin, err := thema.BindLineage(pair, thema.NewRuntime(ctx), thema.SkipBuggyChecks())
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load schemas %s and %s: %w", from, to, err)
+	}
+	return plin.First(), plin.Latest(), nil
+}
+
+func schemaValue(sch thema.Schema) cue.Value {
+	return sch.Underlying().LookupPath(cue.MakePath(cue.Str("schema")))
+}
+
+// insertLens adds the provided lens to the lenses list of the provided lineage
+// node, keeping the list sorted by to, then from. An existing stub lens with the
+// same versions is replaced.
+func insertLens(lin ast.Node, lens ast.Expr) error {
+	lensl, err := astutil.LensList(lin)
+	if err != nil {
+		lensl = ast.NewList()
+		field := &ast.Field{
+			Label: ast.NewIdent("lenses"),
+			Value: lensl,
+		}
+		switch x := lin.(type) {
+		case *ast.File:
+			x.Decls = append(x.Decls, field)
+		case *ast.StructLit:
+			x.Elts = append(x.Elts, field)
+		default:
+			return fmt.Errorf("lineage node must be an *ast.File or *ast.StructLit, got %T", lin)
+		}
+	}
+
+	id, err := lensNodeID(lens)
+	if err != nil {
+		return err
+	}
+
+	for i, el := range lensl.Elts {
+		elid, err := lensNodeID(el)
+		if err != nil {
+			return err
+		}
+		if elid == id {
+			if !isStubLens(el) {
+				return fmt.Errorf("lens %s -> %s is already implemented", id[1], id[0])
+			}
+			lensl.Elts[i] = lens
+			return nil
+		}
+		if id[0].Less(elid[0]) || (id[0] == elid[0] && id[1].Less(elid[1])) {
+			lensl.Elts = append(lensl.Elts[:i], append([]ast.Expr{lens}, lensl.Elts[i:]...)...)
+			return nil
+		}
+	}
+	lensl.Elts = append(lensl.Elts, lens)
+	return nil
+}
+
+// lensNodeID returns the to and from versions of an element of a lineage's
+// lenses list, in that order.
+func lensNodeID(n ast.Node) ([2]thema.SyntacticVersion, error) {
+	var id [2]thema.SyntacticVersion
+	var err error
+	if id[0], err = versionField(n, "to"); err != nil {
+		return id, fmt.Errorf("invalid lens: %w", err)
+	}
+	if id[1], err = versionField(n, "from"); err != nil {
+		return id, fmt.Errorf("invalid lens: %w", err)
+	}
+	return id, nil
+}
+
+// isStubLens indicates whether the result of the provided lens node contains
+// a _|_ literal, other than as an operand in a comparison such as x != _|_.
+func isStubLens(n ast.Node) bool {
+	f, err := astutil.GetFieldByLabel(n, "result")
+	if err != nil {
+		return true
+	}
+	var found bool
+	cueastutil.Apply(f.Value, func(c cueastutil.Cursor) bool {
+		if _, is := c.Node().(*ast.BottomLit); is {
+			if _, is := c.Parent().Node().(*ast.BinaryExpr); !is {
+				found = true
+			}
+		}
+		return !found
+	}, nil)
+	return found
+}
+
+var identRegex = regexp.MustCompile(`^[a-zA-Z$][a-zA-Z0-9$_]*$`)
+
+// selRef returns the CUE reference expression that selects sel from base.
+func selRef(base string, sel cue.Selector) string {
+	if s := sel.String(); identRegex.MatchString(s) {
+		return base + "." + s
+	}
+	return base + "[" + sel.String() + "]"
+}
+
+// oneLine collapses the CUE representation of a value onto a single line, for
+// use in comments.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+type lensGen struct {
+	fromv, tov thema.SyntacticVersion
+
+	// changes in the diff, keyed by path string
+	changes map[string][]thema.SchemaChange
+
+	// All change path strings, for checking whether any change is nested
+	// beneath a path
+	paths []string
+
+	lacunas []string
+}
+
+func scaffoldLens(d *thema.SchemaDiff, fromv, tov thema.SyntacticVersion, tosch cue.Value) (ast.Expr, error) {
+	g := &lensGen{
+		fromv:   fromv,
+		tov:     tov,
+		changes: make(map[string][]thema.SchemaChange),
+	}
+	for _, c := range d.Changes {
+		ps := c.Path.String()
+		g.changes[ps] = append(g.changes[ps], c)
+		g.paths = append(g.paths, ps)
+		if c.Kind == thema.FieldRemoved {
+			g.dropped(c)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "{\nto: [%d, %d]\nfrom: [%d, %d]\ninput: _\nresult: {\n", tov[0], tov[1], fromv[0], fromv[1])
+	g.genStruct(&b, tosch, nil, "input")
+	b.WriteString("}\nlacunas: [")
+	if len(g.lacunas) > 0 {
+		b.WriteString("\n")
+	}
+	for _, lac := range g.lacunas {
+		b.WriteString(lac)
+	}
+	b.WriteString("]\n}")
+
+	expr, err := parser.ParseExpr("lens", b.String(), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("%s\nerror while parsing generated lens: %w", b.String(), err)
+	}
+	return expr, nil
+}
+
+// hasChangesBeneath indicates whether any change in the diff is to a field
+// nested beneath the provided path.
+func (g *lensGen) hasChangesBeneath(ps string) bool {
+	for _, p := range g.paths {
+		if strings.HasPrefix(p, ps+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *lensGen) has(ps string, kinds ...thema.ChangeKind) (thema.SchemaChange, bool) {
+	for _, c := range g.changes[ps] {
+		for _, k := range kinds {
+			if c.Kind == k {
+				return c, true
+			}
+		}
+	}
+	return thema.SchemaChange{}, false
+}
+
+// genStruct writes the fields of a lens result for the provided struct value
+// from the target schema. input is the CUE reference to the corresponding
+// struct in the lens input.
+func (g *lensGen) genStruct(b *strings.Builder, v cue.Value, sels []cue.Selector, input string) {
+	iter, _ := v.Fields(cue.Optional(true))
+	for iter != nil && iter.Next() {
+		sel := iter.Selector()
+		fsels := append(append([]cue.Selector{}, sels...), sel)
+		ps := cue.MakePath(fsels...).String()
+		label := sel.String()
+		src := selRef(input, sel)
+
+		if c, is := g.has(ps, thema.FieldRenamed); is {
+			oldsels := c.OldPath.Selectors()
+			src = selRef(input, oldsels[len(oldsels)-1])
+			fmt.Fprintf(b, "// TODO verify that %s is a rename of %s in schema %s\n", ps, c.OldPath, g.fromv)
+			g.copyField(b, label, src, iter.IsOptional())
+			continue
+		}
+
+		if c, is := g.has(ps, thema.FieldAdded); is {
+			if !iter.IsOptional() {
+				g.placeholder(b, label, c, iter.Value())
+			}
+			continue
+		}
+
+		if c, is := g.has(ps, thema.TypeChanged); is {
+			fmt.Fprintf(b, "// TODO %s changed type from %s to %s, convert the value of %s\n", ps, oneLine(c.Old), oneLine(c.New), src)
+			fmt.Fprintf(b, "%s: _|_\n", label)
+			continue
+		}
+
+		if c, is := g.has(ps, thema.ConstraintNarrowed, thema.ConstraintChanged); is {
+			fmt.Fprintf(b, "// TODO %s changed from %s to %s, map values of %s that are no longer valid\n", ps, oneLine(c.Old), oneLine(c.New), src)
+		}
+		if c, is := g.has(ps, thema.DefaultChanged); is {
+			fmt.Fprintf(b, "// TODO default of %s changed from %s to %s, consider emitting a ChangedDefault lacuna\n", ps, orNone(c.Old), orNone(c.New))
+		}
+		if _, is := g.has(ps, thema.MadeRequired); is {
+			fmt.Fprintf(b, "// TODO %s is required in schema %s but optional in schema %s, provide a value when it is absent\n", ps, g.tov, g.fromv)
+			fmt.Fprintf(b, "%s: %s\n", label, src)
+			continue
+		}
+
+		if iter.Value().IncompleteKind() == cue.StructKind && g.hasChangesBeneath(ps) {
+			if iter.IsOptional() {
+				fmt.Fprintf(b, "if %s != _|_ {\n", src)
+			}
+			fmt.Fprintf(b, "%s: {\n", label)
+			g.genStruct(b, iter.Value(), fsels, src)
+			b.WriteString("}\n")
+			if iter.IsOptional() {
+				b.WriteString("}\n")
+			}
+			continue
+		}
+
+		g.copyField(b, label, src, iter.IsOptional())
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return oneLine(s)
+}
+
+func (g *lensGen) copyField(b *strings.Builder, label, src string, optional bool) {
+	if optional {
+		fmt.Fprintf(b, "if %s != _|_ {\n%s: %s\n}\n", src, label, src)
+		return
+	}
+	fmt.Fprintf(b, "%s: %s\n", label, src)
+}
+
+// placeholder writes a field for a required field that has no counterpart in
+// the source schema, using the field's default if it has one, and records a
+// Placeholder lacuna for it.
+func (g *lensGen) placeholder(b *strings.Builder, label string, c thema.SchemaChange, v cue.Value) {
+	ps := c.Path.String()
+	if def, has := v.Default(); has && def.IsConcrete() {
+		fmt.Fprintf(b, "// TODO %s does not exist in schema %s, verify that its default is an appropriate placeholder\n", ps, g.fromv)
+		fmt.Fprintf(b, "%s: %v\n", label, def)
+	} else {
+		fmt.Fprintf(b, "// TODO %s does not exist in schema %s, provide a placeholder value\n", ps, g.fromv)
+		fmt.Fprintf(b, "%s: _|_\n", label)
+	}
+
+	g.lacunas = append(g.lacunas, fmt.Sprintf(`thema.#Lacuna & {
+sourceFields: []
+targetFields: [{
+path: %q
+value: %s
+}]
+message: %q
+type: thema.#LacunaTypes.Placeholder
+},
+`, ps, pathRef("result", c.Path), fmt.Sprintf("%s does not exist in schema %s, a placeholder value was used", ps, g.fromv)))
+}
+
+// dropped records a DroppedField lacuna for a field that has no counterpart in
+// the target schema.
+func (g *lensGen) dropped(c thema.SchemaChange) {
+	ps := c.Path.String()
+	src := pathRef("input", c.Path)
+	g.lacunas = append(g.lacunas, fmt.Sprintf(`// TODO %s does not exist in schema %s. Map it to another field, or accept the data loss
+if %s != _|_ {
+thema.#Lacuna & {
+sourceFields: [{
+path: %q
+value: %s
+}]
+targetFields: []
+message: %q
+type: thema.#LacunaTypes.DroppedField
+}
+},
+`, ps, g.tov, src, ps, src, fmt.Sprintf("%s does not exist in schema %s, its value was dropped", ps, g.tov)))
+}
+
+func pathRef(base string, p cue.Path) string {
+	for _, sel := range p.Selectors() {
+		base = selRef(base, sel)
+	}
+	return base
+}
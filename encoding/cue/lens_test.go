@@ -0,0 +1,37 @@
+package cue
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/grafana/thema"
+	tastutil "github.com/grafana/thema/internal/astutil"
+	"github.com/grafana/thema/internal/txtartest/vanilla"
+)
+
+func TestScaffoldLenses(t *testing.T) {
+	(&vanilla.TxTarTest{
+		Root:    "./testdata/scaffold",
+		Name:    "scaffold",
+		ThemaFS: thema.CueJointFS,
+	}).Run(t, func(tc *vanilla.Test) {
+		inst := cuecontext.New().BuildInstance(tc.Instance())
+		fromstr, _ := tc.Value("from")
+		tostr, _ := tc.Value("to")
+		from, err := thema.ParseSyntacticVersion(fromstr)
+		if err != nil {
+			tc.Fatal(err)
+		}
+		to, err := thema.ParseSyntacticVersion(tostr)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		f, err := ScaffoldLenses(inst, cue.MakePath(), from, to)
+		if err != nil {
+			tc.Fatal(err)
+		}
+		tc.Write(tastutil.FmtNodeP(f))
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
 	"cuelang.org/go/pkg/encoding/yaml"
 	"github.com/dave/dst"
 	"github.com/dave/dst/dstutil"
@@ -58,6 +59,29 @@ type TypeConfigOpenAPI struct {
 	// UseGoDeclInComments sets the name of the fields and structs at the beginning of each comment.
 	UseGoDeclInComments bool
 
+	// ValidateMethods causes a Validate() error method to be generated for each
+	// type generated from a schema component, such as the type for the root of
+	// the schema. The methods check the constraints of the schema that static Go
+	// types cannot express: bounds, regular expressions, string and list
+	// lengths, enumerated values, and the presence of required fields
+	// represented by pointers. They do not rely on CUE, and errors they return
+	// are [errors.ValidationError]s.
+	//
+	// Validate checks a Go value as it would be encoded to JSON, so an optional
+	// field with a zero value is treated as absent if it is omitted by
+	// omitempty. Constraints that cannot be represented in OpenAPI, as well as
+	// those on disjunctions and other fields of type any, are not checked.
+	//
+	// A Go value does not record whether a field of a non-pointer type, such as
+	// a string, int or struct, was present in the data it was decoded from, so
+	// the presence of such required fields is not checked. Data that omits
+	// them is validated as though they had their zero values. Likewise, fields
+	// not in the schema are discarded when data is decoded into a Go value, so
+	// they are not reported.
+	//
+	// [errors.ValidationError]: https://pkg.go.dev/github.com/grafana/thema/errors#ValidationError
+	ValidateMethods bool
+
 	// Config is passed through to the Thema OpenAPI encoder, [openapi.GenerateSchema].
 	Config *openapi.Config
 }
@@ -68,17 +92,6 @@ func GenerateTypesOpenAPI(sch thema.Schema, cfg *TypeConfigOpenAPI) ([]byte, err
 		cfg = new(TypeConfigOpenAPI)
 	}
 
-	depointer := depointerizer(false)
-	if cfg.NoOptionalPointers {
-		depointer = depointerizer(true)
-	}
-
-	applyFuncs := []dstutil.ApplyFunc{depointer, fixRawData(), fixUnderscoreInTypeName()}
-	if !cfg.UseGoDeclInComments {
-		applyFuncs = append(applyFuncs, fixTODOComments())
-	}
-	applyFuncs = append(applyFuncs, cfg.ApplyFuncs...)
-
 	f, err := openapi.GenerateSchema(sch, cfg.Config)
 	if err != nil {
 		return nil, fmt.Errorf("thema openapi generation failed: %w", err)
@@ -89,15 +102,58 @@ func GenerateTypesOpenAPI(sch thema.Schema, cfg *TypeConfigOpenAPI) ([]byte, err
 		return nil, fmt.Errorf("cue-yaml marshaling failed: %w", err)
 	}
 
+	if cfg.PackageName == "" {
+		cfg.PackageName = sch.Lineage().Name()
+	}
+
+	oT, err := loadOpenAPI([]byte(str))
+	if err != nil {
+		return nil, err
+	}
+	gostr, err := generateFromOpenAPI(oT, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := PostprocessGoFile(GenGoFile{
+		Path:                    fmt.Sprintf("%s_type_gen.go", sch.Lineage().Name()),
+		Appliers:                append(typeAppliers(cfg), cfg.ApplyFuncs...),
+		In:                      []byte(gostr),
+		IgnoreDiscoveredImports: cfg.IgnoreDiscoveredImports,
+	})
+	if err != nil || !cfg.ValidateMethods {
+		return b, err
+	}
+	return addValidateMethods(b, oT, codegen.SchemaNameToTypeName)
+}
+
+// typeAppliers returns the builtin AST manipulation funcs to apply to Go types
+// generated from OpenAPI.
+func typeAppliers(cfg *TypeConfigOpenAPI) []dstutil.ApplyFunc {
+	depointer := depointerizer(false)
+	if cfg.NoOptionalPointers {
+		depointer = depointerizer(true)
+	}
+
+	applyFuncs := []dstutil.ApplyFunc{depointer, fixRawData(), fixUnderscoreInTypeName()}
+	if !cfg.UseGoDeclInComments {
+		applyFuncs = append(applyFuncs, fixTODOComments())
+	}
+	return applyFuncs
+}
+
+func loadOpenAPI(doc []byte) (*openapi3.T, error) {
 	loader := openapi3.NewLoader()
-	oT, err := loader.LoadFromData([]byte(str))
+	oT, err := loader.LoadFromData(doc)
 	if err != nil {
 		return nil, fmt.Errorf("loading generated openapi failed: %w", err)
 	}
-	if cfg.PackageName == "" {
-		cfg.PackageName = sch.Lineage().Name()
-	}
+	return oT, nil
+}
 
+// generateFromOpenAPI generates Go types from the schema components of the
+// provided OpenAPI document.
+func generateFromOpenAPI(oT *openapi3.T, cfg *TypeConfigOpenAPI) (string, error) {
 	ccfg := codegen.Configuration{
 		PackageName: cfg.PackageName,
 		Compatibility: codegen.CompatibilityOptions{
@@ -119,15 +175,9 @@ func GenerateTypesOpenAPI(sch thema.Schema, cfg *TypeConfigOpenAPI) ([]byte, err
 
 	gostr, err := codegen.Generate(oT, ccfg)
 	if err != nil {
-		return nil, fmt.Errorf("openapi generation failed: %w", err)
+		return "", fmt.Errorf("openapi generation failed: %w", err)
 	}
-
-	return PostprocessGoFile(GenGoFile{
-		Path:                    fmt.Sprintf("%s_type_gen.go", sch.Lineage().Name()),
-		Appliers:                applyFuncs,
-		In:                      []byte(gostr),
-		IgnoreDiscoveredImports: cfg.IgnoreDiscoveredImports,
-	})
+	return gostr, nil
 }
 
 // Almost all of the below imports are eliminated by dst transformers and calls
@@ -268,6 +318,18 @@ type BindingConfig struct {
 	// codegen by multiple orders of magnitude. Succeeding silently but slowly is a bad
 	// default behavior when the fix is usually quite easy.)
 	IgnoreDiscoveredImports bool
+
+	// ArtifactInstance is the CUE instance from which the provided lineage was
+	// built. If non-nil, a [thema.BindArtifact] for the lineage is included in
+	// the generated code, and passed to [thema.BindLineage] via
+	// [thema.TrustBindArtifact] by the generated lineage factory, along with
+	// the instance it loads. This allows the compatibility and lens checks
+	// performed by BindLineage to be skipped at runtime, so long as the CUE
+	// source loaded by the factory is unchanged from ArtifactInstance.
+	//
+	// ArtifactInstance should contain the same files as EmbedPath. If it does
+	// not, the artifact never matches, and all checks are performed.
+	ArtifactInstance *build.Instance
 }
 
 // generate scenarios:
@@ -307,6 +369,14 @@ func GenerateLineageBinding(lin thema.Lineage, cfg *BindingConfig) ([]byte, erro
 		TargetSchemaVersion: cfg.TargetSchemaVersion,
 	}
 
+	if cfg.ArtifactInstance != nil {
+		art, err := thema.NewBindArtifact(lin, cfg.ArtifactInstance)
+		if err != nil {
+			return nil, fmt.Errorf("error creating bind artifact: %w", err)
+		}
+		vars.Artifact = art
+	}
+
 	if vars.PackageName == "" {
 		vars.PackageName = strings.ToLower(lin.Name())
 	}
@@ -388,4 +458,7 @@ type bindingVars struct {
 	AssigneeInit string
 
 	TargetSchemaVersion thema.SyntacticVersion
+
+	// Bind artifact to embed, if any
+	Artifact *thema.BindArtifact
 }
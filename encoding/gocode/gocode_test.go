@@ -2,13 +2,16 @@ package gocode
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"cuelang.org/go/cue/cuecontext"
+	"cuelang.org/go/cue/load"
 	copenapi "cuelang.org/go/encoding/openapi"
 
 	"github.com/grafana/thema"
 	"github.com/grafana/thema/encoding/openapi"
+	"github.com/grafana/thema/internal/deepmap/oapi-codegen/pkg/codegen"
 	"github.com/grafana/thema/internal/txtartest/bindlin"
 	"github.com/grafana/thema/internal/txtartest/vanilla"
 	"github.com/grafana/thema/internal/util"
@@ -55,6 +58,19 @@ func TestGenerate(t *testing.T) {
 				UseGoDeclInComments: true,
 			},
 		},
+		{
+			name: "validate",
+			cfg: &TypeConfigOpenAPI{
+				ValidateMethods: true,
+			},
+		},
+		{
+			name: "validatedepointerized",
+			cfg: &TypeConfigOpenAPI{
+				ValidateMethods:    true,
+				NoOptionalPointers: true,
+			},
+		},
 		{
 			name: "expandref",
 			cfg: &TypeConfigOpenAPI{
@@ -102,3 +118,132 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateLineageBindingArtifact(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+	inst := load.Instances([]string{"-"}, &load.Config{
+		Stdin: strings.NewReader(`name: "ship"
+schemas: [{
+	version: [0, 0]
+	schema: name: string
+}, {
+	version: [0, 1]
+	schema: {
+		name: string
+		crew?: int
+	}
+}]
+`),
+	})[0]
+	lin, err := thema.BindLineage(ctx.BuildInstance(inst), rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art, err := thema.NewBindArtifact(lin, inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := GenerateLineageBinding(lin, &BindingConfig{ArtifactInstance: inst})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"thema.TrustBindArtifact(bindArtifactForship, inst)",
+		fmt.Sprintf("Fingerprint: %q", art.Fingerprint),
+		"{0, 0},\n\t\t{0, 1},",
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("generated binding does not contain %q:\n%s", want, b)
+		}
+	}
+}
+
+func TestGenerateLenses(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/gocode/TestGenerateLenses",
+		ThemaFS: thema.CueJointFS,
+		ToDo: map[string]string{
+			"lineage/defaultchange": "default backcompat invariants not working properly yet",
+			"lineage/optional":      "Optional fields do not satisfy struct.MinFields(), causing #Lineage constraints to fail",
+			"lineage/union":         "Test is abominably slow, cue evaluator is choking up on disjunctions",
+		},
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+		saniname := util.SanitizeLabelString(lin.Name())
+		cfg := &LensConfig{}
+		cfg.PackageName = saniname
+		f, err := GenerateLenses(lin, cfg)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		fmt.Fprintf(tc, "== %s_lenses_gen.go\n", saniname)
+		tc.Write(f) //nolint:gosec,errcheck
+	})
+}
+
+func TestGenerateLineageTypes(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/gocode/TestGenerateLineageTypes",
+		ThemaFS: thema.CueJointFS,
+		ToDo: map[string]string{
+			"lineage/defaultchange": "default backcompat invariants not working properly yet",
+			"lineage/optional":      "Optional fields do not satisfy struct.MinFields(), causing #Lineage constraints to fail",
+			"lineage/union":         "Test is abominably slow, cue evaluator is choking up on disjunctions",
+		},
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+		saniname := util.SanitizeLabelString(lin.Name())
+		cfg := &LineageTypeConfigOpenAPI{}
+		cfg.PackageName = saniname
+		f, err := GenerateLineageTypesOpenAPI(lin, cfg)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		fmt.Fprintf(tc, "== %s_types_gen.go\n", saniname)
+		tc.Write(f) //nolint:gosec,errcheck
+	})
+}
+
+func TestLineageTypeVersionKeys(t *testing.T) {
+	seen := make(map[string]thema.SyntacticVersion)
+	for _, v := range []thema.SyntacticVersion{{1, 10}, {11, 0}, {1, 1}, {11, 1}, {0, 11}} {
+		gen := codegen.SchemaNameToTypeName(rootKey("Foo", v))
+		if prior, has := seen[gen]; has {
+			t.Errorf("types for versions %s and %s are both generated as %s", prior, v, gen)
+		}
+		seen[gen] = v
+		if got, want := strings.TrimSuffix(gen, versionKey(v))+versionIdent(v), "Foo"+versionIdent(v); got != want {
+			t.Errorf("expected type for version %s to be renamed to %s, got %s", v, want, got)
+		}
+	}
+}
@@ -0,0 +1,1049 @@
+package gocode
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	goformat "go/format"
+	"go/parser"
+	gotoken "go/token"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/deepmap/oapi-codegen/pkg/codegen"
+)
+
+// LensConfig governs the behavior of [GenerateLenses].
+type LensConfig struct {
+	// LineageTypeConfigOpenAPI controls the generation of the Go types for each
+	// schema, on which the generated lenses operate, as with
+	// [GenerateLineageTypesOpenAPI]. NoOptionalPointers must not be set. If
+	// PackageName is empty, the lowercase version of the Lineage.Name() is
+	// used.
+	//
+	// RootName also determines the names of the generated funcs. For example,
+	// with a RootName of "Foo", the lens from schema 0.1 to schema 0.0 is
+	// generated as FooV0_1ToV0_0, and the lenses are returned from
+	// FooLenses().
+	LineageTypeConfigOpenAPI
+}
+
+// GenerateLenses generates Go code that translates instances between the
+// schemas in the provided lineage without evaluating CUE.
+//
+// The Go types for the schemas in the lineage are generated as with
+// [GenerateLineageTypesOpenAPI], and the result mapping of each lens in the
+// lineage, along with the lacunas it emits, is compiled into a Go func that
+// translates between the types of the lens's from and to schemas. The
+// following constructs are supported in lens results:
+//
+//   - Fields set to literals, or to references to fields of input
+//   - Nested struct literals
+//   - if comprehensions, with conditions that compare fields of input to
+//     literals, to other fields of input, or to _|_ (testing for presence)
+//   - The logical operators !, && and || within conditions
+//
+// Lacunas are supported if their fields are set to literals, or to references
+// to fields of input or result, and their type is one of thema.#LacunaTypes.
+// They may be emitted conditionally by if comprehensions, as above.
+//
+// As in CUE, fields in the target schema that have a default and are not set
+// by the lens are set to the default.
+//
+// The generated funcs are adapted into [thema.ImperativeLens] mappers with
+// [thema.GoLensMapper], and returned from a generated <RootName>Lenses func,
+// suitable for passing to [thema.ImperativeLenses]. Lenses that use any other
+// construct instead execute the CUE lens, via [thema.CUELensMapper].
+func GenerateLenses(lin thema.Lineage, cfg *LensConfig) ([]byte, error) {
+	tcfg := new(LineageTypeConfigOpenAPI)
+	if cfg != nil {
+		*tcfg = cfg.LineageTypeConfigOpenAPI
+	}
+	if tcfg.NoOptionalPointers {
+		return nil, fmt.Errorf("lenses can not be generated for types without optional pointers")
+	}
+	if tcfg.PackageName == "" {
+		tcfg.PackageName = strings.ToLower(lin.Name())
+	}
+
+	lt, err := generateLineageTypes(lin, tcfg)
+	if err != nil {
+		return nil, err
+	}
+	src := lt.src
+	if tcfg.ValidateMethods {
+		if src, err = addValidateMethods(src, lt.doc, lt.goname); err != nil {
+			return nil, err
+		}
+	}
+
+	fset := gotoken.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated file: %w", err)
+	}
+	model := newTypeModel(fset, f)
+	types := make(map[thema.SyntacticVersion]*goType)
+	for sch := lin.First(); sch != nil; sch = sch.Successor() {
+		name := lt.rootType(sch.Version())
+		sref := lt.doc.Components.Schemas[rootKey(lt.root, sch.Version())]
+		if _, has := model.decls[name]; !has || sref == nil || sref.Value == nil {
+			return nil, fmt.Errorf("no type was generated for schema %s", sch.Version())
+		}
+		types[sch.Version()] = model.describe(goast.NewIdent(name), sref.Value)
+	}
+
+	vars := lensVars{
+		Name:   lin.Name(),
+		Prefix: codegen.ToCamelCase(lt.root),
+	}
+	iter, err := lin.Underlying().LookupPath(cue.MakePath(cue.Str("lenses"))).List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list lenses: %w", err)
+	}
+	for iter.Next() {
+		lv := iter.Value()
+		var lf lensFunc
+		if err := lv.LookupPath(cue.MakePath(cue.Str("from"))).Decode(&lf.From); err != nil {
+			return nil, fmt.Errorf("unable to decode lens from version: %w", err)
+		}
+		if err := lv.LookupPath(cue.MakePath(cue.Str("to"))).Decode(&lf.To); err != nil {
+			return nil, fmt.Errorf("unable to decode lens to version: %w", err)
+		}
+		lf.FuncName = vars.Prefix + versionIdent(lf.From) + "To" + versionIdent(lf.To)
+
+		lf.Body, err = compileLens(lv, lf.FuncName, lin.Name(), types[lf.From], types[lf.To], lf.From, lf.To)
+		if err != nil {
+			lf.Fallback = strings.Join(strings.Fields(err.Error()), " ")
+		}
+		vars.Lenses = append(vars.Lenses, lf)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpls.Lookup("lenses.tmpl").Execute(buf, vars); err != nil {
+		return nil, fmt.Errorf("error executing lenses template: %w", err)
+	}
+	if f, err = parser.ParseFile(fset, "", append(src, buf.Bytes()...), parser.ParseComments); err != nil {
+		return nil, fmt.Errorf("error parsing generated lenses: %w", err)
+	}
+	astutil.AddImport(fset, f, "github.com/grafana/thema")
+	if bytes.Contains(buf.Bytes(), []byte("fmt.")) {
+		astutil.AddImport(fset, f, "fmt")
+	}
+
+	out := new(bytes.Buffer)
+	if err = goformat.Node(out, fset, f); err != nil {
+		return nil, fmt.Errorf("error formatting generated lenses: %w", err)
+	}
+	// All imports are present, so this only groups them
+	return imports.Process("", out.Bytes(), nil)
+}
+
+type lensVars struct {
+	// Name of the lineage
+	Name string
+	// Prefix for the generated funcs
+	Prefix string
+	// All lenses in the lineage, in declaration order
+	Lenses []lensFunc
+}
+
+type lensFunc struct {
+	From, To thema.SyntacticVersion
+	// Name of the generated func
+	FuncName string
+	// Rendered declaration of the generated func
+	Body string
+	// Reason for falling back to CUE, if the lens could not be compiled
+	Fallback string
+}
+
+func versionIdent(v thema.SyntacticVersion) string {
+	return fmt.Sprintf("V%d_%d", v[0], v[1])
+}
+
+// unsupportedError indicates that a lens uses a CUE construct that cannot be
+// compiled to Go.
+type unsupportedError struct {
+	node   ast.Node
+	reason string
+}
+
+func unsupported(node ast.Node, format string, args ...interface{}) error {
+	return &unsupportedError{
+		node:   node,
+		reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *unsupportedError) Error() string {
+	b, err := format.Node(e.node)
+	if err != nil {
+		return e.reason
+	}
+	return fmt.Sprintf("%s: %s", e.reason, b)
+}
+
+func compileLens(lens cue.Value, fname, linName string, in, out *goType, from, to thema.SyntacticVersion) (string, error) {
+	lit, err := lensResultLit(lens)
+	if err != nil {
+		return "", err
+	}
+	lacs, err := lensLacunasLit(lens)
+	if err != nil {
+		return "", err
+	}
+	if in.kind != kindStruct || out.kind != kindStruct {
+		return "", fmt.Errorf("schemas %s and %s must both be represented by Go structs", from, to)
+	}
+
+	c := &lensCompiler{
+		in:      in,
+		out:     out,
+		buf:     new(bytes.Buffer),
+		present: make(map[string]int),
+	}
+	set, err := c.compileStruct(lit, out, "out")
+	if err != nil {
+		return "", err
+	}
+	if err = checkSet(out, set, ""); err != nil {
+		return "", err
+	}
+
+	ret := "out, nil, nil"
+	if lacs != nil && len(lacs.Elts) > 0 {
+		c.lacunas = true
+		c.buf.WriteString("var lacunas []thema.Lacuna\n")
+		if err = c.compileLacunas(lacs.Elts); err != nil {
+			return "", err
+		}
+		ret = "out, lacunas, nil"
+	}
+
+	return fmt.Sprintf(`// %s translates an instance of schema %s of the '%s' lineage
+// to schema %s, as specified by the lens defined in CUE.
+func %s(in *%s) (*%s, []thema.Lacuna, error) {
+	out := &%s
+%s	return %s
+}`, fname, from, linName, to, fname, in.expr, out.expr, out.lit(), c.buf, ret), nil
+}
+
+// lensResultLit returns the struct literal from which the result of the
+// provided lens was evaluated.
+func lensResultLit(lens cue.Value) (*ast.StructLit, error) {
+	res := lens.LookupPath(cue.MakePath(cue.Str("result")))
+	_, args := res.Expr()
+	for _, arg := range args {
+		if f, is := arg.Source().(*ast.Field); is {
+			if lit, is := f.Value.(*ast.StructLit); is {
+				return lit, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unable to find source of lens result")
+}
+
+// lensLacunasLit returns the list literal from which the lacunas of the
+// provided lens were evaluated, or nil if the lens declares no lacunas.
+func lensLacunasLit(lens cue.Value) (*ast.ListLit, error) {
+	lacs := lens.LookupPath(cue.MakePath(cue.Str("lacunas")))
+	_, args := lacs.Expr()
+	var found *ast.ListLit
+	for _, arg := range append([]cue.Value{lacs}, args...) {
+		f, is := arg.Source().(*ast.Field)
+		if !is {
+			continue
+		}
+		lit, is := f.Value.(*ast.ListLit)
+		if !is {
+			continue
+		}
+		// The declaration of lacunas in #Lens
+		if len(lit.Elts) == 1 {
+			if _, is := lit.Elts[0].(*ast.Ellipsis); is {
+				continue
+			}
+		}
+		if found != nil && found != lit {
+			return nil, unsupported(lit, "lacunas declared more than once")
+		}
+		found = lit
+	}
+	if found == nil {
+		iter, err := lacs.List()
+		if err != nil || iter.Next() {
+			return nil, fmt.Errorf("unable to find source of lens lacunas")
+		}
+	}
+	return found, nil
+}
+
+// checkSet verifies that all required fields of the struct type t that have
+// no default are among those in set.
+func checkSet(t *goType, set map[*goField]bool, path string) error {
+	for _, f := range t.fields {
+		switch {
+		case f.optional || f.def != "" || set[f]:
+		case f.typ.kind == kindStruct && !f.ptr:
+			if err := checkSet(f.typ, nil, path+f.label+"."); err != nil {
+				return err
+			}
+		case (f.typ.kind == kindList || f.typ.kind == kindMap) && !f.nullable:
+			// Empty unless set, as in CUE
+		default:
+			return fmt.Errorf("required field %s%s is not always set", path, f.label)
+		}
+	}
+	return nil
+}
+
+// lensCompiler compiles the result of a single lens into the statements of a
+// Go func translating from *in to *out.
+type lensCompiler struct {
+	in, out *goType
+	buf     *bytes.Buffer
+	// Go expressions for pointers known to be non-nil within the comprehension
+	// currently being compiled, mapped to the number of enclosing conditions
+	// that establish it
+	present map[string]int
+	// Whether lacunas are being compiled, in which fields of result may be
+	// referenced
+	lacunas bool
+}
+
+// comprehension compiles an if comprehension, calling body to compile its
+// value within the resulting Go if statement.
+func (c *lensCompiler) comprehension(x *ast.Comprehension, body func(ast.Expr) error) error {
+	ifc, is := x.Clauses[0].(*ast.IfClause)
+	if !is || len(x.Clauses) != 1 {
+		return unsupported(x.Clauses[0], "unsupported comprehension clause")
+	}
+	cond, err := c.cond(ifc.Condition)
+	if err != nil {
+		return err
+	}
+	known := c.presentIn(ifc.Condition)
+	for _, k := range known {
+		c.present[k]++
+	}
+	fmt.Fprintf(c.buf, "if %s {\n", cond)
+	if err = body(x.Value); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.buf, "}\n")
+	for _, k := range known {
+		c.present[k]--
+	}
+	return nil
+}
+
+// compileStruct compiles the struct literal, assigning its fields to out, of
+// type t. It returns the fields of t that are always set by the literal.
+func (c *lensCompiler) compileStruct(lit *ast.StructLit, t *goType, out string) (map[*goField]bool, error) {
+	set := make(map[*goField]bool)
+	for _, decl := range lit.Elts {
+		switch x := decl.(type) {
+		case *ast.CommentGroup, *ast.Attribute:
+		case *ast.Field:
+			f, err := c.compileField(x, t, out)
+			if err != nil {
+				return nil, err
+			}
+			set[f] = true
+		case *ast.Comprehension:
+			err := c.comprehension(x, func(v ast.Expr) error {
+				body, is := v.(*ast.StructLit)
+				if !is {
+					return unsupported(v, "unsupported comprehension value")
+				}
+				_, err := c.compileStruct(body, t, out)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, unsupported(decl, "unsupported declaration")
+		}
+	}
+	return set, nil
+}
+
+func (c *lensCompiler) compileField(x *ast.Field, t *goType, out string) (*goField, error) {
+	label, isIdent, err := ast.LabelName(x.Label)
+	if err != nil || (isIdent && (strings.HasPrefix(label, "_") || strings.HasPrefix(label, "#"))) {
+		return nil, unsupported(x.Label, "unsupported field label")
+	}
+	if x.Optional.IsValid() {
+		return nil, unsupported(x, "optional fields are not supported")
+	}
+	f := t.field(label)
+	if f == nil {
+		return nil, unsupported(x.Label, "field does not exist in the target schema")
+	}
+	target := out + "." + f.name
+
+	if lit, is := x.Value.(*ast.StructLit); is {
+		if f.typ.kind != kindStruct {
+			return nil, unsupported(x.Label, "field is not a struct in the target schema")
+		}
+		if f.ptr {
+			fmt.Fprintf(c.buf, "if %s == nil {\n%s = &%s\n}\n", target, target, f.typ.lit())
+		}
+		set, err := c.compileStruct(lit, f.typ, target)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkSet(f.typ, set, label+"."); err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	val, isptr, err := c.value(x.Value, f)
+	if err != nil {
+		return nil, err
+	}
+	if f.ptr && !isptr {
+		fmt.Fprintf(c.buf, "{\nvar v %s = %s\n%s = &v\n}\n", f.typ.expr, val, target)
+	} else {
+		fmt.Fprintf(c.buf, "%s = %s\n", target, val)
+	}
+	return f, nil
+}
+
+// value compiles an expression to be assigned to the field f, returning a Go
+// expression and whether it is a pointer.
+func (c *lensCompiler) value(expr ast.Expr, f *goField) (string, bool, error) {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return c.value(x.X, f)
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		return c.refValue(x, f)
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+		if x, is := expr.(*ast.UnaryExpr); is && x.Op != token.NOT {
+			break
+		}
+		if f.typ.kind == kindAny || f.typ.family() == "bool" {
+			cond, err := c.cond(expr)
+			return cond, false, err
+		}
+		return "", false, unsupported(expr, "boolean expression assigned to non-boolean field")
+	}
+
+	lit, kind, err := goLit(expr)
+	if err != nil {
+		return "", false, err
+	}
+	switch {
+	case kind == "null":
+		if (f.nullable || (f.typ.kind != kindScalar && f.typ.kind != kindStruct)) && !f.omitempty {
+			return lit, f.ptr, nil
+		}
+	case f.typ.kind == kindAny:
+		return lit, false, nil
+	case kind == f.typ.family(), kind == "int" && f.typ.family() == "float":
+		return lit, false, nil
+	}
+	return "", false, unsupported(expr, "literal is not of the field's type in the target schema")
+}
+
+// goLit converts a CUE literal into a Go literal, also returning its kind.
+func goLit(expr ast.Expr) (string, string, error) {
+	neg := ""
+	if x, is := expr.(*ast.UnaryExpr); is && x.Op == token.SUB {
+		neg, expr = "-", x.X
+	}
+	x, is := expr.(*ast.BasicLit)
+	if !is {
+		return "", "", unsupported(expr, "unsupported expression")
+	}
+	if neg != "" && x.Kind != token.INT && x.Kind != token.FLOAT {
+		return "", "", unsupported(expr, "unsupported expression")
+	}
+
+	switch x.Kind {
+	case token.STRING:
+		s, err := literal.Unquote(x.Value)
+		if err != nil || strings.HasPrefix(x.Value, "'") {
+			break
+		}
+		return strconv.Quote(s), "string", nil
+	case token.INT:
+		s := strings.ReplaceAll(x.Value, "_", "")
+		if _, err := strconv.ParseInt(s, 0, 64); err != nil {
+			break
+		}
+		return neg + s, "int", nil
+	case token.FLOAT:
+		s := strings.ReplaceAll(x.Value, "_", "")
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			break
+		}
+		return neg + s, "float", nil
+	case token.TRUE, token.FALSE:
+		return x.Value, "bool", nil
+	case token.NULL:
+		return "nil", "null", nil
+	}
+	return "", "", unsupported(expr, "unsupported literal")
+}
+
+// ref is a reference to a field of input, or of result.
+type ref struct {
+	// Go expression for the field
+	expr string
+	// CUE path of the field, starting with input or result
+	path string
+	fld  *goField
+	// Go expressions for the pointers that must be non-nil to access the field,
+	// and the corresponding CUE paths
+	guards, guardPaths []string
+}
+
+func (c *lensCompiler) ref(expr ast.Expr) (*ref, error) {
+	var sels []string
+	for e := expr; ; {
+		switch x := e.(type) {
+		case *ast.SelectorExpr:
+			label, _, err := ast.LabelName(x.Sel)
+			if err != nil {
+				return nil, unsupported(expr, "unsupported selector")
+			}
+			sels = append([]string{label}, sels...)
+			e = x.X
+			continue
+		case *ast.IndexExpr:
+			lit, is := x.Index.(*ast.BasicLit)
+			if !is || lit.Kind != token.STRING {
+				return nil, unsupported(expr, "unsupported index")
+			}
+			label, err := literal.Unquote(lit.Value)
+			if err != nil {
+				return nil, unsupported(expr, "unsupported index")
+			}
+			sels = append([]string{label}, sels...)
+			e = x.X
+			continue
+		case *ast.Ident:
+			if len(sels) > 0 && (x.Name == "input" || (x.Name == "result" && c.lacunas)) {
+				sels = append([]string{x.Name}, sels...)
+				break
+			}
+			if c.lacunas {
+				return nil, unsupported(expr, "only references to fields of input or result are supported")
+			}
+			return nil, unsupported(expr, "only references to fields of input are supported")
+		default:
+			return nil, unsupported(expr, "unsupported expression")
+		}
+		break
+	}
+
+	r, t := &ref{expr: "in"}, c.in
+	if sels[0] == "result" {
+		r.expr, t = "out", c.out
+	}
+	for i, label := range sels[1:] {
+		f := t.field(label)
+		if f == nil {
+			return nil, unsupported(expr, "field does not exist in the %s schema", map[string]string{"in": "source", "out": "target"}[r.expr[:strings.IndexByte(r.expr+".", '.')]])
+		}
+		r.expr += "." + f.name
+		r.path = strings.Join(sels[:i+2], ".")
+		r.fld = f
+		if i == len(sels)-2 {
+			break
+		}
+		if f.typ.kind != kindStruct {
+			return nil, unsupported(expr, "field is not a struct")
+		}
+		if f.ptr {
+			r.guards = append(r.guards, r.expr)
+			r.guardPaths = append(r.guardPaths, r.path)
+		}
+		t = f.typ
+	}
+	return r, nil
+}
+
+func (c *lensCompiler) guard(expr, path, what string) {
+	if c.present[expr] > 0 {
+		return
+	}
+	fmt.Fprintf(c.buf, "if %s == nil {\nreturn nil, nil, fmt.Errorf(\"%s is %s\")\n}\n", expr, path, what)
+}
+
+// guardRef emits checks that the fields on the path to the referenced field
+// are present, and, if it is optional, the field itself.
+func (c *lensCompiler) guardRef(r *ref) {
+	for i, g := range r.guards {
+		c.guard(g, r.guardPaths[i], "absent")
+	}
+	// References to absent fields are errors in CUE, but null is a value
+	if r.fld.optional && r.fld.ptr {
+		c.guard(r.expr, r.path, "absent")
+	}
+}
+
+// refValue compiles a reference to a field of input to be assigned to the
+// field f, emitting checks that the referenced field is present.
+func (c *lensCompiler) refValue(expr ast.Expr, f *goField) (string, bool, error) {
+	r, err := c.ref(expr)
+	if err != nil {
+		return "", false, err
+	}
+	src := r.fld
+	if src.optional && !src.ptr {
+		return "", false, unsupported(expr, "presence of optional field in the source schema can not be determined")
+	}
+
+	var conv string
+	switch {
+	case f.typ.kind == kindAny:
+	case f.typ.expr == src.typ.expr:
+	case src.typ.kind == kindScalar && f.typ.kind == kindScalar && src.typ.convertible(f.typ):
+		conv = f.typ.expr
+	default:
+		return "", false, unsupported(expr, "field of type %s in the source schema can not be assigned to field of type %s in the target schema", src.typ.expr, f.typ.expr)
+	}
+
+	c.guardRef(r)
+	switch {
+	case !src.ptr:
+		if conv != "" {
+			return fmt.Sprintf("%s(%s)", conv, r.expr), false, nil
+		}
+		return r.expr, false, nil
+	case f.ptr && (conv == "" || src.typ.basic == f.typ.basic):
+		if conv != "" {
+			return fmt.Sprintf("(*%s)(%s)", conv, r.expr), true, nil
+		}
+		return r.expr, true, nil
+	case f.typ.kind == kindAny:
+		return r.expr, true, nil
+	}
+	// Dereferencing a pointer to a null value
+	if src.nullable {
+		c.guard(r.expr, r.path, "null")
+	}
+	if conv != "" {
+		return fmt.Sprintf("%s(*%s)", conv, r.expr), false, nil
+	}
+	return "*" + r.expr, false, nil
+}
+
+// compileLacunas compiles the elements of the lacunas list of a lens,
+// appending each to lacunas.
+func (c *lensCompiler) compileLacunas(elts []ast.Expr) error {
+	for _, elt := range elts {
+		switch x := elt.(type) {
+		case *ast.Comprehension:
+			err := c.comprehension(x, func(v ast.Expr) error {
+				return c.compileLacunas([]ast.Expr{v})
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			if err := c.compileLacuna(elt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lacunaFields collects the fields of a lacuna struct, which may be composed
+// of multiple struct literals and thema.#Lacuna.
+func lacunaFields(expr ast.Expr, fields map[string]ast.Expr) error {
+	switch x := unparen(expr).(type) {
+	case *ast.StructLit:
+		for _, decl := range x.Elts {
+			switch d := decl.(type) {
+			case *ast.CommentGroup, *ast.Attribute:
+			case *ast.EmbedDecl:
+				if err := lacunaFields(d.Expr, fields); err != nil {
+					return err
+				}
+			case *ast.Field:
+				label, _, err := ast.LabelName(d.Label)
+				if err != nil || d.Optional.IsValid() {
+					return unsupported(d.Label, "unsupported lacuna field")
+				}
+				if _, has := fields[label]; has {
+					return unsupported(d.Label, "lacuna field declared more than once")
+				}
+				fields[label] = d.Value
+			default:
+				return unsupported(decl, "unsupported lacuna declaration")
+			}
+		}
+		return nil
+	case *ast.BinaryExpr:
+		if x.Op != token.AND {
+			break
+		}
+		if err := lacunaFields(x.X, fields); err != nil {
+			return err
+		}
+		return lacunaFields(x.Y, fields)
+	case *ast.SelectorExpr:
+		if id, is := x.X.(*ast.Ident); is && id.Name == "thema" && identName(x.Sel) == "#Lacuna" {
+			return nil
+		}
+	case *ast.Ident:
+		if x.Name == "#Lacuna" {
+			return nil
+		}
+	}
+	return unsupported(expr, "unsupported lacuna expression")
+}
+
+func identName(l ast.Label) string {
+	name, _, _ := ast.LabelName(l)
+	return name
+}
+
+func (c *lensCompiler) compileLacuna(expr ast.Expr) error {
+	fields := make(map[string]ast.Expr)
+	if err := lacunaFields(expr, fields); err != nil {
+		return err
+	}
+
+	b := new(strings.Builder)
+	b.WriteString("lacunas = append(lacunas, thema.Lacuna{\n")
+	for _, name := range []string{"sourceFields", "targetFields"} {
+		fexpr, has := fields[name]
+		if !has {
+			continue
+		}
+		refs, is := unparen(fexpr).(*ast.ListLit)
+		if !is {
+			return unsupported(fexpr, "unsupported lacuna %s", name)
+		}
+		fmt.Fprintf(b, "%s: []thema.FieldRef{\n", strings.Title(name)) //nolint:staticcheck
+		for _, elt := range refs.Elts {
+			fr, err := c.fieldRef(elt)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "%s,\n", fr)
+		}
+		b.WriteString("},\n")
+	}
+
+	typ, has := fields["type"]
+	if !has {
+		return unsupported(expr, "lacuna has no type")
+	}
+	lt, err := lacunaType(typ)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(b, "Type: thema.%s,\n", lt)
+
+	msg, has := fields["message"]
+	if !has {
+		return unsupported(expr, "lacuna has no message")
+	}
+	lit, kind, err := goLit(unparen(msg))
+	if err != nil || kind != "string" {
+		return unsupported(msg, "lacuna message must be a string literal")
+	}
+	fmt.Fprintf(b, "Message: %s,\n", lit)
+	b.WriteString("})\n")
+
+	for name := range fields {
+		switch name {
+		case "sourceFields", "targetFields", "type", "message":
+		default:
+			return unsupported(fields[name], "unsupported lacuna field %s", name)
+		}
+	}
+	c.buf.WriteString(b.String())
+	return nil
+}
+
+// fieldRef compiles a struct literal describing a field in a lacuna into a
+// thema.FieldRef literal.
+func (c *lensCompiler) fieldRef(expr ast.Expr) (string, error) {
+	fields := make(map[string]ast.Expr)
+	if err := lacunaFields(expr, fields); err != nil {
+		return "", err
+	}
+	if len(fields) != 2 || fields["path"] == nil || fields["value"] == nil {
+		return "", unsupported(expr, "lacuna field must have only a path and a value")
+	}
+	path, kind, err := goLit(unparen(fields["path"]))
+	if err != nil || kind != "string" {
+		return "", unsupported(fields["path"], "lacuna field path must be a string literal")
+	}
+
+	vexpr := unparen(fields["value"])
+	switch vexpr.(type) {
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		r, err := c.ref(vexpr)
+		if err != nil {
+			return "", err
+		}
+		if r.fld.optional && !r.fld.ptr {
+			return "", unsupported(vexpr, "presence of optional field can not be determined")
+		}
+		c.guardRef(r)
+		val := r.expr
+		if r.fld.ptr && !r.fld.nullable && r.fld.typ.kind == kindScalar {
+			val = "*" + val
+		}
+		return fmt.Sprintf("{Path: %s, Value: %s}", path, val), nil
+	}
+	val, _, err := goLit(vexpr)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("{Path: %s, Value: %s}", path, val), nil
+}
+
+// lacunaType returns the name of the Go constant for the lacuna type
+// referenced by expr, one of thema.#LacunaTypes.
+func lacunaType(expr ast.Expr) (string, error) {
+	x, is := unparen(expr).(*ast.SelectorExpr)
+	if is {
+		var types string
+		switch tx := x.X.(type) {
+		case *ast.Ident:
+			types = tx.Name
+		case *ast.SelectorExpr:
+			if id, is := tx.X.(*ast.Ident); is && id.Name == "thema" {
+				types = identName(tx.Sel)
+			}
+		}
+		if types == "#LacunaTypes" {
+			name := identName(x.Sel)
+			for lt := thema.LacunaType(1); !strings.HasPrefix(lt.String(), "LacunaType("); lt++ {
+				if lt.String() == name {
+					return "Lacuna" + name, nil
+				}
+			}
+		}
+	}
+	return "", unsupported(expr, "lacuna type must be one of thema.#LacunaTypes")
+}
+
+// cond compiles an expression used as a condition into a Go boolean expression.
+func (c *lensCompiler) cond(expr ast.Expr) (string, error) {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return c.cond(x.X)
+	case *ast.UnaryExpr:
+		if x.Op != token.NOT {
+			break
+		}
+		s, err := c.cond(x.X)
+		if err != nil {
+			return "", err
+		}
+		if _, is := unparen(x.X).(*ast.BinaryExpr); is {
+			return "!(" + s + ")", nil
+		}
+		return "!" + s, nil
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.LAND, token.LOR:
+			l, err := c.condOperand(x.X, x.Op)
+			if err != nil {
+				return "", err
+			}
+			r, err := c.condOperand(x.Y, x.Op)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %s", l, x.Op, r), nil
+		case token.EQL, token.NEQ:
+			if _, is := unparen(x.Y).(*ast.BottomLit); is {
+				return c.presence(x.X, x.Op == token.NEQ)
+			}
+			if _, is := unparen(x.X).(*ast.BottomLit); is {
+				return c.presence(x.Y, x.Op == token.NEQ)
+			}
+			return c.compare(x)
+		case token.LSS, token.GTR, token.LEQ, token.GEQ:
+			return c.compare(x)
+		}
+		return "", unsupported(expr, "unsupported operator %s", x.Op)
+	case *ast.BasicLit:
+		if x.Kind == token.TRUE || x.Kind == token.FALSE {
+			return x.Value, nil
+		}
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		r, err := c.ref(x)
+		if err != nil {
+			return "", err
+		}
+		if r.fld.typ.family() != "bool" || r.fld.ptr || r.fld.optional || len(r.guards) > 0 {
+			return "", unsupported(expr, "only required boolean fields may be used as conditions")
+		}
+		return r.expr, nil
+	}
+	return "", unsupported(expr, "unsupported condition")
+}
+
+func (c *lensCompiler) condOperand(expr ast.Expr, op token.Token) (string, error) {
+	s, err := c.cond(expr)
+	if err != nil {
+		return "", err
+	}
+	if x, is := unparen(expr).(*ast.BinaryExpr); is && x.Op != op && (x.Op == token.LAND || x.Op == token.LOR) {
+		return "(" + s + ")", nil
+	}
+	return s, nil
+}
+
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		p, is := expr.(*ast.ParenExpr)
+		if !is {
+			return expr
+		}
+		expr = p.X
+	}
+}
+
+// presentIn returns the Go expressions for pointers that are known to be
+// non-nil if the provided condition is true.
+func (c *lensCompiler) presentIn(cond ast.Expr) []string {
+	x, is := unparen(cond).(*ast.BinaryExpr)
+	if !is {
+		return nil
+	}
+	switch x.Op {
+	case token.LAND:
+		return append(c.presentIn(x.X), c.presentIn(x.Y)...)
+	case token.NEQ:
+		operand := x.X
+		if _, is := unparen(x.X).(*ast.BottomLit); is {
+			operand = x.Y
+		} else if _, is := unparen(x.Y).(*ast.BottomLit); !is {
+			return nil
+		}
+		r, err := c.ref(unparen(operand))
+		if err != nil {
+			return nil
+		}
+		known := r.guards
+		if r.fld.optional && r.fld.ptr {
+			known = append(known, r.expr)
+		}
+		return known
+	}
+	return nil
+}
+
+// presence compiles a comparison of a field of input with _|_.
+func (c *lensCompiler) presence(expr ast.Expr, present bool) (string, error) {
+	r, err := c.ref(unparen(expr))
+	if err != nil {
+		return "", err
+	}
+	if r.fld.optional && !r.fld.ptr {
+		return "", unsupported(expr, "presence of optional field can not be determined")
+	}
+
+	terms := make([]string, 0, len(r.guards)+1)
+	for _, g := range r.guards {
+		terms = append(terms, g+" != nil")
+	}
+	if r.fld.optional {
+		terms = append(terms, r.expr+" != nil")
+	}
+
+	switch {
+	case len(terms) == 0:
+		return strconv.FormatBool(present), nil
+	case present:
+		return strings.Join(terms, " && "), nil
+	case len(terms) == 1:
+		return strings.Replace(terms[0], "!=", "==", 1), nil
+	}
+	return "!(" + strings.Join(terms, " && ") + ")", nil
+}
+
+// compare compiles a comparison between fields and literals.
+func (c *lensCompiler) compare(x *ast.BinaryExpr) (string, error) {
+	l, ltyp, err := c.operand(x.X)
+	if err != nil {
+		return "", err
+	}
+	r, rtyp, err := c.operand(x.Y)
+	if err != nil {
+		return "", err
+	}
+
+	// Operands are either fields, with a Go type, or literals, with a kind
+	var ok bool
+	lkind, rkind := ltyp.family(), rtyp.family()
+	switch {
+	case ltyp.kind == kindAny && rtyp.kind == kindAny:
+	case ltyp.expr != "" && rtyp.expr != "":
+		ok = ltyp.expr == rtyp.expr && ltyp.kind == kindScalar
+	case ltyp.kind == kindAny:
+		ok = rkind == "string" || rkind == "bool"
+	case rtyp.kind == kindAny:
+		ok = lkind == "string" || lkind == "bool"
+	default:
+		ok = lkind == rkind || (lkind == "float" && rkind == "int") || (lkind == "int" && rkind == "float" && rtyp.expr != "")
+	}
+	if x.Op != token.EQL && x.Op != token.NEQ {
+		ok = ok && lkind != "string" && lkind != "bool" && ltyp.kind != kindAny && rtyp.kind != kindAny
+	}
+	if !ok {
+		return "", unsupported(x, "unsupported comparison")
+	}
+	return fmt.Sprintf("%s %s %s", l, x.Op, r), nil
+}
+
+// operand compiles an operand of a comparison, returning a Go expression and
+// either its Go type or, for literals, a type with no expression, of the
+// literal's kind.
+func (c *lensCompiler) operand(expr ast.Expr) (string, *goType, error) {
+	expr = unparen(expr)
+	switch expr.(type) {
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		r, err := c.ref(expr)
+		if err != nil {
+			return "", nil, err
+		}
+		if r.fld.ptr || r.fld.optional || len(r.guards) > 0 {
+			return "", nil, unsupported(expr, "comparisons are only supported on required fields")
+		}
+		switch r.fld.typ.kind {
+		case kindScalar, kindAny:
+			return r.expr, r.fld.typ, nil
+		}
+		return "", nil, unsupported(expr, "comparisons are only supported on scalar fields")
+	}
+
+	lit, kind, err := goLit(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	switch kind {
+	case "null":
+		return "", nil, unsupported(expr, "comparisons with null are not supported")
+	case "int":
+		return lit, &goType{kind: kindScalar, basic: "int"}, nil
+	case "float":
+		return lit, &goType{kind: kindScalar, basic: "float64"}, nil
+	}
+	return lit, &goType{kind: kindScalar, basic: kind}, nil
+}
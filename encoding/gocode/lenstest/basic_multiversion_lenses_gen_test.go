@@ -0,0 +1,268 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// Defines values for BasicMultiversionV0_2WithDefault.
+const (
+	BasicMultiversionV0_2WithDefaultBar BasicMultiversionV0_2WithDefault = "bar"
+	BasicMultiversionV0_2WithDefaultFoo BasicMultiversionV0_2WithDefault = "foo"
+)
+
+// Defines values for BasicMultiversionV0_3WithDefault.
+const (
+	BasicMultiversionV0_3WithDefaultBar BasicMultiversionV0_3WithDefault = "bar"
+	BasicMultiversionV0_3WithDefaultBaz BasicMultiversionV0_3WithDefault = "baz"
+	BasicMultiversionV0_3WithDefaultFoo BasicMultiversionV0_3WithDefault = "foo"
+)
+
+// Defines values for BasicMultiversionV1_0WithDefault.
+const (
+	BasicMultiversionV1_0WithDefaultBar BasicMultiversionV1_0WithDefault = "bar"
+	BasicMultiversionV1_0WithDefaultBaz BasicMultiversionV1_0WithDefault = "baz"
+	BasicMultiversionV1_0WithDefaultFoo BasicMultiversionV1_0WithDefault = "foo"
+)
+
+// Defines values for BasicMultiversionV1_1WithDefault.
+const (
+	BasicMultiversionV1_1WithDefaultBar  BasicMultiversionV1_1WithDefault = "bar"
+	BasicMultiversionV1_1WithDefaultBaz  BasicMultiversionV1_1WithDefault = "baz"
+	BasicMultiversionV1_1WithDefaultBing BasicMultiversionV1_1WithDefault = "bing"
+	BasicMultiversionV1_1WithDefaultFoo  BasicMultiversionV1_1WithDefault = "foo"
+)
+
+// Defines values for BasicMultiversionV2_0WithDefault.
+const (
+	BasicMultiversionV2_0WithDefaultBar  BasicMultiversionV2_0WithDefault = "bar"
+	BasicMultiversionV2_0WithDefaultBaz  BasicMultiversionV2_0WithDefault = "baz"
+	BasicMultiversionV2_0WithDefaultBing BasicMultiversionV2_0WithDefault = "bing"
+	BasicMultiversionV2_0WithDefaultFoo  BasicMultiversionV2_0WithDefault = "foo"
+)
+
+// BasicMultiversionV0_0 defines model for BasicMultiversionV0_0.
+type BasicMultiversionV0_0 struct {
+	Init string `json:"init"`
+}
+
+// BasicMultiversionV0_1 defines model for BasicMultiversionV0_1.
+type BasicMultiversionV0_1 struct {
+	Init     string `json:"init"`
+	Optional *int32 `json:"optional,omitempty"`
+}
+
+// BasicMultiversionV0_2 defines model for BasicMultiversionV0_2.
+type BasicMultiversionV0_2 struct {
+	Init        string                            `json:"init"`
+	Optional    *int32                            `json:"optional,omitempty"`
+	WithDefault *BasicMultiversionV0_2WithDefault `json:"withDefault,omitempty"`
+}
+
+// BasicMultiversionV0_2WithDefault defines model for BasicMultiversionV0_2.WithDefault.
+type BasicMultiversionV0_2WithDefault string
+
+// BasicMultiversionV0_3 defines model for BasicMultiversionV0_3.
+type BasicMultiversionV0_3 struct {
+	Init        string                            `json:"init"`
+	Optional    *int32                            `json:"optional,omitempty"`
+	WithDefault *BasicMultiversionV0_3WithDefault `json:"withDefault,omitempty"`
+}
+
+// BasicMultiversionV0_3WithDefault defines model for BasicMultiversionV0_3.WithDefault.
+type BasicMultiversionV0_3WithDefault string
+
+// BasicMultiversionV1_0 defines model for BasicMultiversionV1_0.
+type BasicMultiversionV1_0 struct {
+	Optional    *int32                           `json:"optional,omitempty"`
+	Renamed     string                           `json:"renamed"`
+	WithDefault BasicMultiversionV1_0WithDefault `json:"withDefault"`
+}
+
+// BasicMultiversionV1_0WithDefault defines model for BasicMultiversionV1_0.WithDefault.
+type BasicMultiversionV1_0WithDefault string
+
+// BasicMultiversionV1_1 defines model for BasicMultiversionV1_1.
+type BasicMultiversionV1_1 struct {
+	Optional    *int32                           `json:"optional,omitempty"`
+	Renamed     string                           `json:"renamed"`
+	WithDefault BasicMultiversionV1_1WithDefault `json:"withDefault"`
+}
+
+// BasicMultiversionV1_1WithDefault defines model for BasicMultiversionV1_1.WithDefault.
+type BasicMultiversionV1_1WithDefault string
+
+// BasicMultiversionV2_0 defines model for BasicMultiversionV2_0.
+type BasicMultiversionV2_0 struct {
+	Optional *int32 `json:"optional,omitempty"`
+	ToObj    struct {
+		Init string `json:"init"`
+	} `json:"toObj"`
+	WithDefault BasicMultiversionV2_0WithDefault `json:"withDefault"`
+}
+
+// BasicMultiversionV2_0WithDefault defines model for BasicMultiversionV2_0.WithDefault.
+type BasicMultiversionV2_0WithDefault string
+
+// BasicMultiversionTypes maps the version of each schema in the 'basic-multiversion' lineage to the
+// Go type generated for it.
+var BasicMultiversionTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(BasicMultiversionV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(BasicMultiversionV0_1{}),
+	thema.SV(0, 2): reflect.TypeOf(BasicMultiversionV0_2{}),
+	thema.SV(0, 3): reflect.TypeOf(BasicMultiversionV0_3{}),
+	thema.SV(1, 0): reflect.TypeOf(BasicMultiversionV1_0{}),
+	thema.SV(1, 1): reflect.TypeOf(BasicMultiversionV1_1{}),
+	thema.SV(2, 0): reflect.TypeOf(BasicMultiversionV2_0{}),
+}
+
+// BasicMultiversionLenses returns the lenses of the 'basic-multiversion' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func BasicMultiversionLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(0, 1),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV0_1ToV0_0),
+		},
+		{
+			From:         thema.SV(0, 2),
+			To:           thema.SV(0, 1),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV0_2ToV0_1),
+		},
+		{
+			From:         thema.SV(0, 3),
+			To:           thema.SV(0, 2),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV0_3ToV0_2),
+		},
+		{
+			From:         thema.SV(1, 0),
+			To:           thema.SV(0, 3),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV1_0ToV0_3),
+		},
+		{
+			From:         thema.SV(0, 3),
+			To:           thema.SV(1, 0),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV0_3ToV1_0),
+		},
+		{
+			From:         thema.SV(1, 1),
+			To:           thema.SV(1, 0),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV1_1ToV1_0),
+		},
+		{
+			From:         thema.SV(2, 0),
+			To:           thema.SV(1, 1),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV2_0ToV1_1),
+		},
+		{
+			From:         thema.SV(1, 1),
+			To:           thema.SV(2, 0),
+			LacunaMapper: thema.GoLensMapper(BasicMultiversionV1_1ToV2_0),
+		},
+	}
+}
+
+// BasicMultiversionV0_1ToV0_0 translates an instance of schema 0.1 of the 'basic-multiversion' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func BasicMultiversionV0_1ToV0_0(in *BasicMultiversionV0_1) (*BasicMultiversionV0_0, []thema.Lacuna, error) {
+	out := &BasicMultiversionV0_0{}
+	out.Init = in.Init
+	return out, nil, nil
+}
+
+// BasicMultiversionV0_2ToV0_1 translates an instance of schema 0.2 of the 'basic-multiversion' lineage
+// to schema 0.1, as specified by the lens defined in CUE.
+func BasicMultiversionV0_2ToV0_1(in *BasicMultiversionV0_2) (*BasicMultiversionV0_1, []thema.Lacuna, error) {
+	out := &BasicMultiversionV0_1{}
+	out.Init = in.Init
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	return out, nil, nil
+}
+
+// BasicMultiversionV0_3ToV0_2 translates an instance of schema 0.3 of the 'basic-multiversion' lineage
+// to schema 0.2, as specified by the lens defined in CUE.
+func BasicMultiversionV0_3ToV0_2(in *BasicMultiversionV0_3) (*BasicMultiversionV0_2, []thema.Lacuna, error) {
+	out := &BasicMultiversionV0_2{}
+	out.Init = in.Init
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	{
+		var v BasicMultiversionV0_2WithDefault = "foo"
+		out.WithDefault = &v
+	}
+	return out, nil, nil
+}
+
+// BasicMultiversionV1_0ToV0_3 translates an instance of schema 1.0 of the 'basic-multiversion' lineage
+// to schema 0.3, as specified by the lens defined in CUE.
+func BasicMultiversionV1_0ToV0_3(in *BasicMultiversionV1_0) (*BasicMultiversionV0_3, []thema.Lacuna, error) {
+	out := &BasicMultiversionV0_3{}
+	out.Init = in.Renamed
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	{
+		var v BasicMultiversionV0_3WithDefault = BasicMultiversionV0_3WithDefault(in.WithDefault)
+		out.WithDefault = &v
+	}
+	return out, nil, nil
+}
+
+// BasicMultiversionV0_3ToV1_0 translates an instance of schema 0.3 of the 'basic-multiversion' lineage
+// to schema 1.0, as specified by the lens defined in CUE.
+func BasicMultiversionV0_3ToV1_0(in *BasicMultiversionV0_3) (*BasicMultiversionV1_0, []thema.Lacuna, error) {
+	out := &BasicMultiversionV1_0{WithDefault: "bar"}
+	out.Renamed = in.Init
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	out.WithDefault = "foo"
+	return out, nil, nil
+}
+
+// BasicMultiversionV1_1ToV1_0 translates an instance of schema 1.1 of the 'basic-multiversion' lineage
+// to schema 1.0, as specified by the lens defined in CUE.
+func BasicMultiversionV1_1ToV1_0(in *BasicMultiversionV1_1) (*BasicMultiversionV1_0, []thema.Lacuna, error) {
+	out := &BasicMultiversionV1_0{WithDefault: "bar"}
+	out.Renamed = in.Renamed
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	out.WithDefault = "foo"
+	return out, nil, nil
+}
+
+// BasicMultiversionV2_0ToV1_1 translates an instance of schema 2.0 of the 'basic-multiversion' lineage
+// to schema 1.1, as specified by the lens defined in CUE.
+func BasicMultiversionV2_0ToV1_1(in *BasicMultiversionV2_0) (*BasicMultiversionV1_1, []thema.Lacuna, error) {
+	out := &BasicMultiversionV1_1{WithDefault: "bar"}
+	out.Renamed = in.ToObj.Init
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	out.WithDefault = "foo"
+	return out, nil, nil
+}
+
+// BasicMultiversionV1_1ToV2_0 translates an instance of schema 1.1 of the 'basic-multiversion' lineage
+// to schema 2.0, as specified by the lens defined in CUE.
+func BasicMultiversionV1_1ToV2_0(in *BasicMultiversionV1_1) (*BasicMultiversionV2_0, []thema.Lacuna, error) {
+	out := &BasicMultiversionV2_0{WithDefault: "bar"}
+	out.ToObj.Init = in.Renamed
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	out.WithDefault = "foo"
+	return out, nil, nil
+}
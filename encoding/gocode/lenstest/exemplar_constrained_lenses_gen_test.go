@@ -0,0 +1,65 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// Defines values for DimensionV0_0Unit.
+const (
+	DimensionV0_0UnitCm DimensionV0_0Unit = "cm"
+	DimensionV0_0UnitM  DimensionV0_0Unit = "m"
+	DimensionV0_0UnitMm DimensionV0_0Unit = "mm"
+)
+
+// Defines values for ExemplarConstrainedV0_0Status.
+const (
+	ExemplarConstrainedV0_0StatusArchived  ExemplarConstrainedV0_0Status = "archived"
+	ExemplarConstrainedV0_0StatusDraft     ExemplarConstrainedV0_0Status = "draft"
+	ExemplarConstrainedV0_0StatusPublished ExemplarConstrainedV0_0Status = "published"
+)
+
+// DimensionV0_0 defines model for DimensionV0_0.
+type DimensionV0_0 struct {
+	Length float32           `json:"length"`
+	Unit   DimensionV0_0Unit `json:"unit"`
+}
+
+// DimensionV0_0Unit defines model for DimensionV0_0.Unit.
+type DimensionV0_0Unit string
+
+// ExemplarConstrainedV0_0 defines model for ExemplarConstrainedV0_0.
+type ExemplarConstrainedV0_0 struct {
+	Code   string                        `json:"code"`
+	Count  int                           `json:"count"`
+	Labels map[string]string             `json:"labels,omitempty"`
+	Name   string                        `json:"name"`
+	Note   *string                       `json:"note"`
+	Parts  []DimensionV0_0               `json:"parts,omitempty"`
+	Ratio  *float32                      `json:"ratio,omitempty"`
+	Size   DimensionV0_0                 `json:"size"`
+	Status ExemplarConstrainedV0_0Status `json:"status"`
+	Tags   []string                      `json:"tags"`
+}
+
+// ExemplarConstrainedV0_0Status defines model for ExemplarConstrainedV0_0.Status.
+type ExemplarConstrainedV0_0Status string
+
+// ExemplarConstrainedTypes maps the version of each schema in the 'constrained' lineage to the
+// Go type generated for it.
+var ExemplarConstrainedTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExemplarConstrainedV0_0{}),
+}
+
+// ExemplarConstrainedLenses returns the lenses of the 'constrained' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExemplarConstrainedLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{}
+}
@@ -0,0 +1,69 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// Defines values for ExemplarDefaultchangeV0_0Aunion.
+const (
+	ExemplarDefaultchangeV0_0AunionBar ExemplarDefaultchangeV0_0Aunion = "bar"
+	ExemplarDefaultchangeV0_0AunionBaz ExemplarDefaultchangeV0_0Aunion = "baz"
+	ExemplarDefaultchangeV0_0AunionFoo ExemplarDefaultchangeV0_0Aunion = "foo"
+)
+
+// Defines values for ExemplarDefaultchangeV1_0Aunion.
+const (
+	ExemplarDefaultchangeV1_0AunionBar ExemplarDefaultchangeV1_0Aunion = "bar"
+	ExemplarDefaultchangeV1_0AunionBaz ExemplarDefaultchangeV1_0Aunion = "baz"
+	ExemplarDefaultchangeV1_0AunionFoo ExemplarDefaultchangeV1_0Aunion = "foo"
+)
+
+// ExemplarDefaultchangeV0_0 defines model for ExemplarDefaultchangeV0_0.
+type ExemplarDefaultchangeV0_0 struct {
+	Aunion ExemplarDefaultchangeV0_0Aunion `json:"aunion"`
+}
+
+// ExemplarDefaultchangeV0_0Aunion defines model for ExemplarDefaultchangeV0_0.Aunion.
+type ExemplarDefaultchangeV0_0Aunion string
+
+// ExemplarDefaultchangeV1_0 defines model for ExemplarDefaultchangeV1_0.
+type ExemplarDefaultchangeV1_0 struct {
+	Aunion ExemplarDefaultchangeV1_0Aunion `json:"aunion"`
+}
+
+// ExemplarDefaultchangeV1_0Aunion defines model for ExemplarDefaultchangeV1_0.Aunion.
+type ExemplarDefaultchangeV1_0Aunion string
+
+// ExemplarDefaultchangeTypes maps the version of each schema in the 'defaultchange' lineage to the
+// Go type generated for it.
+var ExemplarDefaultchangeTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExemplarDefaultchangeV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(ExemplarDefaultchangeV1_0{}),
+}
+
+// ExemplarDefaultchangeLenses returns the lenses of the 'defaultchange' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExemplarDefaultchangeLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			// Falls back to CUE: field does not exist in the source schema: input.anion
+			From:         thema.SV(1, 0),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.CUELensMapper(thema.SV(1, 0), thema.SV(0, 0)),
+		},
+		{
+			// Falls back to CUE: field does not exist in the source schema: input.anion
+			From:         thema.SV(0, 0),
+			To:           thema.SV(1, 0),
+			LacunaMapper: thema.CUELensMapper(thema.SV(0, 0), thema.SV(1, 0)),
+		},
+	}
+}
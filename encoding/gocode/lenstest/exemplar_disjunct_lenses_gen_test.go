@@ -0,0 +1,43 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// ExemplarDisjunctV0_0 defines model for ExemplarDisjunctV0_0.
+type ExemplarDisjunctV0_0 struct {
+	Rootfield any `json:"rootfield"`
+}
+
+// ExemplarDisjunctV0_1 defines model for ExemplarDisjunctV0_1.
+type ExemplarDisjunctV0_1 struct {
+	Rootfield any `json:"rootfield"`
+}
+
+// ExemplarDisjunctTypes maps the version of each schema in the 'disjunct' lineage to the
+// Go type generated for it.
+var ExemplarDisjunctTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExemplarDisjunctV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(ExemplarDisjunctV0_1{}),
+}
+
+// ExemplarDisjunctLenses returns the lenses of the 'disjunct' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExemplarDisjunctLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			// Falls back to CUE: required field rootfield is not always set
+			From:         thema.SV(0, 1),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.CUELensMapper(thema.SV(0, 1), thema.SV(0, 0)),
+		},
+	}
+}
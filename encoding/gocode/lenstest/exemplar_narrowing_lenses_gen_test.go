@@ -0,0 +1,56 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// ExemplarNarrowingV0_0 defines model for ExemplarNarrowingV0_0.
+type ExemplarNarrowingV0_0 struct {
+	Boolish any `json:"boolish"`
+}
+
+// ExemplarNarrowingV1_0 defines model for ExemplarNarrowingV1_0.
+type ExemplarNarrowingV1_0 struct {
+	Properbool bool `json:"properbool"`
+}
+
+// ExemplarNarrowingTypes maps the version of each schema in the 'narrowing' lineage to the
+// Go type generated for it.
+var ExemplarNarrowingTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExemplarNarrowingV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(ExemplarNarrowingV1_0{}),
+}
+
+// ExemplarNarrowingLenses returns the lenses of the 'narrowing' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExemplarNarrowingLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(1, 0),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(ExemplarNarrowingV1_0ToV0_0),
+		},
+		{
+			// Falls back to CUE: unsupported expression: input.boolish & string
+			From:         thema.SV(0, 0),
+			To:           thema.SV(1, 0),
+			LacunaMapper: thema.CUELensMapper(thema.SV(0, 0), thema.SV(1, 0)),
+		},
+	}
+}
+
+// ExemplarNarrowingV1_0ToV0_0 translates an instance of schema 1.0 of the 'narrowing' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func ExemplarNarrowingV1_0ToV0_0(in *ExemplarNarrowingV1_0) (*ExemplarNarrowingV0_0, []thema.Lacuna, error) {
+	out := &ExemplarNarrowingV0_0{}
+	out.Boolish = in.Properbool
+	return out, nil, nil
+}
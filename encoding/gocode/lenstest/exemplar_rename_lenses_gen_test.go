@@ -0,0 +1,67 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// ExemplarRenameV0_0 defines model for ExemplarRenameV0_0.
+type ExemplarRenameV0_0 struct {
+	Before    string `json:"before"`
+	Unchanged string `json:"unchanged"`
+}
+
+// ExemplarRenameV1_0 defines model for ExemplarRenameV1_0.
+type ExemplarRenameV1_0 struct {
+	After     string `json:"after"`
+	Unchanged string `json:"unchanged"`
+}
+
+// ExemplarRenameTypes maps the version of each schema in the 'rename' lineage to the
+// Go type generated for it.
+var ExemplarRenameTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExemplarRenameV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(ExemplarRenameV1_0{}),
+}
+
+// ExemplarRenameLenses returns the lenses of the 'rename' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExemplarRenameLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(1, 0),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(ExemplarRenameV1_0ToV0_0),
+		},
+		{
+			From:         thema.SV(0, 0),
+			To:           thema.SV(1, 0),
+			LacunaMapper: thema.GoLensMapper(ExemplarRenameV0_0ToV1_0),
+		},
+	}
+}
+
+// ExemplarRenameV1_0ToV0_0 translates an instance of schema 1.0 of the 'rename' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func ExemplarRenameV1_0ToV0_0(in *ExemplarRenameV1_0) (*ExemplarRenameV0_0, []thema.Lacuna, error) {
+	out := &ExemplarRenameV0_0{}
+	out.Before = in.After
+	out.Unchanged = in.Unchanged
+	return out, nil, nil
+}
+
+// ExemplarRenameV0_0ToV1_0 translates an instance of schema 0.0 of the 'rename' lineage
+// to schema 1.0, as specified by the lens defined in CUE.
+func ExemplarRenameV0_0ToV1_0(in *ExemplarRenameV0_0) (*ExemplarRenameV1_0, []thema.Lacuna, error) {
+	out := &ExemplarRenameV1_0{}
+	out.After = in.Before
+	out.Unchanged = in.Unchanged
+	return out, nil, nil
+}
@@ -0,0 +1,32 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// ExemplarSingleV0_0 defines model for ExemplarSingleV0_0.
+type ExemplarSingleV0_0 struct {
+	Abool   bool   `json:"abool"`
+	Anint   int    `json:"anint"`
+	Astring string `json:"astring"`
+}
+
+// ExemplarSingleTypes maps the version of each schema in the 'single' lineage to the
+// Go type generated for it.
+var ExemplarSingleTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExemplarSingleV0_0{}),
+}
+
+// ExemplarSingleLenses returns the lenses of the 'single' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExemplarSingleLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{}
+}
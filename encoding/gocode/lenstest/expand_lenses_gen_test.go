@@ -0,0 +1,121 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// Defines values for ExpandV0_2WithDefault.
+const (
+	ExpandV0_2WithDefaultBar ExpandV0_2WithDefault = "bar"
+	ExpandV0_2WithDefaultFoo ExpandV0_2WithDefault = "foo"
+)
+
+// Defines values for ExpandV0_3WithDefault.
+const (
+	ExpandV0_3WithDefaultBar ExpandV0_3WithDefault = "bar"
+	ExpandV0_3WithDefaultBaz ExpandV0_3WithDefault = "baz"
+	ExpandV0_3WithDefaultFoo ExpandV0_3WithDefault = "foo"
+)
+
+// ExpandV0_0 defines model for ExpandV0_0.
+type ExpandV0_0 struct {
+	Init string `json:"init"`
+}
+
+// ExpandV0_1 defines model for ExpandV0_1.
+type ExpandV0_1 struct {
+	Init     string `json:"init"`
+	Optional *int   `json:"optional,omitempty"`
+}
+
+// ExpandV0_2 defines model for ExpandV0_2.
+type ExpandV0_2 struct {
+	Init        string                 `json:"init"`
+	Optional    *int                   `json:"optional,omitempty"`
+	WithDefault *ExpandV0_2WithDefault `json:"withDefault,omitempty"`
+}
+
+// ExpandV0_2WithDefault defines model for ExpandV0_2.WithDefault.
+type ExpandV0_2WithDefault string
+
+// ExpandV0_3 defines model for ExpandV0_3.
+type ExpandV0_3 struct {
+	Init        string                 `json:"init"`
+	Optional    *int                   `json:"optional,omitempty"`
+	WithDefault *ExpandV0_3WithDefault `json:"withDefault,omitempty"`
+}
+
+// ExpandV0_3WithDefault defines model for ExpandV0_3.WithDefault.
+type ExpandV0_3WithDefault string
+
+// ExpandTypes maps the version of each schema in the 'expand' lineage to the
+// Go type generated for it.
+var ExpandTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExpandV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(ExpandV0_1{}),
+	thema.SV(0, 2): reflect.TypeOf(ExpandV0_2{}),
+	thema.SV(0, 3): reflect.TypeOf(ExpandV0_3{}),
+}
+
+// ExpandLenses returns the lenses of the 'expand' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func ExpandLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(0, 1),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(ExpandV0_1ToV0_0),
+		},
+		{
+			From:         thema.SV(0, 2),
+			To:           thema.SV(0, 1),
+			LacunaMapper: thema.GoLensMapper(ExpandV0_2ToV0_1),
+		},
+		{
+			From:         thema.SV(0, 3),
+			To:           thema.SV(0, 2),
+			LacunaMapper: thema.GoLensMapper(ExpandV0_3ToV0_2),
+		},
+	}
+}
+
+// ExpandV0_1ToV0_0 translates an instance of schema 0.1 of the 'expand' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func ExpandV0_1ToV0_0(in *ExpandV0_1) (*ExpandV0_0, []thema.Lacuna, error) {
+	out := &ExpandV0_0{}
+	out.Init = in.Init
+	return out, nil, nil
+}
+
+// ExpandV0_2ToV0_1 translates an instance of schema 0.2 of the 'expand' lineage
+// to schema 0.1, as specified by the lens defined in CUE.
+func ExpandV0_2ToV0_1(in *ExpandV0_2) (*ExpandV0_1, []thema.Lacuna, error) {
+	out := &ExpandV0_1{}
+	out.Init = in.Init
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	return out, nil, nil
+}
+
+// ExpandV0_3ToV0_2 translates an instance of schema 0.3 of the 'expand' lineage
+// to schema 0.2, as specified by the lens defined in CUE.
+func ExpandV0_3ToV0_2(in *ExpandV0_3) (*ExpandV0_2, []thema.Lacuna, error) {
+	out := &ExpandV0_2{}
+	out.Init = in.Init
+	if in.Optional != nil {
+		out.Optional = in.Optional
+	}
+	if in.WithDefault != nil {
+		out.WithDefault = (*ExpandV0_2WithDefault)(in.WithDefault)
+	}
+	return out, nil, nil
+}
@@ -0,0 +1,124 @@
+package lenstest
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// LacunasV0_0 defines model for LacunasV0_0.
+type LacunasV0_0 struct {
+	Note  *string `json:"note,omitempty"`
+	Size  int64   `json:"size"`
+	Title string  `json:"title"`
+}
+
+// LacunasV1_0 defines model for LacunasV1_0.
+type LacunasV1_0 struct {
+	Meta struct {
+		Draft bool `json:"draft"`
+	} `json:"meta"`
+	Name  string  `json:"name"`
+	Note  *string `json:"note,omitempty"`
+	Owner string  `json:"owner"`
+	Size  int64   `json:"size"`
+}
+
+// LacunasTypes maps the version of each schema in the 'lacunas' lineage to the
+// Go type generated for it.
+var LacunasTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(LacunasV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(LacunasV1_0{}),
+}
+
+// LacunasLenses returns the lenses of the 'lacunas' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func LacunasLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(1, 0),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(LacunasV1_0ToV0_0),
+		},
+		{
+			From:         thema.SV(0, 0),
+			To:           thema.SV(1, 0),
+			LacunaMapper: thema.GoLensMapper(LacunasV0_0ToV1_0),
+		},
+	}
+}
+
+// LacunasV1_0ToV0_0 translates an instance of schema 1.0 of the 'lacunas' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func LacunasV1_0ToV0_0(in *LacunasV1_0) (*LacunasV0_0, []thema.Lacuna, error) {
+	out := &LacunasV0_0{}
+	out.Title = in.Name
+	out.Size = in.Size
+	if in.Note != nil {
+		out.Note = in.Note
+	}
+	var lacunas []thema.Lacuna
+	lacunas = append(lacunas, thema.Lacuna{
+		SourceFields: []thema.FieldRef{
+			{Path: "owner", Value: in.Owner},
+		},
+		Type:    thema.LacunaDroppedField,
+		Message: "owner is not represented in the target schema",
+	})
+	if in.Meta.Draft {
+		lacunas = append(lacunas, thema.Lacuna{
+			SourceFields: []thema.FieldRef{
+				{Path: "meta.draft", Value: in.Meta.Draft},
+			},
+			Type:    thema.LacunaDroppedField,
+			Message: "draft status is not represented in the target schema",
+		})
+	}
+	return out, lacunas, nil
+}
+
+// LacunasV0_0ToV1_0 translates an instance of schema 0.0 of the 'lacunas' lineage
+// to schema 1.0, as specified by the lens defined in CUE.
+func LacunasV0_0ToV1_0(in *LacunasV0_0) (*LacunasV1_0, []thema.Lacuna, error) {
+	out := &LacunasV1_0{Meta: struct {
+		Draft bool `json:"draft"`
+	}{Draft: false}}
+	out.Name = in.Title
+	out.Size = in.Size
+	out.Owner = "PLACEHOLDER"
+	if in.Note != nil {
+		out.Note = in.Note
+	}
+	out.Meta.Draft = false
+	var lacunas []thema.Lacuna
+	lacunas = append(lacunas, thema.Lacuna{
+		TargetFields: []thema.FieldRef{
+			{Path: "owner", Value: out.Owner},
+		},
+		Type:    thema.LacunaPlaceholder,
+		Message: "owner is set to a placeholder value",
+	})
+	if in.Note != nil {
+		if out.Note == nil {
+			return nil, nil, fmt.Errorf("result.note is absent")
+		}
+		lacunas = append(lacunas, thema.Lacuna{
+			SourceFields: []thema.FieldRef{
+				{Path: "note", Value: *in.Note},
+			},
+			TargetFields: []thema.FieldRef{
+				{Path: "note", Value: *out.Note},
+			},
+			Type:    thema.LacunaLossyFieldMapping,
+			Message: "note now describes name rather than title",
+		})
+	}
+	return out, lacunas, nil
+}
@@ -0,0 +1,55 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// TrivialTwoCommentsV0_0 defines model for TrivialTwoCommentsV0_0.
+type TrivialTwoCommentsV0_0 struct {
+	// TODO some thing to be done
+	Firstfield string `json:"firstfield"`
+}
+
+// TrivialTwoCommentsV0_1 defines model for TrivialTwoCommentsV0_1.
+type TrivialTwoCommentsV0_1 struct {
+	// TODO some thing to be done
+	Firstfield string `json:"firstfield"`
+
+	// Secondfield but clearly this one is a great idea
+	Secondfield *int32 `json:"secondfield,omitempty"`
+}
+
+// TrivialTwoCommentsTypes maps the version of each schema in the 'trivial-two-comments' lineage to the
+// Go type generated for it.
+var TrivialTwoCommentsTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(TrivialTwoCommentsV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(TrivialTwoCommentsV0_1{}),
+}
+
+// TrivialTwoCommentsLenses returns the lenses of the 'trivial-two-comments' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func TrivialTwoCommentsLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(0, 1),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(TrivialTwoCommentsV0_1ToV0_0),
+		},
+	}
+}
+
+// TrivialTwoCommentsV0_1ToV0_0 translates an instance of schema 0.1 of the 'trivial-two-comments' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func TrivialTwoCommentsV0_1ToV0_0(in *TrivialTwoCommentsV0_1) (*TrivialTwoCommentsV0_0, []thema.Lacuna, error) {
+	out := &TrivialTwoCommentsV0_0{}
+	out.Firstfield = in.Firstfield
+	return out, nil, nil
+}
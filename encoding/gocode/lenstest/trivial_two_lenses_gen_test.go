@@ -0,0 +1,51 @@
+package lenstest
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// TrivialTwoV0_0 defines model for TrivialTwoV0_0.
+type TrivialTwoV0_0 struct {
+	Firstfield string `json:"firstfield"`
+}
+
+// TrivialTwoV0_1 defines model for TrivialTwoV0_1.
+type TrivialTwoV0_1 struct {
+	Firstfield  string `json:"firstfield"`
+	Secondfield *int32 `json:"secondfield,omitempty"`
+}
+
+// TrivialTwoTypes maps the version of each schema in the 'trivial-two' lineage to the
+// Go type generated for it.
+var TrivialTwoTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(TrivialTwoV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(TrivialTwoV0_1{}),
+}
+
+// TrivialTwoLenses returns the lenses of the 'trivial-two' Thema lineage as
+// [thema.ImperativeLens]es, suitable for passing to [thema.BindLineage] via
+// [thema.ImperativeLenses].
+//
+// Each lens is compiled from its definition in CUE into a Go func that
+// operates on the generated types for the schemas it translates between, and
+// reports the lacunas the CUE lens emits. Lenses that use CUE constructs which
+// cannot be compiled instead execute the CUE lens via [thema.CUELensMapper].
+func TrivialTwoLenses() []thema.ImperativeLens {
+	return []thema.ImperativeLens{
+		{
+			From:         thema.SV(0, 1),
+			To:           thema.SV(0, 0),
+			LacunaMapper: thema.GoLensMapper(TrivialTwoV0_1ToV0_0),
+		},
+	}
+}
+
+// TrivialTwoV0_1ToV0_0 translates an instance of schema 0.1 of the 'trivial-two' lineage
+// to schema 0.0, as specified by the lens defined in CUE.
+func TrivialTwoV0_1ToV0_0(in *TrivialTwoV0_1) (*TrivialTwoV0_0, []thema.Lacuna, error) {
+	out := &TrivialTwoV0_0{}
+	out.Firstfield = in.Firstfield
+	return out, nil, nil
+}
@@ -0,0 +1,311 @@
+package gocode
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+type goKind int
+
+const (
+	kindOther goKind = iota
+	kindAny
+	kindScalar
+	kindStruct
+	kindList
+	kindMap
+)
+
+// goType describes a Go type generated to represent a schema, as relevant to
+// the lenses operating on it.
+type goType struct {
+	kind goKind
+	// Go type expression, such as "string", "[]int", or the name of a generated
+	// type
+	expr string
+	// Name of the underlying basic type of a scalar type, such as "int32"
+	basic string
+	// Fields of a struct type
+	fields []*goField
+}
+
+type goField struct {
+	// JSON name of the field, also its CUE label
+	label string
+	// Go name of the field
+	name string
+	typ  *goType
+	// Whether the field is represented as a pointer to typ
+	ptr bool
+	// Whether the field is marked omitempty
+	omitempty bool
+	optional  bool
+	nullable  bool
+	// Go literal for the field's default value, if it has one
+	def string
+}
+
+func (t *goType) field(label string) *goField {
+	for _, f := range t.fields {
+		if f.label == label {
+			return f
+		}
+	}
+	return nil
+}
+
+// family returns the kind of scalar value the type holds: "string", "bool",
+// "int" or "float".
+func (t *goType) family() string {
+	switch {
+	case t.kind != kindScalar:
+		return ""
+	case strings.HasPrefix(t.basic, "int") || strings.HasPrefix(t.basic, "uint"):
+		return "int"
+	case strings.HasPrefix(t.basic, "float"):
+		return "float"
+	}
+	return t.basic
+}
+
+// convertible reports whether values of the scalar type t can be converted
+// to the scalar type to without loss.
+func (t *goType) convertible(to *goType) bool {
+	if t.family() != to.family() || t.family() == "" {
+		return false
+	}
+	if t.basic == to.basic {
+		return true
+	}
+	bits := func(basic string) int {
+		n, err := strconv.Atoi(strings.TrimLeft(basic, "uintfloa"))
+		if err != nil {
+			return 64
+		}
+		return n
+	}
+	signed := func(basic string) bool { return !strings.HasPrefix(basic, "uint") }
+	switch {
+	case signed(t.basic) == signed(to.basic):
+		return bits(t.basic) <= bits(to.basic)
+	case signed(to.basic):
+		return bits(t.basic) < bits(to.basic)
+	}
+	return false
+}
+
+// lit returns a composite literal of the struct type with all fields that
+// have defaults set to their defaults, and required lists and maps set to
+// empty values, as CUE takes them to be.
+func (t *goType) lit() string {
+	var elts []string
+	for _, f := range t.fields {
+		switch {
+		case f.def != "":
+			elts = append(elts, fmt.Sprintf("%s: %s", f.name, f.def))
+		case f.ptr || f.optional:
+		case f.typ.kind == kindStruct:
+			if l := f.typ.lit(); l != f.typ.expr+"{}" {
+				elts = append(elts, fmt.Sprintf("%s: %s", f.name, l))
+			}
+		case (f.typ.kind == kindList || f.typ.kind == kindMap) && !f.nullable:
+			elts = append(elts, fmt.Sprintf("%s: %s{}", f.name, f.typ.expr))
+		}
+	}
+	return fmt.Sprintf("%s{%s}", t.expr, strings.Join(elts, ", "))
+}
+
+// typeModel describes the Go types declared in a file generated from the
+// schema components of an OpenAPI document.
+type typeModel struct {
+	fset *token.FileSet
+	// All types declared in the file, keyed by name
+	decls map[string]*ast.TypeSpec
+	// Described named types, keyed by name
+	named map[string]*goType
+}
+
+func newTypeModel(fset *token.FileSet, f *ast.File) *typeModel {
+	m := &typeModel{
+		fset:  fset,
+		decls: make(map[string]*ast.TypeSpec),
+		named: make(map[string]*goType),
+	}
+	for _, decl := range f.Decls {
+		gd, is := decl.(*ast.GenDecl)
+		if !is || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			m.decls[ts.Name.Name] = ts
+		}
+	}
+	return m
+}
+
+// describe describes the Go type typ, generated from the schema sch.
+func (m *typeModel) describe(typ ast.Expr, sch *openapi3.Schema) *goType {
+	id, isnamed := typ.(*ast.Ident)
+	if isnamed {
+		if t, has := m.named[id.Name]; has {
+			return t
+		}
+	}
+
+	t := &goType{expr: m.render(typ)}
+	if isnamed {
+		// Guard against cycles
+		m.named[id.Name] = t
+	}
+	switch x := m.underlying(typ).(type) {
+	case *ast.Ident:
+		switch x.Name {
+		case "string", "bool", "float32", "float64", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			t.kind, t.basic = kindScalar, x.Name
+		case "any":
+			t.kind = kindAny
+		}
+	case *ast.InterfaceType:
+		t.kind = kindAny
+	case *ast.ArrayType:
+		if id, is := x.Elt.(*ast.Ident); !is || id.Name != "byte" {
+			t.kind = kindList
+		}
+	case *ast.MapType:
+		t.kind = kindMap
+	case *ast.StructType:
+		t.kind = kindStruct
+		if sch == nil {
+			t.kind = kindOther
+			break
+		}
+		props, required := properties(sch)
+		for _, field := range x.Fields.List {
+			f := m.field(field, props, required)
+			if f == nil {
+				// Fields without a property, such as those holding additional
+				// properties, can not be translated.
+				t.kind = kindOther
+				break
+			}
+			t.fields = append(t.fields, f)
+		}
+	}
+	return t
+}
+
+func (m *typeModel) field(field *ast.Field, props openapi3.Schemas, required map[string]bool) *goField {
+	if len(field.Names) != 1 || field.Tag == nil {
+		return nil
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return nil
+	}
+	jsontag := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+	prop, has := props[jsontag[0]]
+	if !has || prop.Value == nil {
+		return nil
+	}
+
+	f := &goField{
+		label:    jsontag[0],
+		name:     field.Names[0].Name,
+		optional: !required[jsontag[0]],
+		nullable: prop.Value.Nullable,
+	}
+	for _, opt := range jsontag[1:] {
+		f.omitempty = f.omitempty || opt == "omitempty"
+	}
+	typ := field.Type
+	if star, is := typ.(*ast.StarExpr); is {
+		f.ptr, typ = true, star.X
+	}
+	f.typ = m.describe(typ, prop.Value)
+	if !f.ptr && !f.optional && f.typ.kind == kindScalar {
+		f.def = defaultLit(prop.Value.Default, f.typ)
+	}
+	return f
+}
+
+// properties returns the properties of the object schema, including those of
+// the schemas it is composed of with allOf, and the set of those that are
+// required.
+func properties(sch *openapi3.Schema) (openapi3.Schemas, map[string]bool) {
+	props, required := make(openapi3.Schemas), make(map[string]bool)
+	for name, prop := range sch.Properties {
+		props[name] = prop
+	}
+	for _, name := range sch.Required {
+		required[name] = true
+	}
+	for _, sub := range sch.AllOf {
+		if sub.Value == nil {
+			continue
+		}
+		subprops, subreq := properties(sub.Value)
+		for name, prop := range subprops {
+			if _, has := props[name]; !has {
+				props[name] = prop
+			}
+		}
+		for name := range subreq {
+			required[name] = true
+		}
+	}
+	return props, required
+}
+
+// defaultLit returns a Go literal for the default value of a property, as
+// decoded from JSON, of the scalar type t.
+func defaultLit(def interface{}, t *goType) string {
+	switch x := def.(type) {
+	case string:
+		if t.family() == "string" {
+			return strconv.Quote(x)
+		}
+	case bool:
+		if t.family() == "bool" {
+			return strconv.FormatBool(x)
+		}
+	case float64:
+		switch t.family() {
+		case "int":
+			if x == float64(int64(x)) {
+				return strconv.FormatInt(int64(x), 10)
+			}
+		case "float":
+			return strconv.FormatFloat(x, 'g', -1, 64)
+		}
+	}
+	return ""
+}
+
+// underlying returns the underlying type of types declared in the file.
+func (m *typeModel) underlying(typ ast.Expr) ast.Expr {
+	for {
+		id, is := typ.(*ast.Ident)
+		if !is || m.decls[id.Name] == nil {
+			return typ
+		}
+		typ = m.decls[id.Name].Type
+	}
+}
+
+func (m *typeModel) render(typ ast.Expr) string {
+	buf := new(bytes.Buffer)
+	if err := format.Node(buf, m.fset, typ); err != nil {
+		// Only possible for malformed ASTs, which the parser does not return
+		panic(err)
+	}
+	return buf.String()
+}
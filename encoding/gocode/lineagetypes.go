@@ -0,0 +1,342 @@
+package gocode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/dave/dst"
+	"github.com/dave/dst/dstutil"
+	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/encoding/openapi"
+	"github.com/grafana/thema/internal/deepmap/oapi-codegen/pkg/codegen"
+	"github.com/grafana/thema/internal/util"
+)
+
+// LineageTypeConfigOpenAPI governs the behavior of [GenerateLineageTypesOpenAPI].
+type LineageTypeConfigOpenAPI struct {
+	// TypeConfigOpenAPI controls the generation of the types for each schema,
+	// as with [GenerateTypesOpenAPI]. Config.RootName is ignored, and
+	// Config.Group must not be set.
+	TypeConfigOpenAPI
+
+	// RootName specifies the name to use as the prefix of the types representing
+	// the root of each schema, and of the generated registry. If empty, this
+	// defaults to the lineage name.
+	RootName string
+}
+
+// GenerateLineageTypesOpenAPI generates native Go code corresponding to all the
+// schemas in the provided lineage, in a single package.
+//
+// The names of generated types are suffixed with the version of the schema
+// they were generated from. For example, with a RootName of "Foo", the type
+// representing the root of schema 0.0 is named FooV0_0, and a type generated
+// from a #Bar definition within it is named BarV0_0. Types other than the root
+// types are shared between schemas when they are structurally identical, and
+// named for the first schema in which they appear.
+//
+// A [thema.TypeRegistry] named <RootName>Types is also generated, mapping the
+// version of each schema to the Go type representing its root. It may be used
+// to verify assignability of the lineage's schemas with
+// [thema.TypeRegistry.Check], or to obtain values to pass to [thema.BindType].
+func GenerateLineageTypesOpenAPI(lin thema.Lineage, cfg *LineageTypeConfigOpenAPI) ([]byte, error) {
+	if cfg == nil {
+		cfg = new(LineageTypeConfigOpenAPI)
+	}
+	if cfg.PackageName == "" {
+		cfg.PackageName = lin.Name()
+	}
+	lt, err := generateLineageTypes(lin, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.ValidateMethods {
+		return lt.src, nil
+	}
+	return addValidateMethods(lt.src, lt.doc, lt.goname)
+}
+
+// lineageTypes contains the Go types generated for all the schemas in a
+// lineage.
+type lineageTypes struct {
+	// The generated Go file
+	src []byte
+	// The OpenAPI document from which the types were generated
+	doc *openapi3.T
+	// Maps the name of a component of doc to the name of the Go type generated
+	// for it
+	goname func(string) string
+	// RootName, with which the names of the components for the roots of each
+	// schema are prefixed
+	root string
+}
+
+// rootType returns the name of the Go type generated for the root of the
+// schema with the provided version.
+func (lt *lineageTypes) rootType(v thema.SyntacticVersion) string {
+	return lt.goname(rootKey(lt.root, v))
+}
+
+func generateLineageTypes(lin thema.Lineage, cfg *LineageTypeConfigOpenAPI) (*lineageTypes, error) {
+	if cfg.Config != nil && cfg.Config.Group {
+		return nil, fmt.Errorf("grouped lineages are not supported")
+	}
+	root := cfg.RootName
+	if root == "" {
+		root = util.SanitizeLabelString(lin.Name())
+	}
+
+	var schemas []*versionComponents
+	for sch := lin.First(); sch != nil; sch = sch.Successor() {
+		vc, err := schemaComponents(sch, root, cfg.Config)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, vc)
+	}
+
+	merged := make(map[string]json.RawMessage)
+	gonames, keys := make(map[string]string), make(map[string]string)
+	registry := new(strings.Builder)
+	fmt.Fprintf(registry, "\n// %sTypes maps the version of each schema in the '%s' lineage to the\n// Go type generated for it.\n", codegen.ToCamelCase(root), lin.Name())
+	fmt.Fprintf(registry, "var %sTypes = thema.TypeRegistry{\n", codegen.ToCamelCase(root))
+	for i, vc := range schemas {
+		for _, name := range vc.order {
+			key, v := componentKey(schemas, i, name)
+			if _, has := merged[key]; has {
+				continue
+			}
+			merged[key] = componentRefs.ReplaceAllFunc(vc.comps[name], func(ref []byte) []byte {
+				rkey, _ := componentKey(schemas, i, string(componentRefs.FindSubmatch(ref)[1]))
+				return []byte(fmt.Sprintf(`"#/components/schemas/%s"`, rkey))
+			})
+
+			// The generator drops underscores from type names, so components are
+			// keyed with a suffix it preserves, which is replaced here.
+			gen := codegen.SchemaNameToTypeName(key)
+			gonames[gen] = strings.TrimSuffix(gen, versionKey(v)) + versionIdent(v)
+			// Component names also appear in the comments on generated types.
+			keys[key] = strings.TrimSuffix(key, versionKey(v)) + versionIdent(v)
+		}
+		fmt.Fprintf(registry, "\tthema.SV(%d, %d): reflect.TypeOf(%s{}),\n", vc.v[0], vc.v[1], codegen.SchemaNameToTypeName(rootKey(root, vc.v)))
+	}
+	registry.WriteString("}\n")
+
+	doc, err := json.Marshal(map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]string{
+			"title":   lin.Name(),
+			"version": lin.Latest().Version().String(),
+		},
+		"paths": map[string]interface{}{},
+		"components": map[string]interface{}{
+			"schemas": merged,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode merged openapi: %w", err)
+	}
+
+	oT, err := loadOpenAPI(doc)
+	if err != nil {
+		return nil, err
+	}
+	gostr, err := generateFromOpenAPI(oT, &cfg.TypeConfigOpenAPI)
+	if err != nil {
+		return nil, err
+	}
+	in, err := addRegistry(gostr, registry.String())
+	if err != nil {
+		return nil, err
+	}
+
+	for gen, goname := range gonames {
+		keys[gen] = goname
+	}
+	appliers := append(typeAppliers(&cfg.TypeConfigOpenAPI), renameTypes(keys))
+	b, err := PostprocessGoFile(GenGoFile{
+		Path:                    fmt.Sprintf("%s_types_gen.go", lin.Name()),
+		Appliers:                append(appliers, cfg.ApplyFuncs...),
+		In:                      in,
+		IgnoreDiscoveredImports: cfg.IgnoreDiscoveredImports,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lineageTypes{
+		src: b,
+		doc: oT,
+		goname: func(name string) string {
+			gen := codegen.SchemaNameToTypeName(name)
+			if goname, has := gonames[gen]; has {
+				return goname
+			}
+			return gen
+		},
+		root: root,
+	}, nil
+}
+
+// versionComponents contains the OpenAPI schema components generated for a
+// single schema in a lineage.
+type versionComponents struct {
+	v thema.SyntacticVersion
+	// JSON for each component, keyed by name
+	comps map[string][]byte
+	// component names, in generation order
+	order []string
+	// canonical forms of components, keyed by name
+	canon map[string]string
+}
+
+var componentRefs = regexp.MustCompile(`"#/components/schemas/([^"]+)"`)
+
+// versionKey returns the suffix of the names of the components generated from
+// the schema with the provided version, in the merged OpenAPI document. Unlike
+// the suffix of [versionIdent], it survives conversion to a Go type name, which
+// retains only letters and digits.
+func versionKey(v thema.SyntacticVersion) string {
+	return fmt.Sprintf("V%dx%d", v[0], v[1])
+}
+
+func rootKey(root string, v thema.SyntacticVersion) string {
+	return root + versionKey(v)
+}
+
+func schemaComponents(sch thema.Schema, root string, ocfg *openapi.Config) (*versionComponents, error) {
+	cfg := new(openapi.Config)
+	if ocfg != nil {
+		*cfg = *ocfg
+		if cfg.Config != nil {
+			// GenerateSchema overwrites NameFunc
+			c := *cfg.Config
+			cfg.Config = &c
+		}
+	}
+	cfg.RootName = rootKey(root, sch.Version())
+
+	f, err := openapi.GenerateSchema(sch, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("thema openapi generation failed for schema %s: %w", sch.Version(), err)
+	}
+	schemas := sch.Lineage().Runtime().Context().BuildFile(f).LookupPath(cue.ParsePath("components.schemas"))
+	iter, err := schemas.Fields()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list openapi components for schema %s: %w", sch.Version(), err)
+	}
+
+	vc := &versionComponents{
+		v:     sch.Version(),
+		comps: make(map[string][]byte),
+		canon: make(map[string]string),
+	}
+	for iter.Next() {
+		name := iter.Selector().Unquoted()
+		b, err := iter.Value().MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode openapi component %s for schema %s: %w", name, sch.Version(), err)
+		}
+		vc.comps[name] = b
+		vc.order = append(vc.order, name)
+	}
+	return vc, nil
+}
+
+// canonical returns the JSON for the named component, with references to other
+// components replaced by their canonical forms, such that components are
+// structurally identical if their canonical forms are equal.
+func (vc *versionComponents) canonical(name string) string {
+	if c, has := vc.canon[name]; has {
+		return c
+	}
+	// Guard against cycles
+	vc.canon[name] = fmt.Sprintf("%q@%s", name, vc.v)
+
+	c := componentRefs.ReplaceAllStringFunc(string(vc.comps[name]), func(ref string) string {
+		rname := componentRefs.FindStringSubmatch(ref)[1]
+		if _, has := vc.comps[rname]; !has {
+			return ref
+		}
+		return vc.canonical(rname)
+	})
+	vc.canon[name] = c
+	return c
+}
+
+// componentKey returns the name with which the named component of the ith
+// schema is included in the merged OpenAPI document, and the version whose
+// suffix that name carries.
+func componentKey(schemas []*versionComponents, i int, name string) (string, thema.SyntacticVersion) {
+	vc := schemas[i]
+	if _, has := vc.comps[name]; !has {
+		return name, vc.v
+	}
+	if !strings.HasSuffix(name, versionKey(vc.v)) {
+		for _, prior := range schemas[:i] {
+			if _, has := prior.comps[name]; has && prior.canonical(name) == vc.canonical(name) {
+				return name + versionKey(prior.v), prior.v
+			}
+		}
+		return name + versionKey(vc.v), vc.v
+	}
+	return name, vc.v
+}
+
+// addRegistry appends the registry declaration to the generated Go code, along
+// with the imports it requires.
+func addRegistry(gostr, registry string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", gostr+registry, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated file: %w", err)
+	}
+	astutil.AddImport(fset, f, "reflect")
+	astutil.AddImport(fset, f, "github.com/grafana/thema")
+
+	buf := new(bytes.Buffer)
+	if err = format.Node(buf, fset, f); err != nil {
+		return nil, fmt.Errorf("error formatting generated file: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renameTypes renames the generated types keyed in names, and identifiers
+// prefixed with their names, such as enum values.
+func renameTypes(names map[string]string) dstutil.ApplyFunc {
+	alts := make([]string, 0, len(names))
+	for gen := range names {
+		alts = append(alts, regexp.QuoteMeta(gen))
+	}
+	// Prefer the longest match
+	sort.Slice(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+	re := regexp.MustCompile(`\b(` + strings.Join(alts, "|") + `)([A-Z_]\w*)?\b`)
+	rename := func(s string) string {
+		return re.ReplaceAllStringFunc(s, func(m string) string {
+			sm := re.FindStringSubmatch(m)
+			return names[sm[1]] + sm[2]
+		})
+	}
+
+	return func(c *dstutil.Cursor) bool {
+		switch x := c.Node().(type) {
+		case *dst.Ident:
+			x.Name = rename(x.Name)
+		case *dst.GenDecl:
+			for i, dec := range x.Decs.Start {
+				x.Decs.Start[i] = rename(dec)
+			}
+		}
+		return true
+	}
+}
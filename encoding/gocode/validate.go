@@ -0,0 +1,465 @@
+package gocode
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/imports"
+)
+
+// addValidateMethods appends Validate methods to the Go types generated from
+// the schema components of the provided OpenAPI document. goname maps the name
+// of a component to the name of the Go type generated for it.
+func addValidateMethods(src []byte, doc *openapi3.T, goname func(string) string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing generated file: %w", err)
+	}
+
+	g := &validatorGen{
+		types:     make(map[string]*ast.TypeSpec),
+		validated: make(map[string]*openapi3.SchemaRef),
+		imports:   make(map[string]bool),
+	}
+	var order []string
+	for _, decl := range f.Decls {
+		gd, is := decl.(*ast.GenDecl)
+		if !is || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			g.types[ts.Name.Name] = ts
+			order = append(order, ts.Name.Name)
+		}
+	}
+	for name, sref := range doc.Components.Schemas {
+		// Types may have been removed or renamed by ApplyFuncs. Methods cannot
+		// be declared on aliases.
+		if ts, has := g.types[goname(name)]; has && !ts.Assign.IsValid() {
+			g.validated[goname(name)] = sref
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	for _, name := range order {
+		if sref, has := g.validated[name]; has {
+			g.method(buf, name, sref)
+		}
+	}
+	if buf.Len() == 0 {
+		return src, nil
+	}
+
+	if f, err = parser.ParseFile(fset, "", append(src, buf.Bytes()...), parser.ParseComments); err != nil {
+		return nil, fmt.Errorf("error parsing generated validation code: %w", err)
+	}
+	g.imports["github.com/grafana/thema/errors"] = true
+	for path := range g.imports {
+		if path == "github.com/grafana/thema/errors" {
+			astutil.AddNamedImport(fset, f, "terrors", path)
+		} else {
+			astutil.AddImport(fset, f, path)
+		}
+	}
+
+	out := new(bytes.Buffer)
+	if err = format.Node(out, fset, f); err != nil {
+		return nil, fmt.Errorf("error formatting generated validation code: %w", err)
+	}
+	// All imports are present, so this only groups them
+	return imports.Process("", out.Bytes(), nil)
+}
+
+// validatorGen generates the Validate methods for the types in a single file.
+type validatorGen struct {
+	// All types declared in the file, keyed by name
+	types map[string]*ast.TypeSpec
+	// Types for which methods are generated, and their schemas
+	validated map[string]*openapi3.SchemaRef
+	// Packages the generated code imports
+	imports map[string]bool
+
+	// Name of the type whose methods are being generated
+	typ string
+	// Package-level declarations needed by the methods being generated
+	vars []string
+	// Number of nested loops
+	depth int
+}
+
+func (g *validatorGen) method(buf *bytes.Buffer, name string, sref *openapi3.SchemaRef) {
+	g.typ, g.vars, g.depth = name, nil, 0
+	body := g.checks("x", g.types[name].Type, sref.Value, "path")
+
+	fmt.Fprintf(buf, `
+// Validate checks that the %[1]s satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x %[1]s) Validate() error {
+	return x.validateAt("")
+}
+
+func (x %[1]s) validateAt(path string) error {
+%[2]sreturn nil
+}
+`, name, body)
+	for _, v := range g.vars {
+		buf.WriteString(v)
+	}
+}
+
+// check returns code that checks the Go value of type typ, at path, against
+// the schema.
+func (g *validatorGen) check(expr string, typ ast.Expr, sref *openapi3.SchemaRef, path string) string {
+	if sref == nil {
+		return ""
+	}
+	if star, is := typ.(*ast.StarExpr); is {
+		deref := "*" + expr
+		if k := g.kind(star.X); k == "struct" || g.isValidated(star.X) {
+			deref = expr
+		}
+		body := g.check(deref, star.X, sref, path)
+		if body == "" {
+			return ""
+		}
+		return fmt.Sprintf("if %s != nil {\n%s}\n", expr, body)
+	}
+	if sref.Ref != "" && g.isValidated(typ) {
+		return fmt.Sprintf("if err := %s.validateAt(%s); err != nil {\nreturn err\n}\n", expr, path)
+	}
+	return g.checks(expr, typ, sref.Value, path)
+}
+
+// checks returns code that checks the Go value of type typ against the
+// constraints of the schema itself, as opposed to a referenced schema.
+func (g *validatorGen) checks(expr string, typ ast.Expr, sch *openapi3.Schema, path string) string {
+	b := new(strings.Builder)
+	for _, sub := range sch.AllOf {
+		b.WriteString(g.check(expr, typ, sub, path))
+	}
+
+	switch k := g.kind(typ); k {
+	case "string":
+		b.WriteString(g.enum(expr, k, sch, path))
+		g.stringChecks(b, expr, sch, path)
+	case "int", "float32", "float64":
+		b.WriteString(g.enum(expr, k, sch, path))
+		g.numberChecks(b, expr, k, sch, path)
+	case "bool":
+		b.WriteString(g.enum(expr, k, sch, path))
+	case "list":
+		if sch.MinItems > 0 {
+			fmt.Fprintf(b, "if len(%s) < %d {\n%s}\n", expr, sch.MinItems, g.fail("OutOfBounds", path, fmt.Sprintf("list.MinItems(%d)", sch.MinItems), ""))
+		}
+		if sch.MaxItems != nil {
+			fmt.Fprintf(b, "if len(%s) > %d {\n%s}\n", expr, *sch.MaxItems, g.fail("OutOfBounds", path, fmt.Sprintf("list.MaxItems(%d)", *sch.MaxItems), ""))
+		}
+		g.depth++
+		i, v := fmt.Sprintf("i%d", g.depth), fmt.Sprintf("v%d", g.depth)
+		body := g.check(v, g.underlying(typ).(*ast.ArrayType).Elt, sch.Items, joinPath(path, fmt.Sprintf("strconv.Itoa(%s)", i)))
+		g.depth--
+		if body != "" {
+			g.imports["strconv"] = true
+			fmt.Fprintf(b, "for %s, %s := range %s {\n%s}\n", i, v, expr, body)
+		}
+	case "map":
+		if sch.AdditionalProperties.Schema == nil {
+			break
+		}
+		g.depth++
+		k, v := fmt.Sprintf("k%d", g.depth), fmt.Sprintf("v%d", g.depth)
+		token := fmt.Sprintf("strings.NewReplacer(\"~\", \"~0\", \"/\", \"~1\").Replace(%s)", k)
+		body := g.check(v, g.underlying(typ).(*ast.MapType).Value, sch.AdditionalProperties.Schema, joinPath(path, token))
+		g.depth--
+		if body != "" {
+			g.imports["strings"] = true
+			fmt.Fprintf(b, "for %s, %s := range %s {\n%s}\n", k, v, expr, body)
+		}
+	case "struct":
+		for _, field := range g.underlying(typ).(*ast.StructType).Fields.List {
+			b.WriteString(g.field(expr, field, sch, path))
+		}
+	}
+	return b.String()
+}
+
+// field returns code that checks a field of a struct against the schema of
+// the corresponding property.
+func (g *validatorGen) field(expr string, field *ast.Field, sch *openapi3.Schema, path string) string {
+	if len(field.Names) != 1 || field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	jsontag := strings.Split(reflect.StructTag(tag).Get("json"), ",")
+	prop, has := sch.Properties[jsontag[0]]
+	if !has || prop.Value == nil {
+		return ""
+	}
+	omitempty := false
+	for _, opt := range jsontag[1:] {
+		omitempty = omitempty || opt == "omitempty"
+	}
+	required := false
+	for _, req := range sch.Required {
+		required = required || req == jsontag[0]
+	}
+
+	fexpr := expr + "." + field.Names[0].Name
+	fpath := joinPath(path, strconv.Quote(jsonPointerToken(jsontag[0])))
+	b := new(strings.Builder)
+	k := g.kind(field.Type)
+	// Absent lists and maps are valid, as CUE takes them to be empty.
+	if required && !prop.Value.Nullable && (k == "ptr" || k == "bytes" || k == "any") {
+		fmt.Fprintf(b, "if %s == nil {\n%s}\n", fexpr, g.fail("MissingField", fpath, "", ""))
+	}
+
+	body := g.check(fexpr, field.Type, prop, fpath)
+	// Fields with zero values are omitted from JSON if marked omitempty, and
+	// are therefore absent, rather than invalid.
+	var present string
+	if omitempty {
+		switch k {
+		case "string":
+			present = fmt.Sprintf("%s != \"\"", fexpr)
+		case "int", "float32", "float64":
+			present = fmt.Sprintf("%s != 0", fexpr)
+		case "bool":
+			present = fexpr
+		case "list", "map", "bytes":
+			present = fmt.Sprintf("len(%s) != 0", fexpr)
+		}
+	}
+	if present != "" && body != "" {
+		fmt.Fprintf(b, "if %s {\n%s}\n", present, body)
+	} else {
+		b.WriteString(body)
+	}
+	return b.String()
+}
+
+func (g *validatorGen) stringChecks(b *strings.Builder, expr string, sch *openapi3.Schema, path string) {
+	if sch.MinLength > 0 || sch.MaxLength != nil {
+		g.imports["unicode/utf8"] = true
+	}
+	if sch.MinLength > 0 {
+		fmt.Fprintf(b, "if utf8.RuneCountInString(string(%s)) < %d {\n%s}\n", expr, sch.MinLength,
+			g.fail("OutOfBounds", path, fmt.Sprintf("strings.MinRunes(%d)", sch.MinLength), g.actual(expr, "string")))
+	}
+	if sch.MaxLength != nil {
+		fmt.Fprintf(b, "if utf8.RuneCountInString(string(%s)) > %d {\n%s}\n", expr, *sch.MaxLength,
+			g.fail("OutOfBounds", path, fmt.Sprintf("strings.MaxRunes(%d)", *sch.MaxLength), g.actual(expr, "string")))
+	}
+	if sch.Pattern != "" {
+		fmt.Fprintf(b, "if !%s.MatchString(string(%s)) {\n%s}\n", g.pattern(sch.Pattern), expr,
+			g.fail("OutOfBounds", path, fmt.Sprintf("=~%q", sch.Pattern), g.actual(expr, "string")))
+	}
+	if sch.Not != nil && sch.Not.Value != nil && sch.Not.Value.Pattern != "" {
+		fmt.Fprintf(b, "if %s.MatchString(string(%s)) {\n%s}\n", g.pattern(sch.Not.Value.Pattern), expr,
+			g.fail("OutOfBounds", path, fmt.Sprintf("!~%q", sch.Not.Value.Pattern), g.actual(expr, "string")))
+	}
+}
+
+func (g *validatorGen) numberChecks(b *strings.Builder, expr, k string, sch *openapi3.Schema, path string) {
+	bound := func(f *float64, excl, upper bool) {
+		if f == nil {
+			return
+		}
+		// The comparison that fails validation, and the bound as CUE
+		op, expected := "<", ">="
+		switch {
+		case upper && excl:
+			op, expected = ">=", "<"
+		case upper:
+			op, expected = ">", "<="
+		case excl:
+			op, expected = "<=", ">"
+		}
+		expected += strconv.FormatFloat(*f, 'f', -1, 64)
+
+		n, x := *f, expr
+		lit := strconv.FormatFloat(n, 'f', -1, 64)
+		switch k {
+		case "int":
+			// Make bounds on integers integral, and omit them if every int64
+			// satisfies them.
+			if n != math.Trunc(n) {
+				if upper {
+					n, op = math.Floor(n), ">"
+				} else {
+					n, op = math.Ceil(n), "<"
+				}
+			}
+			if n >= math.MaxInt64 || n < math.MinInt64 || (n == math.MinInt64 && op == "<") {
+				return
+			}
+			lit, x = strconv.FormatInt(int64(n), 10), fmt.Sprintf("int64(%s)", expr)
+		case "float32":
+			if math.Abs(n) > math.MaxFloat32 {
+				return
+			}
+		}
+		fmt.Fprintf(b, "if %s %s %s {\n%s}\n", x, op, lit, g.fail("OutOfBounds", path, expected, g.actual(expr, k)))
+	}
+	bound(sch.Min, sch.ExclusiveMin, false)
+	bound(sch.Max, sch.ExclusiveMax, true)
+}
+
+// enum returns code that checks that the value is one of the enumerated
+// values of the schema, if any.
+func (g *validatorGen) enum(expr, k string, sch *openapi3.Schema, path string) string {
+	var cases, expected []string
+	for _, v := range sch.Enum {
+		var lit string
+		switch x := v.(type) {
+		case string:
+			if k == "string" {
+				lit = strconv.Quote(x)
+			}
+		case float64:
+			if k == "float32" || k == "float64" || (k == "int" && x == math.Trunc(x)) {
+				lit = strconv.FormatFloat(x, 'f', -1, 64)
+			}
+		case bool:
+			if k == "bool" {
+				lit = strconv.FormatBool(x)
+			}
+		}
+		if lit == "" {
+			continue
+		}
+		cases = append(cases, lit)
+		ev, _ := json.Marshal(v)
+		expected = append(expected, string(ev))
+	}
+	if len(cases) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("switch %s {\ncase %s:\ndefault:\n%s}\n", expr, strings.Join(cases, ", "),
+		g.fail("OutOfBounds", path, strings.Join(expected, " | "), g.actual(expr, k)))
+}
+
+// pattern returns the name of a package-level variable holding the compiled
+// regular expression.
+func (g *validatorGen) pattern(re string) string {
+	g.imports["regexp"] = true
+	name := fmt.Sprintf("%s%sPattern%d", strings.ToLower(g.typ[:1]), g.typ[1:], len(g.vars))
+	g.vars = append(g.vars, fmt.Sprintf("\nvar %s = regexp.MustCompile(%s)\n", name, strconv.Quote(re)))
+	return name
+}
+
+// fail returns code that returns a ValidationError.
+func (g *validatorGen) fail(code, path, expected, actual string) string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "return &terrors.ValidationError{\nCode: terrors.%s,\nDataPath: %s,\n", code, path)
+	if expected != "" {
+		fmt.Fprintf(b, "Expected: %s,\n", strconv.Quote(expected))
+	}
+	if actual != "" {
+		fmt.Fprintf(b, "Actual: %s,\n", actual)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// actual returns an expression that formats the value as CUE.
+func (g *validatorGen) actual(expr, k string) string {
+	g.imports["strconv"] = true
+	switch k {
+	case "string":
+		return fmt.Sprintf("strconv.Quote(string(%s))", expr)
+	case "int":
+		return fmt.Sprintf("strconv.FormatInt(int64(%s), 10)", expr)
+	case "float32":
+		return fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, 32)", expr)
+	case "float64":
+		return fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, 64)", expr)
+	case "bool":
+		return fmt.Sprintf("strconv.FormatBool(bool(%s))", expr)
+	}
+	return ""
+}
+
+func (g *validatorGen) isValidated(typ ast.Expr) bool {
+	id, is := typ.(*ast.Ident)
+	return is && g.validated[id.Name] != nil
+}
+
+// underlying returns the underlying type of types declared in the file.
+func (g *validatorGen) underlying(typ ast.Expr) ast.Expr {
+	for {
+		id, is := typ.(*ast.Ident)
+		if !is || g.types[id.Name] == nil {
+			return typ
+		}
+		typ = g.types[id.Name].Type
+	}
+}
+
+// kind returns the kind of the type, as relevant to validation.
+func (g *validatorGen) kind(typ ast.Expr) string {
+	switch x := g.underlying(typ).(type) {
+	case *ast.Ident:
+		switch x.Name {
+		case "string", "bool", "float32", "float64":
+			return x.Name
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+			return "int"
+		case "any":
+			return "any"
+		}
+	case *ast.StarExpr:
+		return "ptr"
+	case *ast.ArrayType:
+		if id, is := x.Elt.(*ast.Ident); is && id.Name == "byte" {
+			return "bytes"
+		}
+		return "list"
+	case *ast.MapType:
+		return "map"
+	case *ast.StructType:
+		return "struct"
+	case *ast.InterfaceType:
+		return "any"
+	}
+	return ""
+}
+
+// joinPath returns an expression that appends a JSON Pointer reference token
+// to a path. The token is a string expression, quoted if it is a literal.
+func joinPath(path, token string) string {
+	if uq, err := strconv.Unquote(token); err == nil {
+		if ps, err := strconv.Unquote(path); err == nil {
+			return strconv.Quote(ps + "/" + uq)
+		}
+		if i := strings.LastIndex(path, " + "); i != -1 {
+			if ps, err := strconv.Unquote(path[i+3:]); err == nil {
+				return path[:i+3] + strconv.Quote(ps+"/"+uq)
+			}
+		}
+		return path + " + " + strconv.Quote("/"+uq)
+	}
+	return joinPath(path, `""`) + " + " + token
+}
+
+// jsonPointerToken escapes a string for use as a reference token in a JSON
+// Pointer, per RFC 6901.
+func jsonPointerToken(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -0,0 +1,12 @@
+// Package protobuf provides tools for representing Thema schemas as protobuf
+// messages: generating .proto message definitions from a [thema.Schema], and
+// encoding and decoding protobuf wire bytes according to those definitions.
+//
+// Field numbers are assigned deterministically, in order of each field's first
+// appearance within a major version of a lineage. Because Thema guarantees that
+// schemas within a major version are backwards compatible, this keeps the
+// numbering of every field stable across all minor versions: bytes encoded
+// against schema 1.0 decode correctly against the message for schema 1.2, and
+// vice versa. No such guarantee is made across major versions, which receive an
+// independent numbering and, by default, a distinct protobuf package.
+package protobuf
@@ -0,0 +1,368 @@
+package protobuf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"cuelang.org/go/cue"
+	"github.com/grafana/thema"
+)
+
+var pathSchDef = cue.MakePath(cue.Hid("_#schema", "github.com/grafana/thema"))
+
+// Field numbers in this range are reserved for the protobuf implementation.
+const (
+	firstReservedNumber = 19000
+	lastReservedNumber  = 19999
+)
+
+type protoType int
+
+const (
+	typeString protoType = iota + 1
+	typeBool
+	typeBytes
+	typeInt32
+	typeInt64
+	typeUint32
+	typeUint64
+	typeDouble
+	// typeNumber is represented as a double, but decoded as an int when the
+	// value is integral, as the CUE number kind accepts both.
+	typeNumber
+	typeMessage
+)
+
+func (t protoType) String() string {
+	switch t {
+	case typeString:
+		return "string"
+	case typeBool:
+		return "bool"
+	case typeBytes:
+		return "bytes"
+	case typeInt32:
+		return "int32"
+	case typeInt64:
+		return "int64"
+	case typeUint32:
+		return "uint32"
+	case typeUint64:
+		return "uint64"
+	case typeDouble, typeNumber:
+		return "double"
+	default:
+		panic(fmt.Sprintf("no scalar name for protobuf type %d", t))
+	}
+}
+
+// message is the protobuf representation of a CUE struct.
+type message struct {
+	name   string
+	fields []*field
+
+	// reserved holds the numbers and names of fields that existed in a prior
+	// minor version, but no longer do.
+	reserved      []int
+	reservedNames []string
+
+	// next is the next unassigned field number.
+	next int
+}
+
+func (m *message) field(label string) *field {
+	for _, f := range m.fields {
+		if f.label == label {
+			return f
+		}
+	}
+	return nil
+}
+
+func (m *message) byNumber(num int) *field {
+	for _, f := range m.fields {
+		if f.number == num {
+			return f
+		}
+	}
+	return nil
+}
+
+// field is the protobuf representation of a single CUE struct field.
+type field struct {
+	// label is the CUE label of the field.
+	label string
+	// name is the protobuf name of the field.
+	name   string
+	number int
+
+	optional bool
+	repeated bool
+	// isMap indicates the field is a map<string, V>, where typ and msg describe V.
+	isMap bool
+
+	typ protoType
+	// msg is set iff typ is typeMessage.
+	msg *message
+}
+
+// typeName returns the protobuf type of the field, excluding labels.
+func (f *field) typeName() string {
+	var vt string
+	if f.typ == typeMessage {
+		vt = f.msg.name
+	} else {
+		vt = f.typ.String()
+	}
+	if f.isMap {
+		return fmt.Sprintf("map<string, %s>", vt)
+	}
+	return vt
+}
+
+// buildMessages builds the protobuf message for the provided schema, and all
+// of its predecessors within the same major version, such that field numbers
+// are assigned consistently across each.
+func buildMessages(sch thema.Schema, name string) (*message, error) {
+	v := sch.Version()
+	var m *message
+	for isch := thema.SchemaP(sch.Lineage(), thema.SV(v[0], 0)); isch != nil; isch = isch.Successor() {
+		var err error
+		m, err = buildMessage(name, isch.Underlying().LookupPath(pathSchDef), m, nil)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", isch.Version(), err)
+		}
+		if isch.Version() == v {
+			break
+		}
+	}
+	return m, nil
+}
+
+// buildMessage builds a message from the struct-kinded value v. If prev is
+// non-nil, it is the message built for the same struct in the prior minor
+// version, and its field numbers are retained.
+func buildMessage(name string, v cue.Value, prev *message, sels []cue.Selector) (*message, error) {
+	if op, _ := v.Expr(); op == cue.OrOp {
+		if _, has := v.Default(); !has {
+			return nil, fmt.Errorf("%s: disjunctions of structs cannot be represented in protobuf", cue.MakePath(sels...))
+		}
+	}
+
+	m := &message{
+		name: name,
+		next: 1,
+	}
+	if prev != nil {
+		m.next = prev.next
+		m.reserved = append(m.reserved, prev.reserved...)
+		m.reservedNames = append(m.reservedNames, prev.reservedNames...)
+	}
+
+	iter, err := v.Fields(cue.Optional(true))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cue.MakePath(sels...), err)
+	}
+	for iter.Next() {
+		f := &field{
+			label:    iter.Label(),
+			name:     fieldName(iter.Label()),
+			optional: iter.IsOptional(),
+		}
+
+		var pf *field
+		if prev != nil {
+			pf = prev.field(f.label)
+		}
+		if pf != nil {
+			f.number = pf.number
+		} else {
+			f.number = m.assign()
+		}
+
+		if err := resolveType(f, iter.Value(), pf, appendSel(sels, iter.Selector())); err != nil {
+			return nil, err
+		}
+		m.fields = append(m.fields, f)
+	}
+
+	if prev != nil {
+		for _, pf := range prev.fields {
+			if m.field(pf.label) == nil {
+				m.reserved = append(m.reserved, pf.number)
+				m.reservedNames = append(m.reservedNames, pf.name)
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *message) assign() int {
+	if m.next >= firstReservedNumber && m.next <= lastReservedNumber {
+		m.next = lastReservedNumber + 1
+	}
+	num := m.next
+	m.next++
+	return num
+}
+
+// resolveType populates the type information of f from the CUE value v. If pf
+// is non-nil, it is the same field from the prior minor version.
+func resolveType(f *field, v cue.Value, pf *field, sels []cue.Selector) error {
+	path := cue.MakePath(sels...)
+	var pmsg *message
+	if pf != nil {
+		pmsg = pf.msg
+	}
+
+	switch v.IncompleteKind() {
+	case cue.ListKind:
+		f.repeated = true
+		v = v.LookupPath(cue.MakePath(cue.AnyIndex))
+		if !v.Exists() {
+			return fmt.Errorf("%s: lists must have an element type to be represented in protobuf", path)
+		}
+		sels = appendSel(sels, cue.AnyIndex)
+		if v.IncompleteKind() == cue.ListKind {
+			return fmt.Errorf("%s: nested lists cannot be represented in protobuf", path)
+		}
+	case cue.StructKind:
+		if isMap(v) {
+			f.isMap = true
+			v = v.LookupPath(cue.MakePath(cue.AnyString))
+			sels = appendSel(sels, cue.AnyString)
+			switch v.IncompleteKind() {
+			case cue.ListKind:
+				return fmt.Errorf("%s: maps of lists cannot be represented in protobuf", path)
+			case cue.StructKind:
+				if isMap(v) {
+					return fmt.Errorf("%s: maps of maps cannot be represented in protobuf", path)
+				}
+			}
+		}
+	}
+
+	switch k := v.IncompleteKind(); k {
+	case cue.StringKind:
+		f.typ = typeString
+	case cue.BoolKind:
+		f.typ = typeBool
+	case cue.BytesKind:
+		f.typ = typeBytes
+	case cue.IntKind:
+		f.typ = intType(v)
+	case cue.FloatKind:
+		f.typ = typeDouble
+	case cue.NumberKind:
+		f.typ = typeNumber
+	case cue.StructKind:
+		if f.repeated && isMap(v) {
+			return fmt.Errorf("%s: lists of maps cannot be represented in protobuf", path)
+		}
+		f.typ = typeMessage
+		msg, err := buildMessage(messageName(f.label), v, pmsg, sels)
+		if err != nil {
+			return err
+		}
+		f.msg = msg
+	default:
+		return fmt.Errorf("%s: values of kind %s cannot be represented in protobuf", path, k)
+	}
+	return nil
+}
+
+func appendSel(sels []cue.Selector, sel cue.Selector) []cue.Selector {
+	return append(append(make([]cue.Selector, 0, len(sels)+1), sels...), sel)
+}
+
+// isMap reports whether v is a struct with no regular fields and a pattern
+// constraint on its labels, which is represented in protobuf as a map.
+func isMap(v cue.Value) bool {
+	if !v.LookupPath(cue.MakePath(cue.AnyString)).Exists() {
+		return false
+	}
+	iter, err := v.Fields(cue.Optional(true))
+	return err == nil && !iter.Next()
+}
+
+var intTypes = []struct {
+	typ  protoType
+	expr string
+}{
+	{typeInt32, "int32"},
+	{typeUint32, "uint32"},
+	{typeInt64, "int64"},
+	{typeUint64, "uint64"},
+}
+
+// intType selects the narrowest protobuf integer type that can hold all values
+// accepted by v. Unbounded ints are represented as int64.
+func intType(v cue.Value) protoType {
+	for _, it := range intTypes {
+		if v.Context().CompileString(it.expr).Subsume(v, cue.Raw(), cue.Schema()) == nil {
+			return it.typ
+		}
+	}
+	return typeInt64
+}
+
+// fieldName converts a CUE label to a valid protobuf field name.
+func fieldName(label string) string {
+	var b strings.Builder
+	for i, r := range label {
+		switch {
+		case r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) && i > 0):
+			b.WriteRune(r)
+		case unicode.IsDigit(r):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// jsonName is the JSON name protobuf derives from a field name, absent an
+// explicit json_name option.
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// messageName converts a CUE label or lineage name to a protobuf message name.
+func messageName(label string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range label {
+		switch {
+		case r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)):
+			upper = true
+		case upper:
+			if b.Len() == 0 && unicode.IsDigit(r) {
+				b.WriteRune('X')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+			upper = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "X"
+	}
+	return b.String()
+}
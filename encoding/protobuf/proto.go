@@ -0,0 +1,106 @@
+package protobuf
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/thema"
+)
+
+// Config controls protobuf generation from a Thema schema.
+type Config struct {
+	// Package is the name of the generated protobuf package. If empty, this
+	// defaults to the lineage name, converted to a valid package identifier and
+	// suffixed with the schema's major version. Example: "my_lineage.v1"
+	Package string
+
+	// RootName specifies the name to use for the message representing the root
+	// of the schema. If empty, this defaults to titlecasing of the lineage name.
+	RootName string
+
+	// GoPackage, if non-empty, is emitted as the go_package file option.
+	GoPackage string
+}
+
+// GenerateSchema creates a proto3 file containing a message definition that
+// represents the provided Thema Schema. Structs within the schema are
+// represented as nested messages, lists as repeated fields, and structs
+// containing only a pattern constraint (e.g. [string]: int) as maps. Optional
+// fields use the proto3 optional label.
+//
+// Field numbers are assigned in order of first appearance across all the
+// schemas in sch's major version, up to and including sch. Fields that were
+// removed are reserved. An error is returned if the schema contains a value
+// that cannot be represented in protobuf, such as a disjunction of different
+// kinds.
+func GenerateSchema(sch thema.Schema, cfg *Config) ([]byte, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	name := cfg.RootName
+	if name == "" {
+		name = messageName(sch.Lineage().Name())
+	}
+	m, err := buildMessages(sch, name)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := cfg.Package
+	if pkg == "" {
+		pkg = fmt.Sprintf("%s.v%d", strings.ToLower(fieldName(sch.Lineage().Name())), sch.Version()[0])
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by thema. DO NOT EDIT.\n\nsyntax = \"proto3\";\n\npackage %s;\n", pkg)
+	if cfg.GoPackage != "" {
+		fmt.Fprintf(&buf, "\noption go_package = %q;\n", cfg.GoPackage)
+	}
+	fmt.Fprintf(&buf, "\n// %s is schema %s of the %q lineage.\n", m.name, sch.Version(), sch.Lineage().Name())
+	writeMessage(&buf, m, 0)
+	return buf.Bytes(), nil
+}
+
+func writeMessage(buf *bytes.Buffer, m *message, depth int) {
+	indent := strings.Repeat("  ", depth)
+	fmt.Fprintf(buf, "%smessage %s {\n", indent, m.name)
+
+	if len(m.reserved) > 0 {
+		nums := make([]string, len(m.reserved))
+		for i, num := range m.reserved {
+			nums[i] = strconv.Itoa(num)
+		}
+		names := make([]string, len(m.reservedNames))
+		for i, name := range m.reservedNames {
+			names[i] = strconv.Quote(name)
+		}
+		fmt.Fprintf(buf, "%s  reserved %s;\n", indent, strings.Join(nums, ", "))
+		fmt.Fprintf(buf, "%s  reserved %s;\n", indent, strings.Join(names, ", "))
+	}
+
+	for _, f := range m.fields {
+		var label string
+		switch {
+		case f.repeated:
+			label = "repeated "
+		case f.optional && !f.isMap:
+			label = "optional "
+		}
+		fmt.Fprintf(buf, "%s  %s%s %s = %d", indent, label, f.typeName(), f.name, f.number)
+		if jsonName(f.name) != f.label {
+			fmt.Fprintf(buf, " [json_name = %q]", f.label)
+		}
+		buf.WriteString(";\n")
+	}
+
+	for _, f := range m.fields {
+		if f.msg != nil {
+			buf.WriteString("\n")
+			writeMessage(buf, f.msg, depth+1)
+		}
+	}
+	fmt.Fprintf(buf, "%s}\n", indent)
+}
@@ -0,0 +1,162 @@
+package protobuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/txtartest/bindlin"
+	"github.com/grafana/thema/internal/txtartest/vanilla"
+)
+
+func TestGenerate(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/protobuf/TestGenerate",
+		ThemaFS: thema.CueJointFS,
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		for sch := lin.First(); sch != nil; sch = sch.Successor() {
+			b, err := GenerateSchema(sch, nil)
+			if err != nil {
+				fmt.Fprintf(tc, "// error: %s\n", err)
+				continue
+			}
+			_, err = tc.Write(b)
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/protobuf/TestCodecRoundTrip",
+		ThemaFS: thema.CueJointFS,
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		for sch := lin.First(); sch != nil; sch = sch.Successor() {
+			codec, err := NewCodec(sch)
+			if err != nil {
+				continue
+			}
+			for name, inst := range sch.Examples() {
+				tc.Run(fmt.Sprintf("%s/%s", sch.Version(), name), func(t *testing.T) {
+					b, err := codec.Encode(inst.Underlying())
+					require.NoError(t, err)
+					v, err := codec.Decode(ctx, b)
+					require.NoError(t, err)
+					_, err = sch.Validate(v)
+					require.NoError(t, err)
+
+					want, err := json.Marshal(inst.Underlying())
+					require.NoError(t, err)
+					got, err := json.Marshal(v)
+					require.NoError(t, err)
+					assert.JSONEq(t, string(want), string(got))
+				})
+			}
+		}
+	})
+}
+
+func TestFieldNumberStability(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	linstr := `name: "stable"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		first: string
+		nested: {
+			a: int32
+		}
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		added?: bool
+		first: string
+		nested: {
+			b?: [...string]
+			a: int32
+		}
+	}
+}, {
+	version: [1, 0]
+	schema: {
+		renamed: string
+	}
+}]
+`
+	lin, err := thema.BindLineage(ctx.CompileString(linstr), rt)
+	require.NoError(t, err)
+
+	m0, err := buildMessages(thema.SchemaP(lin, thema.SV(0, 0)), "Stable")
+	require.NoError(t, err)
+	m1, err := buildMessages(thema.SchemaP(lin, thema.SV(0, 1)), "Stable")
+	require.NoError(t, err)
+
+	for _, f := range m0.fields {
+		assert.Equal(t, f.number, m1.field(f.label).number, "field %q renumbered", f.label)
+	}
+	assert.Equal(t, 3, m1.field("added").number)
+	assert.Equal(t, 1, m1.field("nested").msg.field("a").number)
+	assert.Equal(t, 2, m1.field("nested").msg.field("b").number)
+
+	m2, err := buildMessages(thema.SchemaP(lin, thema.SV(1, 0)), "Stable")
+	require.NoError(t, err)
+	assert.Equal(t, 1, m2.field("renamed").number, "field numbering should restart in a new major version")
+
+	// Bytes written against 0.0 must be readable against 0.1, and vice versa.
+	c0, err := NewCodec(thema.SchemaP(lin, thema.SV(0, 0)))
+	require.NoError(t, err)
+	c1, err := NewCodec(thema.SchemaP(lin, thema.SV(0, 1)))
+	require.NoError(t, err)
+
+	b, err := c1.Encode(ctx.CompileString(`{added: true, first: "foo", nested: {a: -3, b: ["x", "y"]}}`))
+	require.NoError(t, err)
+	v, err := c0.Decode(ctx, b)
+	require.NoError(t, err)
+	got, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"first": "foo", "nested": {"a": -3}}`, string(got))
+
+	b, err = c0.Encode(v)
+	require.NoError(t, err)
+	v, err = c1.Decode(ctx, b)
+	require.NoError(t, err)
+	got, err = json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"first": "foo", "nested": {"a": -3, "b": []}}`, string(got))
+}
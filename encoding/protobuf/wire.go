@@ -0,0 +1,509 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+	"github.com/grafana/thema"
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("unexpected end of protobuf input")
+
+// Codec decodes protobuf wire bytes into CUE, and encodes CUE values into
+// protobuf wire bytes, according to the message generated for a particular
+// [thema.Schema] by [GenerateSchema].
+//
+// Codec satisfies the vmux.Codec interface.
+type Codec struct {
+	root *message
+}
+
+// NewCodec creates a [Codec] for the message representation of the provided
+// schema. Bytes produced by the Codec are readable by code generated from the
+// output of [GenerateSchema] for sch, and vice versa.
+//
+// Because field numbers are stable within a major version, a Codec can decode
+// bytes produced for any schema in the same major version as sch. Fields not
+// present in sch are ignored.
+func NewCodec(sch thema.Schema) (*Codec, error) {
+	m, err := buildMessages(sch, messageName(sch.Lineage().Name()))
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{root: m}, nil
+}
+
+// Decode converts protobuf wire bytes into a [cue.Value].
+//
+// Consistent with proto3 semantics, absent non-optional fields are decoded to
+// their zero value, and absent optional fields are omitted. Protobuf does not
+// distinguish between absent and empty repeated or map fields, so these are
+// always decoded, even when optional in the schema.
+func (c *Codec) Decode(ctx *cue.Context, b []byte) (cue.Value, error) {
+	expr, err := decodeMessage(c.root, b)
+	if err != nil {
+		return cue.Value{}, err
+	}
+	return ctx.BuildExpr(expr), nil
+}
+
+// Encode converts the provided [cue.Value], which must be concrete, into
+// protobuf wire bytes.
+func (c *Codec) Encode(v cue.Value) ([]byte, error) {
+	return encodeMessage(nil, c.root, v)
+}
+
+type mapEntry struct {
+	key string
+	val ast.Expr
+}
+
+// decoded accumulates the values of a single field while decoding a message.
+type decoded struct {
+	val     ast.Expr
+	list    []ast.Expr
+	entries []mapEntry
+}
+
+func decodeMessage(m *message, b []byte) (*ast.StructLit, error) {
+	vals := make(map[int]*decoded)
+	for len(b) > 0 {
+		num, wt, n := consumeTag(b)
+		if n < 0 {
+			return nil, errTruncated
+		}
+		b = b[n:]
+
+		f := m.byNumber(num)
+		if f == nil {
+			n = skipValue(b, wt)
+			if n < 0 {
+				return nil, fmt.Errorf("field %d: invalid wire type %d", num, wt)
+			}
+			b = b[n:]
+			continue
+		}
+
+		d := vals[num]
+		if d == nil {
+			d = new(decoded)
+			vals[num] = d
+		}
+
+		switch {
+		case f.isMap:
+			if wt != wireBytes {
+				return nil, wireTypeError(f, wt)
+			}
+			raw, n := consumeBytes(b)
+			if n < 0 {
+				return nil, errTruncated
+			}
+			b = b[n:]
+			entry, err := decodeMapEntry(f, raw)
+			if err != nil {
+				return nil, err
+			}
+			d.entries = append(d.entries, entry)
+		case f.repeated && wt == wireBytes && f.typ.packable():
+			raw, n := consumeBytes(b)
+			if n < 0 {
+				return nil, errTruncated
+			}
+			b = b[n:]
+			for len(raw) > 0 {
+				x, n, err := decodeValue(f, f.typ.wireType(), raw)
+				if err != nil {
+					return nil, err
+				}
+				raw = raw[n:]
+				d.list = append(d.list, x)
+			}
+		default:
+			x, n, err := decodeValue(f, wt, b)
+			if err != nil {
+				return nil, err
+			}
+			b = b[n:]
+			if f.repeated {
+				d.list = append(d.list, x)
+			} else {
+				d.val = x
+			}
+		}
+	}
+
+	st := &ast.StructLit{}
+	for _, f := range m.fields {
+		d := vals[f.number]
+		var x ast.Expr
+		switch {
+		case f.isMap:
+			mst := &ast.StructLit{}
+			if d != nil {
+				for _, entry := range d.entries {
+					mst.Elts = append(mst.Elts, &ast.Field{Label: ast.NewString(entry.key), Value: entry.val})
+				}
+			}
+			x = mst
+		case f.repeated:
+			lst := &ast.ListLit{}
+			if d != nil {
+				lst.Elts = d.list
+			}
+			x = lst
+		case d != nil:
+			x = d.val
+		case f.optional:
+			continue
+		default:
+			var err error
+			x, err = zeroValue(f)
+			if err != nil {
+				return nil, err
+			}
+		}
+		st.Elts = append(st.Elts, &ast.Field{Label: ast.NewString(f.label), Value: x})
+	}
+	return st, nil
+}
+
+func decodeMapEntry(f *field, b []byte) (mapEntry, error) {
+	var entry mapEntry
+	for len(b) > 0 {
+		num, wt, n := consumeTag(b)
+		if n < 0 {
+			return entry, errTruncated
+		}
+		b = b[n:]
+		switch num {
+		case 1:
+			if wt != wireBytes {
+				return entry, wireTypeError(f, wt)
+			}
+			var raw []byte
+			if raw, n = consumeBytes(b); n < 0 {
+				return entry, errTruncated
+			}
+			entry.key = string(raw)
+		case 2:
+			var err error
+			if entry.val, n, err = decodeValue(f, wt, b); err != nil {
+				return entry, err
+			}
+		default:
+			if n = skipValue(b, wt); n < 0 {
+				return entry, errTruncated
+			}
+		}
+		b = b[n:]
+	}
+
+	if entry.val == nil {
+		var err error
+		if entry.val, err = zeroValue(f); err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// decodeValue decodes a single, non-repeated value of f's type from b,
+// returning the value and the number of bytes consumed.
+func decodeValue(f *field, wt int, b []byte) (ast.Expr, int, error) {
+	if wt != f.typ.wireType() {
+		return nil, 0, wireTypeError(f, wt)
+	}
+
+	switch f.typ {
+	case typeDouble, typeNumber:
+		if len(b) < 8 {
+			return nil, 0, errTruncated
+		}
+		x, err := floatLit(math.Float64frombits(binary.LittleEndian.Uint64(b)), f.typ == typeNumber)
+		if err != nil {
+			return nil, 0, fmt.Errorf("field %s: %w", f.label, err)
+		}
+		return x, 8, nil
+	case typeString, typeBytes, typeMessage:
+		raw, n := consumeBytes(b)
+		if n < 0 {
+			return nil, 0, errTruncated
+		}
+		switch f.typ {
+		case typeString:
+			return ast.NewString(string(raw)), n, nil
+		case typeBytes:
+			return ast.NewLit(token.STRING, literal.Bytes.Quote(string(raw))), n, nil
+		default:
+			st, err := decodeMessage(f.msg, raw)
+			return st, n, err
+		}
+	}
+
+	u, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, 0, errTruncated
+	}
+	switch f.typ {
+	case typeBool:
+		return ast.NewBool(u != 0), n, nil
+	case typeInt32:
+		return ast.NewLit(token.INT, strconv.FormatInt(int64(int32(u)), 10)), n, nil
+	case typeInt64:
+		return ast.NewLit(token.INT, strconv.FormatInt(int64(u), 10)), n, nil
+	case typeUint32:
+		return ast.NewLit(token.INT, strconv.FormatUint(uint64(uint32(u)), 10)), n, nil
+	default:
+		return ast.NewLit(token.INT, strconv.FormatUint(u, 10)), n, nil
+	}
+}
+
+func zeroValue(f *field) (ast.Expr, error) {
+	switch f.typ {
+	case typeString:
+		return ast.NewString(""), nil
+	case typeBool:
+		return ast.NewBool(false), nil
+	case typeBytes:
+		return ast.NewLit(token.STRING, literal.Bytes.Quote("")), nil
+	case typeDouble:
+		return ast.NewLit(token.FLOAT, "0.0"), nil
+	case typeMessage:
+		return decodeMessage(f.msg, nil)
+	default:
+		return ast.NewLit(token.INT, "0"), nil
+	}
+}
+
+// floatLit converts f to a CUE literal. If intOK is true, integral values are
+// represented as ints.
+func floatLit(f float64, intOK bool) (ast.Expr, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%v cannot be represented in CUE", f)
+	}
+	if intOK && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return ast.NewLit(token.INT, strconv.FormatInt(int64(f), 10)), nil
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return ast.NewLit(token.FLOAT, s), nil
+}
+
+func encodeMessage(b []byte, m *message, v cue.Value) ([]byte, error) {
+	for _, f := range m.fields {
+		fv := v.LookupPath(cue.MakePath(cue.Str(f.label)))
+		if !fv.Exists() {
+			continue
+		}
+
+		var err error
+		switch {
+		case f.isMap:
+			iter, ierr := fv.Fields()
+			if ierr != nil {
+				return nil, fmt.Errorf("%s: %w", fv.Path(), ierr)
+			}
+			for iter.Next() {
+				entry := appendTag(nil, 1, wireBytes)
+				entry = appendBytes(entry, []byte(iter.Label()))
+				entry = appendTag(entry, 2, f.typ.wireType())
+				if entry, err = appendValue(entry, f, iter.Value()); err != nil {
+					return nil, err
+				}
+				b = appendTag(b, f.number, wireBytes)
+				b = appendBytes(b, entry)
+			}
+		case f.repeated:
+			iter, lerr := fv.List()
+			if lerr != nil {
+				return nil, fmt.Errorf("%s: %w", fv.Path(), lerr)
+			}
+			var packed []byte
+			for iter.Next() {
+				if f.typ.packable() {
+					if packed, err = appendValue(packed, f, iter.Value()); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				b = appendTag(b, f.number, f.typ.wireType())
+				if b, err = appendValue(b, f, iter.Value()); err != nil {
+					return nil, err
+				}
+			}
+			if len(packed) > 0 {
+				b = appendTag(b, f.number, wireBytes)
+				b = appendBytes(b, packed)
+			}
+		default:
+			var val []byte
+			if val, err = appendValue(nil, f, fv); err != nil {
+				return nil, err
+			}
+			// proto3 does not serialize non-optional fields holding a zero value
+			if !f.optional && f.typ != typeMessage && isZero(val, f.typ) {
+				continue
+			}
+			b = appendTag(b, f.number, f.typ.wireType())
+			b = append(b, val...)
+		}
+	}
+	return b, nil
+}
+
+// appendValue appends the encoding of a single value of f's type, excluding
+// its tag, to b.
+func appendValue(b []byte, f *field, v cue.Value) ([]byte, error) {
+	var err error
+	switch f.typ {
+	case typeString:
+		var s string
+		if s, err = v.String(); err == nil {
+			b = appendBytes(b, []byte(s))
+		}
+	case typeBytes:
+		var bs []byte
+		if bs, err = v.Bytes(); err == nil {
+			b = appendBytes(b, bs)
+		}
+	case typeBool:
+		var x bool
+		if x, err = v.Bool(); err == nil {
+			var u uint64
+			if x {
+				u = 1
+			}
+			b = binary.AppendUvarint(b, u)
+		}
+	case typeInt32, typeInt64:
+		var x int64
+		if x, err = v.Int64(); err == nil {
+			b = binary.AppendUvarint(b, uint64(x))
+		}
+	case typeUint32, typeUint64:
+		var x uint64
+		if x, err = v.Uint64(); err == nil {
+			b = binary.AppendUvarint(b, x)
+		}
+	case typeDouble, typeNumber:
+		var x float64
+		if x, err = v.Float64(); err == nil {
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(x))
+		}
+	case typeMessage:
+		var mb []byte
+		if mb, err = encodeMessage(nil, f.msg, v); err == nil {
+			b = appendBytes(b, mb)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", v.Path(), err)
+	}
+	return b, nil
+}
+
+func isZero(val []byte, t protoType) bool {
+	switch t {
+	case typeDouble, typeNumber:
+		return binary.LittleEndian.Uint64(val) == 0
+	default:
+		// Zero varints, and empty strings and bytes, are all a single zero byte.
+		return len(val) == 1 && val[0] == 0
+	}
+}
+
+func (t protoType) wireType() int {
+	switch t {
+	case typeDouble, typeNumber:
+		return wireFixed64
+	case typeString, typeBytes, typeMessage:
+		return wireBytes
+	default:
+		return wireVarint
+	}
+}
+
+// packable reports whether repeated fields of the type use packed encoding.
+func (t protoType) packable() bool {
+	return t.wireType() != wireBytes
+}
+
+func wireTypeError(f *field, wt int) error {
+	return fmt.Errorf("field %s (%d): unexpected wire type %d for %s", f.label, f.number, wt, f.typeName())
+}
+
+func appendTag(b []byte, num, wt int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(wt))
+}
+
+func appendBytes(b, val []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(val)))
+	return append(b, val...)
+}
+
+// consumeTag parses a field tag from b, returning a negative length if b is
+// malformed.
+func consumeTag(b []byte) (num, wt, n int) {
+	u, n := binary.Uvarint(b)
+	if n <= 0 || u>>3 == 0 || u>>3 > math.MaxInt32 {
+		return 0, 0, -1
+	}
+	return int(u >> 3), int(u & 7), n
+}
+
+// consumeBytes parses a length-delimited value from b, returning a negative
+// length if b is malformed.
+func consumeBytes(b []byte) ([]byte, int) {
+	u, n := binary.Uvarint(b)
+	if n <= 0 || u > uint64(len(b)-n) {
+		return nil, -1
+	}
+	return b[n : n+int(u)], n + int(u)
+}
+
+// skipValue returns the length of the value of wire type wt at the start of b,
+// or a negative length if b is malformed.
+func skipValue(b []byte, wt int) int {
+	switch wt {
+	case wireVarint:
+		_, n := binary.Uvarint(b)
+		if n <= 0 {
+			return -1
+		}
+		return n
+	case wireFixed64:
+		if len(b) < 8 {
+			return -1
+		}
+		return 8
+	case wireBytes:
+		_, n := consumeBytes(b)
+		return n
+	case wireFixed32:
+		if len(b) < 4 {
+			return -1
+		}
+		return 4
+	default:
+		return -1
+	}
+}
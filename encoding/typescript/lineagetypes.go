@@ -0,0 +1,255 @@
+package typescript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"github.com/grafana/cuetsy"
+	"github.com/grafana/cuetsy/ts/ast"
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/internal/util"
+)
+
+// LineageTypeConfig governs the behavior of [GenerateLineageTypes].
+type LineageTypeConfig struct {
+	// CuetsyConfig is passed directly to cuetsy, the underlying code generator.
+	//
+	// If nil provided, defaults to Export: true.
+	CuetsyConfig *cuetsy.Config
+
+	// RootName specifies the prefix of the names of the generated types. If
+	// empty, this defaults to titlecasing of the lineage name, with any
+	// characters not valid in an identifier removed.
+	RootName string
+
+	// RootAsType controls whether the schemas are generated as TypeScript
+	// interface types (false) or alias types (true).
+	RootAsType bool
+
+	// js causes only the type guards and detector to be generated, as plain
+	// JavaScript.
+	js bool
+}
+
+// GenerateLineageTypes generates native TypeScript types and defaults for all
+// the schemas in the provided lineage.
+//
+// With a RootName of "Foo", the type for schema version 0.0 is named FooV0_0,
+// and so on. The following are also generated:
+//
+//   - FooAnyVersion, a union of the types of all schemas
+//   - FooVersioned, a union of objects pairing a value with the version of
+//     the schema it is an instance of, discriminated by the version
+//   - isFooV0_0 (and so on), type guards that check whether a value is an
+//     instance of a particular schema
+//   - detectFooVersion, which returns the oldest schema version of which a
+//     value is an instance, as with [thema.Lineage.ValidateAny]
+//
+// The type guards check the types of fields, the presence of required fields,
+// the absence of fields not in closed structs, and literal values. Constraints
+// without a TypeScript equivalent, such as bounds, are not checked.
+//
+// Types declared within schemas, rather than generated for the schemas
+// themselves, are included once. An error is returned if such a type differs
+// between schemas.
+func GenerateLineageTypes(lin thema.Lineage, cfg *LineageTypeConfig) (*ast.File, error) {
+	if cfg == nil {
+		cfg = new(LineageTypeConfig)
+	}
+	if cfg.RootName == "" {
+		cfg.RootName = strings.Title(util.SanitizeLabelString(lin.Name())) //nolint:staticcheck
+	}
+
+	vars := lineageTypeVars{
+		Name:     lin.Name(),
+		RootName: cfg.RootName,
+		JS:       cfg.js,
+	}
+	checks := make(map[string]*typeCheck)
+	file := new(ast.File)
+	declared := make(map[string]string)
+	for sch := lin.First(); sch != nil; sch = sch.Successor() {
+		v := sch.Version()
+		vars.Versions = append(vars.Versions, versionType{
+			Version:  v,
+			TypeName: fmt.Sprintf("%sV%d_%d", cfg.RootName, v[0], v[1]),
+		})
+		checks[v.String()] = checkOf(sch.Underlying().LookupPath(pathSchDef))
+		if cfg.js {
+			continue
+		}
+
+		f, err := GenerateTypes(sch, &TypeConfig{
+			CuetsyConfig: cfg.CuetsyConfig,
+			RootName:     vars.Versions[len(vars.Versions)-1].TypeName,
+			RootAsType:   cfg.RootAsType,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("generating TS for schema %s failed: %w", v, err)
+		}
+		for _, n := range f.Nodes {
+			str := n.String()
+			name := declName(str)
+			if prior, has := declared[name]; has {
+				if prior != str {
+					return nil, fmt.Errorf("declaration of %s in schema %s differs from an earlier schema", name, v)
+				}
+				continue
+			}
+			declared[name] = str
+			file.Nodes = append(file.Nodes, n)
+		}
+	}
+
+	b, err := json.MarshalIndent(checks, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode schema checks: %w", err)
+	}
+	vars.Checks = string(b)
+
+	buf := new(bytes.Buffer)
+	if err := tmpls.Lookup("lineagetypes.tmpl").Execute(buf, vars); err != nil {
+		return nil, fmt.Errorf("error executing lineage types template: %w", err)
+	}
+	file.Nodes = append(file.Nodes, ast.Raw{Data: strings.TrimSpace(buf.String())})
+	return file, nil
+}
+
+// declName returns the name declared by a rendered TypeScript declaration, or
+// the whole declaration if no name can be found.
+func declName(decl string) string {
+	fields := strings.Fields(decl)
+	for i, f := range fields {
+		switch f {
+		case "interface", "type", "enum", "const", "let", "var":
+			if i+1 < len(fields) {
+				return strings.TrimRight(fields[i+1], ":=<{")
+			}
+		}
+	}
+	return decl
+}
+
+type lineageTypeVars struct {
+	// Name of the lineage
+	Name string
+	// Prefix of all generated identifiers
+	RootName string
+	// Whether to omit types
+	JS bool
+	// All schemas in the lineage, in order
+	Versions []versionType
+	// JSON object containing the check for each schema, keyed by version
+	Checks string
+}
+
+// T returns the provided type annotation, or nothing if generating JavaScript.
+func (v lineageTypeVars) T(ann string) string {
+	if v.JS {
+		return ""
+	}
+	return ann
+}
+
+type versionType struct {
+	Version thema.SyntacticVersion
+	// Name of the type generated for the schema
+	TypeName string
+}
+
+// typeCheck describes the checks applied to a value by the generated type
+// guards.
+type typeCheck struct {
+	// Field may be absent, because it is optional or has a default
+	Optional bool `json:"optional,omitempty"`
+	// Value must be of one of these JSON types, if any are given
+	Types []string `json:"types,omitempty"`
+	// Value must be one of these literals, if any are given
+	Enum []json.RawMessage `json:"enum,omitempty"`
+	// Value must satisfy one of these checks, if any are given
+	AnyOf []*typeCheck `json:"anyOf,omitempty"`
+	// Fields of a struct
+	Fields map[string]*typeCheck `json:"fields,omitempty"`
+	// Check for values of a struct not in fields, such as from a pattern
+	// constraint
+	Values *typeCheck `json:"values,omitempty"`
+	// Struct permits no fields other than those in fields
+	Closed bool `json:"closed,omitempty"`
+	// Check for elements of a list
+	Elem *typeCheck `json:"elem,omitempty"`
+}
+
+func checkOf(v cue.Value) *typeCheck {
+	c := new(typeCheck)
+	if op, args := v.Expr(); op == cue.OrOp {
+		for _, arg := range args {
+			c.AnyOf = append(c.AnyOf, checkOf(arg))
+		}
+		// Simplify disjunctions of literals
+		var enum []json.RawMessage
+		for _, ac := range c.AnyOf {
+			if len(ac.Enum) == 0 {
+				return c
+			}
+			enum = append(enum, ac.Enum...)
+		}
+		return &typeCheck{Enum: enum}
+	}
+
+	k := v.IncompleteKind()
+	if v.IsConcrete() && k&(cue.StructKind|cue.ListKind) == 0 {
+		b, _ := v.MarshalJSON()
+		c.Enum = append(c.Enum, b)
+		return c
+	}
+	if k != cue.TopKind {
+		for _, kt := range []struct {
+			k cue.Kind
+			t string
+		}{
+			{cue.NullKind, "null"},
+			{cue.BoolKind, "boolean"},
+			{cue.StringKind | cue.BytesKind, "string"},
+			{cue.StructKind, "object"},
+			{cue.ListKind, "array"},
+		} {
+			if k&kt.k != 0 {
+				c.Types = append(c.Types, kt.t)
+			}
+		}
+		switch {
+		case k&cue.FloatKind != 0:
+			c.Types = append(c.Types, "number")
+		case k&cue.IntKind != 0:
+			c.Types = append(c.Types, "integer")
+		}
+	}
+
+	switch k {
+	case cue.StructKind:
+		iter, err := v.Fields(cue.Optional(true))
+		if err != nil {
+			return c
+		}
+		c.Fields = make(map[string]*typeCheck)
+		for iter.Next() {
+			fc := checkOf(iter.Value())
+			_, hasdef := iter.Value().Default()
+			fc.Optional = iter.IsOptional() || hasdef
+			c.Fields[iter.Selector().Unquoted()] = fc
+		}
+		if pv := v.LookupPath(cue.MakePath(cue.AnyString)); pv.Exists() {
+			c.Values = checkOf(pv)
+		} else {
+			c.Closed = !v.Allows(cue.AnyString)
+		}
+	case cue.ListKind:
+		if ev := v.LookupPath(cue.MakePath(cue.AnyIndex)); ev.Exists() {
+			c.Elem = checkOf(ev)
+		}
+	}
+	return c
+}
@@ -0,0 +1,803 @@
+package typescript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/format"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+	"github.com/grafana/thema"
+)
+
+// MigrationConfig governs the behavior of [GenerateMigrations].
+type MigrationConfig struct {
+	// StubUnsupported causes lenses that use CUE constructs which cannot be
+	// compiled to TypeScript to be generated as functions that throw an error,
+	// rather than failing generation. The reason each lens could not be compiled
+	// is included in a comment on its function.
+	StubUnsupported bool
+
+	// js causes plain JavaScript to be generated, omitting all type annotations.
+	js bool
+}
+
+// GenerateMigrations generates a TypeScript module that migrates objects
+// between the schemas in the provided lineage, without evaluating CUE.
+//
+// Each lens in the lineage is compiled into a TypeScript function. The
+// following constructs are supported in lens results and lacunas:
+//
+//   - Fields set to literals, or to references to fields of input
+//   - Nested struct and list literals
+//   - if comprehensions, with conditions that compare fields of input to
+//     literals, to other fields of input, or to _|_ (testing for presence)
+//   - Conditions that test the type of a field of input, such as
+//     (input.foo & string) != _|_, or whether it is one of a set of literals
+//   - The logical operators !, && and || within conditions
+//
+// The module exports a migrate function, which translates an object to any
+// version in the lineage by applying the lenses along the way, and returns the
+// lacunas they emitted. As with [thema.Instance.Translate], fields that have
+// a default in a target schema and are not set by a lens are set to the
+// default. Other than checking for required fields and fields constrained to a
+// set of literals, migrate does not validate objects; they should be validated
+// against the lineage, or the types generated by [GenerateTypes], before
+// migration.
+func GenerateMigrations(lin thema.Lineage, cfg *MigrationConfig) ([]byte, error) {
+	if cfg == nil {
+		cfg = new(MigrationConfig)
+	}
+
+	vars := migrationVars{
+		Name: lin.Name(),
+		JS:   cfg.js,
+	}
+
+	shapes := make(map[string]shape)
+	for sch := lin.First(); sch != nil; sch = sch.Successor() {
+		vars.Versions = append(vars.Versions, sch.Version())
+		shapes[sch.Version().String()] = shapeOf(sch.Underlying().LookupPath(pathSchDef))
+	}
+	b, err := json.MarshalIndent(shapes, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode schema shapes: %w", err)
+	}
+	vars.Shapes = string(b)
+
+	iter, err := lin.Underlying().LookupPath(cue.MakePath(cue.Str("lenses"))).List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list lenses: %w", err)
+	}
+	for iter.Next() {
+		lv := iter.Value()
+		var lf migrationFunc
+		if err := lv.LookupPath(cue.MakePath(cue.Str("from"))).Decode(&lf.From); err != nil {
+			return nil, fmt.Errorf("unable to decode lens from version: %w", err)
+		}
+		if err := lv.LookupPath(cue.MakePath(cue.Str("to"))).Decode(&lf.To); err != nil {
+			return nil, fmt.Errorf("unable to decode lens to version: %w", err)
+		}
+		lf.FuncName = fmt.Sprintf("lens%d_%dTo%d_%d", lf.From[0], lf.From[1], lf.To[0], lf.To[1])
+
+		lf.Body, err = compileMigration(lv, cfg.js)
+		if err != nil {
+			if !cfg.StubUnsupported {
+				return nil, fmt.Errorf("lens %s -> %s can not be compiled to TypeScript: %w", lf.From, lf.To, err)
+			}
+			lf.Unsupported = strings.Join(strings.Fields(err.Error()), " ")
+		}
+		vars.Lenses = append(vars.Lenses, lf)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpls.Lookup("migrations.tmpl").Execute(buf, vars); err != nil {
+		return nil, fmt.Errorf("error executing migrations template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type migrationVars struct {
+	// Name of the lineage
+	Name string
+	// Whether to omit type annotations
+	JS bool
+	// Versions of all schemas in the lineage, in order
+	Versions []thema.SyntacticVersion
+	// JSON object containing the shape of each schema, keyed by version
+	Shapes string
+	// All lenses in the lineage, in declaration order
+	Lenses []migrationFunc
+}
+
+// T returns the provided type annotation, or nothing if generating JavaScript.
+func (v migrationVars) T(ann string) string {
+	if v.JS {
+		return ""
+	}
+	return ann
+}
+
+type migrationFunc struct {
+	From, To thema.SyntacticVersion
+	// Name of the generated function
+	FuncName string
+	// Rendered statements of the generated function
+	Body string
+	// Reason the lens could not be compiled, if it could not
+	Unsupported string
+}
+
+var pathSchDef = cue.MakePath(cue.Hid("_#schema", "github.com/grafana/thema"))
+
+// shape describes the fields of a schema that are checked, and completed with
+// defaults, by the generated migrate function.
+type shape map[string]*fieldShape
+
+type fieldShape struct {
+	Optional bool `json:"optional,omitempty"`
+	// Default value of a required field
+	Default json.RawMessage `json:"default,omitempty"`
+	// Literals to which the field is constrained
+	Enum []json.RawMessage `json:"enum,omitempty"`
+	// Fields of a struct
+	Fields shape `json:"fields,omitempty"`
+}
+
+func shapeOf(v cue.Value) shape {
+	s := make(shape)
+	iter, err := v.Fields(cue.Optional(true))
+	if err != nil {
+		return s
+	}
+	for iter.Next() {
+		fv := iter.Value()
+		f := &fieldShape{
+			Optional: iter.IsOptional(),
+		}
+		if dv, has := fv.Default(); has && !f.Optional && dv.IsConcrete() && dv.Kind() != cue.StructKind {
+			f.Default, _ = dv.MarshalJSON()
+		}
+
+		op, args := fv.Expr()
+		switch {
+		case op == cue.OrOp:
+			for _, arg := range args {
+				if !arg.IsConcrete() || arg.Kind()&(cue.StructKind|cue.ListKind) != 0 {
+					f.Enum = nil
+					break
+				}
+				b, _ := arg.MarshalJSON()
+				f.Enum = append(f.Enum, b)
+			}
+		case fv.IncompleteKind() == cue.StructKind:
+			f.Fields = shapeOf(fv)
+		case fv.IsConcrete() && fv.Kind()&(cue.StructKind|cue.ListKind) == 0:
+			b, _ := fv.MarshalJSON()
+			f.Enum = append(f.Enum, b)
+		}
+		s[iter.Selector().Unquoted()] = f
+	}
+	return s
+}
+
+// unsupportedError indicates that a lens uses a CUE construct that cannot be
+// compiled to TypeScript.
+type unsupportedError struct {
+	node   ast.Node
+	reason string
+}
+
+func unsupported(node ast.Node, format string, args ...interface{}) error {
+	return &unsupportedError{
+		node:   node,
+		reason: fmt.Sprintf(format, args...),
+	}
+}
+
+func (e *unsupportedError) Error() string {
+	b, err := format.Node(e.node)
+	if err != nil {
+		return e.reason
+	}
+	return fmt.Sprintf("%s: %s", e.reason, b)
+}
+
+func compileMigration(lens cue.Value, js bool) (string, error) {
+	c := &migrationCompiler{
+		buf:    new(bytes.Buffer),
+		indent: 1,
+	}
+
+	src, err := fieldSource(lens, "result")
+	if err != nil {
+		return "", err
+	}
+	lit, is := src.(*ast.StructLit)
+	if !is {
+		return "", unsupported(src, "lens result must be a struct literal")
+	}
+	if js {
+		c.line("const result = {};")
+	} else {
+		c.line("const result: any = {};")
+	}
+	if err = c.compileStruct(lit, "result"); err != nil {
+		return "", err
+	}
+
+	if js {
+		c.line("const lacunas = [];")
+	} else {
+		c.line("const lacunas: Lacuna[] = [];")
+	}
+	if lacs, err := fieldSource(lens, "lacunas"); err == nil {
+		list, is := lacs.(*ast.ListLit)
+		if !is {
+			return "", unsupported(lacs, "lens lacunas must be a list literal")
+		}
+		c.lacunas = true
+		for _, elt := range list.Elts {
+			if err = c.compileLacuna(elt); err != nil {
+				return "", err
+			}
+		}
+	}
+	c.line("return { result, lacunas };")
+	return c.buf.String(), nil
+}
+
+// fieldSource returns the expression from which the named field of the
+// provided lens was evaluated, ignoring its declaration in thema.#Lens.
+func fieldSource(lens cue.Value, label string) (ast.Expr, error) {
+	v := lens.LookupPath(cue.MakePath(cue.Str(label)))
+	_, args := v.Expr()
+	for _, arg := range args {
+		f, is := arg.Source().(*ast.Field)
+		if !is {
+			continue
+		}
+		switch x := f.Value.(type) {
+		case *ast.Ident:
+			if x.Name == "_" {
+				continue
+			}
+		case *ast.ListLit:
+			if len(x.Elts) == 1 {
+				if _, is := x.Elts[0].(*ast.Ellipsis); is {
+					continue
+				}
+			}
+		}
+		if l, _, _ := ast.LabelName(f.Label); l == label {
+			return f.Value, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find source of lens %s", label)
+}
+
+// migrationCompiler compiles a single lens into the statements of a TypeScript
+// function.
+type migrationCompiler struct {
+	buf    *bytes.Buffer
+	indent int
+	// Whether the lacunas of the lens are being compiled, in which case
+	// references to result are permitted
+	lacunas bool
+}
+
+func (c *migrationCompiler) line(format string, args ...interface{}) {
+	c.buf.WriteString(strings.Repeat("  ", c.indent))
+	fmt.Fprintf(c.buf, format, args...)
+	c.buf.WriteByte('\n')
+}
+
+// ifClause returns the condition of a comprehension with a single if clause.
+func ifClause(x *ast.Comprehension) (ast.Expr, error) {
+	ifc, is := x.Clauses[0].(*ast.IfClause)
+	if !is || len(x.Clauses) != 1 {
+		return nil, unsupported(x.Clauses[0], "unsupported comprehension clause")
+	}
+	return ifc.Condition, nil
+}
+
+// compileStruct compiles the struct literal, assigning its fields to the
+// object out.
+func (c *migrationCompiler) compileStruct(lit *ast.StructLit, out string) error {
+	for _, decl := range lit.Elts {
+		switch x := decl.(type) {
+		case *ast.CommentGroup, *ast.Attribute:
+		case *ast.Field:
+			label, isIdent, err := ast.LabelName(x.Label)
+			if err != nil || (isIdent && (strings.HasPrefix(label, "_") || strings.HasPrefix(label, "#"))) {
+				return unsupported(x.Label, "unsupported field label")
+			}
+			if x.Optional.IsValid() {
+				return unsupported(x, "optional fields are not supported")
+			}
+			target := out + jsSelector(label)
+
+			if flit, is := x.Value.(*ast.StructLit); is {
+				c.line("if (%s === undefined) {", target)
+				c.indent++
+				c.line("%s = {};", target)
+				c.indent--
+				c.line("}")
+				if err = c.compileStruct(flit, target); err != nil {
+					return err
+				}
+				continue
+			}
+			val, err := c.value(x.Value)
+			if err != nil {
+				return err
+			}
+			c.line("%s = %s;", target, val)
+		case *ast.Comprehension:
+			cond, err := ifClause(x)
+			if err != nil {
+				return err
+			}
+			body, is := x.Value.(*ast.StructLit)
+			if !is {
+				return unsupported(x.Value, "unsupported comprehension value")
+			}
+			if err = c.block(cond, func() error { return c.compileStruct(body, out) }); err != nil {
+				return err
+			}
+		default:
+			return unsupported(decl, "unsupported declaration")
+		}
+	}
+	return nil
+}
+
+// block compiles an if statement with the provided condition, calling fn to
+// compile its body.
+func (c *migrationCompiler) block(cond ast.Expr, fn func() error) error {
+	s, err := c.cond(cond)
+	if err != nil {
+		return err
+	}
+	c.line("if (%s) {", s)
+	c.indent++
+	if err = fn(); err != nil {
+		return err
+	}
+	c.indent--
+	c.line("}")
+	return nil
+}
+
+// compileLacuna compiles an element of the lacunas list of a lens.
+func (c *migrationCompiler) compileLacuna(elt ast.Expr) error {
+	if x, is := elt.(*ast.Comprehension); is {
+		cond, err := ifClause(x)
+		if err != nil {
+			return err
+		}
+		return c.block(cond, func() error { return c.compileLacuna(x.Value) })
+	}
+
+	var fields []*ast.Field
+	if err := lacunaFields(elt, &fields); err != nil {
+		return err
+	}
+	var props []string
+	var cond ast.Expr
+	for _, f := range fields {
+		label, _, err := ast.LabelName(f.Label)
+		if err != nil {
+			return unsupported(f.Label, "unsupported field label")
+		}
+		switch label {
+		case "condition":
+			cond = f.Value
+			continue
+		case "sourceFields", "targetFields":
+			refs, err := c.fieldRefs(f.Value)
+			if err != nil {
+				return err
+			}
+			props = append(props, fmt.Sprintf("%s: %s", label, refs))
+		case "type":
+			id, err := lacunaTypeID(f.Value)
+			if err != nil {
+				return err
+			}
+			props = append(props, fmt.Sprintf("type: %d", id))
+		case "message":
+			msg, err := c.value(f.Value)
+			if err != nil {
+				return err
+			}
+			props = append(props, "message: "+msg)
+		default:
+			return unsupported(f.Label, "unknown lacuna field")
+		}
+	}
+
+	push := func() error {
+		c.line("lacunas.push({ %s });", strings.Join(props, ", "))
+		return nil
+	}
+	if cond != nil {
+		return c.block(cond, push)
+	}
+	return push()
+}
+
+// lacunaFields collects the fields of a lacuna expressed as a struct literal,
+// possibly unified with thema.#Lacuna.
+func lacunaFields(expr ast.Expr, fields *[]*ast.Field) error {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return lacunaFields(x.X, fields)
+	case *ast.StructLit:
+		for _, decl := range x.Elts {
+			switch d := decl.(type) {
+			case *ast.CommentGroup, *ast.Attribute:
+			case *ast.Field:
+				*fields = append(*fields, d)
+			case *ast.EmbedDecl:
+				if err := lacunaFields(d.Expr, fields); err != nil {
+					return err
+				}
+			default:
+				return unsupported(decl, "unsupported declaration in lacuna")
+			}
+		}
+		return nil
+	case *ast.BinaryExpr:
+		if x.Op == token.AND {
+			if err := lacunaFields(x.X, fields); err != nil {
+				return err
+			}
+			return lacunaFields(x.Y, fields)
+		}
+	case *ast.SelectorExpr:
+		if id, is := x.Sel.(*ast.Ident); is && id.Name == "#Lacuna" {
+			return nil
+		}
+	}
+	return unsupported(expr, "unsupported lacuna expression")
+}
+
+// lacunaTypeID resolves a reference to one of thema.#LacunaTypes to its id.
+func lacunaTypeID(expr ast.Expr) (thema.LacunaType, error) {
+	if x, is := expr.(*ast.SelectorExpr); is {
+		if name, _, err := ast.LabelName(x.Sel); err == nil {
+			for lt := thema.LacunaPlaceholder; lt <= thema.LacunaChangedDefault; lt++ {
+				if lt.String() == name {
+					return lt, nil
+				}
+			}
+		}
+	}
+	return 0, unsupported(expr, "lacuna type must be one of thema.#LacunaTypes")
+}
+
+func (c *migrationCompiler) fieldRefs(expr ast.Expr) (string, error) {
+	list, is := expr.(*ast.ListLit)
+	if !is {
+		return "", unsupported(expr, "field references must be a list literal")
+	}
+	elts := make([]string, 0, len(list.Elts))
+	for _, elt := range list.Elts {
+		var fields []*ast.Field
+		if err := lacunaFields(elt, &fields); err != nil {
+			return "", err
+		}
+		var props []string
+		for _, f := range fields {
+			label, _, err := ast.LabelName(f.Label)
+			if err != nil || (label != "path" && label != "value") {
+				return "", unsupported(f.Label, "unknown field reference field")
+			}
+			val, err := c.value(f.Value)
+			if err != nil {
+				return "", err
+			}
+			props = append(props, fmt.Sprintf("%s: %s", label, val))
+		}
+		elts = append(elts, "{ "+strings.Join(props, ", ")+" }")
+	}
+	return "[" + strings.Join(elts, ", ") + "]", nil
+}
+
+var jsIdentRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// jsSelector returns a TypeScript property accessor for the label.
+func jsSelector(label string) string {
+	if jsIdentRe.MatchString(label) {
+		return "." + label
+	}
+	return "[" + jsString(label) + "]"
+}
+
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// value compiles an expression that produces a value.
+func (c *migrationCompiler) value(expr ast.Expr) (string, error) {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return c.value(x.X)
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		return c.ref(x, "get")
+	case *ast.ListLit:
+		elts := make([]string, 0, len(x.Elts))
+		for _, elt := range x.Elts {
+			s, err := c.value(elt)
+			if err != nil {
+				return "", err
+			}
+			elts = append(elts, s)
+		}
+		return "[" + strings.Join(elts, ", ") + "]", nil
+	case *ast.BinaryExpr, *ast.UnaryExpr:
+		if x, is := expr.(*ast.UnaryExpr); is && x.Op != token.NOT {
+			break
+		}
+		return c.cond(expr)
+	}
+	return jsLit(expr)
+}
+
+// jsLit converts a CUE literal into a TypeScript literal.
+func jsLit(expr ast.Expr) (string, error) {
+	neg := ""
+	if x, is := expr.(*ast.UnaryExpr); is && x.Op == token.SUB {
+		neg, expr = "-", x.X
+	}
+	x, is := expr.(*ast.BasicLit)
+	if !is {
+		return "", unsupported(expr, "unsupported expression")
+	}
+	if neg != "" && x.Kind != token.INT && x.Kind != token.FLOAT {
+		return "", unsupported(expr, "unsupported expression")
+	}
+
+	switch x.Kind {
+	case token.STRING:
+		s, err := literal.Unquote(x.Value)
+		if err != nil || strings.HasPrefix(x.Value, "'") {
+			break
+		}
+		return jsString(s), nil
+	case token.INT:
+		i, err := strconv.ParseInt(strings.ReplaceAll(x.Value, "_", ""), 0, 64)
+		if err != nil || i > 1<<53 {
+			break
+		}
+		return neg + strconv.FormatInt(i, 10), nil
+	case token.FLOAT:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(x.Value, "_", ""), 64)
+		if err != nil {
+			break
+		}
+		return neg + strconv.FormatFloat(f, 'g', -1, 64), nil
+	case token.TRUE, token.FALSE, token.NULL:
+		return x.Value, nil
+	}
+	return "", unsupported(expr, "unsupported literal")
+}
+
+// ref compiles a reference to a field of input (or, within lacunas, of
+// result) into a call to the generated helper fn, get or lookup.
+func (c *migrationCompiler) ref(expr ast.Expr, fn string) (string, error) {
+	var sels []string
+	for e := expr; ; {
+		switch x := e.(type) {
+		case *ast.SelectorExpr:
+			label, _, err := ast.LabelName(x.Sel)
+			if err != nil {
+				return "", unsupported(expr, "unsupported selector")
+			}
+			sels = append([]string{jsString(label)}, sels...)
+			e = x.X
+			continue
+		case *ast.IndexExpr:
+			lit, is := x.Index.(*ast.BasicLit)
+			if !is || lit.Kind != token.STRING {
+				return "", unsupported(expr, "unsupported index")
+			}
+			label, err := literal.Unquote(lit.Value)
+			if err != nil {
+				return "", unsupported(expr, "unsupported index")
+			}
+			sels = append([]string{jsString(label)}, sels...)
+			e = x.X
+			continue
+		case *ast.Ident:
+			if len(sels) > 0 && (x.Name == "input" || (c.lacunas && x.Name == "result")) {
+				return fmt.Sprintf("%s(%s, %s)", fn, x.Name, strings.Join(sels, ", ")), nil
+			}
+			return "", unsupported(expr, "only references to fields of input are supported")
+		}
+		return "", unsupported(expr, "unsupported expression")
+	}
+}
+
+// cond compiles an expression used as a condition into a TypeScript boolean
+// expression.
+func (c *migrationCompiler) cond(expr ast.Expr) (string, error) {
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return c.cond(x.X)
+	case *ast.UnaryExpr:
+		if x.Op != token.NOT {
+			break
+		}
+		s, err := c.cond(x.X)
+		if err != nil {
+			return "", err
+		}
+		if _, is := unparen(x.X).(*ast.BinaryExpr); is {
+			return "!(" + s + ")", nil
+		}
+		return "!" + s, nil
+	case *ast.BinaryExpr:
+		switch x.Op {
+		case token.LAND, token.LOR:
+			l, err := c.condOperand(x.X, x.Op)
+			if err != nil {
+				return "", err
+			}
+			r, err := c.condOperand(x.Y, x.Op)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s %s %s", l, x.Op, r), nil
+		case token.EQL, token.NEQ:
+			if _, is := unparen(x.Y).(*ast.BottomLit); is {
+				return c.presence(x.X, x.Op == token.NEQ)
+			}
+			if _, is := unparen(x.X).(*ast.BottomLit); is {
+				return c.presence(x.Y, x.Op == token.NEQ)
+			}
+			return c.compare(x)
+		case token.LSS, token.GTR, token.LEQ, token.GEQ:
+			return c.compare(x)
+		}
+		return "", unsupported(expr, "unsupported operator %s", x.Op)
+	case *ast.BasicLit:
+		if x.Kind == token.TRUE || x.Kind == token.FALSE {
+			return x.Value, nil
+		}
+	case *ast.SelectorExpr, *ast.IndexExpr:
+		r, err := c.ref(x, "get")
+		if err != nil {
+			return "", err
+		}
+		return r + " === true", nil
+	}
+	return "", unsupported(expr, "unsupported condition")
+}
+
+func (c *migrationCompiler) condOperand(expr ast.Expr, op token.Token) (string, error) {
+	s, err := c.cond(expr)
+	if err != nil {
+		return "", err
+	}
+	if x, is := unparen(expr).(*ast.BinaryExpr); is && x.Op != op && (x.Op == token.LAND || x.Op == token.LOR) {
+		return "(" + s + ")", nil
+	}
+	return s, nil
+}
+
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		p, is := expr.(*ast.ParenExpr)
+		if !is {
+			return expr
+		}
+		expr = p.X
+	}
+}
+
+// presence compiles a comparison with _|_, of either a field of input or the
+// unification of a field of input with a type or set of literals.
+func (c *migrationCompiler) presence(expr ast.Expr, present bool) (string, error) {
+	expr = unparen(expr)
+	if x, is := expr.(*ast.BinaryExpr); is && x.Op == token.AND {
+		v, err := c.ref(unparen(x.X), "lookup")
+		if err != nil {
+			return "", err
+		}
+		test, err := typeTest(unparen(x.Y), v)
+		if err != nil {
+			return "", err
+		}
+		if present {
+			return test, nil
+		}
+		return "!(" + test + ")", nil
+	}
+
+	r, err := c.ref(expr, "lookup")
+	if err != nil {
+		return "", err
+	}
+	if present {
+		return r + " !== undefined", nil
+	}
+	return r + " === undefined", nil
+}
+
+// typeTest compiles a test of whether the value v unifies with expr, which
+// must be a type or a disjunction of literals.
+func typeTest(expr ast.Expr, v string) (string, error) {
+	if id, is := expr.(*ast.Ident); is {
+		switch id.Name {
+		case "string", "number":
+			return fmt.Sprintf("typeof %s === %q", v, id.Name), nil
+		case "bool":
+			return fmt.Sprintf("typeof %s === \"boolean\"", v), nil
+		case "int":
+			return fmt.Sprintf("Number.isInteger(%s)", v), nil
+		case "null":
+			return v + " === null", nil
+		}
+		return "", unsupported(expr, "unsupported type")
+	}
+
+	var lits []string
+	var collect func(e ast.Expr) error
+	collect = func(e ast.Expr) error {
+		e = unparen(e)
+		if x, is := e.(*ast.BinaryExpr); is && x.Op == token.OR {
+			if err := collect(x.X); err != nil {
+				return err
+			}
+			return collect(x.Y)
+		}
+		lit, err := jsLit(e)
+		if err != nil {
+			return err
+		}
+		lits = append(lits, lit)
+		return nil
+	}
+	if err := collect(expr); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("[%s].includes(%s)", strings.Join(lits, ", "), v), nil
+}
+
+// compare compiles a comparison between fields of input and literals.
+func (c *migrationCompiler) compare(x *ast.BinaryExpr) (string, error) {
+	operand := func(expr ast.Expr) (string, error) {
+		expr = unparen(expr)
+		switch expr.(type) {
+		case *ast.SelectorExpr, *ast.IndexExpr:
+			return c.ref(expr, "get")
+		}
+		return jsLit(expr)
+	}
+	l, err := operand(x.X)
+	if err != nil {
+		return "", err
+	}
+	r, err := operand(x.Y)
+	if err != nil {
+		return "", err
+	}
+
+	op := x.Op.String()
+	switch x.Op {
+	case token.EQL:
+		op = "==="
+	case token.NEQ:
+		op = "!=="
+	}
+	return fmt.Sprintf("%s %s %s", l, op, r), nil
+}
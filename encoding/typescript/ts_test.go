@@ -1,11 +1,19 @@
 package typescript
 
 import (
-	"github.com/stretchr/testify/require"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
 	"cuelang.org/go/cue/cuecontext"
 	"github.com/grafana/thema"
+	"github.com/grafana/thema/exemplars"
 	"github.com/grafana/thema/internal/txtartest/bindlin"
 	"github.com/grafana/thema/internal/txtartest/vanilla"
 )
@@ -59,3 +67,255 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateMigrations(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/typescript/TestGenerateMigrations",
+		ThemaFS: thema.CueJointFS,
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		b, err := GenerateMigrations(lin, &MigrationConfig{StubUnsupported: true})
+		if err != nil {
+			tc.Fatal(err)
+		}
+		_, err = tc.Write(b) //nolint:gosec,errcheck
+		require.NoError(t, err)
+	})
+}
+
+// migrationDriver runs each case in cases.json through the migrate function of
+// the generated module, writing the results to stdout.
+const migrationDriver = `
+import { readFileSync } from "fs";
+import { migrate } from "./migrations.mjs";
+
+const cases = JSON.parse(readFileSync(new URL("./cases.json", import.meta.url)));
+console.log(JSON.stringify(cases.map((c) => {
+  try {
+    return migrate({ version: c.from, value: c.value }, c.to);
+  } catch (e) {
+    return { error: String(e) };
+  }
+})));
+`
+
+// TestMigrationEquivalence checks that migrating the examples in each
+// exemplar lineage to every schema in the lineage with the generated
+// migrations produces the same results and lacunas as [thema.Instance.Translate].
+func TestMigrationEquivalence(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is required to execute generated migrations")
+	}
+
+	type migrationCase struct {
+		name     string
+		From     thema.SyntacticVersion `json:"from"`
+		Value    interface{}            `json:"value"`
+		To       thema.SyntacticVersion `json:"to"`
+		expected *thema.Instance
+		lacunas  thema.TranslationLacunas
+	}
+	type migrationOutput struct {
+		Error    string `json:"error"`
+		Instance struct {
+			Version thema.SyntacticVersion `json:"version"`
+			Value   json.RawMessage        `json:"value"`
+		} `json:"instance"`
+		Lacunas []struct {
+			V       thema.SyntacticVersion `json:"v"`
+			Lacunas []thema.Lacuna         `json:"lacunas"`
+		} `json:"lacunas"`
+	}
+
+	rt := thema.NewRuntime(cuecontext.New())
+	for name, lin := range exemplars.All(rt) {
+		lin := lin
+		t.Run(name, func(t *testing.T) {
+			b, err := GenerateMigrations(lin, &MigrationConfig{js: true})
+			require.NoError(t, err)
+
+			var cases []*migrationCase
+			for from := lin.First(); from != nil; from = from.Successor() {
+				for exname, example := range from.Examples() {
+					// Decoding would turn empty lists into nulls
+					value, err := example.Underlying().MarshalJSON()
+					require.NoError(t, err)
+					for to := lin.First(); to != nil; to = to.Successor() {
+						mc := &migrationCase{
+							name:  fmt.Sprintf("%s-%s->%s", from.Version(), exname, to.Version()),
+							From:  from.Version(),
+							Value: json.RawMessage(value),
+							To:    to.Version(),
+						}
+						mc.expected, mc.lacunas, _ = example.Translate(to.Version())
+						cases = append(cases, mc)
+					}
+				}
+			}
+			cb, err := json.Marshal(cases)
+			require.NoError(t, err)
+
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "migrations.mjs"), b, 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "cases.json"), cb, 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "driver.mjs"), []byte(migrationDriver), 0644))
+			out, err := exec.Command(node, filepath.Join(dir, "driver.mjs")).CombinedOutput() //nolint:gosec
+			require.NoError(t, err, string(out))
+
+			var outputs []migrationOutput
+			require.NoError(t, json.Unmarshal(out, &outputs), string(out))
+			require.Len(t, outputs, len(cases))
+
+			for i, mc := range cases {
+				mc, mo := mc, outputs[i]
+				t.Run(mc.name, func(t *testing.T) {
+					if mc.expected == nil {
+						assert.NotEmpty(t, mo.Error, "Translate failed, but migrate did not throw")
+						return
+					}
+					require.Empty(t, mo.Error)
+
+					assert.Equal(t, mc.To, mo.Instance.Version)
+					eb, err := json.Marshal(mc.expected.Underlying())
+					require.NoError(t, err)
+					assert.JSONEq(t, string(eb), string(mo.Instance.Value))
+
+					// Normalize lacunas by round-tripping them through thema.Lacuna
+					elb, err := json.Marshal(mc.lacunas)
+					require.NoError(t, err)
+					alb, err := json.Marshal(mo.Lacunas)
+					require.NoError(t, err)
+					if len(mo.Lacunas) == 0 && string(elb) == "null" {
+						elb = alb
+					}
+					assert.JSONEq(t, string(elb), string(alb))
+				})
+			}
+		})
+	}
+}
+
+func TestGenerateLineageTypes(t *testing.T) {
+	test := vanilla.TxTarTest{
+		Root:    "../../testdata/lineage",
+		Name:    "encoding/typescript/TestGenerateLineageTypes",
+		ThemaFS: thema.CueJointFS,
+		Skip: map[string]string{
+			"lineage/refexscalar": "bounds constraints are not supported as they lack a direct typescript equivalent",
+			"lineage/refscalar":   "bounds constraints are not supported as they lack a direct typescript equivalent",
+		},
+	}
+
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	test.Run(t, func(tc *vanilla.Test) {
+		if testing.Short() && tc.HasTag("slow") {
+			t.Skip("case is tagged #slow, skipping for -short")
+		}
+		lin, err := bindlin.BindTxtarLineage(tc, rt)
+		if err != nil {
+			tc.Fatal(err)
+		}
+
+		f, err := GenerateLineageTypes(lin, nil)
+		if err != nil {
+			tc.Fatal(err)
+		}
+		_, err = tc.Write([]byte(f.String())) //nolint:gosec,errcheck
+		require.NoError(t, err)
+	})
+}
+
+// detectorDriver runs each value in values.json through the detector of the
+// generated module, writing the detected versions to stdout.
+const detectorDriver = `
+import { readFileSync } from "fs";
+import { detect } from "./types.mjs";
+
+const values = JSON.parse(readFileSync(new URL("./values.json", import.meta.url)));
+console.log(JSON.stringify(values.map((v) => {
+  const detected = detect(v);
+  return detected === undefined ? null : detected.version;
+})));
+`
+
+// TestDetectVersion checks that the generated version detector for each
+// exemplar lineage agrees with [thema.Lineage.ValidateAny] on the lineage's
+// examples, and on variants of them that are not valid.
+func TestDetectVersion(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is required to execute generated type guards")
+	}
+
+	rt := thema.NewRuntime(cuecontext.New())
+	for name, lin := range exemplars.All(rt) {
+		lin := lin
+		t.Run(name, func(t *testing.T) {
+			cfg := &LineageTypeConfig{RootName: "Lin", js: true}
+			f, err := GenerateLineageTypes(lin, cfg)
+			require.NoError(t, err)
+			mod := f.String() + "\nexport { detectLinVersion as detect };\n"
+
+			values := []interface{}{
+				map[string]interface{}{},
+				map[string]interface{}{"notafield": true},
+				"notastruct",
+			}
+			for sch := lin.First(); sch != nil; sch = sch.Successor() {
+				for _, example := range sch.Examples() {
+					var value map[string]interface{}
+					require.NoError(t, example.Underlying().Decode(&value))
+					values = append(values, value)
+
+					extra := map[string]interface{}{"notafield": true}
+					for k, v := range value {
+						extra[k] = v
+						values = append(values, map[string]interface{}{k: []int{42}})
+					}
+					values = append(values, extra)
+				}
+			}
+			vb, err := json.Marshal(values)
+			require.NoError(t, err)
+
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "types.mjs"), []byte(mod), 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "values.json"), vb, 0644))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "driver.mjs"), []byte(detectorDriver), 0644))
+			out, err := exec.Command(node, filepath.Join(dir, "driver.mjs")).CombinedOutput() //nolint:gosec
+			require.NoError(t, err, string(out))
+
+			var detected []*thema.SyntacticVersion
+			require.NoError(t, json.Unmarshal(out, &detected), string(out))
+			require.Len(t, detected, len(values))
+
+			for i, value := range values {
+				b, err := json.Marshal(value)
+				require.NoError(t, err)
+
+				var expected *thema.SyntacticVersion
+				if inst := lin.ValidateAny(rt.Context().CompileBytes(b)); inst != nil {
+					v := inst.Schema().Version()
+					expected = &v
+				}
+				assert.Equal(t, expected, detected[i], "detected version differs for %s", b)
+			}
+		})
+	}
+}
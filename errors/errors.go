@@ -1,6 +1,8 @@
 package errors
 
 import (
+	"fmt"
+
 	"github.com/cockroachdb/errors"
 )
 
@@ -26,11 +28,103 @@ const (
 	// ExcessField indicates a validation failure in which the schema is treated as
 	// closed, and the data contains a field not specified in the schema.
 	ExcessField
+
+	// Unclassified indicates a validation failure that does not fall into any
+	// of the other classes. The failure is described by the error's Message.
+	Unclassified
 )
 
-// ValidationError is a subtype of
+// String returns the name of the ValidationCode.
+func (c ValidationCode) String() string {
+	switch c {
+	case KindConflict:
+		return "KindConflict"
+	case OutOfBounds:
+		return "OutOfBounds"
+	case MissingField:
+		return "MissingField"
+	case ExcessField:
+		return "ExcessField"
+	case Unclassified:
+		return "Unclassified"
+	default:
+		return fmt.Sprintf("ValidationCode(%d)", uint16(c))
+	}
+}
+
+// MarshalText implements [encoding.TextMarshaler], so that ValidationCodes
+// are represented by their names in JSON.
+func (c ValidationCode) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// Err returns the sentinel error corresponding to the ValidationCode, such as
+// [ErrInvalidMissingField] for [MissingField].
+func (c ValidationCode) Err() error {
+	switch c {
+	case KindConflict:
+		return ErrInvalidKindConflict
+	case OutOfBounds:
+		return ErrInvalidOutOfBounds
+	case MissingField:
+		return ErrInvalidMissingField
+	case ExcessField:
+		return ErrInvalidExcessField
+	default:
+		return ErrInvalidData
+	}
+}
+
+// ValidationError describes a single way in which some data failed validation
+// against a schema. A failed validation may comprise several ValidationErrors,
+// which are returned by [ValidationErrors].
 type ValidationError struct {
-	msg string
+	// Code is the class of validation failure.
+	Code ValidationCode `json:"code"`
+
+	// DataPath is a JSON Pointer (RFC 6901) to the offending value within the
+	// data. For a missing field, it points to where the field was expected.
+	DataPath string `json:"dataPath"`
+
+	// SchemaPath is the CUE path to the offending field within the schema. It
+	// differs from DataPath where the data is constrained by a pattern, such
+	// as the elements of a list, which appear in the path as "[_]".
+	SchemaPath string `json:"schemaPath"`
+
+	// Expected is the value the schema specifies at the path, if any.
+	Expected string `json:"expected,omitempty"`
+
+	// Actual is the value the data contains at the path, if any.
+	Actual string `json:"actual,omitempty"`
+
+	// Message describes the failure, if it has the Unclassified code.
+	Message string `json:"message,omitempty"`
+
+	// SchemaPositions are the positions in the schema's source that specify
+	// the expected value.
+	SchemaPositions []Position `json:"schemaPositions,omitempty"`
+
+	// DataPositions are the positions in the data's source of the actual value.
+	DataPositions []Position `json:"dataPositions,omitempty"`
+}
+
+func (ve *ValidationError) Error() string {
+	path := ve.DataPath
+	if path == "" {
+		path = "/"
+	}
+	switch {
+	case ve.Message != "":
+		return fmt.Sprintf("%s: %s: %s", path, ve.Code.Err(), ve.Message)
+	case ve.Expected != "" && ve.Actual != "":
+		return fmt.Sprintf("%s: %s: schema expected `%s` but data contained `%s`", path, ve.Code.Err(), ve.Expected, ve.Actual)
+	case ve.Expected != "":
+		return fmt.Sprintf("%s: %s: schema expected `%s`", path, ve.Code.Err(), ve.Expected)
+	case ve.Actual != "":
+		return fmt.Sprintf("%s: %s: data contained `%s`", path, ve.Code.Err(), ve.Actual)
+	default:
+		return fmt.Sprintf("%s: %s", path, ve.Code.Err())
+	}
 }
 
 // Unwrap implements standard Go error unwrapping, relied on by errors.Is.
@@ -40,6 +134,38 @@ func (ve *ValidationError) Unwrap() error {
 	return ErrInvalidData
 }
 
+// Is reports whether target is the sentinel error corresponding to the
+// ValidationError's Code, such as [ErrInvalidExcessField].
+func (ve *ValidationError) Is(target error) bool {
+	return target == ve.Code.Err()
+}
+
+// Position is a position within a source file.
+type Position struct {
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+// ValidationErrors returns the individual [ValidationError]s that comprise a
+// validation failure, such as those returned from [thema.Schema.Validate]. If
+// err is not a validation failure, nil is returned.
+func ValidationErrors(err error) []*ValidationError {
+	var vf interface{ ValidationErrors() []*ValidationError }
+	if errors.As(err, &vf) {
+		return vf.ValidationErrors()
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return []*ValidationError{ve}
+	}
+	return nil
+}
+
 // Validation error codes/types
 var (
 	// ErrInvalidData is the general error that indicates some data failed validation
@@ -85,6 +211,40 @@ var (
 	// always indicates a problem with the lens as it is written, and as such is a
 	// child of ErrInvalidLens.
 	ErrLensResultIsInvalidData = errors.New("result of lens translation is not valid for target schema")
+
+	// ErrLacunaResolutionIsInvalidData indicates that the values returned by a
+	// lacuna resolver passed to [thema.Instance.Translate] produced a result that
+	// was not an instance of the target schema. Unlike the other translation
+	// errors, this indicates a problem with the resolver, not the lens.
+	ErrLacunaResolutionIsInvalidData = errors.New("result of lacuna resolution is not valid for target schema")
+)
+
+// Patch errors. These may be returned from [thema.ApplyPatch].
+var (
+	// ErrInvalidPatch indicates that a patch document is malformed, or
+	// contains an operation that cannot be applied to the instance being
+	// patched, such as removing a nonexistent field.
+	ErrInvalidPatch = errors.New("invalid patch")
+
+	// ErrPatchTestFailed indicates that a "test" operation in a JSON Patch
+	// document did not match the instance being patched. It is a child of
+	// ErrInvalidPatch.
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+)
+
+// Registry errors. These may be returned from the methods of [thema.Registry].
+var (
+	// ErrDuplicateLineage indicates that a lineage could not be registered
+	// because a lineage with the same name is already registered.
+	ErrDuplicateLineage = errors.New("lineage with same name is already registered")
+
+	// ErrLineageNotExist indicates that no lineage with a given name is
+	// registered.
+	ErrLineageNotExist = errors.New("no lineage registered with name")
+
+	// ErrNoDiscriminator indicates that data did not contain the fields used
+	// to identify the lineage, or schema, of which it is an instance.
+	ErrNoDiscriminator = errors.New("data does not identify its lineage")
 )
 
 // Lower level general errors
@@ -101,6 +261,16 @@ var (
 	// or more of the Thema invariants.
 	ErrInvalidLineage = errors.New("invalid lineage")
 
+	// ErrNotBackwardsCompatible indicates that a schema in a lineage is not
+	// backwards compatible with its predecessor, despite having the same major
+	// version. It is a child of ErrInvalidLineage.
+	ErrNotBackwardsCompatible = errors.New("schema is not backwards compatible with its predecessor")
+
+	// ErrNotBackwardsIncompatible indicates that the first schema in a major
+	// version is backwards compatible with its predecessor, and should instead
+	// be declared as a minor version. It is a child of ErrInvalidLineage.
+	ErrNotBackwardsIncompatible = errors.New("schema is backwards compatible with its predecessor, but has a new major version")
+
 	// ErrInvalidSchemasOrder indicates that schemas in a lineage are not ordered
 	// by version.
 	ErrInvalidSchemasOrder = errors.New("schemas in lineage are not ordered by version")
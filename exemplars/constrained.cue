@@ -0,0 +1,68 @@
+package exemplars
+
+import (
+	"strings"
+
+	"github.com/grafana/thema"
+)
+
+constrained: {
+	description: "A schema with constraints that the types of its fields cannot express, such as bounds, patterns, lengths and enumerations."
+	l:           thema.#Lineage & {
+		schemas: [{
+			version: [0, 0]
+			schema: {
+				#Dimension: {
+					length: number & >0
+					unit:   "mm" | "cm" | "m"
+				}
+
+				name:   string & strings.MinRunes(1) & strings.MaxRunes(16)
+				code:   =~"^[A-Z]{3}-[0-9]+$"
+				count:  int & >=1 & <=12
+				ratio?: number & >=0 & <1
+				status: "draft" | "published" | "archived"
+				tags: [...string & strings.MinRunes(2)]
+				size:    #Dimension
+				parts?: [...#Dimension]
+				labels?: [string]: string & !~"^internal:"
+				note:    string | null
+			}
+			examples: {
+				minimal: {
+					name:   "a"
+					code:   "ABC-1"
+					count:  1
+					status: "draft"
+					tags: []
+					size: {
+						length: 0.5
+						unit:   "mm"
+					}
+					note: null
+				}
+				full: {
+					name:   "sixteen-runes-ok"
+					code:   "XYZ-42"
+					count:  12
+					ratio:  0.25
+					status: "archived"
+					tags: ["ab", "cde"]
+					size: {
+						length: 10
+						unit:   "m"
+					}
+					parts: [{
+						length: 1
+						unit:   "cm"
+					}]
+					labels: {
+						team:  "core"
+						"a/b": "public:x"
+					}
+					note: "a note"
+				}
+			}
+		}]
+	}
+}
@@ -8,9 +8,17 @@ defaultchange: {
 		schemas: [{
 			version: [0, 0]
 			schema: aunion: *"foo" | "bar" | "baz"
+			examples: {
+				isDefault: aunion: "foo"
+				isNotDefault: aunion: "baz"
+			}
 		}, {
 			version: [1, 0]
 			schema: aunion: "foo" | *"bar" | "baz"
+			examples: {
+				isDefault: aunion: "bar"
+				isNotDefault: aunion: "baz"
+			}
 		}]
 
 		lenses: [{
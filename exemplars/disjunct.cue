@@ -14,6 +14,10 @@ disjunct: {
 					branchtwo: string
 				}
 			}
+			examples: one: rootfield: {
+				branch:    1
+				branchone: "foo"
+			}
 		}, {
 			version: [0, 1]
 			schema: {
@@ -32,6 +36,16 @@ disjunct: {
 					}
 				}
 			}
+			examples: {
+				one: rootfield: {
+					branch:    1
+					branchone: "foo"
+				}
+				three: rootfield: {
+					branch: 3
+					branchthree: branchthreeinner: 42
+				}
+			}
 		}]
 
 		lenses: [{
@@ -37,12 +37,13 @@ func All(rt *thema.Runtime) map[string]thema.Lineage {
 }
 
 var nameOpts = map[string][]thema.BindOption{
-	"defaultchange": {thema.SkipBuggyChecks()},
-	"disjunct":      {thema.SkipBuggyChecks()},
+	"defaultchange": {},
+	"disjunct":      {},
 	"narrowing":     {},
 	"rename":        {},
 	"expand":        {},
 	"single":        {},
+	"constrained":   {},
 }
 
 func buildAll(ctx *cue.Context) cue.Value {
@@ -143,10 +144,6 @@ func harnessForExemplar(name string, rt *thema.Runtime) cue.Value {
 
 // Build a Lineage representing a single exemplar.
 func lineageForExemplar(name string, rt *thema.Runtime, o ...thema.BindOption) (thema.Lineage, error) {
-	switch name {
-	case "defaultchange", "narrowing", "rename":
-		o = append(o, thema.SkipBuggyChecks())
-	}
 	return thema.BindLineage(harnessForExemplar(name, rt), rt, o...)
 }
 
@@ -10,6 +10,7 @@ expand: {
 			schema: {
 				init: string
 			}
+			examples: simple: init: "foo"
 		},
 			{
 				version: [0, 1]
@@ -17,6 +18,13 @@ expand: {
 					init:      string
 					optional?: int
 				}
+				examples: {
+					withoutOptional: init: "foo"
+					withOptional: {
+						init:     "foo"
+						optional: 42
+					}
+				}
 			},
 			{
 				version: [0, 2]
@@ -25,6 +33,14 @@ expand: {
 					optional?:    int
 					withDefault?: *"foo" | "bar"
 				}
+				examples: {
+					withoutOptional: init: "foo"
+					withOptional: {
+						init:        "foo"
+						optional:    42
+						withDefault: "bar"
+					}
+				}
 			},
 			{
 				version: [0, 3]
@@ -33,6 +49,14 @@ expand: {
 					optional?:    int
 					withDefault?: *"foo" | "bar" | "baz"
 				}
+				examples: {
+					withoutOptional: init: "foo"
+					withOptional: {
+						init:        "foo"
+						optional:    42
+						withDefault: "baz"
+					}
+				}
 			}]
 
 		lenses: [{
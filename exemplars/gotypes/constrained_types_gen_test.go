@@ -0,0 +1,202 @@
+package gotypes
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
+)
+
+// Defines values for DimensionV0_0Unit.
+const (
+	DimensionV0_0UnitCm DimensionV0_0Unit = "cm"
+	DimensionV0_0UnitM  DimensionV0_0Unit = "m"
+	DimensionV0_0UnitMm DimensionV0_0Unit = "mm"
+)
+
+// Defines values for ConstrainedV0_0Status.
+const (
+	ConstrainedV0_0StatusArchived  ConstrainedV0_0Status = "archived"
+	ConstrainedV0_0StatusDraft     ConstrainedV0_0Status = "draft"
+	ConstrainedV0_0StatusPublished ConstrainedV0_0Status = "published"
+)
+
+// DimensionV0_0 defines model for DimensionV0_0.
+type DimensionV0_0 struct {
+	Length float32           `json:"length"`
+	Unit   DimensionV0_0Unit `json:"unit"`
+}
+
+// DimensionV0_0Unit defines model for DimensionV0_0.Unit.
+type DimensionV0_0Unit string
+
+// ConstrainedV0_0 defines model for constrainedV0_0.
+type ConstrainedV0_0 struct {
+	Code   string                `json:"code"`
+	Count  int                   `json:"count"`
+	Labels map[string]string     `json:"labels,omitempty"`
+	Name   string                `json:"name"`
+	Note   *string               `json:"note"`
+	Parts  []DimensionV0_0       `json:"parts,omitempty"`
+	Ratio  *float32              `json:"ratio,omitempty"`
+	Size   DimensionV0_0         `json:"size"`
+	Status ConstrainedV0_0Status `json:"status"`
+	Tags   []string              `json:"tags"`
+}
+
+// ConstrainedV0_0Status defines model for ConstrainedV0_0.Status.
+type ConstrainedV0_0Status string
+
+// ConstrainedTypes maps the version of each schema in the 'constrained' lineage to the
+// Go type generated for it.
+var ConstrainedTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ConstrainedV0_0{}),
+}
+
+// Validate checks that the DimensionV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x DimensionV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x DimensionV0_0) validateAt(path string) error {
+	if x.Length <= 0 {
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/length",
+			Expected: ">0",
+			Actual:   strconv.FormatFloat(float64(x.Length), 'g', -1, 32),
+		}
+	}
+	switch x.Unit {
+	case "mm", "cm", "m":
+	default:
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/unit",
+			Expected: "\"mm\" | \"cm\" | \"m\"",
+			Actual:   strconv.Quote(string(x.Unit)),
+		}
+	}
+	return nil
+}
+
+// Validate checks that the ConstrainedV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x ConstrainedV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x ConstrainedV0_0) validateAt(path string) error {
+	if !constrainedV0_0Pattern0.MatchString(string(x.Code)) {
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/code",
+			Expected: "=~\"^[A-Z]{3}-[0-9]+$\"",
+			Actual:   strconv.Quote(string(x.Code)),
+		}
+	}
+	if int64(x.Count) < 1 {
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/count",
+			Expected: ">=1",
+			Actual:   strconv.FormatInt(int64(x.Count), 10),
+		}
+	}
+	if int64(x.Count) > 12 {
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/count",
+			Expected: "<=12",
+			Actual:   strconv.FormatInt(int64(x.Count), 10),
+		}
+	}
+	if len(x.Labels) != 0 {
+		for k1, v1 := range x.Labels {
+			if constrainedV0_0Pattern1.MatchString(string(v1)) {
+				return &terrors.ValidationError{
+					Code:     terrors.OutOfBounds,
+					DataPath: path + "/labels/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(k1),
+					Expected: "!~\"^internal:\"",
+					Actual:   strconv.Quote(string(v1)),
+				}
+			}
+		}
+	}
+	if utf8.RuneCountInString(string(x.Name)) < 1 {
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/name",
+			Expected: "strings.MinRunes(1)",
+			Actual:   strconv.Quote(string(x.Name)),
+		}
+	}
+	if utf8.RuneCountInString(string(x.Name)) > 16 {
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/name",
+			Expected: "strings.MaxRunes(16)",
+			Actual:   strconv.Quote(string(x.Name)),
+		}
+	}
+	if len(x.Parts) != 0 {
+		for i1, v1 := range x.Parts {
+			if err := v1.validateAt(path + "/parts/" + strconv.Itoa(i1)); err != nil {
+				return err
+			}
+		}
+	}
+	if x.Ratio != nil {
+		if *x.Ratio < 0 {
+			return &terrors.ValidationError{
+				Code:     terrors.OutOfBounds,
+				DataPath: path + "/ratio",
+				Expected: ">=0",
+				Actual:   strconv.FormatFloat(float64(*x.Ratio), 'g', -1, 32),
+			}
+		}
+		if *x.Ratio >= 1 {
+			return &terrors.ValidationError{
+				Code:     terrors.OutOfBounds,
+				DataPath: path + "/ratio",
+				Expected: "<1",
+				Actual:   strconv.FormatFloat(float64(*x.Ratio), 'g', -1, 32),
+			}
+		}
+	}
+	if err := x.Size.validateAt(path + "/size"); err != nil {
+		return err
+	}
+	switch x.Status {
+	case "draft", "published", "archived":
+	default:
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/status",
+			Expected: "\"draft\" | \"published\" | \"archived\"",
+			Actual:   strconv.Quote(string(x.Status)),
+		}
+	}
+	for i1, v1 := range x.Tags {
+		if utf8.RuneCountInString(string(v1)) < 2 {
+			return &terrors.ValidationError{
+				Code:     terrors.OutOfBounds,
+				DataPath: path + "/tags/" + strconv.Itoa(i1),
+				Expected: "strings.MinRunes(2)",
+				Actual:   strconv.Quote(string(v1)),
+			}
+		}
+	}
+	return nil
+}
+
+var constrainedV0_0Pattern0 = regexp.MustCompile("^[A-Z]{3}-[0-9]+$")
+
+var constrainedV0_0Pattern1 = regexp.MustCompile("^internal:")
@@ -0,0 +1,88 @@
+package gotypes
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
+)
+
+// Defines values for DefaultchangeV0_0Aunion.
+const (
+	DefaultchangeV0_0AunionBar DefaultchangeV0_0Aunion = "bar"
+	DefaultchangeV0_0AunionBaz DefaultchangeV0_0Aunion = "baz"
+	DefaultchangeV0_0AunionFoo DefaultchangeV0_0Aunion = "foo"
+)
+
+// Defines values for DefaultchangeV1_0Aunion.
+const (
+	DefaultchangeV1_0AunionBar DefaultchangeV1_0Aunion = "bar"
+	DefaultchangeV1_0AunionBaz DefaultchangeV1_0Aunion = "baz"
+	DefaultchangeV1_0AunionFoo DefaultchangeV1_0Aunion = "foo"
+)
+
+// DefaultchangeV0_0 defines model for defaultchangeV0_0.
+type DefaultchangeV0_0 struct {
+	Aunion DefaultchangeV0_0Aunion `json:"aunion"`
+}
+
+// DefaultchangeV0_0Aunion defines model for DefaultchangeV0_0.Aunion.
+type DefaultchangeV0_0Aunion string
+
+// DefaultchangeV1_0 defines model for defaultchangeV1_0.
+type DefaultchangeV1_0 struct {
+	Aunion DefaultchangeV1_0Aunion `json:"aunion"`
+}
+
+// DefaultchangeV1_0Aunion defines model for DefaultchangeV1_0.Aunion.
+type DefaultchangeV1_0Aunion string
+
+// DefaultchangeTypes maps the version of each schema in the 'defaultchange' lineage to the
+// Go type generated for it.
+var DefaultchangeTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(DefaultchangeV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(DefaultchangeV1_0{}),
+}
+
+// Validate checks that the DefaultchangeV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x DefaultchangeV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x DefaultchangeV0_0) validateAt(path string) error {
+	switch x.Aunion {
+	case "foo", "bar", "baz":
+	default:
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/aunion",
+			Expected: "\"foo\" | \"bar\" | \"baz\"",
+			Actual:   strconv.Quote(string(x.Aunion)),
+		}
+	}
+	return nil
+}
+
+// Validate checks that the DefaultchangeV1_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x DefaultchangeV1_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x DefaultchangeV1_0) validateAt(path string) error {
+	switch x.Aunion {
+	case "bar", "foo", "baz":
+	default:
+		return &terrors.ValidationError{
+			Code:     terrors.OutOfBounds,
+			DataPath: path + "/aunion",
+			Expected: "\"bar\" | \"foo\" | \"baz\"",
+			Actual:   strconv.Quote(string(x.Aunion)),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package gotypes
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
+)
+
+// DisjunctV0_0 defines model for disjunctV0_0.
+type DisjunctV0_0 struct {
+	Rootfield any `json:"rootfield"`
+}
+
+// DisjunctV0_1 defines model for disjunctV0_1.
+type DisjunctV0_1 struct {
+	Rootfield any `json:"rootfield"`
+}
+
+// DisjunctTypes maps the version of each schema in the 'disjunct' lineage to the
+// Go type generated for it.
+var DisjunctTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(DisjunctV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(DisjunctV0_1{}),
+}
+
+// Validate checks that the DisjunctV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x DisjunctV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x DisjunctV0_0) validateAt(path string) error {
+	if x.Rootfield == nil {
+		return &terrors.ValidationError{
+			Code:     terrors.MissingField,
+			DataPath: path + "/rootfield",
+		}
+	}
+	return nil
+}
+
+// Validate checks that the DisjunctV0_1 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x DisjunctV0_1) Validate() error {
+	return x.validateAt("")
+}
+
+func (x DisjunctV0_1) validateAt(path string) error {
+	if x.Rootfield == nil {
+		return &terrors.ValidationError{
+			Code:     terrors.MissingField,
+			DataPath: path + "/rootfield",
+		}
+	}
+	return nil
+}
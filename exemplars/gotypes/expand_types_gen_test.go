@@ -0,0 +1,130 @@
+package gotypes
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
+)
+
+// Defines values for ExpandV0_2WithDefault.
+const (
+	ExpandV0_2WithDefaultBar ExpandV0_2WithDefault = "bar"
+	ExpandV0_2WithDefaultFoo ExpandV0_2WithDefault = "foo"
+)
+
+// Defines values for ExpandV0_3WithDefault.
+const (
+	ExpandV0_3WithDefaultBar ExpandV0_3WithDefault = "bar"
+	ExpandV0_3WithDefaultBaz ExpandV0_3WithDefault = "baz"
+	ExpandV0_3WithDefaultFoo ExpandV0_3WithDefault = "foo"
+)
+
+// ExpandV0_0 defines model for expandV0_0.
+type ExpandV0_0 struct {
+	Init string `json:"init"`
+}
+
+// ExpandV0_1 defines model for expandV0_1.
+type ExpandV0_1 struct {
+	Init     string `json:"init"`
+	Optional *int   `json:"optional,omitempty"`
+}
+
+// ExpandV0_2 defines model for expandV0_2.
+type ExpandV0_2 struct {
+	Init        string                 `json:"init"`
+	Optional    *int                   `json:"optional,omitempty"`
+	WithDefault *ExpandV0_2WithDefault `json:"withDefault,omitempty"`
+}
+
+// ExpandV0_2WithDefault defines model for ExpandV0_2.WithDefault.
+type ExpandV0_2WithDefault string
+
+// ExpandV0_3 defines model for expandV0_3.
+type ExpandV0_3 struct {
+	Init        string                 `json:"init"`
+	Optional    *int                   `json:"optional,omitempty"`
+	WithDefault *ExpandV0_3WithDefault `json:"withDefault,omitempty"`
+}
+
+// ExpandV0_3WithDefault defines model for ExpandV0_3.WithDefault.
+type ExpandV0_3WithDefault string
+
+// ExpandTypes maps the version of each schema in the 'expand' lineage to the
+// Go type generated for it.
+var ExpandTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(ExpandV0_0{}),
+	thema.SV(0, 1): reflect.TypeOf(ExpandV0_1{}),
+	thema.SV(0, 2): reflect.TypeOf(ExpandV0_2{}),
+	thema.SV(0, 3): reflect.TypeOf(ExpandV0_3{}),
+}
+
+// Validate checks that the ExpandV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x ExpandV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x ExpandV0_0) validateAt(path string) error {
+	return nil
+}
+
+// Validate checks that the ExpandV0_1 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x ExpandV0_1) Validate() error {
+	return x.validateAt("")
+}
+
+func (x ExpandV0_1) validateAt(path string) error {
+	return nil
+}
+
+// Validate checks that the ExpandV0_2 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x ExpandV0_2) Validate() error {
+	return x.validateAt("")
+}
+
+func (x ExpandV0_2) validateAt(path string) error {
+	if x.WithDefault != nil {
+		switch *x.WithDefault {
+		case "foo", "bar":
+		default:
+			return &terrors.ValidationError{
+				Code:     terrors.OutOfBounds,
+				DataPath: path + "/withDefault",
+				Expected: "\"foo\" | \"bar\"",
+				Actual:   strconv.Quote(string(*x.WithDefault)),
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that the ExpandV0_3 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x ExpandV0_3) Validate() error {
+	return x.validateAt("")
+}
+
+func (x ExpandV0_3) validateAt(path string) error {
+	if x.WithDefault != nil {
+		switch *x.WithDefault {
+		case "foo", "bar", "baz":
+		default:
+			return &terrors.ValidationError{
+				Code:     terrors.OutOfBounds,
+				DataPath: path + "/withDefault",
+				Expected: "\"foo\" | \"bar\" | \"baz\"",
+				Actual:   strconv.Quote(string(*x.WithDefault)),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package gotypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/thema"
+	"github.com/grafana/thema/encoding/gocode"
+	terrors "github.com/grafana/thema/errors"
+	"github.com/grafana/thema/exemplars"
+	"github.com/grafana/thema/internal/envvars"
+)
+
+// registries contains the type registries generated for each exemplar lineage
+// by TestGoTypesUpToDate.
+var registries = map[string]thema.TypeRegistry{
+	"constrained":   ConstrainedTypes,
+	"defaultchange": DefaultchangeTypes,
+	"disjunct":      DisjunctTypes,
+	"expand":        ExpandTypes,
+	"narrowing":     NarrowingTypes,
+	"rename":        RenameTypes,
+	"single":        SingleTypes,
+}
+
+// fixtures contains data, in addition to the examples in each exemplar
+// lineage, against which the generated Validate methods are checked. Each is
+// checked against every schema in the lineage.
+var fixtures = map[string][]string{
+	"constrained": {
+		`{}`,
+		`{"name": "", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "seventeen-runes-x", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "sixteen-rünes-ok", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "abc-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 0, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 13, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "ratio": 0, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "ratio": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "ratio": -0.5, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "deleted", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": ["ab", "c"], "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "size": {"length": 1, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 0, "unit": "mm"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "km"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "parts": [], "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "parts": [{"length": 1, "unit": "m"}, {"length": -1, "unit": "m"}], "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "labels": {"x/y": "internal:secret"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "labels": {"x": "external:internal:"}, "note": null}`,
+		`{"name": "a", "code": "ABC-1", "count": 1, "status": "draft", "tags": [], "size": {"length": 1, "unit": "mm"}, "note": "b"}`,
+	},
+	"expand": {
+		`{}`,
+		`{"init": "foo", "withDefault": "bar"}`,
+		`{"init": "foo", "withDefault": "baz"}`,
+		`{"init": "foo", "withDefault": "bing"}`,
+	},
+	"narrowing": {
+		`{"properbool": true}`,
+		`{"boolish": "true"}`,
+		`{"boolish": "yes"}`,
+	},
+}
+
+// unchecked contains the exemplar lineages with constraints that the generated
+// Validate methods do not check, and why. Invalid data is skipped for these.
+var unchecked = map[string]string{
+	"disjunct": "disjunctions are represented as any, and not checked",
+}
+
+func TestGoTypesUpToDate(t *testing.T) {
+	for name, lin := range exemplars.All(thema.NewRuntime(cuecontext.New())) {
+		path := fmt.Sprintf("%s_types_gen_test.go", name)
+		t.Run(name, func(t *testing.T) {
+			b, err := gocode.GenerateLineageTypesOpenAPI(lin, &gocode.LineageTypeConfigOpenAPI{
+				TypeConfigOpenAPI: gocode.TypeConfigOpenAPI{
+					PackageName:     "gotypes",
+					ValidateMethods: true,
+				},
+			})
+			require.NoError(t, err)
+
+			if envvars.UpdateGoldenFiles {
+				require.NoError(t, os.WriteFile(path, b, 0644)) //nolint:gosec
+				return
+			}
+			existing, err := os.ReadFile(path) //nolint:gosec
+			require.NoError(t, err)
+			if string(existing) != string(b) {
+				t.Fatalf("%s is out of date, run tests with %s=1 to regenerate it", path, envvars.VarUpdateGolden)
+			}
+		})
+	}
+}
+
+// TestValidateConformance checks that the generated Validate methods agree with
+// [thema.Schema.Validate] about the validity of the examples in each exemplar
+// lineage, and the fixtures, against every schema in the lineage.
+//
+// The original data is checked by [thema.Schema.Validate], and the value it
+// decodes to by the Validate method. The Go value cannot record whether a
+// required field of a non-pointer type was present, nor hold fields that are
+// not in the schema, so data that is invalid only because of such fields is
+// valid to the Validate method, as long as the value is valid once encoded
+// back to JSON.
+func TestValidateConformance(t *testing.T) {
+	ctx := cuecontext.New()
+	for name, lin := range exemplars.All(thema.NewRuntime(ctx)) {
+		data := fixtures[name]
+		for sch := lin.First(); sch != nil; sch = sch.Successor() {
+			for _, example := range sch.Examples() {
+				b, err := example.Underlying().MarshalJSON()
+				require.NoError(t, err)
+				data = append(data, string(b))
+			}
+		}
+
+		for sch := lin.First(); sch != nil; sch = sch.Successor() {
+			for _, d := range data {
+				sch, d := sch, d
+				t.Run(fmt.Sprintf("%s/%s/%s", name, sch.Version(), d), func(t *testing.T) {
+					v, err := registries[name].New(sch.Version())
+					require.NoError(t, err)
+					if err = json.Unmarshal([]byte(d), v); err != nil {
+						t.Skipf("not representable by %T: %s", v, err)
+					}
+
+					_, cueerr := sch.Validate(ctx.CompileString(d))
+					goerr := v.(interface{ Validate() error }).Validate()
+					if cueerr == nil {
+						assert.NoError(t, goerr, "%s is valid", d)
+						return
+					}
+					if reason, has := unchecked[name]; has {
+						t.Skip(reason)
+					}
+
+					var paths []string
+					unrepresentable := true
+					for _, cve := range terrors.ValidationErrors(cueerr) {
+						paths = append(paths, cve.DataPath)
+						unrepresentable = unrepresentable && (cve.Code == terrors.MissingField || cve.Code == terrors.ExcessField)
+					}
+					if goerr == nil && unrepresentable {
+						b, err := json.Marshal(v)
+						require.NoError(t, err)
+						_, cueerr = sch.Validate(ctx.CompileBytes(b))
+						require.NoError(t, cueerr, "%s is valid to Validate, but %s is not", d, b)
+						t.Skipf("absent non-pointer and excess fields are not represented by %T: %v", v, paths)
+					}
+					require.Error(t, goerr, "%s is invalid: %s", d, cueerr)
+					require.True(t, errors.Is(goerr, terrors.ErrInvalidData))
+
+					var ve *terrors.ValidationError
+					require.True(t, errors.As(goerr, &ve))
+					assert.Contains(t, paths, ve.DataPath, "%s is invalid: %s", d, cueerr)
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package gotypes
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+	terrors "github.com/grafana/thema/errors"
+)
+
+// NarrowingV0_0 defines model for narrowingV0_0.
+type NarrowingV0_0 struct {
+	Boolish any `json:"boolish"`
+}
+
+// NarrowingV1_0 defines model for narrowingV1_0.
+type NarrowingV1_0 struct {
+	Properbool bool `json:"properbool"`
+}
+
+// NarrowingTypes maps the version of each schema in the 'narrowing' lineage to the
+// Go type generated for it.
+var NarrowingTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(NarrowingV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(NarrowingV1_0{}),
+}
+
+// Validate checks that the NarrowingV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x NarrowingV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x NarrowingV0_0) validateAt(path string) error {
+	if x.Boolish == nil {
+		return &terrors.ValidationError{
+			Code:     terrors.MissingField,
+			DataPath: path + "/boolish",
+		}
+	}
+	return nil
+}
+
+// Validate checks that the NarrowingV1_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x NarrowingV1_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x NarrowingV1_0) validateAt(path string) error {
+	return nil
+}
@@ -0,0 +1,48 @@
+package gotypes
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// RenameV0_0 defines model for renameV0_0.
+type RenameV0_0 struct {
+	Before    string `json:"before"`
+	Unchanged string `json:"unchanged"`
+}
+
+// RenameV1_0 defines model for renameV1_0.
+type RenameV1_0 struct {
+	After     string `json:"after"`
+	Unchanged string `json:"unchanged"`
+}
+
+// RenameTypes maps the version of each schema in the 'rename' lineage to the
+// Go type generated for it.
+var RenameTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(RenameV0_0{}),
+	thema.SV(1, 0): reflect.TypeOf(RenameV1_0{}),
+}
+
+// Validate checks that the RenameV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x RenameV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x RenameV0_0) validateAt(path string) error {
+	return nil
+}
+
+// Validate checks that the RenameV1_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x RenameV1_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x RenameV1_0) validateAt(path string) error {
+	return nil
+}
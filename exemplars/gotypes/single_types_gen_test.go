@@ -0,0 +1,31 @@
+package gotypes
+
+import (
+	"reflect"
+
+	"github.com/grafana/thema"
+)
+
+// SingleV0_0 defines model for singleV0_0.
+type SingleV0_0 struct {
+	Abool   bool   `json:"abool"`
+	Anint   int    `json:"anint"`
+	Astring string `json:"astring"`
+}
+
+// SingleTypes maps the version of each schema in the 'single' lineage to the
+// Go type generated for it.
+var SingleTypes = thema.TypeRegistry{
+	thema.SV(0, 0): reflect.TypeOf(SingleV0_0{}),
+}
+
+// Validate checks that the SingleV0_0 satisfies the constraints of the schema it was
+// generated from, without relying on CUE. If it does not, the returned error
+// is a *errors.ValidationError describing the first violation found.
+func (x SingleV0_0) Validate() error {
+	return x.validateAt("")
+}
+
+func (x SingleV0_0) validateAt(path string) error {
+	return nil
+}
@@ -8,9 +8,18 @@ narrowing: {
 		schemas: [{
 			version: [0, 0]
 			schema: boolish: "true" | "false" | bool | string
+			examples: {
+				boolString: boolish: "true"
+				otherString: boolish: "yes"
+				realBool: boolish: false
+			}
 		}, {
 			version: [1, 0]
 			schema: properbool: bool
+			examples: {
+				isTrue: properbool: true
+				isFalse: properbool: false
+			}
 		}]
 
 		lenses: [{
@@ -11,12 +11,20 @@ rename: {
 				before:    string
 				unchanged: string
 			}
+			examples: simple: {
+				before:    "foo"
+				unchanged: "bar"
+			}
 		}, {
 			version: [1, 0]
 			schema: {
 				after:     string
 				unchanged: string
 			}
+			examples: simple: {
+				after:     "foo"
+				unchanged: "bar"
+			}
 		}]
 
 		lenses: [{
@@ -12,6 +12,11 @@ single: {
 				anint:   int
 				abool:   bool
 			}
+			examples: simple: {
+				astring: "foo"
+				anint:   42
+				abool:   true
+			}
 		}]
 
 		lenses: []
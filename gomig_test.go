@@ -287,7 +287,7 @@ schemas: [{
 			t.Run(tname, func(t *testing.T) {
 				tinst, lacunas, err := tex.Translate(end.Version())
 				require.NoError(t, err)
-				assert.Nil(t, lacunas, "pure go migrations cannot emit lacunas")
+				assert.Nil(t, lacunas, "Mapper funcs cannot emit lacunas")
 
 				b, err := tinst.Underlying().MarshalJSON()
 				require.NoError(t, err)
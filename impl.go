@@ -2,23 +2,67 @@ package thema
 
 import (
 	"fmt"
+	"strings"
 
 	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/errors"
 )
 
-type compatInvariantError struct {
-	rawlin    cue.Value
-	violation [2]SyntacticVersion
-	detail    error
+// CompatError is the error returned from [BindLineage] when a schema violates
+// Thema's backwards compatibility invariants with respect to its predecessor
+// in the lineage:
+//
+//   - A schema with a non-zero minor version must be backwards compatible with
+//     its predecessor.
+//   - A schema with a zero minor version - the first in a new major version -
+//     must be backwards incompatible with its predecessor.
+//
+// Use errors.As to retrieve a CompatError from an error returned by BindLineage.
+type CompatError struct {
+	// Prev is the version of the predecessor schema.
+	Prev SyntacticVersion
+
+	// Next is the version of the schema that violates the invariant.
+	Next SyntacticVersion
+
+	// Paths are the paths to the fields in the Next schema that are backwards
+	// incompatible with the Prev schema. Always empty when Next is the first
+	// schema in a major version, as the problem is then that no field is
+	// incompatible.
+	//
+	// Changes to the pattern constraints of a struct, or to the elements of an
+	// open list, are reported at the path of the struct or list, followed by
+	// [cue.AnyString] or [cue.AnyIndex] respectively.
+	Paths []cue.Path
+
+	detail error
+}
+
+// Major reports whether the error is the result of a new major version
+// schema being backwards compatible with its predecessor, rather than a new
+// minor version being backwards incompatible.
+func (e *CompatError) Major() bool {
+	return e.Next[1] == 0
 }
 
-func (e *compatInvariantError) Error() string {
-	if e.violation[0][0] == e.violation[1][0] {
-		// TODO better
-		return errors.Details(e.detail, nil)
+func (e *CompatError) Error() string {
+	if e.Major() {
+		return fmt.Sprintf("schema %s must be backwards incompatible with schema %s: introduce a breaking change, or redeclare as version %s", e.Next, e.Prev, synv(e.Prev[0], e.Prev[1]+1))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "schema %s is not backwards compatible with schema %s", e.Next, e.Prev)
+	if len(e.Paths) > 0 {
+		strs := make([]string, 0, len(e.Paths))
+		for _, p := range e.Paths {
+			strs = append(strs, p.String())
+		}
+		fmt.Fprintf(&b, " (incompatible fields: %s)", strings.Join(strs, ", "))
+	}
+	if e.detail != nil {
+		fmt.Fprintf(&b, ":\n%s", errors.Details(e.detail, nil))
 	}
-	return fmt.Sprintf("schema %s must be backwards incompatible with schema %s", e.violation[1], e.violation[0])
+	return b.String()
 }
 
 // Call with no args to get init v, {0, 0}
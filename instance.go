@@ -5,6 +5,7 @@ import (
 
 	"cuelang.org/go/cue"
 	cerrors "cuelang.org/go/cue/errors"
+	cuejson "cuelang.org/go/encoding/json"
 	"cuelang.org/go/pkg/encoding/json"
 	"github.com/cockroachdb/errors"
 
@@ -59,8 +60,7 @@ func (i *Instance) check() {
 func (i *Instance) Hydrate() *Instance {
 	i.check()
 
-	i.sch.Lineage().Runtime()
-	ni, err := doHydrate(i.sch.Underlying(), i.raw)
+	ni, err := doHydrate(i.sch.Underlying().LookupPath(pathSchDef), i.raw)
 	// FIXME For now, just no-op it if we error
 	if err != nil {
 		return i
@@ -82,7 +82,7 @@ func (i *Instance) Hydrate() *Instance {
 func (i *Instance) Dehydrate() *Instance {
 	i.check()
 
-	ni, _, err := doDehydrate(i.sch.Underlying(), i.raw)
+	ni, _, err := doDehydrate(i.sch.Underlying().LookupPath(pathSchDef), i.raw)
 	// FIXME For now, just no-op it if we error
 	if err != nil {
 		return i
@@ -203,54 +203,157 @@ func (inst *TypedInstance[T]) ValueP() T {
 //
 // Errors only occur in cases where lenses were written in an unexpected way -
 // for example, not all fields were mapped over, and the resulting object is not
-// concrete. All errors returned from this func will children of [terrors.ErrInvalidLens].
-func (i *Instance) Translate(to SyntacticVersion) (*Instance, TranslationLacunas, error) {
+// concrete. All errors returned from this func will children of [terrors.ErrInvalidLens],
+// except those arising from lacuna resolvers.
+//
+// Lacunas may be resolved during translation by passing [ResolveLacunaType]
+// or [ResolveLacunaPath] options. Values returned by resolvers replace those
+// in the translated result, which is then revalidated against the target
+// schema, and only the lacunas that remain unresolved are returned. If the
+// resolved result is invalid, the returned error is a child of
+// [terrors.ErrLacunaResolutionIsInvalidData].
+func (i *Instance) Translate(to SyntacticVersion, opts ...TranslateOption) (*Instance, TranslationLacunas, error) {
 	i.check()
 
-	if len(i.Schema().Lineage().(*baseLineage).lensmap) > 0 {
-		return i.translateGo(to)
+	tr, err := newTranslator(i.Schema().Lineage().(*baseLineage), i.Schema().Version(), to)
+	if err != nil {
+		// TODO return an error instead, marked with ErrVersionNotExist
+		panic(fmt.Sprintf("no schema in lineage with version %v, cannot translate", to))
 	}
+	tinst, lac, err := tr.translate(i)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newTranslateConfig(opts).resolve(i, tinst, lac)
+}
 
-	// TODO define this in terms of AsSuccessor and AsPredecessor, rather than those in terms of this.
-	newsch, err := i.Schema().Lineage().Schema(to)
+// translator holds everything needed to translate instances from one schema
+// in a lineage to another that does not depend on the instance itself, so that
+// it can be prepared once and reused across many translations.
+type translator struct {
+	from, to SyntacticVersion
+
+	newsch Schema
+
+	// The #Translate CUE func, with all arguments other than inst already
+	// applied. Unused if the lineage has Go lenses.
+	fn cue.Value
+
+	golens bool
+}
+
+func newTranslator(lin *baseLineage, from, to SyntacticVersion) (*translator, error) {
+	newsch, err := lin.Schema(to)
 	if err != nil {
-		panic(fmt.Sprintf("no schema in lineage with version %v, cannot translate", to))
+		return nil, err
+	}
+
+	tr := &translator{
+		from:   from,
+		to:     to,
+		newsch: newsch,
+		golens: len(lin.lensmap) > 0,
+	}
+	if tr.golens {
+		return tr, nil
 	}
 
-	out, err := cueArgs{
-		"inst": i.raw,
+	// TODO define this in terms of AsSuccessor and AsPredecessor, rather than those in terms of this.
+	tr.fn, err = cueArgs{
 		"to":   to,
-		"from": i.Schema().Version(),
-		"lin":  i.Schema().Lineage().Underlying(),
-	}.call("#Translate", i.rt())
+		"from": from,
+		"lin":  lin.Underlying(),
+	}.make("#Translate", lin.rt)
 	if err != nil {
 		// This can't happen without a name change or an invariant violation
 		panic(err)
 	}
+	return tr, nil
+}
+
+// translate translates the provided instance, which must be an instance of the
+// translator's from schema.
+func (tr *translator) translate(i *Instance) (*Instance, TranslationLacunas, error) {
+	if tr.golens {
+		return i.translateGo(tr.to)
+	}
+
+	raw, lac, err := tr.evaluate(i)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Ensure the result is a valid instance of the target schema
+	inst, err := tr.newsch.Validate(raw)
+	if err != nil {
+		return nil, nil, errors.Mark(err, terrors.ErrLensResultIsInvalidData)
+	}
+	return inst, lac, err
+}
+
+// evaluate runs the #Translate CUE func on the provided instance, returning the
+// concrete result and any lacunas emitted by lenses. All evaluation of values
+// derived from the lineage happens here, under the Runtime's lock.
+func (tr *translator) evaluate(i *Instance) (cue.Value, TranslationLacunas, error) {
+	rt := i.rt()
+	rt.l()
+	defer rt.u()
+
+	fn := tr.fn.FillPath(cue.MakePath(cue.Str("inst")), i.raw)
+	if err := fn.LookupPath(cue.MakePath(cue.Str("inst"))).Err(); err != nil {
+		// This can't happen without a name change or an invariant violation
+		panic(&errInvalidCUEFuncArg{
+			cuefunc: "#Translate",
+			argpath: "inst",
+			err:     err,
+		})
+	}
+	out := fn.LookupPath(outpath)
 
 	if out.Err() != nil {
-		return nil, nil, errors.Mark(out.Err(), terrors.ErrInvalidLens)
+		return cue.Value{}, nil, errors.Mark(out.Err(), terrors.ErrInvalidLens)
 	}
 
 	lac := make(multiTranslationLacunas, 0)
-	out.LookupPath(cue.MakePath(cue.Str("lacunas"))).Decode(&lac)
+	iter, err := out.LookupPath(cue.MakePath(cue.Str("steps"))).List()
+	if err != nil {
+		return cue.Value{}, nil, errors.Mark(err, terrors.ErrInvalidLens)
+	}
+	for iter.Next() {
+		step := iter.Value()
+		var item struct {
+			V   SyntacticVersion `json:"v"`
+			Lac []Lacuna         `json:"lacunas"`
+		}
+		if err := step.LookupPath(cue.MakePath(cue.Str("to"))).Decode(&item.V); err != nil {
+			return cue.Value{}, nil, errors.Mark(err, terrors.ErrInvalidLens)
+		}
+		if err := step.LookupPath(cue.MakePath(cue.Str("lacunas"))).Decode(&item.Lac); err != nil {
+			return cue.Value{}, nil, errors.Mark(fmt.Errorf("lens emitted malformed lacunas: %w", err), terrors.ErrInvalidLens)
+		}
+		if len(item.Lac) > 0 {
+			lac = append(lac, item)
+		}
+	}
 
 	// Attempt to evaluate #Translate result to remove intermediate structures created by #Translate.
 	// Otherwise, all the #Translate results are non-concrete, which leads to undesired effects.
 	raw, _ := out.LookupPath(cue.MakePath(cue.Str("result"), cue.Str("result"))).Default()
 
 	// Check that the result is concrete by trying to marshal/export it as JSON
-	_, err = json.Marshal(raw)
+	str, err := json.Marshal(raw)
 	if err != nil {
-		return nil, nil, errors.Mark(fmt.Errorf("lens produced a non-concrete result: %s", cerrors.Details(err, nil)), terrors.ErrLensIncomplete)
+		return cue.Value{}, nil, errors.Mark(fmt.Errorf("lens produced a non-concrete result: %s", cerrors.Details(err, nil)), terrors.ErrLensIncomplete)
 	}
 
-	// Ensure the result is a valid instance of the target schema
-	inst, err := newsch.Validate(raw)
+	// The result shares structure with the lineage, which CUE may mutate when
+	// evaluating later translations. Rebuild it from its JSON representation
+	// so that the returned instance can be read without holding the lock.
+	expr, err := cuejson.Extract("translated", []byte(str))
 	if err != nil {
-		return nil, nil, errors.Mark(err, terrors.ErrLensResultIsInvalidData)
+		return cue.Value{}, nil, err
 	}
-	return inst, lac, err
+	return rt.Context().BuildExpr(expr), lac, nil
 }
 
 func (i *Instance) translateGo(to SyntacticVersion) (*Instance, TranslationLacunas, error) {
@@ -264,6 +367,7 @@ func (i *Instance) translateGo(to SyntacticVersion) (*Instance, TranslationLacun
 	sch := i.Schema()
 	ti := new(Instance)
 	*ti = *i
+	var lac multiTranslationLacunas
 	for sch.Version() != to {
 		var nsch Schema
 		if to.Less(from) {
@@ -277,7 +381,18 @@ func (i *Instance) translateGo(to SyntacticVersion) (*Instance, TranslationLacun
 		if to.Less(from) || sch.Version()[0] != nsch.Version()[0] {
 			// Going backward, or crossing major version - need explicit lens
 			mlid := lid(sch.Version(), nsch.Version())
-			rti, err = lensmap[mlid].Mapper(ti, nsch)
+			if lens := lensmap[mlid]; lens.LacunaMapper != nil {
+				var steplac []Lacuna
+				rti, steplac, err = lens.LacunaMapper(ti, nsch)
+				if len(steplac) > 0 {
+					lac = append(lac, struct {
+						V   SyntacticVersion `json:"v"`
+						Lac []Lacuna         `json:"lacunas"`
+					}{V: nsch.Version(), Lac: steplac})
+				}
+			} else {
+				rti, err = lens.Mapper(ti, nsch)
+			}
 			if err != nil {
 				return nil, nil, fmt.Errorf("error executing %s migration: %w", mlid, err)
 			}
@@ -301,7 +416,10 @@ func (i *Instance) translateGo(to SyntacticVersion) (*Instance, TranslationLacun
 		sch = nsch
 	}
 
-	return ti, nil, nil
+	if len(lac) == 0 {
+		return ti, nil, nil
+	}
+	return ti, lac, nil
 }
 
 type multiTranslationLacunas []struct {
@@ -0,0 +1,343 @@
+package thema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// InstanceChangeOp identifies the kind of a single [InstanceChange]. The values
+// correspond to JSON Patch (RFC 6902) operations.
+type InstanceChangeOp string
+
+const (
+	// ValueAdded indicates a value that exists in the newer instance, but not
+	// the older one.
+	ValueAdded InstanceChangeOp = "add"
+
+	// ValueRemoved indicates a value that exists in the older instance, but not
+	// the newer one.
+	ValueRemoved InstanceChangeOp = "remove"
+
+	// ValueReplaced indicates a value that exists in both instances, but differs.
+	ValueReplaced InstanceChangeOp = "replace"
+)
+
+// InstanceChange describes a single difference between the data of two
+// instances.
+type InstanceChange struct {
+	// Op is the kind of change.
+	Op InstanceChangeOp
+
+	// Path is the path to the changed value.
+	Path cue.Path
+
+	// Old is the value in the older instance. It does not exist for
+	// [ValueAdded].
+	Old cue.Value
+
+	// New is the value in the newer instance. It does not exist for
+	// [ValueRemoved].
+	New cue.Value
+
+	// Schema is the schema constraining the value at Path. It does not exist if
+	// the schema places no constraints on the value.
+	Schema cue.Value
+}
+
+func (c InstanceChange) String() string {
+	var sch string
+	if s := schemaSummary(c.Schema); s != "" {
+		sch = " <" + s + ">"
+	}
+	switch c.Op {
+	case ValueAdded:
+		return fmt.Sprintf("%s: %s%s (%s)", c.Op, c.Path, sch, compactJSON(c.New))
+	case ValueRemoved:
+		return fmt.Sprintf("%s: %s%s (%s)", c.Op, c.Path, sch, compactJSON(c.Old))
+	default:
+		return fmt.Sprintf("%s: %s%s (%s -> %s)", c.Op, c.Path, sch, compactJSON(c.Old), compactJSON(c.New))
+	}
+}
+
+// MarshalJSON implements [json.Marshaler], representing the change as a JSON
+// Patch operation. The schema annotation is carried in an additional "schema"
+// member, which JSON Patch implementations ignore.
+func (c InstanceChange) MarshalJSON() ([]byte, error) {
+	jc := struct {
+		Op     InstanceChangeOp `json:"op"`
+		Path   string           `json:"path"`
+		Value  *cue.Value       `json:"value,omitempty"`
+		Schema string           `json:"schema,omitempty"`
+	}{
+		Op:     c.Op,
+		Path:   pathPointer(c.Path).String(),
+		Schema: schemaSummary(c.Schema),
+	}
+	if c.Op != ValueRemoved {
+		jc.Value = &c.New
+	}
+	return json.Marshal(jc)
+}
+
+// InstanceDiff is the set of changes between the data of two instances, as
+// produced by [DiffInstances].
+type InstanceDiff struct {
+	// Version is the version of the schema both instances were translated to
+	// for comparison.
+	Version SyntacticVersion `json:"version"`
+
+	// Changes are the individual changes between the instances. Applied in
+	// order, they transform the older instance into the newer one.
+	Changes []InstanceChange `json:"changes"`
+}
+
+// JSONPatch returns the changes as a JSON Patch (RFC 6902) document.
+func (d *InstanceDiff) JSONPatch() ([]byte, error) {
+	changes := d.Changes
+	if changes == nil {
+		changes = []InstanceChange{}
+	}
+	return json.Marshal(changes)
+}
+
+// DiffOption defines an option that affects the behavior of [DiffInstances].
+type DiffOption diffOption
+
+type diffOption func(c *diffConfig)
+
+type diffConfig struct {
+	version *SyntacticVersion
+	topts   []TranslateOption
+}
+
+// DiffAtVersion specifies the version of the schema to which both instances
+// are translated before comparison. By default, the version of the newer
+// instance is used.
+func DiffAtVersion(v SyntacticVersion) DiffOption {
+	return func(c *diffConfig) {
+		c.version = &v
+	}
+}
+
+// DiffTranslateOptions specifies options to use when translating either
+// instance for comparison.
+func DiffTranslateOptions(opts ...TranslateOption) DiffOption {
+	return func(c *diffConfig) {
+		c.topts = append(c.topts, opts...)
+	}
+}
+
+// DiffInstances computes the differences between the data of instances a and
+// b, treating a as the older instance and b as the newer one. The instances may
+// be of any schema in the same lineage.
+//
+// Both instances are translated to a common version (see [DiffAtVersion]) and
+// dehydrated, such that values differing only by whether they are explicitly
+// set to their schema default are not reported. The lacunas emitted by
+// translation are returned.
+//
+// Structs and lists are descended into, such that changes are reported on the
+// most deeply nested value possible. Values of a disjunction are descended
+// into only when both select the same branch. Each change is annotated with the
+// schema for its value.
+func DiffInstances(a, b *Instance, opts ...DiffOption) (*InstanceDiff, TranslationLacunas, error) {
+	a.check()
+	b.check()
+
+	cfg := &diffConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	lin := b.Schema().Lineage()
+	if a.Schema().Lineage() != lin {
+		return nil, nil, fmt.Errorf("cannot diff instances of different lineages")
+	}
+	ver := b.Schema().Version()
+	if cfg.version != nil {
+		ver = *cfg.version
+	}
+	sch, err := lin.Schema(ver)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ta, alac, err := translateTo(a, ver, cfg.topts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error translating older instance to %s: %w", ver, err)
+	}
+	tb, blac, err := translateTo(b, ver, cfg.topts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error translating newer instance to %s: %w", ver, err)
+	}
+
+	var ax, bx interface{}
+	if err = decodeJSONNumber(ta.Dehydrate().Underlying(), &ax); err != nil {
+		return nil, nil, err
+	}
+	if err = decodeJSONNumber(tb.Dehydrate().Underlying(), &bx); err != nil {
+		return nil, nil, err
+	}
+
+	d := &instanceDiffer{ctx: b.Underlying().Context()}
+	d.diff(schemaValue(sch), nil, ax, bx)
+	return &InstanceDiff{
+		Version: ver,
+		Changes: d.changes,
+	}, concatLacunas(alac, blac), nil
+}
+
+type instanceDiffer struct {
+	ctx     *cue.Context
+	changes []InstanceChange
+}
+
+func (d *instanceDiffer) value(x interface{}) cue.Value {
+	v, err := encodeJSONData(d.ctx, x)
+	if err != nil {
+		// Unreachable, as the value was itself decoded from JSON
+		panic(err)
+	}
+	return v
+}
+
+func (d *instanceDiffer) add(op InstanceChangeOp, sch cue.Value, sels []cue.Selector, a, b interface{}) {
+	c := InstanceChange{
+		Op:     op,
+		Path:   cue.MakePath(sels...),
+		Schema: sch,
+	}
+	if op != ValueAdded {
+		c.Old = d.value(a)
+	}
+	if op != ValueRemoved {
+		c.New = d.value(b)
+	}
+	d.changes = append(d.changes, c)
+}
+
+func (d *instanceDiffer) diff(sch cue.Value, sels []cue.Selector, a, b interface{}) {
+	if jsonEqual(a, b) {
+		return
+	}
+
+	if sch.Exists() {
+		if op, arms := sch.Expr(); op == cue.OrOp {
+			ai, bi := disjunctBranch(arms, d.value(a)), disjunctBranch(arms, d.value(b))
+			if bi < 0 || ai != bi {
+				d.add(ValueReplaced, sch, sels, a, b)
+				return
+			}
+			sch = arms[bi]
+		}
+	}
+
+	switch bx := b.(type) {
+	case map[string]interface{}:
+		if ax, is := a.(map[string]interface{}); is {
+			d.diffObjects(sch, sels, ax, bx)
+			return
+		}
+	case []interface{}:
+		if ax, is := a.([]interface{}); is {
+			d.diffLists(sch, sels, ax, bx)
+			return
+		}
+	}
+	d.add(ValueReplaced, sch, sels, a, b)
+}
+
+func (d *instanceDiffer) diffObjects(sch cue.Value, sels []cue.Selector, a, b map[string]interface{}) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, has := a[k]; !has {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		ks, path := fieldSchema(sch, k), appendSel(sels, cue.Str(k))
+		av, ahas := a[k]
+		bv, bhas := b[k]
+		switch {
+		case !ahas:
+			d.add(ValueAdded, ks, path, nil, bv)
+		case !bhas:
+			d.add(ValueRemoved, ks, path, av, nil)
+		default:
+			d.diff(ks, path, av, bv)
+		}
+	}
+}
+
+func (d *instanceDiffer) diffLists(sch cue.Value, sels []cue.Selector, a, b []interface{}) {
+	elem := cue.Value{}
+	if sch.Exists() {
+		elem = sch.LookupPath(cue.MakePath(cue.AnyIndex))
+	}
+
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		d.diff(elem, appendSel(sels, cue.Index(i)), a[i], b[i])
+	}
+	for i := n; i < len(b); i++ {
+		d.add(ValueAdded, elem, appendSel(sels, cue.Index(i)), nil, b[i])
+	}
+	// Remove from the end, so that the changes apply as a JSON Patch
+	for i := len(a) - 1; i >= n; i-- {
+		d.add(ValueRemoved, elem, appendSel(sels, cue.Index(i)), a[i], nil)
+	}
+}
+
+// pathPointer converts a CUE path into a JSON Pointer.
+func pathPointer(p cue.Path) jsonPointer {
+	sels := p.Selectors()
+	ptr := make(jsonPointer, 0, len(sels))
+	for _, sel := range sels {
+		if sel.Type() == cue.IndexLabel {
+			ptr = append(ptr, strconv.Itoa(sel.Index()))
+		} else {
+			ptr = append(ptr, sel.Unquoted())
+		}
+	}
+	return ptr
+}
+
+// schemaSummary returns a brief, single-line description of a schema value, for
+// annotating changes. Structs and lists are described by their kind alone.
+func schemaSummary(sch cue.Value) string {
+	if !sch.Exists() {
+		return ""
+	}
+	switch sch.IncompleteKind() {
+	case cue.StructKind:
+		return "struct"
+	case cue.ListKind:
+		return "list"
+	}
+	s := fmt.Sprint(sch)
+	if strings.ContainsRune(s, '\n') {
+		return sch.IncompleteKind().String()
+	}
+	return s
+}
+
+func compactJSON(v cue.Value) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,171 @@
+package thema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diffInstLinstr = `name: "diffinst"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		theme: *"dark" | "light"
+		tags: [...string]
+		target: #Text | #Graph
+
+		#Text: {
+			kind:    "text"
+			content: string
+		}
+		#Graph: {
+			kind:   "graph"
+			series: int
+		}
+	}
+}]
+`
+
+func TestDiffInstances(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(diffInstLinstr), rt)
+	require.NoError(t, err)
+
+	validate := func(t *testing.T, str string) *Instance {
+		t.Helper()
+		inst, err := lin.First().Validate(ctx.CompileString(str))
+		require.NoError(t, err)
+		return inst
+	}
+
+	a := validate(t, `{
+		title: "a"
+		theme: "dark"
+		tags: ["x", "y", "z"]
+		target: {kind: "text", content: "c"}
+	}`)
+
+	table := map[string]struct {
+		b    string
+		want []string
+	}{
+		"identical": {
+			b: `{title: "a", theme: "dark", tags: ["x", "y", "z"], target: {kind: "text", content: "c"}}`,
+		},
+		"defaultonly": {
+			b: `{title: "a", tags: ["x", "y", "z"], target: {kind: "text", content: "c"}}`,
+		},
+		"fields": {
+			b: `{title: "b", theme: "light", tags: ["x", "q"], target: {kind: "text", content: "d"}}`,
+			want: []string{
+				`replace: tags[1] <string> ("y" -> "q")`,
+				`remove: tags[2] <string> ("z")`,
+				`replace: target.content <string> ("c" -> "d")`,
+				`add: theme <*"dark" | "light"> ("light")`,
+				`replace: title <string> ("a" -> "b")`,
+			},
+		},
+		"branch": {
+			b: `{title: "a", tags: ["x", "y", "z", "w"], target: {kind: "graph", series: 2}}`,
+			want: []string{
+				`add: tags[3] <string> ("w")`,
+				`replace: target <struct> ({"content":"c","kind":"text"} -> {"kind":"graph","series":2})`,
+			},
+		},
+	}
+
+	for name, tt := range table {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			b := validate(t, tt.b)
+			d, _, err := DiffInstances(a, b)
+			require.NoError(t, err)
+			assert.Equal(t, SV(0, 0), d.Version)
+
+			var got []string
+			for _, c := range d.Changes {
+				got = append(got, c.String())
+			}
+			assert.Equal(t, tt.want, got)
+
+			// The patch transforms the dehydrated older instance into the
+			// dehydrated newer one
+			patch, err := d.JSONPatch()
+			require.NoError(t, err)
+			var doc interface{}
+			require.NoError(t, decodeJSONNumber(a.Dehydrate().Underlying(), &doc))
+			doc, err = applyPatchDocument(doc, patch)
+			require.NoError(t, err, string(patch))
+			pb, err := json.Marshal(doc)
+			require.NoError(t, err)
+			wb, err := json.Marshal(b.Dehydrate().Underlying())
+			require.NoError(t, err)
+			assert.JSONEq(t, string(wb), string(pb))
+		})
+	}
+}
+
+func TestDiffInstancesJSONPatch(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(diffInstLinstr), rt)
+	require.NoError(t, err)
+
+	a, err := lin.First().Validate(ctx.CompileString(`{title: "a", "tags": ["x/y"], target: {kind: "text", content: "c"}}`))
+	require.NoError(t, err)
+	b, err := lin.First().Validate(ctx.CompileString(`{title: "a", "tags": [], target: {kind: "text", content: "c"}}`))
+	require.NoError(t, err)
+
+	d, _, err := DiffInstances(a, b)
+	require.NoError(t, err)
+	patch, err := d.JSONPatch()
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"op": "remove", "path": "/tags/0", "schema": "string"}]`, string(patch))
+
+	d, _, err = DiffInstances(a, a)
+	require.NoError(t, err)
+	patch, err = d.JSONPatch()
+	require.NoError(t, err)
+	assert.Equal(t, `[]`, string(patch))
+}
+
+func TestDiffInstancesAcrossVersions(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(lacunaLinstr), rt)
+	require.NoError(t, err)
+
+	a, err := SchemaP(lin, SV(0, 0)).Validate(ctx.CompileString(`{title: "foo", count: 3}`))
+	require.NoError(t, err)
+	b, err := SchemaP(lin, SV(1, 0)).Validate(ctx.CompileString(`{title: "bar", owner: "someone", count: 3, nested: level: 2}`))
+	require.NoError(t, err)
+
+	// At the older version, the newer instance's extra fields are dropped
+	d, lac, err := DiffInstances(a, b, DiffAtVersion(SV(0, 0)))
+	require.NoError(t, err)
+	assert.Equal(t, SV(0, 0), d.Version)
+	require.Len(t, d.Changes, 1)
+	assert.Equal(t, `replace: title <string> ("foo" -> "bar")`, d.Changes[0].String())
+	require.Len(t, lac.AsList(), 1)
+	assert.Equal(t, LacunaDroppedField, lac.AsList()[0].Type)
+
+	// At the newer version, the older instance's placeholders differ
+	d, lac, err = DiffInstances(a, b)
+	require.NoError(t, err)
+	assert.Equal(t, SV(1, 0), d.Version)
+	var got []string
+	for _, c := range d.Changes {
+		got = append(got, c.String())
+	}
+	assert.Equal(t, []string{
+		`replace: nested.level <int> (-1 -> 2)`,
+		`replace: owner <string> ("PLACEHOLDER" -> "someone")`,
+		`replace: title <string> ("foo" -> "bar")`,
+	}, got)
+	assert.Len(t, lac.AsList(), 2)
+}
@@ -182,3 +182,184 @@ func BenchmarkBasicTranslate(b *testing.B) {
 		})
 	})
 }
+
+type initType struct {
+	Init string `json:"init"`
+}
+
+func BenchmarkTypedTranslate(b *testing.B) {
+	test := vanilla.TxTarTest{
+		Root:    "./testdata/lineage",
+		Name:    "core/instance/typedtranslate",
+		ThemaFS: CueJointFS,
+	}
+
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+
+	test.RunBenchmark(b, func(bc *vanilla.Benchmark) {
+		if !bc.HasTag("multiversion") {
+			bc.Skip()
+		}
+
+		lval := ctx.BuildInstance(bc.Instance())
+		lin, err := BindLineage(lval, rt)
+		require.NoError(b, err)
+
+		tsch, err := BindType[*initType](lin.First(), &initType{})
+		require.NoError(b, err)
+		examples := lin.Latest().Examples()
+
+		bc.Run("translate-bind", func(b *testing.B) {
+			for name, iexample := range examples {
+				example := iexample
+				b.Run(name, func(b *testing.B) {
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						tinst, _, _ := example.Translate(tsch.Version())
+						BindInstanceType(tinst, tsch) //nolint:errcheck
+					}
+				})
+			}
+		})
+		bc.Run("typed-translator", func(b *testing.B) {
+			tt := NewTypedTranslator(tsch)
+			for name, iexample := range examples {
+				example := iexample
+				b.Run(name, func(b *testing.B) {
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						tt.Translate(example) //nolint:errcheck
+					}
+				})
+			}
+		})
+	})
+}
+
+var typedTranslateLin = `
+name: "typed-translate"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		before: string
+	}
+}, {
+	version: [1, 0]
+	schema: {
+		after: string
+	}
+}]
+lenses: [{
+	to: [0, 0]
+	from: [1, 0]
+	input: _
+	result: before: input.after
+}, {
+	to: [1, 0]
+	from: [0, 0]
+	input: _
+	result: after: input.before
+}]
+`
+
+type beforeType struct {
+	Before string `json:"before"`
+}
+
+type afterType struct {
+	After string `json:"after"`
+}
+
+func TestTypedTranslator(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(typedTranslateLin), rt)
+	require.NoError(t, err)
+
+	tsch0, err := BindType[*beforeType](lin.First(), &beforeType{})
+	require.NoError(t, err)
+	tsch1, err := BindType[*afterType](lin.Latest(), &afterType{})
+	require.NoError(t, err)
+
+	inst0, err := lin.First().Validate(ctx.CompileString(`{before: "val"}`))
+	require.NoError(t, err)
+	inst1, err := lin.Latest().Validate(ctx.CompileString(`{after: "val"}`))
+	require.NoError(t, err)
+
+	t.Run("forward", func(t *testing.T) {
+		tinst, _, err := NewTypedTranslator(tsch1).Translate(inst0)
+		require.NoError(t, err)
+		require.Equal(t, SV(1, 0), tinst.Schema().Version())
+		require.Equal(t, &afterType{After: "val"}, tinst.ValueP())
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		tinst, _, err := NewTypedTranslator(tsch0).Translate(inst1)
+		require.NoError(t, err)
+		require.Equal(t, SV(0, 0), tinst.Schema().Version())
+		require.Equal(t, &beforeType{Before: "val"}, tinst.ValueP())
+	})
+
+	t.Run("same-version", func(t *testing.T) {
+		tinst, lac, err := NewTypedTranslator(tsch0).Translate(inst0)
+		require.NoError(t, err)
+		require.Nil(t, lac)
+		require.Equal(t, &beforeType{Before: "val"}, tinst.ValueP())
+	})
+
+	t.Run("reuse", func(t *testing.T) {
+		tt := NewTypedTranslator(tsch1)
+		for _, s := range []string{"a", "b", "c"} {
+			inst, err := lin.First().Validate(ctx.CompileString(fmt.Sprintf(`{before: %q}`, s)))
+			require.NoError(t, err)
+			tinst, _, err := tt.Translate(inst)
+			require.NoError(t, err)
+			require.Equal(t, &afterType{After: s}, tinst.ValueP())
+		}
+	})
+
+	t.Run("other-lineage", func(t *testing.T) {
+		olin, err := BindLineage(ctx.CompileString(typedTranslateLin), rt)
+		require.NoError(t, err)
+		oinst, err := olin.First().Validate(ctx.CompileString(`{before: "val"}`))
+		require.NoError(t, err)
+		_, _, err = NewTypedTranslator(tsch1).Translate(oinst)
+		require.Error(t, err)
+	})
+}
+
+// TestInstance_HydrateDehydrate checks that defaults are drawn from the
+// schema itself, rather than from the #SchemaDef that contains it.
+func TestInstance_HydrateDehydrate(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(`name: "hydrate"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		size:  int | *3
+		style: "flat" | *"round"
+	}
+}]
+`), rt)
+	require.NoError(t, err)
+
+	marshal := func(inst *Instance) string {
+		t.Helper()
+		b, err := json.Marshal(inst.Underlying())
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	inst, err := lin.First().Validate(ctx.CompileString(`{title: "foo"}`))
+	require.NoError(t, err)
+	hinst := inst.Hydrate()
+	require.JSONEq(t, `{"title": "foo", "size": 3, "style": "round"}`, marshal(hinst))
+
+	inst, err = lin.First().Validate(ctx.CompileString(`{title: "foo", size: 3, style: "flat"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"title": "foo", "style": "flat"}`, marshal(inst.Dehydrate()))
+	require.JSONEq(t, `{"title": "foo"}`, marshal(hinst.Dehydrate()))
+}
@@ -11,59 +11,29 @@ import (
 	"cuelang.org/go/cue/token"
 )
 
-// FindSeqs finds the seqs field within what is expected to be a valid lineage ast.Node
-func FindSeqs(n ast.Node) *ast.ListLit {
-	var ret *ast.ListLit
-	ast.Walk(n, func(n ast.Node) bool {
-		if ret != nil {
-			return false
-		}
-		if isFieldWithLabel(n, "seqs") {
-			if x, ok := n.(*ast.Field).Value.(*ast.ListLit); ok {
-				ret = x
-				return false
-			}
-		}
-		return true
-	}, nil)
-	return ret
+// SchemaList finds the ListLit for the schemas field of what is expected to be
+// the struct-ish ast.Node in which a lineage is declared.
+func SchemaList(n ast.Node) (*ast.ListLit, error) {
+	return listForField(n, "schemas")
 }
 
-// LatestSchemaList finds the ListLit for the latest sequence in what is expected
-// to be a valid lineage ast.Node
-func LatestSchemaList(n ast.Node) (*ast.ListLit, error) {
-	seqlist := FindSeqs(n)
-	if seqlist == nil {
-		return nil, fmt.Errorf("could not find seqs list in input")
-	}
-	return listForField(seqlist.Elts[len(seqlist.Elts)-1], "schemas")
-}
-
-// SchemaListFor finds the ListLit for a particular major version number in what
-// is expected to be a valid lineage ast.Node
-func SchemaListFor(n ast.Node, majv uint) (*ast.ListLit, error) {
-	seqlist := FindSeqs(n)
-	if seqlist == nil {
-		return nil, fmt.Errorf("could not find seqs list in input")
-	}
-	if majv >= uint(len(seqlist.Elts)) {
-		return nil, fmt.Errorf("major version %v not present in lineage", majv)
-	}
-
-	return listForField(seqlist.Elts[majv], "schemas")
+// LensList finds the ListLit for the lenses field of what is expected to be the
+// struct-ish ast.Node in which a lineage is declared.
+func LensList(n ast.Node) (*ast.ListLit, error) {
+	return listForField(n, "lenses")
 }
 
 func listForField(n ast.Node, label string) (*ast.ListLit, error) {
-	seqs, err := GetFieldByLabel(n, label)
+	field, err := GetFieldByLabel(n, label)
 	if err != nil {
 		return nil, err
 	}
-	seqlist, is := seqs.Value.(*ast.ListLit)
+	list, is := field.Value.(*ast.ListLit)
 	if !is {
-		return nil, fmt.Errorf("expected %q field to be an ast.ListLit, got %T", label, seqs)
+		return nil, fmt.Errorf("expected %q field to be an ast.ListLit, got %T", label, field.Value)
 	}
 
-	return seqlist, nil
+	return list, nil
 }
 
 // GetFieldByLabel returns the ast.Field with a given label from a struct-ish input.
@@ -2,28 +2,231 @@ package compat
 
 import (
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/token"
 )
 
 // ThemaCompatible is the canonical Thema algorithm for checking that the
 // [cue.Value] s is (backwards) compatible with p. A nil return indicates
-// compatibility.
+// compatibility. Otherwise, the returned error contains one error for each
+// field reported by [IncompatiblePaths].
 //
-// The behavior of this function is undefined if s and p are not closed
-// structs. TODO check this and error if conditions aren't met
+// CUE's subsumption algorithm is not relied upon for structs and lists, as it
+// both rejects identical structs containing optional fields with pattern
+// constraints, and accepts lists whose elements have changed type. Instead,
+// structs and lists are compared field by field and element by element, and
+// only other values, including disjunctions, are checked by subsumption.
 func ThemaCompatible(p, s cue.Value) error {
-	return s.Subsume(p, cue.Raw(), cue.All())
+	var err errors.Error
+	for _, inc := range incompatibilities(p, s, nil) {
+		err = errors.Append(err, errors.Newf(token.NoPos, "%s: %s", pathString(inc.path), inc.reason))
+	}
+	if err == nil {
+		return nil
+	}
+	return err
 }
 
-// type CompatInvariantError struct {
-// 	rawlin    cue.Value
-// 	violation [2]SyntacticVersion
-// 	detail    error
-// }
+// IncompatiblePaths returns the paths to the fields in s that are not
+// backwards compatible with their counterparts in p.
 //
-// func (e *CompatInvariantError) Error() string {
-// 	if e.violation[0][0] == e.violation[1][0] {
-// 		// TODO better
-// 		return e.detail.Error()
-// 	}
-// 	return fmt.Sprintf("schema %s must be backwards incompatible with schema %s", e.violation[1], e.violation[0])
-// }
+// A field is incompatible if it was removed, if it is required and was either
+// added or previously optional, or if its value no longer subsumes the
+// previous value. Structs and lists are descended into, so the most deeply
+// nested incompatible field is reported. Changes to the pattern constraints
+// of a struct or the element type of a list are reported with the
+// corresponding [cue.AnyString] or [cue.AnyIndex] selector.
+//
+// Paths are relative to p and s.
+func IncompatiblePaths(p, s cue.Value) []cue.Path {
+	incs := incompatibilities(p, s, nil)
+	paths := make([]cue.Path, 0, len(incs))
+	for _, inc := range incs {
+		paths = append(paths, inc.path)
+	}
+	return paths
+}
+
+type incompatibility struct {
+	path   cue.Path
+	reason string
+}
+
+func pathString(p cue.Path) string {
+	if len(p.Selectors()) == 0 {
+		return "schema"
+	}
+	return p.String()
+}
+
+func incompatibilities(p, s cue.Value, sels []cue.Selector) []incompatibility {
+	pk := p.IncompleteKind()
+	if pk != s.IncompleteKind() || isDisjunction(p) || isDisjunction(s) || (pk != cue.StructKind && pk != cue.ListKind) {
+		// Subsumption of disjunctions that contain structs or lists may still be
+		// subject to CUE's false results.
+		if s.Subsume(p, cue.Raw(), cue.Schema()) != nil {
+			reason := "not all previously valid values are still valid"
+			if defaultChanged(p, s) && s.Subsume(p, cue.Schema()) == nil {
+				// Only defaults are disregarded without cue.Raw()
+				reason = "default value changed"
+			}
+			return []incompatibility{{path: cue.MakePath(sels...), reason: reason}}
+		}
+		if defaultChanged(p, s) {
+			return []incompatibility{{path: cue.MakePath(sels...), reason: "default value changed"}}
+		}
+		return nil
+	}
+
+	if pk == cue.StructKind {
+		return structIncompatibilities(p, s, sels)
+	}
+	incs := listIncompatibilities(p, s, sels)
+	if len(incs) == 0 && defaultChanged(p, s) {
+		incs = append(incs, incompatibility{path: cue.MakePath(sels...), reason: "default value changed"})
+	}
+	return incs
+}
+
+// defaultChanged reports whether the default values of p and s differ. Lists
+// always have a default in CUE, so non-concrete defaults are disregarded.
+func defaultChanged(p, s cue.Value) bool {
+	pd, pok := p.Default()
+	sd, sok := s.Default()
+	pok = pok && pd.Validate(cue.Concrete(true)) == nil
+	sok = sok && sd.Validate(cue.Concrete(true)) == nil
+	if !pok || !sok {
+		return pok != sok
+	}
+	return pd.Subsume(sd, cue.Final()) != nil || sd.Subsume(pd, cue.Final()) != nil
+}
+
+func structIncompatibilities(p, s cue.Value, sels []cue.Selector) []incompatibility {
+	at := func(sel cue.Selector) []cue.Selector {
+		return append(append([]cue.Selector{}, sels...), sel)
+	}
+	ppat, spat := pattern(p), pattern(s)
+
+	type field struct {
+		val      cue.Value
+		optional bool
+	}
+	pfields := make(map[string]field)
+	var porder []cue.Selector
+	iter, _ := p.Fields(cue.Optional(true))
+	for iter != nil && iter.Next() {
+		sel := iter.Selector()
+		pfields[sel.String()] = field{val: iter.Value(), optional: iter.IsOptional()}
+		porder = append(porder, sel)
+	}
+
+	var incs []incompatibility
+	seen := make(map[string]bool)
+	iter, _ = s.Fields(cue.Optional(true))
+	for iter != nil && iter.Next() {
+		sel := iter.Selector()
+		seen[sel.String()] = true
+
+		pf, has := pfields[sel.String()]
+		switch {
+		case !iter.IsOptional() && (!has || pf.optional):
+			reason := "required field was added"
+			if has {
+				reason = "optional field became required"
+			}
+			incs = append(incs, incompatibility{path: cue.MakePath(at(sel)...), reason: reason})
+		case has:
+			incs = append(incs, incompatibilities(pf.val, iter.Value(), at(sel))...)
+		case ppat.Exists():
+			// The field was previously allowed by the pattern
+			incs = append(incs, incompatibilities(ppat, iter.Value(), at(sel))...)
+		}
+	}
+
+	for _, sel := range porder {
+		if seen[sel.String()] {
+			continue
+		}
+		if spat.Exists() {
+			// The field is still allowed by the pattern
+			incs = append(incs, incompatibilities(pfields[sel.String()].val, spat, at(sel))...)
+			continue
+		}
+		incs = append(incs, incompatibility{path: cue.MakePath(at(sel)...), reason: "field was removed"})
+	}
+
+	switch {
+	case ppat.Exists() && !spat.Exists():
+		incs = append(incs, incompatibility{path: cue.MakePath(at(cue.AnyString)...), reason: "fields not explicitly declared are no longer allowed"})
+	case ppat.Exists():
+		incs = append(incs, incompatibilities(ppat, spat, at(cue.AnyString))...)
+	}
+	return incs
+}
+
+func listIncompatibilities(p, s cue.Value, sels []cue.Selector) []incompatibility {
+	at := func(sel cue.Selector) []cue.Selector {
+		return append(append([]cue.Selector{}, sels...), sel)
+	}
+	pelem, selem := p.LookupPath(cue.MakePath(cue.AnyIndex)), s.LookupPath(cue.MakePath(cue.AnyIndex))
+
+	if pelem.Exists() {
+		if !selem.Exists() {
+			return []incompatibility{{path: cue.MakePath(at(cue.AnyIndex)...), reason: "list length is no longer unbounded"}}
+		}
+		// The elements of open lists are those of their default, so any
+		// elements preceding the open tail are checked by subsumption.
+		incs := incompatibilities(pelem, selem, at(cue.AnyIndex))
+		if len(incs) == 0 && s.Subsume(p, cue.Raw(), cue.Schema()) != nil {
+			incs = append(incs, incompatibility{path: cue.MakePath(sels...), reason: "not all previously valid values are still valid"})
+		}
+		return incs
+	}
+
+	pitems, sitems := items(p), items(s)
+	var incs []incompatibility
+	for i, pv := range pitems {
+		switch {
+		case i < len(sitems):
+			incs = append(incs, incompatibilities(pv, sitems[i], at(cue.Index(i)))...)
+		case selem.Exists():
+			incs = append(incs, incompatibilities(pv, selem, at(cue.Index(i)))...)
+		default:
+			incs = append(incs, incompatibility{path: cue.MakePath(at(cue.Index(i))...), reason: "list must be shorter"})
+		}
+	}
+	for i := len(pitems); i < len(sitems); i++ {
+		incs = append(incs, incompatibility{path: cue.MakePath(at(cue.Index(i))...), reason: "list must be longer"})
+	}
+	return incs
+}
+
+// isDisjunction reports whether v is a disjunction, including one that has a
+// default. Lists are only considered to have a default if it has elements, as
+// every open list has the empty list as its default in CUE.
+func isDisjunction(v cue.Value) bool {
+	if op, _ := v.Expr(); op == cue.OrOp {
+		return true
+	}
+	d, ok := v.Default()
+	if !ok || (v.IncompleteKind() == cue.ListKind && len(items(d)) == 0) {
+		return false
+	}
+	return d.Subsume(v, cue.Raw()) != nil
+}
+
+// pattern returns the constraint applied to fields of the struct that are not
+// explicitly declared, which does not exist if there are no such fields.
+func pattern(v cue.Value) cue.Value {
+	return v.LookupPath(cue.MakePath(cue.AnyString))
+}
+
+// items returns the elements of the list that are always present.
+func items(v cue.Value) []cue.Value {
+	var vals []cue.Value
+	iter, err := v.List()
+	for err == nil && iter.Next() {
+		vals = append(vals, iter.Value())
+	}
+	return vals
+}
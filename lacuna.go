@@ -1,5 +1,17 @@
 package thema
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"cuelang.org/go/cue"
+	cuejson "cuelang.org/go/encoding/json"
+	"github.com/cockroachdb/errors"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
 // TranslationLacunas defines common patterns for unary and composite lineages
 // in the lacunas their translations emit.
 type TranslationLacunas interface {
@@ -47,8 +59,308 @@ type Lacuna struct {
 // FIXME this is a terrible way of doing this and needs to change
 type LacunaType uint16
 
+// The LacunaTypes declared in #LacunaTypes in lacuna.cue. See there for
+// documentation of each type's semantics.
+const (
+	LacunaPlaceholder LacunaType = iota + 1
+	LacunaDroppedField
+	LacunaLossyFieldMapping
+	LacunaChangedDefault
+)
+
+func (lt LacunaType) String() string {
+	switch lt {
+	case LacunaPlaceholder:
+		return "Placeholder"
+	case LacunaDroppedField:
+		return "DroppedField"
+	case LacunaLossyFieldMapping:
+		return "LossyFieldMapping"
+	case LacunaChangedDefault:
+		return "ChangedDefault"
+	default:
+		return fmt.Sprintf("LacunaType(%d)", uint16(lt))
+	}
+}
+
+// UnmarshalJSON implements [json.Unmarshaler]. It accepts either the bare
+// numeric identifier of a LacunaType, or the #LacunaType struct form
+// (`{"name": "Placeholder", "id": 1}`) in which lenses declared in CUE emit it.
+func (lt *LacunaType) UnmarshalJSON(b []byte) error {
+	var id uint16
+	if err := json.Unmarshal(b, &id); err == nil {
+		*lt = LacunaType(id)
+		return nil
+	}
+
+	var st struct {
+		ID *uint16 `json:"id"`
+	}
+	if err := json.Unmarshal(b, &st); err != nil || st.ID == nil {
+		return fmt.Errorf("cannot unmarshal %s into a LacunaType", b)
+	}
+	*lt = LacunaType(*st.ID)
+	return nil
+}
+
 // FieldRef identifies a path/field and the value in it within a Lacuna.
 type FieldRef struct {
 	Path  string      `json:"path"`
 	Value interface{} `json:"value"`
 }
+
+// A LacunaResolver is called during translation to resolve a [Lacuna] emitted
+// by a lens. Resolvers are registered with [ResolveLacunaType] or
+// [ResolveLacunaPath], and passed to [Instance.Translate].
+//
+// The resolver receives the lacuna, the source instance being translated, and
+// the partial result of translation, reflecting the values returned by any
+// resolvers called before it. It returns replacement values for fields in the
+// result, each identified by a path relative to the root of the result, in
+// CUE path syntax. A replacement value may be a [cue.Value], or any Go value
+// that can be marshaled to JSON.
+//
+// If resolved is false, the lacuna is left unresolved: any replacement
+// values are ignored, and the lacuna is reported to the caller of Translate.
+// A non-nil error aborts translation.
+type LacunaResolver func(lac Lacuna, src *Instance, partial cue.Value) (repl []FieldRef, resolved bool, err error)
+
+// ResolveLacunaType registers a [LacunaResolver] for all lacunas of the
+// provided type.
+//
+// Resolvers registered with [ResolveLacunaPath] take precedence over those
+// registered by type. Multiple resolvers may be registered for the same type,
+// in which case they are called in order until one resolves the lacuna.
+func ResolveLacunaType(lt LacunaType, fn LacunaResolver) TranslateOption {
+	return func(c *translateConfig) {
+		c.typeres = append(c.typeres, typeResolver{lt: lt, fn: fn})
+	}
+}
+
+// ResolveLacunaPath registers a [LacunaResolver] for all lacunas having a
+// target field, or failing that a source field, with the provided path. The
+// path is in CUE path syntax, e.g. "foo.bar[2]". Where resolvers are
+// registered for more than one of a lacuna's fields, only those for the first
+// such field are called.
+//
+// Multiple resolvers may be registered for the same path, in which case they
+// are called in order until one resolves the lacuna.
+func ResolveLacunaPath(path string, fn LacunaResolver) TranslateOption {
+	return func(c *translateConfig) {
+		c.pathres = append(c.pathres, pathResolver{path: normalizeLacunaPath(path), fn: fn})
+	}
+}
+
+type typeResolver struct {
+	lt LacunaType
+	fn LacunaResolver
+}
+
+type pathResolver struct {
+	path string
+	fn   LacunaResolver
+}
+
+// resolvers returns the resolvers applicable to the lacuna, in the order in
+// which they should be tried.
+func (c *translateConfig) resolvers(lac Lacuna) []LacunaResolver {
+	var paths []string
+	for _, ref := range lac.TargetFields {
+		paths = append(paths, normalizeLacunaPath(ref.Path))
+	}
+	for _, ref := range lac.SourceFields {
+		paths = append(paths, normalizeLacunaPath(ref.Path))
+	}
+
+	var fns []LacunaResolver
+	for _, p := range paths {
+		for _, pr := range c.pathres {
+			if pr.path == p {
+				fns = append(fns, pr.fn)
+			}
+		}
+		if len(fns) > 0 {
+			// Only the resolvers for the first matching path apply
+			break
+		}
+	}
+	for _, tr := range c.typeres {
+		if tr.lt == lac.Type {
+			fns = append(fns, tr.fn)
+		}
+	}
+	return fns
+}
+
+func normalizeLacunaPath(p string) string {
+	if cp := cue.ParsePath(p); cp.Err() == nil {
+		return cp.String()
+	}
+	return p
+}
+
+// resolve calls the registered resolvers for each of the lacunas emitted by
+// translating src to tinst. The resolved instance is returned, along with the
+// lacunas that remain unresolved.
+func (c *translateConfig) resolve(src, tinst *Instance, lac TranslationLacunas) (*Instance, TranslationLacunas, error) {
+	if lac == nil || len(lac.AsList()) == 0 || (len(c.typeres) == 0 && len(c.pathres) == 0) {
+		return tinst, lac, nil
+	}
+
+	// Replacing a value is not possible in CUE, where values can only be
+	// further unified, so replacements are made in the JSON representation.
+	var data interface{}
+	if err := decodeJSONNumber(tinst.Underlying(), &data); err != nil {
+		return nil, nil, err
+	}
+	partial := tinst.Underlying()
+
+	resolved := make(map[int]bool)
+	for i, l := range lac.AsList() {
+		for _, fn := range c.resolvers(l) {
+			repl, ok, err := fn(l, src, partial)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error resolving %s lacuna: %w", l.Type, err)
+			}
+			if !ok {
+				continue
+			}
+			for _, ref := range repl {
+				if data, err = replaceAtPath(data, ref.Path, ref.Value); err != nil {
+					return nil, nil, fmt.Errorf("error applying resolution of %s lacuna: %w", l.Type, err)
+				}
+			}
+			if len(repl) > 0 {
+				if partial, err = encodeJSONData(partial.Context(), data); err != nil {
+					return nil, nil, err
+				}
+			}
+			resolved[i] = true
+			break
+		}
+	}
+
+	if len(resolved) == 0 {
+		return tinst, lac, nil
+	}
+
+	rinst, err := tinst.Schema().Validate(partial)
+	if err != nil {
+		return nil, nil, errors.Mark(fmt.Errorf("resolved lacunas produced invalid data: %w", err), terrors.ErrLacunaResolutionIsInvalidData)
+	}
+	return rinst, filterLacunas(lac, func(i int) bool { return !resolved[i] }), nil
+}
+
+// filterLacunas returns the lacunas for which keep returns true, given each
+// lacuna's index in lac.AsList(). The structure of lac is preserved where
+// possible.
+func filterLacunas(lac TranslationLacunas, keep func(i int) bool) TranslationLacunas {
+	var i int
+	switch x := lac.(type) {
+	case multiTranslationLacunas:
+		ret := make(multiTranslationLacunas, 0, len(x))
+		for _, step := range x {
+			var kept []Lacuna
+			for _, l := range step.Lac {
+				if keep(i) {
+					kept = append(kept, l)
+				}
+				i++
+			}
+			if len(kept) > 0 {
+				step.Lac = kept
+				ret = append(ret, step)
+			}
+		}
+		return ret
+	default:
+		ret := make(flatLacunas, 0)
+		for _, l := range lac.AsList() {
+			if keep(i) {
+				ret = append(ret, l)
+			}
+			i++
+		}
+		return ret
+	}
+}
+
+// decodeJSONNumber decodes the JSON representation of v into x, retaining the
+// literal representation of numbers so that the distinction between ints and
+// floats survives a round trip.
+func decodeJSONNumber(v interface{}, x *interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(x)
+}
+
+func encodeJSONData(ctx *cue.Context, data interface{}) (cue.Value, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return cue.Value{}, err
+	}
+	expr, err := cuejson.Extract("resolved", b)
+	if err != nil {
+		return cue.Value{}, err
+	}
+	return ctx.BuildExpr(expr), nil
+}
+
+// replaceAtPath replaces the value at the provided CUE path within data, which
+// is of the form produced by decodeJSONNumber, creating intermediate objects
+// as needed.
+func replaceAtPath(data interface{}, path string, val interface{}) (interface{}, error) {
+	cp := cue.ParsePath(path)
+	if cp.Err() != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, cp.Err())
+	}
+
+	var nval interface{}
+	if err := decodeJSONNumber(val, &nval); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+	return replaceSels(data, cp.Selectors(), nval, path)
+}
+
+func replaceSels(data interface{}, sels []cue.Selector, val interface{}, path string) (interface{}, error) {
+	if len(sels) == 0 {
+		return val, nil
+	}
+
+	sel := sels[0]
+	switch sel.LabelType() {
+	case cue.StringLabel:
+		obj, is := data.(map[string]interface{})
+		if data == nil {
+			obj = make(map[string]interface{})
+		} else if !is {
+			return nil, fmt.Errorf("%s: %s is not an object", path, sel)
+		}
+		x, err := replaceSels(obj[sel.Unquoted()], sels[1:], val, path)
+		if err != nil {
+			return nil, err
+		}
+		obj[sel.Unquoted()] = x
+		return obj, nil
+	case cue.IndexLabel:
+		lst, is := data.([]interface{})
+		if !is {
+			return nil, fmt.Errorf("%s: %s is not a list", path, sel)
+		}
+		if sel.Index() >= len(lst) {
+			return nil, fmt.Errorf("%s: index %d out of range", path, sel.Index())
+		}
+		x, err := replaceSels(lst[sel.Index()], sels[1:], val, path)
+		if err != nil {
+			return nil, err
+		}
+		lst[sel.Index()] = x
+		return lst, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported path element %s", path, sel)
+	}
+}
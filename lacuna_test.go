@@ -0,0 +1,356 @@
+package thema
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+const lacunaLinstr = `name: "lacunas"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		count: int
+	}
+}, {
+	version: [1, 0]
+	schema: {
+		title: string
+		owner: string
+		count: int
+		nested: {
+			level: int
+		}
+	}
+}]
+lenses: [{
+	to: [0, 0]
+	from: [1, 0]
+	input: _
+	result: {
+		title: input.title
+		count: input.count
+	}
+	lacunas: [{
+		sourceFields: [{path: "owner", value: input.owner}]
+		message: "owner was dropped"
+		type: {name: "DroppedField", id: 2}
+	}]
+}, {
+	to: [1, 0]
+	from: [0, 0]
+	input: _
+	result: {
+		title: input.title
+		owner: "PLACEHOLDER"
+		count: input.count
+		nested: level: -1
+	}
+	lacunas: [{
+		targetFields: [{path: "owner", value: result.owner}]
+		message: "owner is a placeholder"
+		type: {name: "Placeholder", id: 1}
+	}, {
+		targetFields: [{path: "nested.level", value: result.nested.level}]
+		message: "level is a placeholder"
+		type: {name: "Placeholder", id: 1}
+	}]
+}]
+`
+
+func TestTranslateResolveLacunas(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(lacunaLinstr), rt)
+	require.NoError(t, err)
+
+	inst, err := lin.First().Validate(ctx.CompileString(`{title: "foo", count: 3}`))
+	require.NoError(t, err)
+
+	lookup := func(t *testing.T, inst *Instance, path string) cue.Value {
+		t.Helper()
+		v := inst.Underlying().LookupPath(cue.ParsePath(path))
+		require.True(t, v.Exists(), "%s does not exist", path)
+		return v
+	}
+
+	t.Run("none", func(t *testing.T) {
+		tinst, lac, err := inst.Translate(SV(1, 0))
+		require.NoError(t, err)
+		assert.Len(t, lac.AsList(), 2)
+		owner, _ := lookup(t, tinst, "owner").String()
+		assert.Equal(t, "PLACEHOLDER", owner)
+	})
+
+	t.Run("bytype", func(t *testing.T) {
+		var calls int
+		tinst, lac, err := inst.Translate(SV(1, 0), ResolveLacunaType(LacunaPlaceholder, func(l Lacuna, src *Instance, partial cue.Value) ([]FieldRef, bool, error) {
+			calls++
+			assert.Equal(t, LacunaPlaceholder, l.Type)
+			assert.Equal(t, SV(0, 0), src.Schema().Version())
+			switch l.TargetFields[0].Path {
+			case "owner":
+				title, err := src.Underlying().LookupPath(cue.ParsePath("title")).String()
+				require.NoError(t, err)
+				return []FieldRef{{Path: "owner", Value: title + "-owner"}}, true, nil
+			default:
+				// The partial result reflects the previous resolution
+				owner, err := partial.LookupPath(cue.ParsePath("owner")).String()
+				require.NoError(t, err)
+				assert.Equal(t, "foo-owner", owner)
+				return []FieldRef{{Path: "nested.level", Value: ctx.CompileString("7")}}, true, nil
+			}
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.Empty(t, lac.AsList())
+
+		owner, _ := lookup(t, tinst, "owner").String()
+		assert.Equal(t, "foo-owner", owner)
+		level := lookup(t, tinst, "nested.level")
+		assert.Equal(t, cue.IntKind, level.Kind())
+		lv, _ := level.Int64()
+		assert.Equal(t, int64(7), lv)
+		count := lookup(t, tinst, "count")
+		assert.Equal(t, cue.IntKind, count.Kind(), "untouched ints must remain ints")
+		assert.Equal(t, SV(1, 0), tinst.Schema().Version())
+	})
+
+	t.Run("bypath", func(t *testing.T) {
+		tinst, lac, err := inst.Translate(SV(1, 0),
+			ResolveLacunaType(LacunaPlaceholder, func(l Lacuna, src *Instance, partial cue.Value) ([]FieldRef, bool, error) {
+				return nil, false, nil
+			}),
+			ResolveLacunaPath("nested.level", func(l Lacuna, src *Instance, partial cue.Value) ([]FieldRef, bool, error) {
+				return []FieldRef{{Path: "nested.level", Value: 42}}, true, nil
+			}),
+		)
+		require.NoError(t, err)
+		require.Len(t, lac.AsList(), 1, "only the unresolved lacuna should be reported")
+		assert.Equal(t, "owner", lac.AsList()[0].TargetFields[0].Path)
+
+		lv, _ := lookup(t, tinst, "nested.level").Int64()
+		assert.Equal(t, int64(42), lv)
+		owner, _ := lookup(t, tinst, "owner").String()
+		assert.Equal(t, "PLACEHOLDER", owner)
+	})
+
+	t.Run("acknowledge", func(t *testing.T) {
+		tinst1, _, err := inst.Translate(SV(1, 0), ResolveLacunaType(LacunaPlaceholder, func(Lacuna, *Instance, cue.Value) ([]FieldRef, bool, error) {
+			return []FieldRef{{Path: "owner", Value: "someone"}, {Path: "nested.level", Value: 1}}, true, nil
+		}))
+		require.NoError(t, err)
+
+		tinst0, lac, err := tinst1.Translate(SV(0, 0), ResolveLacunaPath("owner", func(Lacuna, *Instance, cue.Value) ([]FieldRef, bool, error) {
+			// Resolution without replacement values acknowledges the lacuna
+			return nil, true, nil
+		}))
+		require.NoError(t, err)
+		assert.Empty(t, lac.AsList())
+		assert.Equal(t, SV(0, 0), tinst0.Schema().Version())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, _, err := inst.Translate(SV(1, 0), ResolveLacunaPath("owner", func(Lacuna, *Instance, cue.Value) ([]FieldRef, bool, error) {
+			return []FieldRef{{Path: "owner", Value: 42}}, true, nil
+		}))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, terrors.ErrLacunaResolutionIsInvalidData), "unexpected error: %s", err)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, _, err := inst.Translate(SV(1, 0), ResolveLacunaType(LacunaPlaceholder, func(Lacuna, *Instance, cue.Value) ([]FieldRef, bool, error) {
+			return nil, false, errors.New("resolver failed")
+		}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "resolver failed")
+	})
+}
+
+// condLacunaLinstr is a lineage of three major versions, where the lenses to
+// each emit a lacuna only for some instances.
+const condLacunaLinstr = `name: "condlacunas"
+schemas: [{
+	version: [0, 0]
+	schema: count: int
+}, {
+	version: [1, 0]
+	schema: {
+		count: int
+		capped: int
+	}
+}, {
+	version: [2, 0]
+	schema: {
+		capped: int
+		label: string
+	}
+}]
+lenses: [{
+	to: [0, 0]
+	from: [1, 0]
+	input: _
+	result: count: input.count
+}, {
+	to: [1, 0]
+	from: [0, 0]
+	input: _
+	result: {
+		count: input.count
+		if input.count > 5 {
+			capped: 5
+		}
+		if input.count <= 5 {
+			capped: input.count
+		}
+	}
+	lacunas: [{
+		condition: input.count > 5
+		sourceFields: [{path: "count", value: input.count}]
+		targetFields: [{path: "capped", value: result.capped}]
+		message: "count was capped"
+		type: {name: "LossyFieldMapping", id: 3}
+	}]
+}, {
+	to: [1, 0]
+	from: [2, 0]
+	input: _
+	result: {
+		count: input.capped
+		capped: input.capped
+	}
+}, {
+	to: [2, 0]
+	from: [1, 0]
+	input: _
+	result: {
+		capped: input.capped
+		label: "PLACEHOLDER"
+	}
+	lacunas: [{
+		targetFields: [{path: "label", value: result.label}]
+		message: "label is a placeholder"
+		type: {name: "Placeholder", id: 1}
+	}]
+}]
+`
+
+// TestTranslateCUELacunas checks the lacunas emitted by the #Translate CUE
+// func, independent of their decoding in Go.
+func TestTranslateCUELacunas(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(condLacunaLinstr), rt)
+	require.NoError(t, err)
+
+	steps := func(t *testing.T, count int) cue.Value {
+		t.Helper()
+		out, err := cueArgs{
+			"from": SV(0, 0),
+			"to":   SV(2, 0),
+			"lin":  lin.Underlying(),
+			"inst": ctx.CompileString(`count: ` + strconv.Itoa(count)),
+		}.call("#Translate", rt)
+		require.NoError(t, err)
+		return out.LookupPath(cue.ParsePath("steps"))
+	}
+
+	t.Run("conditional", func(t *testing.T) {
+		lac := steps(t, 10).LookupPath(cue.MakePath(cue.Index(0), cue.Str("lacunas")))
+		n, err := lac.Len().Int64()
+		require.NoError(t, err)
+		require.Equal(t, int64(1), n)
+
+		msg, err := lac.LookupPath(cue.MakePath(cue.Index(0), cue.Str("message"))).String()
+		require.NoError(t, err)
+		assert.Equal(t, "count was capped", msg)
+		id, err := lac.LookupPath(cue.MakePath(cue.Index(0), cue.Str("type"), cue.Str("id"))).Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(LacunaLossyFieldMapping), id)
+		val, err := lac.LookupPath(cue.MakePath(cue.Index(0), cue.Str("targetFields"), cue.Index(0), cue.Str("value"))).Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), val)
+	})
+
+	t.Run("unmet", func(t *testing.T) {
+		st := steps(t, 3)
+		n, err := st.LookupPath(cue.MakePath(cue.Index(0), cue.Str("lacunas"))).Len().Int64()
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), n, "lacunas whose condition is false must not be emitted")
+
+		msg, err := st.LookupPath(cue.MakePath(cue.Index(1), cue.Str("lacunas"), cue.Index(0), cue.Str("message"))).String()
+		require.NoError(t, err)
+		assert.Equal(t, "label is a placeholder", msg)
+	})
+}
+
+func TestTranslateLacunas(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(condLacunaLinstr), rt)
+	require.NoError(t, err)
+
+	translate := func(t *testing.T, count int) multiTranslationLacunas {
+		t.Helper()
+		inst, err := lin.First().Validate(ctx.CompileString(`count: ` + strconv.Itoa(count)))
+		require.NoError(t, err)
+		_, lac, err := inst.Translate(SV(2, 0))
+		require.NoError(t, err)
+		require.IsType(t, multiTranslationLacunas{}, lac)
+		return lac.(multiTranslationLacunas)
+	}
+
+	t.Run("bystep", func(t *testing.T) {
+		lac := translate(t, 10)
+		require.Len(t, lac, 2)
+		assert.Equal(t, SV(1, 0), lac[0].V)
+		assert.Equal(t, []Lacuna{{
+			SourceFields: []FieldRef{{Path: "count", Value: 10}},
+			TargetFields: []FieldRef{{Path: "capped", Value: 5}},
+			Type:         LacunaLossyFieldMapping,
+			Message:      "count was capped",
+		}}, lac[0].Lac)
+		assert.Equal(t, SV(2, 0), lac[1].V)
+		assert.Equal(t, []Lacuna{{
+			SourceFields: []FieldRef{},
+			TargetFields: []FieldRef{{Path: "label", Value: "PLACEHOLDER"}},
+			Type:         LacunaPlaceholder,
+			Message:      "label is a placeholder",
+		}}, lac[1].Lac)
+	})
+
+	t.Run("omitsempty", func(t *testing.T) {
+		lac := translate(t, 3)
+		require.Len(t, lac, 1, "steps emitting no lacunas must be omitted")
+		assert.Equal(t, SV(2, 0), lac[0].V)
+		assert.Len(t, lac.AsList(), 1)
+	})
+}
+
+func TestLacunaTypeUnmarshalJSON(t *testing.T) {
+	for in, want := range map[string]LacunaType{
+		`1`:                                 LacunaPlaceholder,
+		`{"name": "DroppedField", "id": 2}`: LacunaDroppedField,
+		`{"id": 4}`:                         LacunaChangedDefault,
+		`{"name": "Placeholder", "id": 1, "x": true}`: LacunaPlaceholder,
+	} {
+		var lt LacunaType
+		if assert.NoError(t, json.Unmarshal([]byte(in), &lt), in) {
+			assert.Equal(t, want, lt, in)
+		}
+	}
+
+	for _, in := range []string{`"Placeholder"`, `{"name": "Placeholder"}`, `-1`, `{"id": "1"}`} {
+		var lt LacunaType
+		assert.Error(t, json.Unmarshal([]byte(in), &lt), in)
+	}
+}
@@ -0,0 +1,94 @@
+package thema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+// GoLensMapper adapts a func that translates between Go representations of two
+// schemas in a lineage into a func suitable for use as an
+// [ImperativeLens.LacunaMapper]. Such funcs are typically generated from a
+// lineage's CUE lenses by [gocode.GenerateLenses].
+//
+// The instance being translated is hydrated, then converted to an F via JSON.
+// The T returned from fn is converted back via JSON and validated against the
+// target schema. The lacunas returned from fn are returned unchanged.
+//
+// [gocode.GenerateLenses]: https://pkg.go.dev/github.com/grafana/thema/encoding/gocode#GenerateLenses
+func GoLensMapper[F, T any](fn func(*F) (*T, []Lacuna, error)) func(inst *Instance, to Schema) (*Instance, []Lacuna, error) {
+	return func(inst *Instance, to Schema) (*Instance, []Lacuna, error) {
+		rt := inst.rt()
+		rt.l()
+		b, err := json.Marshal(inst.Hydrate().raw)
+		rt.u()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to encode instance as JSON: %w", err)
+		}
+
+		in := new(F)
+		if err = json.Unmarshal(b, in); err != nil {
+			return nil, nil, fmt.Errorf("unable to decode instance into %T: %w", in, err)
+		}
+		out, lac, err := fn(in)
+		if err != nil {
+			return nil, nil, err
+		}
+		if out == nil {
+			return nil, nil, fmt.Errorf("lens returned a nil %T", out)
+		}
+
+		data, err := encodeJSONData(to.Underlying().Context(), out)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to encode %T as CUE: %w", out, err)
+		}
+		inst, err = to.Validate(data)
+		if err != nil {
+			return nil, nil, errors.Mark(err, terrors.ErrLensResultIsInvalidData)
+		}
+		return inst, lac, nil
+	}
+}
+
+// CUELensMapper returns a func suitable for use as an
+// [ImperativeLens.LacunaMapper] that executes the CUE lens declared in the
+// lineage for translating from the from version to the to version, returning
+// the lacunas it emits. It allows a set of [ImperativeLenses] to include lenses
+// that are not (or cannot be) written in Go.
+func CUELensMapper(from, to SyntacticVersion) func(inst *Instance, to Schema) (*Instance, []Lacuna, error) {
+	return func(inst *Instance, sch Schema) (*Instance, []Lacuna, error) {
+		if inst.Schema().Version() != from || sch.Version() != to {
+			return nil, nil, fmt.Errorf("CUE lens %s can not translate from %s to %s", lid(from, to), inst.Schema().Version(), sch.Version())
+		}
+
+		lin := inst.Schema().Lineage().(*baseLineage)
+		fn, err := cueArgs{
+			"to":   to,
+			"from": from,
+			"lin":  lin.Underlying(),
+		}.make("#Translate", lin.rt)
+		if err != nil {
+			// This can't happen without a name change or an invariant violation
+			panic(err)
+		}
+
+		tr := &translator{
+			from:   from,
+			to:     to,
+			newsch: sch,
+			fn:     fn,
+		}
+		raw, lac, err := tr.evaluate(inst)
+		if err != nil {
+			return nil, nil, err
+		}
+		ninst, err := sch.Validate(raw)
+		if err != nil {
+			return nil, nil, errors.Mark(err, terrors.ErrLensResultIsInvalidData)
+		}
+		return ninst, lac.AsList(), nil
+	}
+}
@@ -47,6 +47,13 @@ type baseLineage struct {
 // invariants, such as translatability and backwards compatibility version
 // numbering. If these checks succeed, a [Lineage] is returned.
 //
+// Every schema with a non-zero minor version must be backwards compatible with
+// its predecessor, and the first schema in each major version must be
+// backwards incompatible with its predecessor. Violations are reported as a
+// [*CompatError], which names the versions and field paths involved. These
+// checks are not performed if [SkipBuggyChecks] is passed, or if the lineage
+// matches the [BindArtifact] passed with [TrustBindArtifact].
+//
 // This function is the only way to create non-nil Lineage objects. As a result,
 // all non-nil instances of Lineage in any Go program are guaranteed to follow
 // Thema invariants.
@@ -125,9 +132,19 @@ func BindLineage(v cue.Value, rt *Runtime, opts ...BindOption) (Lineage, error)
 	if err := ml.checkNativeValidity(cfg); err != nil {
 		return nil, err
 	}
+	ml.trusted = ml.trustArtifact(cfg)
 	if err := ml.checkGoValidity(cfg); err != nil {
 		return nil, err
 	}
+	if ml.trusted && !cfg.artifact.matchesVersions(ml.allv) {
+		// The artifact does not describe this lineage after all, so the
+		// skipped checks must be performed
+		ml.trusted = false
+		ml.schlist, ml.allv = nil, nil
+		if err := ml.checkGoValidity(cfg); err != nil {
+			return nil, err
+		}
+	}
 	if err := ml.checkLensesOrder(); err != nil {
 		return nil, err
 	}
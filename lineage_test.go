@@ -274,3 +274,128 @@ func BenchmarkBindLineage(b *testing.B) {
 		}
 	})
 }
+
+// benchArtifactLinstr is a lineage with schemas complex enough, and enough of
+// them, that the compatibility checks between them are a significant part of
+// the cost of binding it.
+var benchArtifactLinstr = `
+name: "dashboard"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		#Panel: {
+			type:   "graph" | "table" | "text"
+			title?: string
+			gridPos: {x: int & >=0, y: int & >=0, w: int & >0 & <=24, h: int & >0}
+			targets?: [...{refId: string, expr?: string, hide?: bool}]
+		}
+		title: string
+		panels?: [...#Panel | {type: "row", collapsed: bool, panels: [...#Panel]}]
+		time?: {from: string | *"now-6h", to: string | *"now"}
+		refresh?: string | false
+		links?: [...{title: string, url: string, targetBlank?: bool}]
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		#Panel: {
+			type:   "graph" | "table" | "text"
+			title?: string
+			gridPos: {x: int & >=0, y: int & >=0, w: int & >0 & <=24, h: int & >0}
+			targets?: [...{refId: string, expr?: string, hide?: bool}]
+		}
+		title: string
+		panels?: [...#Panel | {type: "row", collapsed: bool, panels: [...#Panel]}]
+		time?: {from: string | *"now-6h", to: string | *"now"}
+		refresh?: string | false
+		links?: [...{title: string, url: string, targetBlank?: bool}]
+		tags?: [...string]
+	}
+}, {
+	version: [0, 2]
+	schema: {
+		#Panel: {
+			type:   "graph" | "table" | "text"
+			title?: string
+			gridPos: {x: int & >=0, y: int & >=0, w: int & >0 & <=24, h: int & >0}
+			targets?: [...{refId: string, expr?: string, hide?: bool}]
+			description?: string
+		}
+		title: string
+		panels?: [...#Panel | {type: "row", collapsed: bool, panels: [...#Panel]}]
+		time?: {from: string | *"now-6h", to: string | *"now"}
+		refresh?: string | false
+		links?: [...{title: string, url: string, targetBlank?: bool}]
+		tags?: [...string]
+	}
+}, {
+	version: [0, 3]
+	schema: {
+		#Panel: {
+			type:   "graph" | "table" | "text"
+			title?: string
+			gridPos: {x: int & >=0, y: int & >=0, w: int & >0 & <=24, h: int & >0}
+			targets?: [...{refId: string, expr?: string, hide?: bool}]
+			description?: string
+		}
+		title: string
+		panels?: [...#Panel | {type: "row", collapsed: bool, panels: [...#Panel]}]
+		time?: {from: string | *"now-6h", to: string | *"now"}
+		refresh?: string | false
+		links?: [...{title: string, url: string, targetBlank?: bool}]
+		tags?: [...string]
+		editable?: bool | *true
+	}
+}]
+`
+
+// BenchmarkBindLineageArtifact benchmarks binding a lineage from a freshly
+// loaded CUE instance in a new cue.Context, as on program startup, with and
+// without trusting a BindArtifact. Binding the same cue.Value repeatedly, as
+// BenchmarkBindLineage does, would benefit from evaluation cached by CUE.
+func BenchmarkBindLineageArtifact(b *testing.B) {
+	inst := loadInstanceString(b, benchArtifactLinstr)
+	ctx := cuecontext.New()
+	lin, err := BindLineage(ctx.BuildInstance(inst), NewRuntime(ctx))
+	if err != nil {
+		b.Fatal(err)
+	}
+	art, err := NewBindArtifact(lin, inst)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bc := range []struct {
+		name string
+		opts func(inst *build.Instance) []BindOption
+	}{
+		{
+			name: "Full",
+			opts: func(*build.Instance) []BindOption { return nil },
+		},
+		{
+			name: "Trusted",
+			opts: func(inst *build.Instance) []BindOption {
+				return []BindOption{TrustBindArtifact(art, inst)}
+			},
+		},
+	} {
+		bc := bc
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// Loading CUE is the same with or without an artifact, so is
+				// excluded to show the difference between binds
+				b.StopTimer()
+				inst := loadInstanceString(b, benchArtifactLinstr)
+				ctx := cuecontext.New()
+				rt, linv := NewRuntime(ctx), ctx.BuildInstance(inst)
+				b.StartTimer()
+
+				_, err := BindLineage(linv, rt, bc.opts(inst)...)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
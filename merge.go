@@ -0,0 +1,265 @@
+package thema
+
+import (
+	"fmt"
+	"sort"
+
+	"cuelang.org/go/cue"
+)
+
+// MergeConflict describes a single location at which both sides of a
+// three-way merge changed the base in incompatible ways.
+type MergeConflict struct {
+	// Path is the path to the conflicting value within the merged instance.
+	Path cue.Path
+
+	// Base, Ours and Theirs are the values at Path in each of the inputs to the
+	// merge, after translation to the merge version. A value for which Exists()
+	// is false indicates that the field was absent from that input.
+	Base, Ours, Theirs cue.Value
+}
+
+// Merge3 performs a three-way merge of two instances, ours and theirs, that
+// were both derived from a common base instance. The three instances may be of
+// any schema in the same lineage.
+//
+// base and theirs are first translated to the version of ours, which is the
+// version of the merged instance. The provided options are used for both
+// translations, and the lacunas they emit are returned.
+//
+// Merging proceeds field by field, guided by the schema:
+//
+//   - A value changed on only one side takes that side's value.
+//   - Structs changed on both sides are merged recursively, field by field.
+//   - Lists changed on both sides are merged element by element when all three
+//     have the same length, and by concatenating both sides' appended elements
+//     when each side only appended to the base list.
+//   - Values of a disjunction are merged recursively only when both sides
+//     select the same branch.
+//
+// Any other change made by both sides is a conflict. Conflicts are resolved in
+// favor of ours, and reported in the returned slice. The merged data is
+// validated against the schema of ours, and an error is returned if it is
+// invalid.
+func Merge3(base, ours, theirs *Instance, opts ...TranslateOption) (*Instance, []MergeConflict, TranslationLacunas, error) {
+	base.check()
+	ours.check()
+	theirs.check()
+
+	sch := ours.Schema()
+	if base.Schema().Lineage() != sch.Lineage() || theirs.Schema().Lineage() != sch.Lineage() {
+		return nil, nil, nil, fmt.Errorf("cannot merge instances of different lineages")
+	}
+
+	tbase, blac, err := translateTo(base, sch.Version(), opts...)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error translating base instance to %s: %w", sch.Version(), err)
+	}
+	ttheirs, tlac, err := translateTo(theirs, sch.Version(), opts...)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error translating their instance to %s: %w", sch.Version(), err)
+	}
+
+	var b, o, t interface{}
+	for _, in := range []struct {
+		inst *Instance
+		x    *interface{}
+	}{{tbase, &b}, {ours, &o}, {ttheirs, &t}} {
+		if err = decodeJSONNumber(in.inst.Underlying(), in.x); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	ctx := ours.Underlying().Context()
+	m := &merger{ctx: ctx}
+	merged := m.merge(schemaValue(sch), nil, mval{b, true}, mval{o, true}, mval{t, true})
+
+	v, err := encodeJSONData(ctx, merged.x)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	minst, err := sch.Validate(v)
+	if err != nil {
+		return nil, m.conflicts, nil, fmt.Errorf("merged data is not valid: %w", err)
+	}
+	return minst, m.conflicts, concatLacunas(blac, tlac), nil
+}
+
+// translateTo translates the instance to the provided version, returning it
+// unchanged if it is already of that version.
+func translateTo(inst *Instance, to SyntacticVersion, opts ...TranslateOption) (*Instance, TranslationLacunas, error) {
+	if inst.Schema().Version() == to {
+		return inst, nil, nil
+	}
+	return inst.Translate(to, opts...)
+}
+
+// mval is a value within JSON data of the form produced by decodeJSONNumber,
+// along with whether it exists.
+type mval struct {
+	x  interface{}
+	ok bool
+}
+
+func (v mval) equals(w mval) bool {
+	if !v.ok || !w.ok {
+		return v.ok == w.ok
+	}
+	return jsonEqual(v.x, w.x)
+}
+
+type merger struct {
+	ctx       *cue.Context
+	conflicts []MergeConflict
+}
+
+func (m *merger) cueValue(v mval) cue.Value {
+	if !v.ok {
+		return cue.Value{}
+	}
+	cv, err := encodeJSONData(m.ctx, v.x)
+	if err != nil {
+		// Unreachable, as the value was itself decoded from JSON
+		panic(err)
+	}
+	return cv
+}
+
+// conflict records a conflict at the provided path, and resolves it in favor
+// of ours.
+func (m *merger) conflict(sels []cue.Selector, b, o, t mval) mval {
+	m.conflicts = append(m.conflicts, MergeConflict{
+		Path:   cue.MakePath(sels...),
+		Base:   m.cueValue(b),
+		Ours:   m.cueValue(o),
+		Theirs: m.cueValue(t),
+	})
+	return o
+}
+
+// merge merges the values b, o and t, which are at the location described by
+// sels and constrained by the schema value sch.
+func (m *merger) merge(sch cue.Value, sels []cue.Selector, b, o, t mval) mval {
+	switch {
+	case o.equals(t), b.equals(t):
+		return o
+	case b.equals(o):
+		return t
+	case !b.ok || !o.ok || !t.ok:
+		// Added on both sides with different values, or removed on one side
+		// and changed on the other
+		return m.conflict(sels, b, o, t)
+	}
+
+	if sch.Exists() {
+		if op, arms := sch.Expr(); op == cue.OrOp {
+			oarm, tarm := disjunctBranch(arms, m.cueValue(o)), disjunctBranch(arms, m.cueValue(t))
+			if oarm < 0 || oarm != tarm {
+				return m.conflict(sels, b, o, t)
+			}
+			sch = arms[oarm]
+		}
+	}
+
+	switch ox := o.x.(type) {
+	case map[string]interface{}:
+		bx, bok := b.x.(map[string]interface{})
+		tx, tok := t.x.(map[string]interface{})
+		if !bok || !tok {
+			return m.conflict(sels, b, o, t)
+		}
+		return m.mergeStructs(sch, sels, bx, ox, tx)
+	case []interface{}:
+		bx, bok := b.x.([]interface{})
+		tx, tok := t.x.([]interface{})
+		if !bok || !tok {
+			return m.conflict(sels, b, o, t)
+		}
+		return m.mergeLists(sch, sels, bx, ox, tx)
+	default:
+		return m.conflict(sels, b, o, t)
+	}
+}
+
+func (m *merger) mergeStructs(sch cue.Value, sels []cue.Selector, b, o, t map[string]interface{}) mval {
+	keys := make(map[string]bool, len(o))
+	for _, x := range []map[string]interface{}{b, o, t} {
+		for k := range x {
+			keys[k] = true
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	ret := make(map[string]interface{}, len(keys))
+	for _, k := range sorted {
+		bv, bok := b[k]
+		ov, ook := o[k]
+		tv, tok := t[k]
+		v := m.merge(fieldSchema(sch, k), append(sels[:len(sels):len(sels)], cue.Str(k)), mval{bv, bok}, mval{ov, ook}, mval{tv, tok})
+		if v.ok {
+			ret[k] = v.x
+		}
+	}
+	return mval{ret, true}
+}
+
+func (m *merger) mergeLists(sch cue.Value, sels []cue.Selector, b, o, t []interface{}) mval {
+	elem := sch.LookupPath(cue.MakePath(cue.AnyIndex))
+
+	if len(b) == len(o) && len(b) == len(t) {
+		ret := make([]interface{}, len(b))
+		for i := range b {
+			v := m.merge(elem, append(sels[:len(sels):len(sels)], cue.Index(i)), mval{b[i], true}, mval{o[i], true}, mval{t[i], true})
+			ret[i] = v.x
+		}
+		return mval{ret, true}
+	}
+
+	if isJSONPrefix(b, o) && isJSONPrefix(b, t) {
+		ret := make([]interface{}, 0, len(o)+len(t)-len(b))
+		ret = append(append(ret, o...), t[len(b):]...)
+		return mval{ret, true}
+	}
+	return m.conflict(sels, mval{b, true}, mval{o, true}, mval{t, true})
+}
+
+// disjunctBranch returns the index of the first of the disjunction's arms that
+// accepts the provided value, or -1 if none do.
+func disjunctBranch(arms []cue.Value, v cue.Value) int {
+	for i, arm := range arms {
+		if arm.Unify(v).Validate(cue.Concrete(true)) == nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// fieldSchema returns the schema for the field with the provided label within
+// the struct schema sch, falling back on any pattern constraint.
+func fieldSchema(sch cue.Value, label string) cue.Value {
+	if !sch.Exists() {
+		return sch
+	}
+	for _, sel := range []cue.Selector{cue.Str(label), cue.Str(label).Optional(), cue.AnyString} {
+		if v := sch.LookupPath(cue.MakePath(sel)); v.Exists() {
+			return v
+		}
+	}
+	return cue.Value{}
+}
+
+func isJSONPrefix(prefix, l []interface{}) bool {
+	if len(prefix) > len(l) {
+		return false
+	}
+	for i := range prefix {
+		if !jsonEqual(prefix[i], l[i]) {
+			return false
+		}
+	}
+	return true
+}
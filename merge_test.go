@@ -0,0 +1,211 @@
+package thema
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+const mergeLinstr = `name: "merge"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		tags: [...string]
+		panels: [...#Panel]
+		target: #Text | #Graph
+		min: int
+		max: int & >=min
+
+		#Panel: {
+			id:    int
+			title: string
+		}
+		#Text: {
+			kind:    "text"
+			content: string
+		}
+		#Graph: {
+			kind:   "graph"
+			series: int
+		}
+	}
+}]
+`
+
+func TestMerge3(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(mergeLinstr), rt)
+	require.NoError(t, err)
+
+	validate := func(t *testing.T, str string) *Instance {
+		t.Helper()
+		inst, err := lin.First().Validate(ctx.CompileString(str))
+		require.NoError(t, err)
+		return inst
+	}
+
+	base := validate(t, `{
+		title: "a"
+		tags: ["x"]
+		panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+		target: {kind: "text", content: "c"}
+		min: 0
+		max: 10
+	}`)
+
+	t.Run("clean", func(t *testing.T) {
+		ours := validate(t, `{
+			title: "b"
+			tags: ["x", "y"]
+			panels: [{id: 1, title: "q1"}, {id: 2, title: "p2"}]
+			target: {kind: "text", content: "c"}
+			min: 0
+			max: 10
+		}`)
+		theirs := validate(t, `{
+			title: "a"
+			tags: ["x", "z"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "q2"}]
+			target: {kind: "text", content: "d"}
+			min: 0
+			max: 20
+		}`)
+
+		minst, conflicts, _, err := Merge3(base, ours, theirs)
+		require.NoError(t, err)
+		assert.Empty(t, conflicts)
+		assert.True(t, minst.Underlying().Equals(ctx.CompileString(`{
+			title: "b"
+			tags: ["x", "y", "z"]
+			panels: [{id: 1, title: "q1"}, {id: 2, title: "q2"}]
+			target: {kind: "text", content: "d"}
+			min: 0
+			max: 20
+		}`)), "got %v", minst.Underlying())
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		ours := validate(t, `{
+			title: "b"
+			tags: ["y"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+			target: {kind: "graph", series: 2}
+			min: 0
+			max: 10
+		}`)
+		theirs := validate(t, `{
+			title: "c"
+			tags: ["z", "x"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+			target: {kind: "text", content: "d"}
+			min: 0
+			max: 10
+		}`)
+
+		minst, conflicts, _, err := Merge3(base, ours, theirs)
+		require.NoError(t, err)
+
+		var paths []string
+		for _, c := range conflicts {
+			paths = append(paths, c.Path.String())
+		}
+		assert.Equal(t, []string{"tags", "target", "title"}, paths)
+
+		title := conflicts[2]
+		assert.True(t, title.Base.Equals(ctx.CompileString(`"a"`)))
+		assert.True(t, title.Ours.Equals(ctx.CompileString(`"b"`)))
+		assert.True(t, title.Theirs.Equals(ctx.CompileString(`"c"`)))
+
+		// Conflicts are resolved in favor of ours
+		assert.True(t, minst.Underlying().Equals(ours.Underlying()), "got %v", minst.Underlying())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		ours := validate(t, `{
+			title: "a"
+			tags: ["x"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+			target: {kind: "text", content: "e"}
+			min: 0
+			max: 10
+		}`)
+		theirs := validate(t, `{
+			title: "a"
+			tags: ["x"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+			target: {kind: "text", content: "d"}
+			min: 1
+			max: 10
+		}`)
+
+		minst, conflicts, _, err := Merge3(base, ours, theirs)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "target.content", conflicts[0].Path.String())
+		min, _ := minst.Underlying().LookupPath(cue.ParsePath("min")).Int64()
+		assert.Equal(t, int64(1), min)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		ours := validate(t, `{
+			title: "a"
+			tags: ["x"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+			target: {kind: "text", content: "c"}
+			min: 5
+			max: 10
+		}`)
+		theirs := validate(t, `{
+			title: "a"
+			tags: ["x"]
+			panels: [{id: 1, title: "p1"}, {id: 2, title: "p2"}]
+			target: {kind: "text", content: "c"}
+			min: 0
+			max: 3
+		}`)
+
+		// Each change is valid alone, but not in combination
+		_, conflicts, _, err := Merge3(base, ours, theirs)
+		assert.Empty(t, conflicts)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, terrors.ErrInvalidData), "unexpected error: %s", err)
+	})
+}
+
+func TestMerge3AcrossVersions(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(lacunaLinstr), rt)
+	require.NoError(t, err)
+
+	base, err := SchemaP(lin, SV(1, 0)).Validate(ctx.CompileString(`{title: "foo", owner: "someone", count: 3, nested: level: 2}`))
+	require.NoError(t, err)
+	ours, err := SchemaP(lin, SV(1, 0)).Validate(ctx.CompileString(`{title: "foo", owner: "someone else", count: 3, nested: level: 2}`))
+	require.NoError(t, err)
+	theirs, err := SchemaP(lin, SV(0, 0)).Validate(ctx.CompileString(`{title: "bar", count: 3}`))
+	require.NoError(t, err)
+
+	minst, conflicts, lac, err := Merge3(base, ours, theirs)
+	require.NoError(t, err)
+	assert.Equal(t, SV(1, 0), minst.Schema().Version())
+
+	// Translating theirs up fills placeholders. The owner placeholder conflicts
+	// with the change made on our side, while the level placeholder is taken as
+	// their change, and reported as a lacuna.
+	require.Len(t, lac.AsList(), 2)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "owner", conflicts[0].Path.String())
+
+	title, _ := minst.Underlying().LookupPath(cue.ParsePath("title")).String()
+	assert.Equal(t, "bar", title)
+	level, _ := minst.Underlying().LookupPath(cue.ParsePath("nested.level")).Int64()
+	assert.Equal(t, int64(-1), level)
+}
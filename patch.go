@@ -0,0 +1,454 @@
+package thema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+// ApplyPatch applies a patch document, written against the schema with
+// version patchVersion, to the provided instance, which may be of any schema
+// in the same lineage.
+//
+// Two kinds of patch documents are accepted, distinguished by their JSON type:
+//
+//   - A JSON array is treated as an RFC 6902 JSON Patch.
+//   - Any other JSON value is treated as an RFC 7396 JSON Merge Patch.
+//
+// The instance is first translated to patchVersion, the patch applied to the
+// translated instance, and the result validated against the schema at
+// patchVersion. The patched instance is then translated back to the version of
+// the provided instance, and returned along with the lacunas emitted by
+// translation in both directions. The provided options are used for both
+// translations.
+//
+// Errors arising from malformed or inapplicable patches are children of
+// [terrors.ErrInvalidPatch]. If the patched data is not valid for the schema at
+// patchVersion, the validation error is returned.
+func ApplyPatch(inst *Instance, patch []byte, patchVersion SyntacticVersion, opts ...TranslateOption) (*Instance, TranslationLacunas, error) {
+	inst.check()
+	psch, err := inst.Schema().Lineage().Schema(patchVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pinst, lac, err := inst.Translate(patchVersion, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error translating instance to patch version %s: %w", patchVersion, err)
+	}
+
+	var doc interface{}
+	if err = decodeJSONNumber(pinst.Underlying(), &doc); err != nil {
+		return nil, nil, err
+	}
+	if doc, err = applyPatchDocument(doc, patch); err != nil {
+		return nil, nil, err
+	}
+
+	v, err := encodeJSONData(inst.Underlying().Context(), doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	patched, err := psch.Validate(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rinst, rlac, err := patched.Translate(inst.Schema().Version(), opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error translating patched instance back to %s: %w", inst.Schema().Version(), err)
+	}
+	return rinst, concatLacunas(lac, rlac), nil
+}
+
+// concatLacunas combines the lacunas from two successive translations.
+func concatLacunas(a, b TranslationLacunas) TranslationLacunas {
+	ma, isa := a.(multiTranslationLacunas)
+	mb, isb := b.(multiTranslationLacunas)
+	switch {
+	case (a == nil || isa) && (b == nil || isb):
+		ret := make(multiTranslationLacunas, 0, len(ma)+len(mb))
+		return append(append(ret, ma...), mb...)
+	default:
+		var ret flatLacunas
+		if a != nil {
+			ret = append(ret, a.AsList()...)
+		}
+		if b != nil {
+			ret = append(ret, b.AsList()...)
+		}
+		return ret
+	}
+}
+
+func patchErr(format string, args ...interface{}) error {
+	return errors.Mark(fmt.Errorf(format, args...), terrors.ErrInvalidPatch)
+}
+
+// applyPatchDocument applies a JSON Patch or JSON Merge Patch to doc, which is
+// of the form produced by decodeJSONNumber.
+func applyPatchDocument(doc interface{}, patch []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.UseNumber()
+
+	trimmed := bytes.TrimSpace(patch)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var ops []jsonPatchOp
+		if err := dec.Decode(&ops); err != nil {
+			return nil, patchErr("malformed JSON Patch: %w", err)
+		}
+		for i, op := range ops {
+			var err error
+			if doc, err = op.apply(doc); err != nil {
+				return nil, fmt.Errorf("JSON Patch operation %d (%s): %w", i, op.Op, err)
+			}
+		}
+		return doc, nil
+	}
+
+	var mp interface{}
+	if err := dec.Decode(&mp); err != nil {
+		return nil, patchErr("malformed JSON Merge Patch: %w", err)
+	}
+	return mergePatch(doc, mp), nil
+}
+
+// mergePatch implements the MergePatch function from RFC 7396.
+func mergePatch(target, patch interface{}) interface{} {
+	pobj, is := patch.(map[string]interface{})
+	if !is {
+		return patch
+	}
+	tobj, is := target.(map[string]interface{})
+	if !is {
+		tobj = make(map[string]interface{})
+	}
+	for k, v := range pobj {
+		if v == nil {
+			delete(tobj, k)
+		} else {
+			tobj[k] = mergePatch(tobj[k], v)
+		}
+	}
+	return tobj
+}
+
+// jsonPatchOp is a single operation in an RFC 6902 JSON Patch document.
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (op jsonPatchOp) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, patchErr("missing path")
+	}
+	path, err := parseJSONPointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, patchErr("missing value")
+		}
+		dec := json.NewDecoder(bytes.NewReader(op.Value))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, patchErr("malformed value: %w", err)
+		}
+		return v, nil
+	}
+	from := func() (jsonPointer, error) {
+		if op.From == nil {
+			return nil, patchErr("missing from")
+		}
+		return parseJSONPointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return path.add(doc, v)
+	case "remove":
+		doc, _, err := path.remove(doc)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return v, nil
+		}
+		if doc, _, err = path.remove(doc); err != nil {
+			return nil, err
+		}
+		return path.add(doc, v)
+	case "move":
+		fp, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if fp.isPrefixOf(path) && len(fp) != len(path) {
+			return nil, patchErr("cannot move a value into one of its own children")
+		}
+		doc, v, err := fp.remove(doc)
+		if err != nil {
+			return nil, err
+		}
+		return path.add(doc, v)
+	case "copy":
+		fp, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := fp.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		return path.add(doc, deepCopyJSON(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		got, err := path.get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(got, v) {
+			return nil, errors.Mark(errors.Mark(fmt.Errorf("value at %s does not match", *op.Path), terrors.ErrPatchTestFailed), terrors.ErrInvalidPatch)
+		}
+		return doc, nil
+	default:
+		return nil, patchErr("unknown operation %q", op.Op)
+	}
+}
+
+// jsonPointer is an RFC 6901 JSON Pointer, as a list of unescaped reference
+// tokens.
+type jsonPointer []string
+
+func parseJSONPointer(s string) (jsonPointer, error) {
+	if s == "" {
+		return jsonPointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, patchErr("JSON Pointer %q does not begin with /", s)
+	}
+	toks := strings.Split(s[1:], "/")
+	for i, tok := range toks {
+		toks[i] = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+	}
+	return toks, nil
+}
+
+func (p jsonPointer) String() string {
+	var sb strings.Builder
+	for _, tok := range p {
+		sb.WriteByte('/')
+		sb.WriteString(strings.ReplaceAll(strings.ReplaceAll(tok, "~", "~0"), "/", "~1"))
+	}
+	return sb.String()
+}
+
+func (p jsonPointer) isPrefixOf(o jsonPointer) bool {
+	if len(p) > len(o) {
+		return false
+	}
+	for i := range p {
+		if p[i] != o[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses tok as an index into an array of length n. If end is true, the
+// "-" token, indicating the position after the last element, is permitted.
+func arrayIndex(tok string, n int, end bool) (int, error) {
+	if end && tok == "-" {
+		return n, nil
+	}
+	if tok == "" || (len(tok) > 1 && tok[0] == '0') || strings.TrimLeft(tok, "0123456789") != "" {
+		return 0, patchErr("invalid array index %q", tok)
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i > n || (!end && i == n) {
+		return 0, patchErr("array index %s out of range", tok)
+	}
+	return i, nil
+}
+
+func (p jsonPointer) get(doc interface{}) (interface{}, error) {
+	for i, tok := range p {
+		switch x := doc.(type) {
+		case map[string]interface{}:
+			v, has := x[tok]
+			if !has {
+				return nil, patchErr("%s does not exist", p[:i+1])
+			}
+			doc = v
+		case []interface{}:
+			idx, err := arrayIndex(tok, len(x), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = x[idx]
+		default:
+			return nil, patchErr("%s does not exist", p[:i+1])
+		}
+	}
+	return doc, nil
+}
+
+// update calls fn with the parent of the location referenced by p and the
+// final reference token, replacing the parent with the value fn returns. The
+// updated document is returned. p must be non-empty.
+func (p jsonPointer) update(doc interface{}, fn func(parent interface{}, tok string) (interface{}, error)) (interface{}, error) {
+	if len(p) == 1 {
+		return fn(doc, p[0])
+	}
+	parent, err := p[:1].get(doc)
+	if err != nil {
+		return nil, err
+	}
+	child, err := p[1:].update(parent, fn)
+	if err != nil {
+		return nil, err
+	}
+	switch x := doc.(type) {
+	case map[string]interface{}:
+		x[p[0]] = child
+	case []interface{}:
+		idx, _ := arrayIndex(p[0], len(x), false) // nolint: errcheck
+		x[idx] = child
+	}
+	return doc, nil
+}
+
+func (p jsonPointer) add(doc, v interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return v, nil
+	}
+	return p.update(doc, func(parent interface{}, tok string) (interface{}, error) {
+		switch x := parent.(type) {
+		case map[string]interface{}:
+			x[tok] = v
+			return x, nil
+		case []interface{}:
+			idx, err := arrayIndex(tok, len(x), true)
+			if err != nil {
+				return nil, err
+			}
+			x = append(x, nil)
+			copy(x[idx+1:], x[idx:])
+			x[idx] = v
+			return x, nil
+		default:
+			return nil, patchErr("parent of %s is not an object or array", p)
+		}
+	})
+}
+
+func (p jsonPointer) remove(doc interface{}) (interface{}, interface{}, error) {
+	if len(p) == 0 {
+		return nil, nil, patchErr("cannot remove the root of the document")
+	}
+	var removed interface{}
+	doc, err := p.update(doc, func(parent interface{}, tok string) (interface{}, error) {
+		switch x := parent.(type) {
+		case map[string]interface{}:
+			v, has := x[tok]
+			if !has {
+				return nil, patchErr("%s does not exist", p)
+			}
+			removed = v
+			delete(x, tok)
+			return x, nil
+		case []interface{}:
+			idx, err := arrayIndex(tok, len(x), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = x[idx]
+			return append(x[:idx:idx], x[idx+1:]...), nil
+		default:
+			return nil, patchErr("%s does not exist", p)
+		}
+	})
+	return doc, removed, err
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(x))
+		for k, ev := range x {
+			ret[k] = deepCopyJSON(ev)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(x))
+		for i, ev := range x {
+			ret[i] = deepCopyJSON(ev)
+		}
+		return ret
+	default:
+		return v
+	}
+}
+
+// jsonEqual reports whether a and b are equal according to the rules of the
+// JSON Patch "test" operation. Numbers are equal if their values are equal.
+func jsonEqual(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, is := b.(map[string]interface{})
+		if !is || len(x) != len(y) {
+			return false
+		}
+		for k, xv := range x {
+			yv, has := y[k]
+			if !has || !jsonEqual(xv, yv) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, is := b.([]interface{})
+		if !is || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !jsonEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, is := b.(json.Number)
+		if !is {
+			return false
+		}
+		xr, xok := new(big.Rat).SetString(string(x))
+		yr, yok := new(big.Rat).SetString(string(y))
+		return xok && yok && xr.Cmp(yr) == 0
+	default:
+		return a == b
+	}
+}
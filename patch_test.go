@@ -0,0 +1,231 @@
+package thema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+func TestApplyPatchDocument(t *testing.T) {
+	// Cases largely drawn from RFC 6902, Appendix A, and RFC 7396, Appendix A
+	table := map[string]struct {
+		doc, patch, want string
+		err              error
+	}{
+		"add-object-member": {
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			want:  `{"baz": "qux", "foo": "bar"}`,
+		},
+		"add-array-element": {
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			want:  `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		"add-array-end": {
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			want:  `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		"add-nested-nonexistent": {
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:   terrors.ErrInvalidPatch,
+		},
+		"remove-object-member": {
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			want:  `{"foo": "bar"}`,
+		},
+		"remove-array-element": {
+			doc:   `{"foo": ["bar", "qux", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/1"}]`,
+			want:  `{"foo": ["bar", "baz"]}`,
+		},
+		"remove-nonexistent": {
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			err:   terrors.ErrInvalidPatch,
+		},
+		"replace": {
+			doc:   `{"baz": "qux", "foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			want:  `{"baz": "boo", "foo": "bar"}`,
+		},
+		"replace-root": {
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "replace", "path": "", "value": {"baz": 1}}]`,
+			want:  `{"baz": 1}`,
+		},
+		"move-object-member": {
+			doc:   `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch: `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			want:  `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		"move-array-element": {
+			doc:   `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch: `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			want:  `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		"move-into-child": {
+			doc:   `{"foo": {"bar": {}}}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+			err:   terrors.ErrInvalidPatch,
+		},
+		"copy": {
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "copy", "from": "/foo", "path": "/baz"}, {"op": "replace", "path": "/baz/bar", "value": 2}]`,
+			want:  `{"foo": {"bar": 1}, "baz": {"bar": 2}}`,
+		},
+		"test-success": {
+			doc:   `{"baz": "qux", "foo": ["a", 2, "c"], "n": 1}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}, {"op": "test", "path": "/n", "value": 1.0}]`,
+			want:  `{"baz": "qux", "foo": ["a", 2, "c"], "n": 1}`,
+		},
+		"test-failure": {
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:   terrors.ErrPatchTestFailed,
+		},
+		"escaped-pointer": {
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": 10}, {"op": "remove", "path": "/~1"}]`,
+			want:  `{"~1": 10}`,
+		},
+		"invalid-index": {
+			doc:   `{"foo": [1]}`,
+			patch: `[{"op": "add", "path": "/foo/01", "value": 2}]`,
+			err:   terrors.ErrInvalidPatch,
+		},
+		"unknown-op": {
+			doc:   `{}`,
+			patch: `[{"op": "frob", "path": "/a"}]`,
+			err:   terrors.ErrInvalidPatch,
+		},
+		"missing-value": {
+			doc:   `{}`,
+			patch: `[{"op": "add", "path": "/a"}]`,
+			err:   terrors.ErrInvalidPatch,
+		},
+		"merge": {
+			doc:   `{"a": "b", "c": {"d": "e", "f": "g"}}`,
+			patch: `{"a": "z", "c": {"f": null}}`,
+			want:  `{"a": "z", "c": {"d": "e"}}`,
+		},
+		"merge-replace-array": {
+			doc:   `{"a": ["b"]}`,
+			patch: `{"a": ["c", "d"]}`,
+			want:  `{"a": ["c", "d"]}`,
+		},
+		"merge-into-scalar": {
+			doc:   `{"a": "foo"}`,
+			patch: `{"a": {"bb": {"ccc": null}}}`,
+			want:  `{"a": {"bb": {}}}`,
+		},
+		"merge-malformed": {
+			doc:   `{}`,
+			patch: `{"a":`,
+			err:   terrors.ErrInvalidPatch,
+		},
+	}
+
+	for name, tt := range table {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var doc interface{}
+			require.NoError(t, decodeJSONNumber(json.RawMessage(tt.doc), &doc))
+
+			got, err := applyPatchDocument(doc, []byte(tt.patch))
+			if tt.err != nil {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, tt.err), "expected %q, got %q", tt.err, err)
+				return
+			}
+			require.NoError(t, err)
+			b, err := json.Marshal(got)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(lacunaLinstr), rt)
+	require.NoError(t, err)
+
+	stored, err := SchemaP(lin, SV(1, 0)).Validate(ctx.CompileString(`{
+		title: "foo"
+		owner: "someone"
+		count: 3
+		nested: level: 2
+	}`))
+	require.NoError(t, err)
+
+	t.Run("sameversion", func(t *testing.T) {
+		pinst, lac, err := ApplyPatch(stored, []byte(`[{"op": "replace", "path": "/nested/level", "value": 5}]`), SV(1, 0))
+		require.NoError(t, err)
+		assert.Empty(t, lac.AsList())
+		assert.True(t, pinst.Underlying().Equals(ctx.CompileString(`{title: "foo", owner: "someone", count: 3, nested: level: 5}`)), "got %v", pinst.Underlying())
+	})
+
+	t.Run("olderversion", func(t *testing.T) {
+		pinst, lac, err := ApplyPatch(stored, []byte(`{"title": "bar"}`), SV(0, 0))
+		require.NoError(t, err)
+		assert.Equal(t, SV(1, 0), pinst.Schema().Version())
+
+		title, _ := pinst.Underlying().LookupPath(cue.ParsePath("title")).String()
+		assert.Equal(t, "bar", title)
+		count := pinst.Underlying().LookupPath(cue.ParsePath("count"))
+		assert.Equal(t, cue.IntKind, count.Kind())
+
+		// Dropped on the way down, placeholders on the way back up
+		var types []LacunaType
+		for _, l := range lac.AsList() {
+			types = append(types, l.Type)
+		}
+		assert.Equal(t, []LacunaType{LacunaDroppedField, LacunaPlaceholder, LacunaPlaceholder}, types)
+	})
+
+	t.Run("resolved", func(t *testing.T) {
+		keep := func(path string) TranslateOption {
+			return ResolveLacunaPath(path, func(l Lacuna, _ *Instance, _ cue.Value) ([]FieldRef, bool, error) {
+				if l.Type == LacunaDroppedField {
+					// Acknowledge the drop, as the stored value is restored on the way back
+					return nil, true, nil
+				}
+				return []FieldRef{{Path: path, Value: stored.Underlying().LookupPath(cue.ParsePath(path))}}, true, nil
+			})
+		}
+		pinst, lac, err := ApplyPatch(stored, []byte(`[{"op": "test", "path": "/count", "value": 3}, {"op": "replace", "path": "/count", "value": 4}]`), SV(0, 0),
+			keep("owner"), keep("nested.level"))
+		require.NoError(t, err)
+		assert.Empty(t, lac.AsList())
+		assert.True(t, pinst.Underlying().Equals(ctx.CompileString(`{title: "foo", owner: "someone", count: 4, nested: level: 2}`)), "got %v", pinst.Underlying())
+	})
+
+	t.Run("invalidresult", func(t *testing.T) {
+		_, _, err := ApplyPatch(stored, []byte(`{"count": "three"}`), SV(0, 0))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, terrors.ErrInvalidData), "unexpected error: %s", err)
+	})
+
+	t.Run("testfailed", func(t *testing.T) {
+		_, _, err := ApplyPatch(stored, []byte(`[{"op": "test", "path": "/count", "value": 4}]`), SV(0, 0))
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, terrors.ErrPatchTestFailed), "unexpected error: %s", err)
+	})
+
+	t.Run("noversion", func(t *testing.T) {
+		_, _, err := ApplyPatch(stored, []byte(`{}`), SV(2, 0))
+		assert.True(t, errors.Is(err, terrors.ErrVersionNotExist), "unexpected error: %s", err)
+	})
+}
@@ -0,0 +1,127 @@
+package thema
+
+import (
+	"runtime"
+	"sync"
+
+	"cuelang.org/go/cue/cuecontext"
+)
+
+// A LineagePool holds replicas of a single lineage, each bound with its own
+// [Runtime] and cue.Context, allowing operations such as [Schema.Validate] and
+// [Instance.Translate] to run in parallel across goroutines.
+//
+// CUE evaluation is not safe for concurrent use, so each Runtime serializes
+// all operations on the lineages bound with it. Lineages bound with different
+// Runtimes, in different cue.Contexts, share no evaluation state, and may be
+// used concurrently without contention.
+//
+// Replicas are bound on demand, when all existing replicas are in use, up to
+// the maximum size of the pool, and are reused thereafter. Once the pool is
+// full, callers wait for a replica to be returned. Each replica is bound with
+// all of the checks performed by [BindLineage], unless the bind func passes
+// [TrustBindArtifact].
+//
+// A LineagePool is safe for concurrent use.
+type LineagePool struct {
+	bind func(*Runtime, ...BindOption) (Lineage, error)
+	opts []BindOption
+
+	// sem holds one token for each replica that is in use, or being bound.
+	sem chan struct{}
+
+	mut  sync.Mutex
+	free []Lineage
+}
+
+// replicaMut serializes the binding of replicas across all pools. Building a
+// new Runtime and binding a lineage in it initializes state that CUE shares
+// between cue.Contexts, such as builtin packages, which is not safe to do
+// concurrently.
+var replicaMut sync.Mutex
+
+// NewLineagePool creates a [LineagePool] for the lineage returned by the
+// provided bind func, which is typically a lineage factory generated by
+// `thema lineage gen gobindings`. The provided [BindOption]s are passed to
+// each call of bind.
+//
+// The pool holds at most size replicas. If size is not positive, the value of
+// runtime.GOMAXPROCS(0) is used.
+//
+// The first replica is bound immediately, and any error encountered doing so
+// is returned.
+func NewLineagePool(bind func(rt *Runtime, opts ...BindOption) (Lineage, error), size int, opts ...BindOption) (*LineagePool, error) {
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+	p := &LineagePool{
+		bind: bind,
+		opts: opts,
+		sem:  make(chan struct{}, size),
+	}
+	lin, err := p.bindReplica()
+	if err != nil {
+		return nil, err
+	}
+	p.free = []Lineage{lin}
+	return p, nil
+}
+
+// Do calls fn with a replica of the pool's lineage that is not in use by any
+// other call to Do, binding a new replica if necessary. The replica is
+// returned to the pool when fn returns, and the error from fn is returned.
+//
+// If all replicas are in use and the pool is full, Do waits until one is
+// returned by another call.
+//
+// Data should be converted to CUE values with the replica's cue.Context,
+// obtained via lin.Runtime().Context(). CUE values obtained within fn, such
+// as the [Instance]s returned from Validate and Translate, belong to the
+// replica, and must not be retained after fn returns. Results should instead
+// be decoded into Go values or serialized before returning.
+func (p *LineagePool) Do(fn func(lin Lineage) error) error {
+	lin, err := p.get()
+	if err != nil {
+		return err
+	}
+	defer p.put(lin)
+	return fn(lin)
+}
+
+// Size returns the maximum number of replicas held by the pool.
+func (p *LineagePool) Size() int {
+	return cap(p.sem)
+}
+
+func (p *LineagePool) get() (Lineage, error) {
+	p.sem <- struct{}{}
+
+	p.mut.Lock()
+	if n := len(p.free); n > 0 {
+		lin := p.free[n-1]
+		p.free = p.free[:n-1]
+		p.mut.Unlock()
+		return lin, nil
+	}
+	p.mut.Unlock()
+
+	lin, err := p.bindReplica()
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return lin, nil
+}
+
+func (p *LineagePool) put(lin Lineage) {
+	p.mut.Lock()
+	p.free = append(p.free, lin)
+	p.mut.Unlock()
+	<-p.sem
+}
+
+func (p *LineagePool) bindReplica() (Lineage, error) {
+	replicaMut.Lock()
+	defer replicaMut.Unlock()
+	return p.bind(NewRuntime(cuecontext.New()), p.opts...)
+}
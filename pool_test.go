@@ -0,0 +1,212 @@
+package thema
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bindLacunaLineage(rt *Runtime, opts ...BindOption) (Lineage, error) {
+	return BindLineage(rt.Context().CompileString(lacunaLinstr), rt, opts...)
+}
+
+// compileData compiles, but does not evaluate, a 0.0 instance of the lacunas
+// lineage in the lineage's cue.Context. Compiling outside of thema's API must be
+// serialized with it, so it takes the Runtime's lock.
+func compileData(lin Lineage, n int) cue.Value {
+	rt := lin.Runtime()
+	rt.l()
+	defer rt.u()
+	return rt.Context().CompileString(fmt.Sprintf(`{title: "foo", count: %d}`, n))
+}
+
+// validateAndTranslate validates a 0.0 instance of the lacunas lineage,
+// translates it to 1.0, and returns the result as JSON. Evaluation of the
+// instance happens lazily within Validate and Translate, which are guarded only
+// by the Runtime's own locking. Marshaling the result evaluates it outside of
+// thema's API, so it takes the Runtime's lock.
+func validateAndTranslate(lin Lineage, n int) ([]byte, error) {
+	inst, err := lin.First().Validate(compileData(lin, n))
+	if err != nil {
+		return nil, err
+	}
+	tinst, _, err := inst.Translate(SV(1, 0))
+	if err != nil {
+		return nil, err
+	}
+	rt := lin.Runtime()
+	rt.l()
+	defer rt.u()
+	return json.Marshal(tinst.Underlying())
+}
+
+func expectTranslated(n int) string {
+	return fmt.Sprintf(`{"title": "foo", "owner": "PLACEHOLDER", "count": %d, "nested": {"level": -1}}`, n)
+}
+
+// The concurrency tests are most useful when run with the race detector:
+//
+//	go test -race -run 'Concurrent|Pool' .
+
+func TestRuntimeConcurrentAccess(t *testing.T) {
+	ctx := cuecontext.New()
+	lin, err := bindLacunaLineage(NewRuntime(ctx))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				b, err := validateAndTranslate(lin, g*10+i)
+				if assert.NoError(t, err) {
+					assert.JSONEq(t, expectTranslated(g*10+i), string(b))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func TestLineagePool(t *testing.T) {
+	pool, err := NewLineagePool(bindLacunaLineage, 2)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mut sync.Mutex
+	replicas := make(map[Lineage]bool)
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 5; i++ {
+				var b []byte
+				err := pool.Do(func(lin Lineage) error {
+					mut.Lock()
+					replicas[lin] = true
+					mut.Unlock()
+
+					var err error
+					b, err = validateAndTranslate(lin, g*10+i)
+					return err
+				})
+				if assert.NoError(t, err) {
+					assert.JSONEq(t, expectTranslated(g*10+i), string(b))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, len(replicas), pool.Size())
+	contexts := make(map[interface{}]bool)
+	for lin := range replicas {
+		contexts[lin.Runtime().Context()] = true
+	}
+	assert.Len(t, contexts, len(replicas), "replicas must not share a cue.Context")
+
+	t.Run("size", func(t *testing.T) {
+		pool, err := NewLineagePool(bindLacunaLineage, 1)
+		require.NoError(t, err)
+
+		held := make(chan struct{})
+		release := make(chan struct{})
+		go pool.Do(func(lin Lineage) error { //nolint:errcheck
+			close(held)
+			<-release
+			return nil
+		})
+		<-held
+
+		done := make(chan Lineage)
+		go pool.Do(func(lin Lineage) error { //nolint:errcheck
+			done <- lin
+			return nil
+		})
+		select {
+		case <-done:
+			t.Fatal("Do must wait for a replica when the pool is full")
+		case <-time.After(50 * time.Millisecond):
+		}
+		close(release)
+		<-done
+	})
+
+	t.Run("binderror", func(t *testing.T) {
+		_, err := NewLineagePool(func(rt *Runtime, opts ...BindOption) (Lineage, error) {
+			return BindLineage(rt.Context().CompileString(`name: "bad"`), rt, opts...)
+		}, 0)
+		assert.Error(t, err)
+	})
+}
+
+// BenchmarkValidateParallel and BenchmarkTranslateParallel compare operations
+// on a single lineage shared across goroutines with operations on a
+// LineagePool. Run with -cpu to vary the number of goroutines.
+func BenchmarkValidateParallel(b *testing.B) {
+	benchParallel(b, func(lin Lineage) error {
+		_, err := lin.First().Validate(compileData(lin, 3))
+		return err
+	})
+}
+
+func BenchmarkTranslateParallel(b *testing.B) {
+	benchParallel(b, func(lin Lineage) error {
+		_, err := validateAndTranslate(lin, 3)
+		return err
+	})
+}
+
+func benchParallel(b *testing.B, fn func(lin Lineage) error) {
+	b.Run("shared", func(b *testing.B) {
+		lin, err := bindLacunaLineage(NewRuntime(cuecontext.New()))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := fn(lin); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	b.Run("pool", func(b *testing.B) {
+		pool, err := NewLineagePool(bindLacunaLineage, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		// Bind a replica per goroutine before timing
+		var lins []Lineage
+		for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+			lin, err := pool.get()
+			if err != nil {
+				b.Fatal(err)
+			}
+			lins = append(lins, lin)
+		}
+		for _, lin := range lins {
+			pool.put(lin)
+		}
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if err := pool.Do(fn); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
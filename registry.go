@@ -0,0 +1,196 @@
+package thema
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"cuelang.org/go/cue"
+	"github.com/cockroachdb/errors"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+// Registry is a set of lineages bound with the same [Runtime], each identified
+// by its unique name. Each Runtime has a single Registry, returned by
+// [Runtime.Registry].
+//
+// Lineages are not registered automatically when bound. Programs that work
+// with many lineages register them explicitly with [Registry.Register].
+//
+// A Registry is safe for concurrent use.
+type Registry struct {
+	rt *Runtime
+
+	mut  sync.RWMutex
+	lins map[string]Lineage
+}
+
+func newRegistry(rt *Runtime) *Registry {
+	return &Registry{
+		rt:   rt,
+		lins: make(map[string]Lineage),
+	}
+}
+
+// Registry returns the [Registry] of lineages bound with this Runtime.
+func (rt *Runtime) Registry() *Registry {
+	return rt.reg
+}
+
+// Runtime returns the [Runtime] to which the Registry belongs.
+func (r *Registry) Runtime() *Runtime {
+	return r.rt
+}
+
+// Register adds the provided lineages to the Registry. Registration is
+// all-or-nothing: if any of the lineages cannot be registered, none are.
+//
+// Lineages must have been bound with the Registry's [Runtime]. If a lineage's
+// name is the same as that of an already-registered lineage, or another of the
+// provided lineages, an error marked with [terrors.ErrDuplicateLineage] is
+// returned.
+func (r *Registry) Register(lins ...Lineage) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	seen := make(map[string]bool, len(lins))
+	for _, lin := range lins {
+		name := lin.Name()
+		if lin.Runtime() != r.rt {
+			return fmt.Errorf("cannot register lineage %s, it was bound with a different thema.Runtime", name)
+		}
+		if _, has := r.lins[name]; has || seen[name] {
+			return errors.Mark(errors.Newf("cannot register lineage %s", name), terrors.ErrDuplicateLineage)
+		}
+		seen[name] = true
+	}
+
+	for _, lin := range lins {
+		r.lins[lin.Name()] = lin
+	}
+	return nil
+}
+
+// Lineage returns the registered lineage with the provided name. If no such
+// lineage is registered, an error marked with [terrors.ErrLineageNotExist] is
+// returned.
+func (r *Registry) Lineage(name string) (Lineage, error) {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	lin, has := r.lins[name]
+	if !has {
+		return nil, errors.Mark(errors.Newf("no lineage registered with name %q", name), terrors.ErrLineageNotExist)
+	}
+	return lin, nil
+}
+
+// Schema returns the schema with the provided version from the registered
+// lineage with the provided name.
+func (r *Registry) Schema(name string, v SyntacticVersion) (Schema, error) {
+	lin, err := r.Lineage(name)
+	if err != nil {
+		return nil, err
+	}
+	return lin.Schema(v)
+}
+
+// Lineages returns all registered lineages, sorted by name.
+func (r *Registry) Lineages() []Lineage {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+
+	lins := make([]Lineage, 0, len(r.lins))
+	for _, lin := range r.lins {
+		lins = append(lins, lin)
+	}
+	sort.Slice(lins, func(i, j int) bool {
+		return lins[i].Name() < lins[j].Name()
+	})
+	return lins
+}
+
+// Validate identifies the lineage of which the provided data is an instance
+// using the provided [Discriminator], and validates the data against it.
+//
+// If the discriminator identifies a schema version, the data is validated
+// against that schema. Otherwise, it is validated against any schema in the
+// lineage, as with [Lineage.ValidateAny].
+func (r *Registry) Validate(data cue.Value, disc Discriminator) (*Instance, error) {
+	name, v, err := disc(data)
+	if err != nil {
+		return nil, err
+	}
+	lin, err := r.Lineage(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v != nil {
+		sch, err := lin.Schema(*v)
+		if err != nil {
+			return nil, err
+		}
+		return sch.Validate(data)
+	}
+	if inst := lin.ValidateAny(data); inst != nil {
+		return inst, nil
+	}
+	return nil, errors.Mark(errors.Newf("data is not valid for any schema in lineage %s", name), terrors.ErrInvalidData)
+}
+
+// A Discriminator identifies the lineage of which some data is an instance,
+// returning the name of the lineage. If the data also identifies the version of
+// the schema of which it is an instance, that version is returned; otherwise,
+// the returned version is nil.
+type Discriminator func(data cue.Value) (name string, v *SyntacticVersion, err error)
+
+// FieldDiscriminator returns a [Discriminator] that reads the lineage name from
+// the string field at namePath within data, and the schema version from the
+// field at versionPath. The version may be either a string (e.g. "1.0"), or a
+// list of two integers (e.g. [1, 0]).
+//
+// If versionPath is empty, or the version field is absent from data, the
+// version is not identified. If the name field is absent, an error marked with
+// [terrors.ErrNoDiscriminator] is returned.
+//
+// As schemas are closed, data containing these fields is valid only if its
+// schemas also declare them.
+func FieldDiscriminator(namePath, versionPath string) Discriminator {
+	np := cue.ParsePath(namePath)
+	var vp cue.Path
+	if versionPath != "" {
+		vp = cue.ParsePath(versionPath)
+	}
+
+	return func(data cue.Value) (string, *SyntacticVersion, error) {
+		nv := data.LookupPath(np)
+		if !nv.Exists() {
+			return "", nil, errors.Mark(errors.Newf("no lineage name at %s", namePath), terrors.ErrNoDiscriminator)
+		}
+		name, err := nv.String()
+		if err != nil {
+			return "", nil, errors.Mark(errors.Wrapf(err, "invalid lineage name at %s", namePath), terrors.ErrNoDiscriminator)
+		}
+		if versionPath == "" {
+			return name, nil, nil
+		}
+
+		vv := data.LookupPath(vp)
+		if !vv.Exists() {
+			return name, nil, nil
+		}
+		var sv SyntacticVersion
+		if vv.Kind() == cue.StringKind {
+			s, _ := vv.String()
+			sv, err = ParseSyntacticVersion(s)
+		} else {
+			err = vv.Decode(&sv)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid schema version at %s: %w", versionPath, err)
+		}
+		return name, &sv, nil
+	}
+}
@@ -0,0 +1,141 @@
+package thema
+
+import (
+	"testing"
+
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/cockroachdb/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+)
+
+const registryShipLinstr = `name: "ship"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		kind: "ship"
+		version?: string
+		name: string
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		kind: "ship"
+		version?: string
+		name: string
+		crew?: int
+	}
+}]
+`
+
+const registryPortLinstr = `name: "port"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		kind: "port"
+		version?: string
+		city: string
+	}
+}]
+`
+
+func TestRegistry(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	ship, err := BindLineage(ctx.CompileString(registryShipLinstr), rt)
+	require.NoError(t, err)
+	port, err := BindLineage(ctx.CompileString(registryPortLinstr), rt)
+	require.NoError(t, err)
+
+	reg := rt.Registry()
+	assert.Same(t, reg, rt.Registry())
+	require.NoError(t, reg.Register(ship, port))
+
+	t.Run("duplicate", func(t *testing.T) {
+		dup, err := BindLineage(ctx.CompileString(registryShipLinstr), rt)
+		require.NoError(t, err)
+		err = reg.Register(dup)
+		assert.True(t, errors.Is(err, terrors.ErrDuplicateLineage), "unexpected error: %s", err)
+
+		// Registration is all-or-nothing
+		other, err := BindLineage(ctx.CompileString(`name: "other"
+schemas: [{version: [0, 0], schema: {}}]`), rt)
+		require.NoError(t, err)
+		err = reg.Register(other, dup)
+		assert.True(t, errors.Is(err, terrors.ErrDuplicateLineage), "unexpected error: %s", err)
+		_, err = reg.Lineage("other")
+		assert.True(t, errors.Is(err, terrors.ErrLineageNotExist), "unexpected error: %s", err)
+	})
+
+	t.Run("otherruntime", func(t *testing.T) {
+		ortctx := cuecontext.New()
+		olin, err := BindLineage(ortctx.CompileString(`name: "other"
+schemas: [{version: [0, 0], schema: {}}]`), NewRuntime(ortctx))
+		require.NoError(t, err)
+		assert.Error(t, reg.Register(olin))
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+		lin, err := reg.Lineage("ship")
+		require.NoError(t, err)
+		assert.Same(t, ship, lin)
+
+		sch, err := reg.Schema("ship", SV(0, 1))
+		require.NoError(t, err)
+		assert.Equal(t, SV(0, 1), sch.Version())
+
+		_, err = reg.Schema("ship", SV(2, 0))
+		assert.True(t, errors.Is(err, terrors.ErrVersionNotExist), "unexpected error: %s", err)
+		_, err = reg.Schema("nope", SV(0, 0))
+		assert.True(t, errors.Is(err, terrors.ErrLineageNotExist), "unexpected error: %s", err)
+	})
+
+	t.Run("iterate", func(t *testing.T) {
+		var names []string
+		for _, lin := range reg.Lineages() {
+			names = append(names, lin.Name())
+		}
+		assert.Equal(t, []string{"port", "ship"}, names)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		disc := FieldDiscriminator("kind", "version")
+
+		inst, err := reg.Validate(ctx.CompileString(`{kind: "port", city: "Oslo"}`), disc)
+		require.NoError(t, err)
+		assert.Equal(t, "port", inst.Schema().Lineage().Name())
+
+		inst, err = reg.Validate(ctx.CompileString(`{kind: "ship", name: "Argo", crew: 50}`), disc)
+		require.NoError(t, err)
+		assert.Equal(t, SV(0, 1), inst.Schema().Version())
+
+		inst, err = reg.Validate(ctx.CompileString(`{kind: "ship", version: "0.1", name: "Argo"}`), disc)
+		require.NoError(t, err)
+		assert.Equal(t, SV(0, 1), inst.Schema().Version())
+
+		_, err = reg.Validate(ctx.CompileString(`{kind: "ship", version: "0.0", name: "Argo", crew: 50}`), disc)
+		assert.True(t, errors.Is(err, terrors.ErrInvalidData), "unexpected error: %s", err)
+
+		_, err = reg.Validate(ctx.CompileString(`{kind: "port", name: "Argo"}`), disc)
+		assert.True(t, errors.Is(err, terrors.ErrInvalidData), "unexpected error: %s", err)
+
+		_, err = reg.Validate(ctx.CompileString(`{name: "Argo"}`), disc)
+		assert.True(t, errors.Is(err, terrors.ErrNoDiscriminator), "unexpected error: %s", err)
+
+		_, err = reg.Validate(ctx.CompileString(`{kind: "dock"}`), disc)
+		assert.True(t, errors.Is(err, terrors.ErrLineageNotExist), "unexpected error: %s", err)
+	})
+
+	t.Run("listversion", func(t *testing.T) {
+		name, v, err := FieldDiscriminator("meta.kind", "meta.version")(ctx.CompileString(`{meta: {kind: "ship", version: [1, 2]}}`))
+		require.NoError(t, err)
+		assert.Equal(t, "ship", name)
+		require.NotNil(t, v)
+		assert.Equal(t, SV(1, 2), *v)
+
+		_, _, err = FieldDiscriminator("kind", "version")(ctx.CompileString(`{kind: "ship", version: "one"}`))
+		assert.True(t, errors.Is(err, terrors.ErrMalformedSyntacticVersion), "unexpected error: %s", err)
+	})
+}
@@ -16,6 +16,9 @@ import (
 var rtOnce sync.Once
 var themaBI *build.Instance
 
+// buildMut serializes building of themaBI, as building an instance mutates it.
+var buildMut sync.Mutex
+
 func loadRuntime() *build.Instance {
 	rtOnce.Do(func() {
 		path := filepath.Join(util.Prefix, "github.com", "grafana", "thema")
@@ -58,14 +61,25 @@ func loadRuntime() *build.Instance {
 //
 // Each Thema Runtime is bound to a single cue.Context, determined by the parameter
 // passed to [NewRuntime].
+//
+// Operations on lineages bound with a Runtime, such as [Schema.Validate] and
+// [Instance.Translate], are serialized and may be called concurrently. Callers
+// evaluating values in the Runtime's cue.Context outside of Thema, including
+// compiling data or marshaling an instance to JSON, must not do so concurrently
+// with other use of the Runtime.
 type Runtime struct {
 	// Value corresponds to loading the whole github.com/grafana/thema:thema
 	// package.
 	val cue.Value
 
-	// Until CUE is safe for certain concurrent operations, keep a mutex to
-	// help guard...at least somewhat.
-	mut sync.RWMutex
+	// CUE evaluation lazily mutates shared structures, even when only reading
+	// from already-built values, so all evaluation against values in the
+	// Runtime's cue.Context must be serialized. Programs needing parallelism
+	// should use a [LineagePool], which binds each replica of a lineage with
+	// its own cue.Context.
+	mut sync.Mutex
+
+	reg *Registry
 }
 
 // NewRuntime parses, loads and builds a full CUE instance/value representing
@@ -79,22 +93,28 @@ func NewRuntime(ctx *cue.Context) *Runtime {
 	if ctx == nil {
 		panic("nil context provided")
 	}
-	rt := ctx.BuildInstance(loadRuntime())
+	bi := loadRuntime()
+	buildMut.Lock()
+	rt := ctx.BuildInstance(bi)
+	buildMut.Unlock()
 
 	// FIXME preload all the known funcs into a map[string]cue.Value here to avoid runtime cost
-	return &Runtime{
+	trt := &Runtime{
 		val: rt,
 	}
+	trt.reg = newRegistry(trt)
+	return trt
 }
 
+// rl and ru guard operations that only read from CUE values. As reads may
+// still trigger evaluation, which is not safe for concurrent use, they take
+// the exclusive lock.
 func (rt *Runtime) rl() {
-	rt.mut.RLock()
-	// rt.mut.Lock()
+	rt.mut.Lock()
 }
 
 func (rt *Runtime) ru() {
-	rt.mut.RUnlock()
-	// rt.mut.Unlock()
+	rt.mut.Unlock()
 }
 
 func (rt *Runtime) l() {
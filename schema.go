@@ -88,7 +88,7 @@ func (sch *schemaDef) Validate(data cue.Value) (*Instance, error) {
 	// ie: every field defined by the schema has a concrete value associated to it,
 	// and no required field was omitted.
 	if err := x.Validate(cue.Concrete(true)); err != nil {
-		return nil, mungeValidateErr(err, sch)
+		return nil, mungeValidateErr(err, sch, data)
 	}
 
 	return &Instance{
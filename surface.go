@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/build"
 
 	terrors "github.com/grafana/thema/errors"
 	"github.com/grafana/thema/internal/envvars"
@@ -92,6 +93,11 @@ type Lineage interface {
 type ImperativeLens struct {
 	To, From SyntacticVersion
 	Mapper   func(inst *Instance, to Schema) (*Instance, error)
+
+	// LacunaMapper is an alternative to Mapper for lenses that emit lacunas.
+	// If it is non-nil, it is called instead of Mapper, and the lacunas it
+	// returns are reported by [Instance.Translate].
+	LacunaMapper func(inst *Instance, to Schema) (*Instance, []Lacuna, error)
 }
 
 // SchemaP returns the schema identified by the provided version. If no schema
@@ -170,10 +176,14 @@ type bindOption func(c *bindConfig)
 type bindConfig struct {
 	skipbuggychecks bool
 	implens         []ImperativeLens
+	artifact        *BindArtifact
+	artifactInst    *build.Instance
 }
 
 // SkipBuggyChecks indicates that [BindLineage] should skip validation checks
 // which have known bugs (e.g. panics) for certain should-be-valid CUE inputs.
+// Currently, this skips the checks that each schema is backwards compatible,
+// or incompatible, with its predecessor as its version requires.
 //
 // By default, BindLineage performs these checks anyway, as otherwise the
 // default behavior of BindLineage is to not provide the guarantees it's
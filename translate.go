@@ -0,0 +1,104 @@
+package thema
+
+import (
+	"fmt"
+)
+
+// A TranslateOption defines options that may be specified when translating an
+// [Instance] with [Instance.Translate] or [TypedTranslator.Translate].
+type TranslateOption translateOption
+
+// Internal representation of TranslateOption.
+type translateOption func(c *translateConfig)
+
+// Internal translation configuration options.
+type translateConfig struct {
+	typeres []typeResolver
+	pathres []pathResolver
+}
+
+func newTranslateConfig(opts []TranslateOption) *translateConfig {
+	cfg := &translateConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// TypedTranslator translates an [Instance] of any schema in a lineage to a
+// [TypedInstance] of a single [TypedSchema] in that lineage.
+//
+// It is equivalent to calling [Instance.Translate] followed by
+// [BindInstanceType], but the work that does not depend on the instance being
+// translated - preparing the translation from each schema in the lineage, and
+// checking that the result is bindable to the Go type - is done once, when the
+// TypedTranslator is created, instead of on every call.
+type TypedTranslator[T Assignee] struct {
+	tsch TypedSchema[T]
+	lin  *baseLineage
+	trs  map[SyntacticVersion]*translator
+}
+
+// NewTypedTranslator creates a [TypedTranslator] that translates instances to
+// the provided [TypedSchema].
+func NewTypedTranslator[T Assignee](tsch TypedSchema[T]) *TypedTranslator[T] {
+	lin := tsch.Lineage().(*baseLineage)
+	tt := &TypedTranslator[T]{
+		tsch: tsch,
+		lin:  lin,
+		trs:  make(map[SyntacticVersion]*translator, len(lin.allv)),
+	}
+
+	for _, v := range lin.allv {
+		if v == tsch.Version() {
+			continue
+		}
+		tr, err := newTranslator(lin, v, tsch.Version())
+		if err != nil {
+			panic(fmt.Sprintf("unreachable - version %s from lineage must exist in lineage: %s", v, err))
+		}
+		tt.trs[v] = tr
+	}
+	return tt
+}
+
+// TypedSchema returns the [TypedSchema] to which this TypedTranslator
+// translates instances.
+func (tt *TypedTranslator[T]) TypedSchema() TypedSchema[T] {
+	return tt.tsch
+}
+
+// Translate transforms the provided [Instance] into a [TypedInstance] of the
+// TypedTranslator's [TypedSchema], along with any lacunas accumulated along the
+// way. The semantics of translation, including the handling of any provided
+// [TranslateOption], are identical to [Instance.Translate].
+//
+// An error is returned if the provided instance is not of a schema in the same
+// lineage as the TypedTranslator's TypedSchema, or if any error occurs during
+// translation.
+func (tt *TypedTranslator[T]) Translate(inst *Instance, opts ...TranslateOption) (*TypedInstance[T], TranslationLacunas, error) {
+	inst.check()
+	if ilin, is := inst.Schema().Lineage().(*baseLineage); !is || ilin != tt.lin {
+		return nil, nil, fmt.Errorf("instance is of schema from lineage %q, not the lineage of the typed schema %q", inst.Schema().Lineage().Name(), tt.lin.Name())
+	}
+
+	v := inst.Schema().Version()
+	if v == tt.tsch.Version() {
+		return &TypedInstance[T]{
+			Instance: inst,
+			tsch:     tt.tsch,
+		}, nil, nil
+	}
+
+	tinst, lac, err := tt.trs[v].translate(inst)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tinst, lac, err = newTranslateConfig(opts).resolve(inst, tinst, lac); err != nil {
+		return nil, nil, err
+	}
+	return &TypedInstance[T]{
+		Instance: tinst,
+		tsch:     tt.tsch,
+	}, lac, nil
+}
@@ -3,10 +3,13 @@ package thema
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
 	"cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/format"
 	"cuelang.org/go/cue/token"
 
 	terrors "github.com/grafana/thema/errors"
@@ -53,6 +56,16 @@ func (e *onesidederr) Unwrap() error {
 	return terrors.ErrInvalidData
 }
 
+func (e *onesidederr) validationError() *terrors.ValidationError {
+	ve := e.coords.validationError(e.code, e.schpos, e.datapos)
+	if e.code == terrors.MissingField {
+		ve.Expected = e.val
+	} else {
+		ve.Actual = e.val
+	}
+	return ve
+}
+
 type twosidederr struct {
 	schpos, datapos []token.Pos
 	code            terrors.ValidationCode
@@ -79,6 +92,12 @@ func (e *twosidederr) Unwrap() error {
 	return terrors.ErrInvalidData
 }
 
+func (e *twosidederr) validationError() *terrors.ValidationError {
+	ve := e.coords.validationError(e.code, e.schpos, e.datapos)
+	ve.Expected, ve.Actual = e.sv, e.dv
+	return ve
+}
+
 // TODO differentiate this once we have generic composition to support trimming out irrelevant disj branches
 type emptydisjunction struct {
 	schpos, datapos []token.Pos
@@ -86,16 +105,111 @@ type emptydisjunction struct {
 	brancherrs      []error
 }
 
+func (e *emptydisjunction) Error() string {
+	var buf bytes.Buffer
+	for i, berr := range e.brancherrs {
+		if i > 0 {
+			fmt.Fprintf(&buf, "\n")
+		}
+		fmt.Fprint(&buf, berr.Error())
+	}
+	if buf.Len() == 0 {
+		fmt.Fprintf(&buf, "%s: validation failed, data matches no branch of disjunction", e.coords)
+	}
+	return buf.String()
+}
+
 func (e *emptydisjunction) Unwrap() error {
 	return terrors.ErrInvalidData
 }
 
+// validationError reports the disjunction as a single failure, expecting any
+// one of its branches.
+func (e *emptydisjunction) validationError() *terrors.ValidationError {
+	ve := e.coords.validationError(terrors.KindConflict, e.schpos, e.datapos)
+	var expected []string
+	for _, berr := range e.brancherrs {
+		tse, ok := berr.(*twosidederr)
+		if !ok {
+			continue
+		}
+		expected = append(expected, tse.sv)
+		if ve.Actual == "" {
+			ve.Actual = tse.dv
+		}
+		// If any branch is of the same kind as the data, the data is only out
+		// of the bounds of the disjunction
+		if tse.code == terrors.OutOfBounds {
+			ve.Code = terrors.OutOfBounds
+		}
+	}
+	ve.Expected = strings.Join(expected, " | ")
+	return ve
+}
+
+// unclassifiederr is a validation failure that could not be mapped to a more
+// specific error type.
+type unclassifiederr struct {
+	schpos, datapos []token.Pos
+	coords          coords
+	msg             string
+}
+
+func (e *unclassifiederr) Error() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s: validation failed, data is not an instance:\n\t%s", e.coords, e.msg)
+	for _, pos := range e.schpos {
+		fmt.Fprintf(&buf, "\n\t\t%s", pos.String())
+	}
+	for _, pos := range e.datapos {
+		fmt.Fprintf(&buf, "\n\t\t%s", pos.String())
+	}
+	return buf.String()
+}
+
+func (e *unclassifiederr) Unwrap() error {
+	return terrors.ErrInvalidData
+}
+
+func (e *unclassifiederr) validationError() *terrors.ValidationError {
+	ve := e.coords.validationError(terrors.Unclassified, e.schpos, e.datapos)
+	ve.Message = e.msg
+	return ve
+}
+
 type validationFailure []error
 
 func (vf validationFailure) Unwrap() error {
 	return terrors.ErrInvalidData
 }
 
+// Errors returns each of the individual validation failures that together
+// comprise the validationFailure.
+func (vf validationFailure) Errors() []error {
+	return vf
+}
+
+// ValidationErrors returns a structured representation of each of the
+// individual validation failures, in the same order as Errors. Failures
+// without a more specific representation have the Unclassified code.
+func (vf validationFailure) ValidationErrors() []*terrors.ValidationError {
+	ret := make([]*terrors.ValidationError, 0, len(vf))
+	for _, e := range vf {
+		if ve, ok := e.(interface {
+			validationError() *terrors.ValidationError
+		}); ok {
+			ret = append(ret, ve.validationError())
+		} else {
+			ret = append(ret, &terrors.ValidationError{
+				Code:    terrors.Unclassified,
+				Message: e.Error(),
+			})
+		}
+	}
+	return ret
+}
+
 func (vf validationFailure) Error() string {
 	var buf bytes.Buffer
 	for _, e := range vf {
@@ -107,13 +221,26 @@ func (vf validationFailure) Error() string {
 }
 
 // HERE BE DRAGONS, BRING A SWORD.
-func mungeValidateErr(err error, sch Schema) error {
+func mungeValidateErr(err error, sch Schema, data cue.Value) error {
 	_, is := err.(errors.Error)
 	if !is {
 		return err
 	}
 
 	var errs validationFailure
+	// The errors for each branch of an empty disjunction immediately follow
+	// the error for the disjunction itself.
+	var disj *emptydisjunction
+	var branches int
+	add := func(err error) {
+		if branches > 0 {
+			disj.brancherrs = append(disj.brancherrs, err)
+			branches--
+			return
+		}
+		errs = append(errs, err)
+	}
+
 	for _, ee := range errors.Errors(err) {
 		inputPositions := ee.InputPositions()
 		schpos, datapos := splitTokens(inputPositions)
@@ -123,7 +250,32 @@ func mungeValidateErr(err error, sch Schema) error {
 		}
 
 		msg, vals := ee.Msg()
+		if n, ok := disjunctionBranches(msg, vals); ok && branches == 0 {
+			disj = &emptydisjunction{
+				schpos:  schpos,
+				datapos: datapos,
+				coords:  x,
+			}
+			errs = append(errs, disj)
+			branches = n
+			continue
+		}
+
 		switch len(vals) {
+		case 0:
+			// Closedness errors do not carry the offending value, so get it
+			// from the data
+			if !strings.Contains(msg, "not allowed") {
+				break
+			}
+			add(&onesidederr{
+				schpos:  schpos,
+				datapos: datapos,
+				coords:  x,
+				code:    terrors.ExcessField,
+				val:     fmt.Sprint(data.LookupPath(x.dataPath())),
+			})
+			continue
 		case 1:
 			val, ok := vals[0].(string)
 			if !ok {
@@ -144,7 +296,7 @@ func mungeValidateErr(err error, sch Schema) error {
 				break
 			}
 
-			errs = append(errs, err)
+			add(err)
 			continue
 		case 2:
 			var dataval, schval string
@@ -162,7 +314,7 @@ func mungeValidateErr(err error, sch Schema) error {
 				break
 			}
 
-			errs = append(errs, &twosidederr{
+			add(&twosidederr{
 				schpos:  schpos,
 				datapos: datapos,
 				coords:  x,
@@ -207,13 +359,115 @@ func mungeValidateErr(err error, sch Schema) error {
 				err.code = terrors.KindConflict
 			}
 
-			errs = append(errs, err)
+			add(err)
 			continue
 		}
+
+		add(&unclassifiederr{
+			schpos:  schpos,
+			datapos: datapos,
+			coords:  x,
+			msg:     fmt.Sprintf(msg, vals...),
+		})
+	}
+
+	// CUE reports absent required fields only when there are no other errors.
+	// Find them separately, so that all failures are reported at once.
+	if len(errs) > 0 {
+		errs = append(errs, missingFields(sch, data, errs)...)
+	}
+	return errs
+}
+
+// disjunctionBranches reports whether the CUE error message is that of an
+// empty disjunction, returning the number of branch errors that follow it.
+func disjunctionBranches(msg string, vals []interface{}) (int, bool) {
+	if !strings.Contains(msg, "empty disjunction") || len(vals) != 1 {
+		return 0, false
+	}
+	n, ok := vals[0].(int)
+	return n, ok
+}
+
+// missingFields returns an error for each required field in the schema that
+// is absent from the data, and has no default, excluding those already
+// reported. Absent fields are reported regardless of their kind, without
+// descending into them.
+func missingFields(sch Schema, data cue.Value, reported validationFailure) []error {
+	seen := make(map[string]bool)
+	for _, e := range reported {
+		if ose, ok := e.(*onesidederr); ok && ose.code == terrors.MissingField {
+			seen[strings.Join(ose.coords.fieldpath, ".")] = true
+		}
+	}
+
+	var errs []error
+	var walk func(schv, datv cue.Value, path []string)
+	walk = func(schv, datv cue.Value, path []string) {
+		switch schv.IncompleteKind() {
+		case cue.StructKind:
+			iter, err := schv.Fields()
+			if err != nil {
+				return
+			}
+			for iter.Next() {
+				fpath := append(path[:len(path):len(path)], iter.Selector().String())
+				fv, dv := iter.Value(), datv.LookupPath(cue.MakePath(iter.Selector()))
+				if dv.Exists() {
+					walk(fv, dv, fpath)
+					continue
+				}
+				if d, has := fv.Default(); (has && d.IsConcrete()) || seen[strings.Join(fpath, ".")] {
+					continue
+				}
+				errs = append(errs, &onesidederr{
+					schpos: []token.Pos{valuePos(fv)},
+					coords: coords{sch: sch, fieldpath: fpath},
+					code:   terrors.MissingField,
+					val:    missingFieldType(fv),
+				})
+			}
+		case cue.ListKind:
+			iter, err := datv.List()
+			if err != nil {
+				return
+			}
+			elem := schv.LookupPath(cue.MakePath(cue.AnyIndex))
+			for iter.Next() {
+				sel := iter.Selector()
+				ev := schv.LookupPath(cue.MakePath(sel))
+				if !ev.Exists() {
+					ev = elem
+				}
+				walk(ev, iter.Value(), append(path[:len(path):len(path)], sel.String()))
+			}
+		}
 	}
+	walk(sch.Underlying().LookupPath(pathSchDef), data, nil)
 	return errs
 }
 
+// missingFieldType returns the type of an absent field, for reporting. As
+// formatting a struct value omits its pattern constraints, the source of struct
+// fields is used instead.
+func missingFieldType(v cue.Value) string {
+	if f, ok := v.Source().(*ast.Field); ok && v.IncompleteKind() == cue.StructKind {
+		if b, err := format.Node(f.Value); err == nil {
+			return string(b)
+		}
+	}
+	return humanReadableCUEType(fmt.Sprint(v))
+}
+
+// valuePos returns the position of the value of a field in its source, rather
+// than that of the field's label.
+func valuePos(v cue.Value) token.Pos {
+	if f, ok := v.Source().(*ast.Field); ok {
+		return f.Value.Pos()
+	}
+	return v.Pos()
+}
+
 var schErrMsgFormatMap = map[string]string{
 	"int & >=0 & <=255":                                    "uint8",
 	"int & >=0 & <=65535":                                  "uint16",
@@ -291,3 +545,80 @@ type coords struct {
 func (c coords) String() string {
 	return fmt.Sprintf("<%s@v%s>.%s", c.sch.Lineage().Name(), c.sch.Version(), strings.Join(c.fieldpath, "."))
 }
+
+// dataPath returns the path to the field within data.
+func (c coords) dataPath() cue.Path {
+	sels := make([]cue.Selector, 0, len(c.fieldpath))
+	for _, sel := range c.fieldpath {
+		if i, err := strconv.Atoi(sel); err == nil {
+			sels = append(sels, cue.Index(i))
+		} else if uq, err := strconv.Unquote(sel); err == nil {
+			sels = append(sels, cue.Str(uq))
+		} else {
+			sels = append(sels, cue.Str(sel))
+		}
+	}
+	return cue.MakePath(sels...)
+}
+
+// schemaPath returns the path to the field within the schema. Where the schema
+// has no field with the label of a selector in the data path, such as for the
+// elements of a list, the field is given by a pattern constraint, written as
+// "[_]".
+func (c coords) schemaPath() string {
+	var buf strings.Builder
+	v := c.sch.Underlying().LookupPath(pathSchDef)
+	for _, sel := range c.dataPath().Selectors() {
+		next := v.LookupPath(cue.MakePath(sel))
+		if !next.Exists() && sel.Type() == cue.StringLabel {
+			next = v.LookupPath(cue.MakePath(sel.Optional()))
+		}
+		switch {
+		case next.Exists() && sel.Type() == cue.IndexLabel:
+			fmt.Fprintf(&buf, "[%d]", sel.Index())
+		case next.Exists():
+			if buf.Len() > 0 {
+				buf.WriteByte('.')
+			}
+			buf.WriteString(sel.String())
+		default:
+			pattern := cue.AnyString
+			if sel.Type() == cue.IndexLabel {
+				pattern = cue.AnyIndex
+			}
+			next = v.LookupPath(cue.MakePath(pattern))
+			buf.WriteString("[_]")
+		}
+		v = next
+	}
+	return buf.String()
+}
+
+func (c coords) validationError(code terrors.ValidationCode, schpos, datapos []token.Pos) *terrors.ValidationError {
+	ptr := make(jsonPointer, 0, len(c.fieldpath))
+	for _, sel := range c.fieldpath {
+		if uq, err := strconv.Unquote(sel); err == nil {
+			sel = uq
+		}
+		ptr = append(ptr, sel)
+	}
+	return &terrors.ValidationError{
+		Code:            code,
+		DataPath:        ptr.String(),
+		SchemaPath:      c.schemaPath(),
+		SchemaPositions: toPositions(schpos),
+		DataPositions:   toPositions(datapos),
+	}
+}
+
+func toPositions(poslist []token.Pos) []terrors.Position {
+	var ret []terrors.Position
+	for _, pos := range poslist {
+		ret = append(ret, terrors.Position{
+			Filename: pos.Filename(),
+			Line:     pos.Line(),
+			Column:   pos.Column(),
+		})
+	}
+	return ret
+}
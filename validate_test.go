@@ -1,6 +1,7 @@
 package thema
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -10,8 +11,10 @@ import (
 	"cuelang.org/go/cue/cuecontext"
 	cjson "cuelang.org/go/encoding/json"
 	"cuelang.org/go/pkg/strings"
-	"github.com/grafana/thema/internal/txtartest/vanilla"
 	"github.com/stretchr/testify/require"
+
+	terrors "github.com/grafana/thema/errors"
+	"github.com/grafana/thema/internal/txtartest/vanilla"
 )
 
 // Validation-related test cases look for `*.data.json` files within
@@ -69,3 +72,115 @@ func decodeData(rt *Runtime, inputJSON string) (cue.Value, error) {
 
 	return ctx.BuildExpr(expr), nil
 }
+
+func TestValidationErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := NewRuntime(ctx)
+	lin, err := BindLineage(ctx.CompileString(`name: "valerrs"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		title: string
+		count: int & <10
+		nested: {
+			flag: bool
+		}
+		meta: {
+			note?: string
+		}
+		"a/b"?: string
+		tags: [...string]
+		kind: "a" | "b"
+		items: [...{name: string}]
+	}
+}]`, cue.Filename("valerrs.cue")), rt)
+	require.NoError(t, err)
+
+	data, err := decodeData(rt, `{
+	"count": 12,
+	"nested": {"flag": "yes"},
+	"a/b": 1,
+	"tags": ["x", 2],
+	"kind": "c",
+	"items": [{"name": "x"}, {"name": 3}, {}]
+}`)
+	require.NoError(t, err)
+
+	_, err = lin.First().Validate(data)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, terrors.ErrInvalidData))
+
+	verrs := terrors.ValidationErrors(err)
+	var multi interface{ Errors() []error }
+	require.True(t, errors.As(err, &multi))
+	require.Len(t, verrs, len(multi.Errors()), "each error must have a structured form")
+	got := make(map[string]*terrors.ValidationError)
+	for _, ve := range verrs {
+		require.NotContains(t, got, ve.DataPath, "duplicate error for %s", ve.DataPath)
+		got[ve.DataPath] = ve
+	}
+
+	tt := map[string]struct {
+		code     terrors.ValidationCode
+		sentinel error
+		schpath  string
+		expected string
+		actual   string
+	}{
+		"/count":        {terrors.OutOfBounds, terrors.ErrInvalidOutOfBounds, "count", "<10", "12"},
+		"/nested/flag":  {terrors.KindConflict, terrors.ErrInvalidKindConflict, "nested.flag", "bool", `"yes"`},
+		"/a~1b":         {terrors.KindConflict, terrors.ErrInvalidKindConflict, `"a/b"`, "string", "1"},
+		"/tags/1":       {terrors.KindConflict, terrors.ErrInvalidKindConflict, "tags[_]", "string", "2"},
+		"/kind":         {terrors.OutOfBounds, terrors.ErrInvalidOutOfBounds, "kind", `"a" | "b"`, `"c"`},
+		"/items/1/name": {terrors.KindConflict, terrors.ErrInvalidKindConflict, "items[_].name", "string", "3"},
+		// CUE itself reports absent fields only when there are no other errors
+		"/title":        {terrors.MissingField, terrors.ErrInvalidMissingField, "title", "string", ""},
+		"/items/2/name": {terrors.MissingField, terrors.ErrInvalidMissingField, "items[_].name", "string", ""},
+		"/meta":         {terrors.MissingField, terrors.ErrInvalidMissingField, "meta", "{\n\tnote?: string\n}", ""},
+	}
+	for path, want := range tt {
+		ve, has := got[path]
+		require.True(t, has, "no validation error at %s, got %v", path, got)
+		require.Equal(t, want.code, ve.Code, path)
+		require.True(t, errors.Is(ve, want.sentinel), path)
+		require.True(t, errors.Is(ve, terrors.ErrInvalidData), path)
+		require.Equal(t, want.schpath, ve.SchemaPath, path)
+		require.Equal(t, want.expected, ve.Expected, path)
+		require.Equal(t, want.actual, ve.Actual, path)
+	}
+
+	require.Len(t, got, len(tt))
+
+	data, err = decodeData(rt, `{"title": "foo", "count": 1, "nested": {"flag": true}, "meta": {}, "tags": [], "kind": "a", "items": [], "extra": true}`)
+	require.NoError(t, err)
+	_, err = lin.First().Validate(data)
+	require.Error(t, err)
+	verrs = terrors.ValidationErrors(err)
+	require.Len(t, verrs, 1)
+	require.Equal(t, terrors.ExcessField, verrs[0].Code)
+	require.Equal(t, "/extra", verrs[0].DataPath)
+	require.True(t, errors.Is(verrs[0], terrors.ErrInvalidExcessField))
+
+	data, err = decodeData(rt, `{"count": 1, "nested": {"flag": true}, "meta": {}, "tags": [], "kind": "a", "items": []}`)
+	require.NoError(t, err)
+	_, err = lin.First().Validate(data)
+	require.Error(t, err)
+	verrs = terrors.ValidationErrors(err)
+	require.Len(t, verrs, 1)
+	require.Equal(t, terrors.MissingField, verrs[0].Code)
+	require.Equal(t, "/title", verrs[0].DataPath)
+	require.Equal(t, "string", verrs[0].Expected)
+	require.True(t, errors.Is(verrs[0], terrors.ErrInvalidMissingField))
+
+	b, err := json.Marshal(got["/count"])
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"code":"OutOfBounds"`)
+	require.Contains(t, string(b), `"dataPath":"/count"`)
+
+	// Failures that cannot be classified are still reported
+	verrs = terrors.ValidationErrors(validationFailure{errors.New("something unexpected")})
+	require.Len(t, verrs, 1)
+	require.Equal(t, terrors.Unclassified, verrs[0].Code)
+	require.Equal(t, "something unexpected", verrs[0].Message)
+	require.True(t, errors.Is(verrs[0], terrors.ErrInvalidData))
+}
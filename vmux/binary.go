@@ -0,0 +1,142 @@
+package vmux
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/literal"
+	"cuelang.org/go/cue/token"
+)
+
+var errTruncated = errors.New("unexpected end of input")
+
+// valueAppender is implemented by binary formats that encode the JSON data
+// model, plus byte strings, to allow encoding of CUE values by appendValue.
+type valueAppender interface {
+	appendNull(b []byte) []byte
+	appendBool(b []byte, x bool) []byte
+	appendInt(b []byte, x *big.Int) ([]byte, error)
+	appendFloat(b []byte, x float64) []byte
+	appendString(b []byte, s string) []byte
+	appendBytes(b []byte, x []byte) []byte
+	appendListHeader(b []byte, n int) []byte
+	appendMapHeader(b []byte, n int) []byte
+}
+
+// appendValue appends the encoding of v in the format implemented by w to b.
+//
+// Only regular fields are encoded: optional fields that are absent from v are
+// absent from the output, and are never encoded as null. Ints and floats are
+// kept distinct, such that an integral float (e.g. 1.0) is encoded as a float.
+func appendValue(w valueAppender, b []byte, v cue.Value) ([]byte, error) {
+	v, _ = v.Default()
+	switch v.Kind() {
+	case cue.NullKind:
+		return w.appendNull(b), nil
+	case cue.BoolKind:
+		x, err := v.Bool()
+		if err != nil {
+			return nil, err
+		}
+		return w.appendBool(b, x), nil
+	case cue.IntKind:
+		x, err := v.Int(nil)
+		if err != nil {
+			return nil, err
+		}
+		return w.appendInt(b, x)
+	case cue.FloatKind:
+		x, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return w.appendFloat(b, x), nil
+	case cue.StringKind:
+		x, err := v.String()
+		if err != nil {
+			return nil, err
+		}
+		return w.appendString(b, x), nil
+	case cue.BytesKind:
+		x, err := v.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return w.appendBytes(b, x), nil
+	case cue.ListKind:
+		iter, err := v.List()
+		if err != nil {
+			return nil, err
+		}
+		var elems []cue.Value
+		for iter.Next() {
+			elems = append(elems, iter.Value())
+		}
+		b = w.appendListHeader(b, len(elems))
+		for _, ev := range elems {
+			if b, err = appendValue(w, b, ev); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case cue.StructKind:
+		iter, err := v.Fields()
+		if err != nil {
+			return nil, err
+		}
+		var labels []string
+		var vals []cue.Value
+		for iter.Next() {
+			labels = append(labels, iter.Label())
+			vals = append(vals, iter.Value())
+		}
+		b = w.appendMapHeader(b, len(labels))
+		for i, fv := range vals {
+			b = w.appendString(b, labels[i])
+			if b, err = appendValue(w, b, fv); err != nil {
+				return nil, fmt.Errorf("%s: %w", labels[i], err)
+			}
+		}
+		return b, nil
+	default:
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s: cannot encode non-concrete value", v.Path())
+	}
+}
+
+func intLit(x *big.Int) ast.Expr {
+	return ast.NewLit(token.INT, x.String())
+}
+
+func uintLit(x uint64) ast.Expr {
+	return ast.NewLit(token.INT, strconv.FormatUint(x, 10))
+}
+
+// floatLit returns a CUE float literal for f. Integral values are given a
+// fractional part so that they remain floats when passed through CUE.
+func floatLit(f float64) (ast.Expr, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%v cannot be represented in CUE", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return ast.NewLit(token.FLOAT, s), nil
+}
+
+func bytesLit(b []byte) ast.Expr {
+	return ast.NewLit(token.STRING, literal.Bytes.Quote(string(b)))
+}
+
+func field(label string, v ast.Expr) *ast.Field {
+	return &ast.Field{Label: ast.NewString(label), Value: v}
+}
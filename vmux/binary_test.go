@@ -0,0 +1,248 @@
+package vmux
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"github.com/grafana/thema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type binaryCase struct {
+	hex  string
+	want string
+	kind cue.Kind
+}
+
+func checkBinaryDecode(t *testing.T, codec Codec, table []binaryCase) {
+	t.Helper()
+	ctx := cuecontext.New()
+	for _, tc := range table {
+		b, err := hex.DecodeString(tc.hex)
+		require.NoError(t, err)
+		v, err := codec.Decode(ctx, b)
+		if !assert.NoError(t, err, tc.hex) {
+			continue
+		}
+		require.NoError(t, v.Err(), tc.hex)
+		assert.True(t, v.Equals(ctx.CompileString(tc.want)), "%s: got %v, want %s", tc.hex, v, tc.want)
+		if tc.kind != cue.BottomKind {
+			assert.Equal(t, tc.kind, v.Kind(), tc.hex)
+		}
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	// Mostly drawn from RFC 8949, Appendix A
+	checkBinaryDecode(t, NewCBORCodec("test"), []binaryCase{
+		{"00", "0", cue.IntKind},
+		{"17", "23", cue.IntKind},
+		{"1818", "24", cue.IntKind},
+		{"1903e8", "1000", cue.IntKind},
+		{"1bffffffffffffffff", "18446744073709551615", cue.IntKind},
+		{"c249010000000000000000", "18446744073709551616", cue.IntKind},
+		{"3bffffffffffffffff", "-18446744073709551616", cue.IntKind},
+		{"c349010000000000000000", "-18446744073709551617", cue.IntKind},
+		{"20", "-1", cue.IntKind},
+		{"3903e7", "-1000", cue.IntKind},
+		{"f90000", "0.0", cue.FloatKind},
+		{"f93c00", "1.0", cue.FloatKind},
+		{"fb3ff199999999999a", "1.1", cue.FloatKind},
+		{"f93e00", "1.5", cue.FloatKind},
+		{"f97bff", "65504.0", cue.FloatKind},
+		{"fa47c35000", "100000.0", cue.FloatKind},
+		{"f90001", "5.960464477539063e-8", cue.FloatKind},
+		{"f4", "false", cue.BoolKind},
+		{"f5", "true", cue.BoolKind},
+		{"f6", "null", cue.NullKind},
+		{"4401020304", `'\x01\x02\x03\x04'`, cue.BytesKind},
+		{"6449455446", `"IETF"`, cue.StringKind},
+		{"c074323031332d30332d32315432303a30343a30305a", `"2013-03-21T20:04:00Z"`, cue.StringKind},
+		{"83010203", "[1, 2, 3]", cue.ListKind},
+		{"a26161016162820203", "{a: 1, b: [2, 3]}", cue.StructKind},
+		{"9f018202039f0405ffff", "[1, [2, 3], [4, 5]]", cue.ListKind},
+		{"bf61610161629f0203ffff", "{a: 1, b: [2, 3]}", cue.StructKind},
+		{"5f42010243030405ff", `'\x01\x02\x03\x04\x05'`, cue.BytesKind},
+		{"7f657374726561646d696e67ff", `"streaming"`, cue.StringKind},
+		// An undefined map value is treated as an absent field
+		{"a2616101616df7", "{a: 1}", cue.StructKind},
+	})
+}
+
+func TestCBORDecodeErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	for _, h := range []string{
+		"",           // empty
+		"1a0000",     // truncated argument
+		"830102",     // truncated array
+		"a10102",     // non-text map key
+		"0000",       // trailing input
+		"f7",         // top-level undefined
+		"ff",         // unexpected break
+		"fa7fc00000", // NaN
+		"62c328",     // invalid UTF-8
+	} {
+		b, err := hex.DecodeString(h)
+		require.NoError(t, err)
+		_, err = NewCBORCodec("test").Decode(ctx, b)
+		assert.Error(t, err, h)
+	}
+}
+
+func TestMsgpackDecode(t *testing.T) {
+	checkBinaryDecode(t, NewMsgpackCodec("test"), []binaryCase{
+		{"00", "0", cue.IntKind},
+		{"7f", "127", cue.IntKind},
+		{"cc80", "128", cue.IntKind},
+		{"cdffff", "65535", cue.IntKind},
+		{"cfffffffffffffffff", "18446744073709551615", cue.IntKind},
+		{"ff", "-1", cue.IntKind},
+		{"e0", "-32", cue.IntKind},
+		{"d080", "-128", cue.IntKind},
+		{"d1ff7f", "-129", cue.IntKind},
+		{"d38000000000000000", "-9223372036854775808", cue.IntKind},
+		{"ca3fc00000", "1.5", cue.FloatKind},
+		{"cb3ff0000000000000", "1.0", cue.FloatKind},
+		{"c2", "false", cue.BoolKind},
+		{"c3", "true", cue.BoolKind},
+		{"c0", "null", cue.NullKind},
+		{"a3666f6f", `"foo"`, cue.StringKind},
+		{"d903666f6f", `"foo"`, cue.StringKind},
+		{"c4020102", `'\x01\x02'`, cue.BytesKind},
+		{"93010203", "[1, 2, 3]", cue.ListKind},
+		{"dc0002c0c3", "[null, true]", cue.ListKind},
+		{"82a16101a16292cb400000000000000003", "{a: 1, b: [2.0, 3]}", cue.StructKind},
+		{"81a16fc0", "{o: null}", cue.StructKind},
+	})
+}
+
+func TestMsgpackDecodeErrors(t *testing.T) {
+	ctx := cuecontext.New()
+	for _, h := range []string{
+		"",         // empty
+		"cd00",     // truncated uint16
+		"92010203", // trailing input
+		"9301",     // truncated array
+		"810102",   // non-string map key
+		"c1",       // never used
+		"d40100",   // fixext
+		"a2c328",   // invalid UTF-8
+	} {
+		b, err := hex.DecodeString(h)
+		require.NoError(t, err)
+		_, err = NewMsgpackCodec("test").Decode(ctx, b)
+		assert.Error(t, err, h)
+	}
+}
+
+func TestBinaryEncode(t *testing.T) {
+	ctx := cuecontext.New()
+	v := ctx.CompileString(`{a: 1, b: [2, 3.0], c: null, d: 'x', e: -200}`)
+
+	b, err := NewCBORCodec("test").Encode(v)
+	require.NoError(t, err)
+	assert.Equal(t, "a561610161628202fb40080000000000006163f661644178616538c7", hex.EncodeToString(b))
+
+	b, err = NewMsgpackCodec("test").Encode(v)
+	require.NoError(t, err)
+	assert.Equal(t, "85a16101a1629202cb4008000000000000a163c0a164c40178a165d1ff38", hex.EncodeToString(b))
+
+	_, err = NewCBORCodec("test").Encode(ctx.CompileString(`{a: int}`))
+	assert.Error(t, err, "non-concrete values cannot be encoded")
+	_, err = NewMsgpackCodec("test").Encode(ctx.CompileString(`18446744073709551616`))
+	assert.Error(t, err, "ints beyond uint64 cannot be encoded in MessagePack")
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	ctx := cuecontext.New()
+	long := strings.Repeat("x", 70000)
+	vals := []string{
+		`{
+			i: 9223372036854775807
+			n: -9223372036854775808
+			u: 18446744073709551615
+			f: 2.0
+			g: -0.5
+			s: "short"
+			m: "` + strings.Repeat("m", 40) + `"
+			l: "` + long + `"
+			b: '\x00\xff'
+			nul: null
+			t: true
+			list: [` + strings.Repeat("1, ", 20) + `1]
+			nested: {x: [{y: null}], "z w": "q"}
+		}`,
+		`[` + strings.Repeat(`{a: 1}, `, 300) + `{}]`,
+	}
+
+	for name, codec := range map[string]Codec{"cbor": NewCBORCodec("test"), "msgpack": NewMsgpackCodec("test")} {
+		for i, str := range vals {
+			v := ctx.CompileString(str)
+			b, err := codec.Encode(v)
+			require.NoError(t, err, "%s/%d", name, i)
+			got, err := codec.Decode(ctx, b)
+			require.NoError(t, err, "%s/%d", name, i)
+			assert.True(t, got.Equals(v), "%s/%d: value changed in round trip", name, i)
+			if f := got.LookupPath(cue.ParsePath("f")); f.Exists() {
+				assert.Equal(t, cue.FloatKind, f.Kind(), "%s/%d: integral float decoded as int", name, i)
+			}
+		}
+	}
+
+	big := ctx.CompileString(`{a: 18446744073709551616, b: -18446744073709551617}`)
+	b, err := NewCBORCodec("test").Encode(big)
+	require.NoError(t, err)
+	got, err := NewCBORCodec("test").Decode(ctx, b)
+	require.NoError(t, err)
+	assert.True(t, got.Equals(big))
+}
+
+func TestBinaryNullAndAbsent(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	lin, err := thema.BindLineage(ctx.CompileString(`name: "nullabs"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		req:  string
+		opt?: null | string
+		def:  int | *3
+	}
+}]`), rt)
+	require.NoError(t, err)
+	sch := lin.First()
+
+	for name, codec := range map[string]Codec{"cbor": NewCBORCodec("test"), "msgpack": NewMsgpackCodec("test")} {
+		t.Run(name, func(t *testing.T) {
+			mux := NewUntypedMux(sch, codec)
+
+			b := e(codec.Encode(ctx.CompileString(`{req: "a", opt: null, def: 4}`))).Err(t)
+			inst, _, err := mux(b)
+			require.NoError(t, err)
+			opt := inst.Underlying().LookupPath(cue.ParsePath("opt"))
+			require.True(t, opt.Exists(), "explicit null must not be dropped")
+			assert.Equal(t, cue.NullKind, opt.Kind())
+
+			out := e(codec.Encode(inst.Underlying())).Err(t)
+			assert.Equal(t, b, out)
+
+			b = e(codec.Encode(ctx.CompileString(`{req: "a"}`))).Err(t)
+			inst, _, err = mux(b)
+			require.NoError(t, err)
+			assert.False(t, inst.Underlying().LookupPath(cue.ParsePath("opt")).Exists(), "absent field must not become null")
+
+			hyd := inst.Hydrate()
+			def, err := hyd.Underlying().LookupPath(cue.ParsePath("def")).Int64()
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), def)
+			out = e(codec.Encode(hyd.Underlying())).Err(t)
+			got := e(codec.Decode(ctx, out)).Err(t)
+			assert.True(t, got.Equals(ctx.CompileString(`{req: "a", def: 3}`)), "got %v", got)
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package vmux
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"unicode/utf8"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+)
+
+// CBOR major types.
+const (
+	cborUint byte = iota
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+const (
+	cborFalse     = 20
+	cborTrue      = 21
+	cborNull      = 22
+	cborUndefined = 23
+	cborFloat16   = 25
+	cborFloat32   = 26
+	cborFloat64   = 27
+	cborIndef     = 31
+
+	cborTagPosBignum = 2
+	cborTagNegBignum = 3
+)
+
+var errCBORUndefined = errors.New("undefined cannot be represented in CUE")
+
+type cborCodec struct {
+	path string
+}
+
+// NewCBORCodec creates a [Codec] that decodes from and encodes to CBOR
+// (RFC 8949) []byte.
+//
+// CBOR integers, including bignums, decode to CUE ints, and CBOR floats of
+// any width decode to CUE floats, even where their value is integral. Byte
+// strings decode to CUE bytes, and text strings to CUE strings. Map keys must
+// be text strings. Indefinite-length items are supported, and tags other than
+// bignums are ignored.
+//
+// A null decodes to a CUE null, which is distinct from an absent field. A map
+// entry whose value is undefined is treated as if it were absent.
+//
+// The provided path is used to prefix errors produced by the decoder.
+func NewCBORCodec(path string) Codec {
+	return cborCodec{
+		path: path,
+	}
+}
+
+func (c cborCodec) Decode(ctx *cue.Context, data []byte) (cue.Value, error) {
+	r := &cborReader{b: data}
+	expr, err := r.value()
+	if err == nil && len(r.b) > 0 {
+		err = fmt.Errorf("%d bytes of unexpected trailing input", len(r.b))
+	}
+	if err == errCBORUndefined {
+		err = errors.New("top-level value is undefined")
+	}
+	if err != nil {
+		if c.path != "" {
+			return cue.Value{}, fmt.Errorf("%s: invalid CBOR: %w", c.path, err)
+		}
+		return cue.Value{}, fmt.Errorf("invalid CBOR: %w", err)
+	}
+	return ctx.BuildExpr(expr), nil
+}
+
+func (c cborCodec) Encode(v cue.Value) ([]byte, error) {
+	return appendValue(cborAppender{}, nil, v)
+}
+
+type cborReader struct {
+	b []byte
+}
+
+func (r *cborReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.b)) {
+		return nil, errTruncated
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+// head reads the initial byte and argument of a data item. indef reports
+// whether the item has indefinite length.
+func (r *cborReader) head() (major byte, info byte, arg uint64, indef bool, err error) {
+	if len(r.b) == 0 {
+		return 0, 0, 0, false, errTruncated
+	}
+	major, info = r.b[0]>>5, r.b[0]&0x1f
+	r.b = r.b[1:]
+
+	switch {
+	case info < 24:
+		return major, info, uint64(info), false, nil
+	case info == cborIndef:
+		switch major {
+		case cborBytes, cborText, cborArray, cborMap, cborSimple:
+			return major, info, 0, true, nil
+		}
+	case info <= 27:
+		var b []byte
+		if b, err = r.next(1 << (info - 24)); err != nil {
+			return
+		}
+		switch len(b) {
+		case 1:
+			arg = uint64(b[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(b))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(b))
+		default:
+			arg = binary.BigEndian.Uint64(b)
+		}
+		return major, info, arg, false, nil
+	}
+	return 0, 0, 0, false, fmt.Errorf("invalid initial byte 0x%x", major<<5|info)
+}
+
+// isBreak reports whether the next byte is the "break" stop code, consuming it
+// if so.
+func (r *cborReader) isBreak() (bool, error) {
+	if len(r.b) == 0 {
+		return false, errTruncated
+	}
+	if r.b[0] == cborSimple<<5|cborIndef {
+		r.b = r.b[1:]
+		return true, nil
+	}
+	return false, nil
+}
+
+// str reads the content of a byte or text string of the provided major type.
+func (r *cborReader) str(major byte, n uint64, indef bool) ([]byte, error) {
+	if !indef {
+		return r.next(n)
+	}
+
+	// An indefinite-length string is a sequence of definite-length chunks of
+	// the same major type, terminated by a break.
+	var b []byte
+	for {
+		brk, err := r.isBreak()
+		if err != nil {
+			return nil, err
+		}
+		if brk {
+			return b, nil
+		}
+		cmaj, _, cn, cindef, err := r.head()
+		if err != nil {
+			return nil, err
+		}
+		if cmaj != major || cindef {
+			return nil, errors.New("invalid chunk in indefinite-length string")
+		}
+		chunk, err := r.next(cn)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, chunk...)
+	}
+}
+
+// items calls fn n times, or until a break if indef is true.
+func (r *cborReader) items(n uint64, indef bool, fn func() error) error {
+	if !indef && n > uint64(len(r.b)) {
+		// Every item takes at least one byte
+		return errTruncated
+	}
+	for i := uint64(0); indef || i < n; i++ {
+		if indef {
+			brk, err := r.isBreak()
+			if err != nil {
+				return err
+			}
+			if brk {
+				return nil
+			}
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *cborReader) value() (ast.Expr, error) {
+	major, info, arg, indef, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUint:
+		return uintLit(arg), nil
+	case cborNegInt:
+		x := new(big.Int).SetUint64(arg)
+		return intLit(x.Not(x)), nil
+	case cborBytes:
+		b, err := r.str(major, arg, indef)
+		if err != nil {
+			return nil, err
+		}
+		return bytesLit(b), nil
+	case cborText:
+		b, err := r.str(major, arg, indef)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(b) {
+			return nil, errors.New("text string is not valid UTF-8")
+		}
+		return ast.NewString(string(b)), nil
+	case cborArray:
+		lst := &ast.ListLit{}
+		err := r.items(arg, indef, func() error {
+			x, err := r.value()
+			lst.Elts = append(lst.Elts, x)
+			return err
+		})
+		return lst, err
+	case cborMap:
+		st := &ast.StructLit{}
+		err := r.items(arg, indef, func() error {
+			kmaj, _, kn, kindef, err := r.head()
+			if err != nil {
+				return err
+			}
+			if kmaj != cborText {
+				return errors.New("map keys must be text strings")
+			}
+			k, err := r.str(kmaj, kn, kindef)
+			if err != nil {
+				return err
+			}
+			x, err := r.value()
+			if err == errCBORUndefined {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			st.Elts = append(st.Elts, field(string(k), x))
+			return nil
+		})
+		return st, err
+	case cborTag:
+		if arg != cborTagPosBignum && arg != cborTagNegBignum {
+			return r.value()
+		}
+		bmaj, _, bn, bindef, err := r.head()
+		if err != nil {
+			return nil, err
+		}
+		if bmaj != cborBytes {
+			return nil, errors.New("bignum content must be a byte string")
+		}
+		b, err := r.str(bmaj, bn, bindef)
+		if err != nil {
+			return nil, err
+		}
+		x := new(big.Int).SetBytes(b)
+		if arg == cborTagNegBignum {
+			x.Not(x)
+		}
+		return intLit(x), nil
+	default:
+		switch info {
+		case cborFalse:
+			return ast.NewBool(false), nil
+		case cborTrue:
+			return ast.NewBool(true), nil
+		case cborNull:
+			return ast.NewNull(), nil
+		case cborUndefined:
+			return nil, errCBORUndefined
+		case cborFloat16:
+			return floatLit(float16(uint16(arg)))
+		case cborFloat32:
+			return floatLit(float64(math.Float32frombits(uint32(arg))))
+		case cborFloat64:
+			return floatLit(math.Float64frombits(arg))
+		case cborIndef:
+			return nil, errors.New("unexpected break")
+		default:
+			return nil, fmt.Errorf("unsupported simple value %d", arg)
+		}
+	}
+}
+
+// float16 converts an IEEE 754 half-precision float to a float64.
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
+
+type cborAppender struct{}
+
+func (cborAppender) head(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major<<5|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major<<5|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major<<5|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major<<5|27), n)
+	}
+}
+
+func (cborAppender) appendNull(b []byte) []byte {
+	return append(b, cborSimple<<5|cborNull)
+}
+
+func (cborAppender) appendBool(b []byte, x bool) []byte {
+	if x {
+		return append(b, cborSimple<<5|cborTrue)
+	}
+	return append(b, cborSimple<<5|cborFalse)
+}
+
+func (a cborAppender) appendInt(b []byte, x *big.Int) ([]byte, error) {
+	major, tag := cborUint, uint64(cborTagPosBignum)
+	if x.Sign() < 0 {
+		// Negative integers are encoded as -1-x
+		major, tag = cborNegInt, cborTagNegBignum
+		x = new(big.Int).Not(x)
+	}
+	if x.IsUint64() {
+		return a.head(b, major, x.Uint64()), nil
+	}
+	b = a.head(b, cborTag, tag)
+	return a.appendBytes(b, x.Bytes()), nil
+}
+
+func (cborAppender) appendFloat(b []byte, x float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, cborSimple<<5|cborFloat64), math.Float64bits(x))
+}
+
+func (a cborAppender) appendString(b []byte, s string) []byte {
+	return append(a.head(b, cborText, uint64(len(s))), s...)
+}
+
+func (a cborAppender) appendBytes(b []byte, x []byte) []byte {
+	return append(a.head(b, cborBytes, uint64(len(x))), x...)
+}
+
+func (a cborAppender) appendListHeader(b []byte, n int) []byte {
+	return a.head(b, cborArray, uint64(n))
+}
+
+func (a cborAppender) appendMapHeader(b []byte, n int) []byte {
+	return a.head(b, cborMap, uint64(n))
+}
@@ -0,0 +1,369 @@
+package vmux
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"unicode/utf8"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/ast"
+)
+
+// MessagePack format bytes, excluding the fixed-size ranges.
+const (
+	mpNil      byte = 0xc0
+	mpFalse    byte = 0xc2
+	mpTrue     byte = 0xc3
+	mpBin8     byte = 0xc4
+	mpBin16    byte = 0xc5
+	mpBin32    byte = 0xc6
+	mpExt8     byte = 0xc7
+	mpExt32    byte = 0xc9
+	mpFloat32  byte = 0xca
+	mpFloat64  byte = 0xcb
+	mpUint8    byte = 0xcc
+	mpUint16   byte = 0xcd
+	mpUint32   byte = 0xce
+	mpUint64   byte = 0xcf
+	mpInt8     byte = 0xd0
+	mpInt16    byte = 0xd1
+	mpInt32    byte = 0xd2
+	mpInt64    byte = 0xd3
+	mpFixExt1  byte = 0xd4
+	mpFixExt16 byte = 0xd8
+	mpStr8     byte = 0xd9
+	mpStr16    byte = 0xda
+	mpStr32    byte = 0xdb
+	mpArray16  byte = 0xdc
+	mpArray32  byte = 0xdd
+	mpMap16    byte = 0xde
+	mpMap32    byte = 0xdf
+
+	mpFixMap   byte = 0x80
+	mpFixArray byte = 0x90
+	mpFixStr   byte = 0xa0
+)
+
+type msgpackCodec struct {
+	path string
+}
+
+// NewMsgpackCodec creates a [Codec] that decodes from and encodes to
+// MessagePack []byte.
+//
+// MessagePack integers decode to CUE ints, and floats of either width decode
+// to CUE floats, even where their value is integral. The bin family decodes to
+// CUE bytes, and the str family to CUE strings. Map keys must be strings.
+// Extension types are not supported.
+//
+// A nil decodes to a CUE null, which is distinct from an absent field.
+//
+// The provided path is used to prefix errors produced by the decoder.
+func NewMsgpackCodec(path string) Codec {
+	return msgpackCodec{
+		path: path,
+	}
+}
+
+func (c msgpackCodec) Decode(ctx *cue.Context, data []byte) (cue.Value, error) {
+	r := &msgpackReader{b: data}
+	expr, err := r.value()
+	if err == nil && len(r.b) > 0 {
+		err = fmt.Errorf("%d bytes of unexpected trailing input", len(r.b))
+	}
+	if err != nil {
+		if c.path != "" {
+			return cue.Value{}, fmt.Errorf("%s: invalid MessagePack: %w", c.path, err)
+		}
+		return cue.Value{}, fmt.Errorf("invalid MessagePack: %w", err)
+	}
+	return ctx.BuildExpr(expr), nil
+}
+
+func (c msgpackCodec) Encode(v cue.Value) ([]byte, error) {
+	return appendValue(msgpackAppender{}, nil, v)
+}
+
+type msgpackReader struct {
+	b []byte
+}
+
+func (r *msgpackReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.b)) {
+		return nil, errTruncated
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (r *msgpackReader) uint(size int) (uint64, error) {
+	b, err := r.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// length reads the length of a str, bin, array or map, which is a fixed-size
+// prefix for format bytes with a base of first, first+1 and first+2.
+func (r *msgpackReader) length(f, first byte) (uint64, error) {
+	return r.uint(1 << (f - first))
+}
+
+// str reads a string whose format byte has already been consumed, for use
+// with map keys.
+func (r *msgpackReader) str(f byte) (string, error) {
+	var n uint64
+	var err error
+	switch {
+	case f&0xe0 == mpFixStr:
+		n = uint64(f & 0x1f)
+	case f >= mpStr8 && f <= mpStr32:
+		if n, err = r.length(f, mpStr8); err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("map keys must be strings")
+	}
+	b, err := r.next(n)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errors.New("string is not valid UTF-8")
+	}
+	return string(b), nil
+}
+
+func (r *msgpackReader) value() (ast.Expr, error) {
+	if len(r.b) == 0 {
+		return nil, errTruncated
+	}
+	f := r.b[0]
+	r.b = r.b[1:]
+
+	switch {
+	case f <= 0x7f:
+		return uintLit(uint64(f)), nil
+	case f >= 0xe0:
+		return intLit(big.NewInt(int64(int8(f)))), nil
+	case f&0xe0 == mpFixStr, f >= mpStr8 && f <= mpStr32:
+		s, err := r.str(f)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewString(s), nil
+	case f&0xf0 == mpFixArray:
+		return r.array(uint64(f & 0x0f))
+	case f&0xf0 == mpFixMap:
+		return r.mapp(uint64(f & 0x0f))
+	}
+
+	switch f {
+	case mpNil:
+		return ast.NewNull(), nil
+	case mpFalse:
+		return ast.NewBool(false), nil
+	case mpTrue:
+		return ast.NewBool(true), nil
+	case mpBin8, mpBin16, mpBin32:
+		n, err := r.length(f, mpBin8)
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return bytesLit(b), nil
+	case mpFloat32:
+		x, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return floatLit(float64(math.Float32frombits(uint32(x))))
+	case mpFloat64:
+		x, err := r.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return floatLit(math.Float64frombits(x))
+	case mpUint8, mpUint16, mpUint32, mpUint64:
+		x, err := r.uint(1 << (f - mpUint8))
+		if err != nil {
+			return nil, err
+		}
+		return uintLit(x), nil
+	case mpInt8, mpInt16, mpInt32, mpInt64:
+		size := 1 << (f - mpInt8)
+		x, err := r.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return intLit(big.NewInt(int64(x<<shift) >> shift)), nil
+	case mpArray16, mpArray32:
+		n, err := r.uint(2 << (f - mpArray16))
+		if err != nil {
+			return nil, err
+		}
+		return r.array(n)
+	case mpMap16, mpMap32:
+		n, err := r.uint(2 << (f - mpMap16))
+		if err != nil {
+			return nil, err
+		}
+		return r.mapp(n)
+	}
+
+	if f >= mpExt8 && f <= mpExt32 || f >= mpFixExt1 && f <= mpFixExt16 {
+		return nil, errors.New("extension types are not supported")
+	}
+	return nil, fmt.Errorf("invalid format byte 0x%x", f)
+}
+
+func (r *msgpackReader) array(n uint64) (ast.Expr, error) {
+	if n > uint64(len(r.b)) {
+		// Every element takes at least one byte
+		return nil, errTruncated
+	}
+	lst := &ast.ListLit{}
+	for ; n > 0; n-- {
+		x, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		lst.Elts = append(lst.Elts, x)
+	}
+	return lst, nil
+}
+
+func (r *msgpackReader) mapp(n uint64) (ast.Expr, error) {
+	if n > uint64(len(r.b)) {
+		return nil, errTruncated
+	}
+	st := &ast.StructLit{}
+	for ; n > 0; n-- {
+		if len(r.b) == 0 {
+			return nil, errTruncated
+		}
+		f := r.b[0]
+		r.b = r.b[1:]
+		k, err := r.str(f)
+		if err != nil {
+			return nil, err
+		}
+		x, err := r.value()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		st.Elts = append(st.Elts, field(k, x))
+	}
+	return st, nil
+}
+
+type msgpackAppender struct{}
+
+// sized appends the smallest of the format bytes f8, f16 and f32 whose length
+// prefix can hold n, followed by n. f8 is zero for arrays and maps, which have
+// no 8 bit variant.
+func (msgpackAppender) sized(b []byte, f8, f16, f32 byte, n int) []byte {
+	switch {
+	case f8 != 0 && n <= math.MaxUint8:
+		return append(b, f8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, f16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, f32), uint32(n))
+	}
+}
+
+func (msgpackAppender) appendNull(b []byte) []byte {
+	return append(b, mpNil)
+}
+
+func (msgpackAppender) appendBool(b []byte, x bool) []byte {
+	if x {
+		return append(b, mpTrue)
+	}
+	return append(b, mpFalse)
+}
+
+func (msgpackAppender) appendInt(b []byte, x *big.Int) ([]byte, error) {
+	if !x.IsInt64() {
+		if x.IsUint64() {
+			return binary.BigEndian.AppendUint64(append(b, mpUint64), x.Uint64()), nil
+		}
+		return nil, fmt.Errorf("%s overflows MessagePack integer", x)
+	}
+
+	i := x.Int64()
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		return append(b, byte(i)), nil
+	case i >= -32 && i < 0:
+		return append(b, byte(int8(i))), nil
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, mpUint8, byte(i)), nil
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, mpUint16), uint16(i)), nil
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, mpUint32), uint32(i)), nil
+	case i >= 0:
+		return binary.BigEndian.AppendUint64(append(b, mpUint64), uint64(i)), nil
+	case i >= math.MinInt8:
+		return append(b, mpInt8, byte(int8(i))), nil
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, mpInt16), uint16(int16(i))), nil
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, mpInt32), uint32(int32(i))), nil
+	default:
+		return binary.BigEndian.AppendUint64(append(b, mpInt64), uint64(i)), nil
+	}
+}
+
+func (msgpackAppender) appendFloat(b []byte, x float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, mpFloat64), math.Float64bits(x))
+}
+
+func (a msgpackAppender) appendString(b []byte, s string) []byte {
+	if len(s) < 32 {
+		b = append(b, mpFixStr|byte(len(s)))
+	} else {
+		b = a.sized(b, mpStr8, mpStr16, mpStr32, len(s))
+	}
+	return append(b, s...)
+}
+
+func (a msgpackAppender) appendBytes(b []byte, x []byte) []byte {
+	return append(a.sized(b, mpBin8, mpBin16, mpBin32, len(x)), x...)
+}
+
+func (a msgpackAppender) appendListHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, mpFixArray|byte(n))
+	}
+	return a.sized(b, 0, mpArray16, mpArray32, n)
+}
+
+func (a msgpackAppender) appendMapHeader(b []byte, n int) []byte {
+	if n < 16 {
+		return append(b, mpFixMap|byte(n))
+	}
+	return a.sized(b, 0, mpMap16, mpMap32, n)
+}
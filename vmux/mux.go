@@ -9,6 +9,8 @@ import (
 	"cuelang.org/go/encoding/yaml"
 	pyaml "cuelang.org/go/pkg/encoding/yaml"
 	"github.com/grafana/thema"
+	"github.com/grafana/thema/encoding/avro"
+	"github.com/grafana/thema/encoding/protobuf"
 )
 
 func allvstr(sch thema.Schema) string {
@@ -99,3 +101,36 @@ func (e yamlCodec) Encode(v cue.Value) ([]byte, error) {
 	s, err := pyaml.Marshal(v)
 	return []byte(s), err
 }
+
+// NewProtobufCodec creates a [Codec] that decodes from and encodes to protobuf
+// wire bytes, using the message representation of the provided schema that is
+// produced by [protobuf.GenerateSchema].
+//
+// Protobuf wire bytes carry no information about the schema they were encoded
+// against. The returned Codec can decode bytes produced for any schema within
+// the same major version as sch, but not for other major versions. See
+// [protobuf.NewCodec] for details.
+func NewProtobufCodec(sch thema.Schema) (Codec, error) {
+	return protobuf.NewCodec(sch)
+}
+
+// NewAvroCodec creates a [Codec] that decodes from and encodes to a single
+// datum in the Avro binary encoding, using the record schema for the provided
+// schema that is produced by [avro.GenerateSchema].
+//
+// The Avro binary encoding carries no information about the schema it was
+// encoded against, so the returned Codec can only decode bytes produced for
+// sch itself. Use [NewAvroOCFCodec] to decode data written for other schemas.
+func NewAvroCodec(sch thema.Schema) (Codec, error) {
+	return avro.NewCodec(sch)
+}
+
+// NewAvroOCFCodec creates a [Codec] that decodes from and encodes to Avro
+// object container files containing a single datum.
+//
+// Object container files embed their writer schema, so the returned Codec can
+// decode files produced for any schema, including those for other versions of
+// sch's lineage. See [avro.NewOCFCodec] for details.
+func NewAvroOCFCodec(sch thema.Schema) (Codec, error) {
+	return avro.NewOCFCodec(sch)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"testing"
 
+	"cuelang.org/go/cue"
 	"cuelang.org/go/cue/cuecontext"
 	"github.com/grafana/thema"
 	"github.com/grafana/thema/exemplars"
@@ -299,3 +300,108 @@ func handleLE(t *testing.T, im image, lac thema.TranslationLacunas, err error) {
 	// TODO For now, pass this off to require. Totally needs special handling, though
 	// require.EqualValues(t, im.lac, lac)
 }
+
+func TestProtobufCodec(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	lin := e(exemplars.RenameLineage(rt)).Err(t)
+	sch := e(lin.Schema(thema.SV(1, 0))).Err(t)
+	codec := e(NewProtobufCodec(sch)).Err(t)
+
+	b := e(codec.Encode(ctx.CompileString(`{after: "renamedstr", unchanged: "unchanged str val"}`))).Err(t)
+	inst, lac, err := NewUntypedMux(sch, codec)(b)
+	require.NoError(t, err)
+	require.Empty(t, lac)
+	require.Equal(t, thema.SV(1, 0), inst.Schema().Version())
+
+	out := e(codec.Encode(inst.Underlying())).Err(t)
+	require.Equal(t, b, out)
+}
+
+func TestAvroCodec(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	lin := e(exemplars.RenameLineage(rt)).Err(t)
+	sch := e(lin.Schema(thema.SV(1, 0))).Err(t)
+	codec := e(NewAvroCodec(sch)).Err(t)
+
+	b := e(codec.Encode(ctx.CompileString(`{after: "renamedstr", unchanged: "unchanged str val"}`))).Err(t)
+	inst, lac, err := NewUntypedMux(sch, codec)(b)
+	require.NoError(t, err)
+	require.Empty(t, lac)
+	require.Equal(t, thema.SV(1, 0), inst.Schema().Version())
+
+	out := e(codec.Encode(inst.Underlying())).Err(t)
+	require.Equal(t, b, out)
+}
+
+func TestAvroOCFCodecAcrossVersions(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+
+	lin := e(exemplars.RenameLineage(rt)).Err(t)
+	sch0 := e(lin.Schema(thema.SV(0, 0))).Err(t)
+	sch1 := e(lin.Schema(thema.SV(1, 0))).Err(t)
+	codec0 := e(NewAvroOCFCodec(sch0)).Err(t)
+	codec1 := e(NewAvroOCFCodec(sch1)).Err(t)
+
+	// The file embeds the 0.0 writer schema, so the 1.0 codec can decode it
+	b := e(codec0.Encode(ctx.CompileString(`{before: "foo", unchanged: "bar"}`))).Err(t)
+	inst, _, err := NewUntypedMux(sch1, codec1)(b)
+	require.NoError(t, err)
+	require.Equal(t, thema.SV(1, 0), inst.Schema().Version())
+
+	after, err := inst.Underlying().LookupPath(cue.ParsePath("after")).String()
+	require.NoError(t, err)
+	require.Equal(t, "foo", after)
+}
+
+func TestRegistryMux(t *testing.T) {
+	ctx := cuecontext.New()
+	rt := thema.NewRuntime(ctx)
+	bind := func(str string) thema.Lineage {
+		return e(thema.BindLineage(ctx.CompileString(str), rt)).Err(t)
+	}
+	ship := bind(`name: "ship"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		kind: "ship"
+		name: string
+	}
+}, {
+	version: [0, 1]
+	schema: {
+		kind: "ship"
+		name: string
+		crew?: int
+	}
+}]`)
+	port := bind(`name: "port"
+schemas: [{
+	version: [0, 0]
+	schema: {
+		kind: "port"
+		city: string
+	}
+}]`)
+	require.NoError(t, rt.Registry().Register(ship, port))
+
+	mux := NewRegistryMux(rt.Registry(), thema.FieldDiscriminator("kind", ""), NewJSONCodec("test"), map[string]thema.SyntacticVersion{
+		"ship": thema.SV(0, 1),
+	})
+
+	inst, _, err := mux([]byte(`{"kind": "port", "city": "Oslo"}`))
+	require.NoError(t, err)
+	require.Equal(t, "port", inst.Schema().Lineage().Name())
+
+	inst, _, err = mux([]byte(`{"kind": "ship", "name": "Argo"}`))
+	require.NoError(t, err)
+	require.Equal(t, "ship", inst.Schema().Lineage().Name())
+	require.Equal(t, thema.SV(0, 1), inst.Schema().Version())
+
+	_, _, err = mux([]byte(`{"kind": "dock"}`))
+	require.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package vmux
+
+import (
+	"fmt"
+
+	"github.com/grafana/thema"
+)
+
+// NewRegistryMux creates an [UntypedMux] that accepts data that is an instance
+// of any lineage in the provided [thema.Registry].
+//
+// When the returned mux func is called, it will:
+//
+//   - Decode the input []byte using the provided [Decoder], then
+//   - Identify the lineage and schema of the result with [thema.Registry.Validate], using the provided [thema.Discriminator], then
+//   - Call [thema.Instance.Translate] on the result, to the version in targets for the identified lineage, then
+//   - Return the resulting [thema.Instance], [thema.TranslationLacunas], and error
+//
+// Lineages without an entry in targets are translated to their latest schema.
+// Lineages registered after the mux is created are also accepted.
+//
+// The returned error may be from any of the above steps.
+func NewRegistryMux(reg *thema.Registry, disc thema.Discriminator, dec Decoder, targets map[string]thema.SyntacticVersion) UntypedMux {
+	ctx := reg.Runtime().Context()
+
+	return func(b []byte) (*thema.Instance, thema.TranslationLacunas, error) {
+		v, err := dec.Decode(ctx, b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		inst, err := reg.Validate(v, disc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lin := inst.Schema().Lineage()
+		to, has := targets[lin.Name()]
+		if !has {
+			to = thema.LatestVersion(lin)
+		} else if _, err = lin.Schema(to); err != nil {
+			return nil, nil, fmt.Errorf("invalid target version for lineage %s: %w", lin.Name(), err)
+		}
+		if inst.Schema().Version() == to {
+			return inst, nil, nil
+		}
+		return inst.Translate(to)
+	}
+}
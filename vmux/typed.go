@@ -16,7 +16,7 @@ type TypedMux[T thema.Assignee] func(b []byte) (*thema.TypedInstance[T], thema.T
 //
 //   - Decode the input []byte using the provided [Decoder], then
 //   - Pass the result to [thema.TypedSchema.ValidateTyped], then
-//   - Call [thema.Instance.Translate] on the result, to the version of the provided [thema.TypedSchema], then
+//   - Translate the result to the version of the provided [thema.TypedSchema] with a [thema.TypedTranslator], then
 //   - Return the resulting [thema.TypedInstance], [thema.TranslationLacunas], and error
 //
 // The returned error may be from any of the above steps.
@@ -24,6 +24,7 @@ func NewTypedMux[T thema.Assignee](sch thema.TypedSchema[T], dec Decoder) TypedM
 	ctx := sch.Lineage().Underlying().Context()
 	// Prepare no-match error string once for reuse
 	vstring := allvstr(sch)
+	tt := thema.NewTypedTranslator(sch)
 
 	return func(b []byte) (*thema.TypedInstance[T], thema.TranslationLacunas, error) {
 		v, err := dec.Decode(ctx, b)
@@ -48,17 +49,7 @@ func NewTypedMux[T thema.Assignee](sch thema.TypedSchema[T], dec Decoder) TypedM
 			}
 
 			if inst, ierr := isch.Validate(v); ierr == nil {
-				trinst, lac, err := inst.Translate(sch.Version())
-				if err != nil {
-					return nil, nil, err
-				}
-
-				// TODO perf: introduce a typed translator to avoid wastefully re-binding the go type every time
-				tinst, err := thema.BindInstanceType(trinst, sch)
-				if err != nil {
-					panic(fmt.Errorf("unreachable, instance type should always be bindable: %w", err))
-				}
-				return tinst, lac, nil
+				return tt.Translate(inst)
 			}
 		}
 